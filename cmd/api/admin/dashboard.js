@@ -0,0 +1,158 @@
+"use strict";
+
+// The dashboard polls GET /v1/admin/dashboard with the token of an admin
+// user, kept in session storage so it's forgotten when the tab is closed.
+
+const tokenKey = "greenlight-admin-token";
+const pollInterval = 5000;
+let timer;
+
+function $(id) {
+	return document.getElementById(id);
+}
+
+function cell(row, text) {
+	const td = document.createElement("td");
+	td.textContent = text;
+	row.appendChild(td);
+	return td;
+}
+
+function tiles(dl, values) {
+	dl.replaceChildren();
+	for (const [name, value] of Object.entries(values)) {
+		const div = document.createElement("div");
+		const dt = document.createElement("dt");
+		const dd = document.createElement("dd");
+		dt.textContent = name.replaceAll("_", " ");
+		dd.textContent = value ?? "–";
+		div.append(dt, dd);
+		dl.appendChild(div);
+	}
+}
+
+function sum(counts) {
+	return Object.values(counts ?? {}).reduce((total, n) => total + n, 0);
+}
+
+function render(d) {
+	$("status").textContent = d.status;
+	$("status").className = "status " + d.status;
+
+	const users = d.counts.users;
+	tiles($("counts"), {
+		movies: d.counts.movies,
+		users: users.total,
+		activated_users: users.activated,
+		pending_activation: users.pending_activation,
+		deactivated_users: users.deactivated,
+	});
+
+	const m = d.metrics;
+	const byStatus = {};
+	for (const classes of Object.values(m.responses_by_status ?? {})) {
+		for (const [class_, n] of Object.entries(classes)) {
+			byStatus[class_] = (byStatus[class_] ?? 0) + n;
+		}
+	}
+	tiles($("metrics"), {
+		requests_received: m.total_requests_received,
+		requests_in_flight: m.requests_in_flight,
+		"4xx_responses": byStatus["4xx"] ?? 0,
+		"5xx_responses": byStatus["5xx"] ?? 0,
+		requests_shed: m.requests_shed,
+		panics_recovered: sum(m.panics_recovered),
+		slow_queries: sum(m.db_slow_queries),
+		jobs_queued: m.jobs_queued,
+		token_cache_hit_rate: Math.round(m.token_cache_hit_rate * 100) + "%",
+		goroutines: m.goroutines,
+	});
+
+	const components = $("components");
+	components.replaceChildren();
+	for (const name of Object.keys(d.components).sort()) {
+		const c = d.components[name];
+		const row = components.insertRow();
+		cell(row, name);
+		cell(row, c.status).className = "status " + c.status;
+		cell(row, c.detail ?? "");
+		cell(row, new Date(c.updated_at).toLocaleTimeString());
+	}
+
+	const errors = $("errors");
+	errors.replaceChildren();
+	if (d.recent_errors.length === 0) {
+		cell(errors.insertRow(), "No errors since startup.").colSpan = 4;
+	}
+	for (const e of d.recent_errors) {
+		const row = errors.insertRow();
+		cell(row, new Date(e.time).toLocaleString());
+		cell(row, e.method + " " + e.uri);
+		cell(row, e.message);
+		cell(row, e.request_id ?? "");
+	}
+
+	$("updated").textContent = new Date().toLocaleTimeString();
+}
+
+function showSignIn(message) {
+	clearTimeout(timer);
+	sessionStorage.removeItem(tokenKey);
+	$("dashboard").hidden = true;
+	$("sign-out").hidden = true;
+	$("status").textContent = "";
+	$("status").className = "status";
+	$("sign-in").hidden = false;
+	$("sign-in-error").textContent = message ?? "";
+}
+
+async function poll() {
+	const token = sessionStorage.getItem(tokenKey);
+	if (!token) {
+		showSignIn();
+		return;
+	}
+	try {
+		const res = await fetch("/v1/admin/dashboard", {
+			headers: { Authorization: "Bearer " + token },
+		});
+		const body = await res.json();
+		if (res.status === 401 || res.status === 403) {
+			showSignIn(body.error);
+			return;
+		}
+		if (!res.ok) {
+			throw new Error(body.error);
+		}
+		$("sign-in").hidden = true;
+		$("dashboard").hidden = false;
+		$("sign-out").hidden = false;
+		render(body.dashboard);
+	} catch (err) {
+		$("updated").textContent = "failed: " + err.message;
+	}
+	timer = setTimeout(poll, pollInterval);
+}
+
+$("sign-in").addEventListener("submit", async (event) => {
+	event.preventDefault();
+	const form = event.target;
+	const res = await fetch("/v1/tokens/authentication", {
+		method: "POST",
+		headers: { "Content-Type": "application/json" },
+		body: JSON.stringify({ email: form.email.value, password: form.password.value }),
+	});
+	const body = await res.json();
+	if (!res.ok) {
+		const error = typeof body.error === "string" ? body.error : Object.values(body.error).join(", ");
+		$("sign-in-error").textContent = error;
+		return;
+	}
+	form.password.value = "";
+	sessionStorage.setItem(tokenKey, body.authentication_token.Plaintext);
+	poll();
+});
+
+$("sign-out").addEventListener("click", () => showSignIn());
+
+poll();
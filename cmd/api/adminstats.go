@@ -0,0 +1,102 @@
+package main
+
+import (
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// statsWindows are the windows GET /v1/admin/stats can report over. Request
+// counts are kept in memory for at most the longest of them.
+var statsWindows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+// showAdminStatsHandler returns operational statistics over the window given
+// by ?window=, for dashboards and alerting. Request rates are those of this
+// instance since it started, while everything else covers every instance.
+func (app *application) showAdminStatsHandler(w http.ResponseWriter, r *http.Request) {
+	window := app.readString(r.URL.Query(), "window", "24h")
+	span, ok := statsWindows[window]
+	v := validator.New()
+	v.CheckCode(ok, "window", "invalid_value", "invalid window value")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	now := time.Now()
+	since := now.Add(-span)
+
+	registrations, err := app.models.Users.GetRegistrationsPerDay(r.Context(), since)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	tokens, err := app.models.Tokens.CountActive(r.Context())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	emails, err := app.models.Emails.CountByStatus(r.Context(), since)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Fill in the days without registrations, as for movie views.
+	days := []*data.DailyRegistrations{}
+	for day := since.UTC().Truncate(24 * time.Hour); !day.After(now); day = day.AddDate(0, 0, 1) {
+		entry := &data.DailyRegistrations{Day: day, Date: day.Format(time.DateOnly)}
+		if i := slices.IndexFunc(registrations, func(d *data.DailyRegistrations) bool { return d.Day.Equal(day) }); i >= 0 {
+			entry = registrations[i]
+		}
+		days = append(days, entry)
+	}
+
+	requests, requestsSince := requestHistory.since(since)
+	minutes := max(now.Sub(requestsSince).Minutes(), 1)
+	requestStats := envelope{
+		"since":             requestsSince,
+		"total":             requests.Total,
+		"client_errors":     requests.ClientErrors,
+		"server_errors":     requests.ServerErrors,
+		"per_minute":        float64(requests.Total) / minutes,
+		"error_rate":        fraction(requests.ServerErrors, requests.Total),
+		"client_error_rate": fraction(requests.ClientErrors, requests.Total),
+	}
+
+	sent, failed := emails[data.EmailStatusSent], emails[data.EmailStatusFailed]
+	stats := envelope{
+		"window":                window,
+		"since":                 since,
+		"registrations_per_day": days,
+		"active_tokens":         tokens,
+		"requests":              requestStats,
+		"queues": envelope{
+			"jobs":           envelope{"depth": len(app.jobs.jobs), "capacity": cap(app.jobs.jobs)},
+			"views":          envelope{"depth": app.views.len(), "capacity": app.views.max},
+			"emails_pending": emails[data.EmailStatusPending],
+		},
+		"emails": envelope{
+			"sent":         sent,
+			"failed":       failed,
+			"failure_rate": fraction(int64(failed), int64(sent+failed)),
+		},
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// fraction returns n as a fraction of total, or 0 when total is 0.
+func fraction(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRequestSeries(t *testing.T) {
+	now := time.Now()
+	rs := newRequestSeries(time.Hour)
+	rs.start = now.Add(-2 * time.Hour)
+	rs.record(now.Add(-90*time.Minute), http.StatusOK) // overwritten below
+	rs.record(now.Add(-30*time.Minute), http.StatusOK)
+	rs.record(now.Add(-5*time.Minute), http.StatusNotFound)
+	rs.record(now, http.StatusInternalServerError)
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  requestCounts
+	}{
+		{name: "hour", since: now.Add(-time.Hour), want: requestCounts{Total: 3, ClientErrors: 1, ServerErrors: 1}},
+		{name: "ten minutes", since: now.Add(-10 * time.Minute), want: requestCounts{Total: 2, ClientErrors: 1, ServerErrors: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := rs.since(tt.since)
+			if got != tt.want {
+				t.Errorf("got %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+
+	rs.start = now
+	if _, since := rs.since(now.Add(-time.Hour)); !since.Equal(now) {
+		t.Errorf("got since %v; want the series start %v", since, now)
+	}
+}
+
+func TestShowAdminStatsHandler(t *testing.T) {
+	models := mocks.NewModels()
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	models.Users.(*mocks.UserStore).GetRegistrationsPerDayFunc = func(ctx context.Context, since time.Time) ([]*data.DailyRegistrations, error) {
+		return []*data.DailyRegistrations{{Day: today, Date: today.Format(time.DateOnly), Registrations: 4}}, nil
+	}
+	models.Tokens.(*mocks.TokenStore).CountActiveFunc = func(ctx context.Context) (map[string]int, error) {
+		return map[string]int{data.ScopeAuthentication: 2}, nil
+	}
+	models.Emails.(*mocks.EmailStore).CountByStatusFunc = func(ctx context.Context, since time.Time) (map[string]int, error) {
+		return map[string]int{data.EmailStatusSent: 3, data.EmailStatusFailed: 1}, nil
+	}
+	app := newTestApplication(t, models)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantDays   int
+	}{
+		{name: "default", query: "", wantStatus: http.StatusOK, wantDays: 2},
+		{name: "week", query: "?window=7d", wantStatus: http.StatusOK, wantDays: 8},
+		{name: "invalid window", query: "?window=2d", wantStatus: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRequest(t, http.MethodGet, "/v1/admin/stats"+tt.query, "", testUser, nil)
+			status, _, body := serve(t, app.showAdminStatsHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			stats, _ := body["stats"].(map[string]any)
+			days, _ := stats["registrations_per_day"].([]any)
+			// The window starts part way through its first day, which is
+			// included.
+			if len(days) != tt.wantDays {
+				t.Errorf("got %d days; want %d", len(days), tt.wantDays)
+			}
+			last, _ := days[len(days)-1].(map[string]any)
+			if last["registrations"] != float64(4) {
+				t.Errorf("got last day %v; want 4 registrations", last)
+			}
+			emails, _ := stats["emails"].(map[string]any)
+			if emails["failure_rate"] != 0.25 {
+				t.Errorf("got emails %v; want a failure rate of 0.25", emails)
+			}
+		})
+	}
+}
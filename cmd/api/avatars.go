@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/storage"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"image"
+	"net/http"
+)
+
+const (
+	// avatarSize is the width and height, in pixels, avatars are stored at.
+	avatarSize = 256
+	// maxAvatarBytes is the largest upload accepted.
+	maxAvatarBytes = 5 << 20
+)
+
+// resizeAvatar crops the largest centred square from img and scales it down
+// to avatarSize. Smaller images keep their size. Transparent areas become
+// white.
+func resizeAvatar(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	offset := bounds.Min.Add(image.Pt((bounds.Dx()-side)/2, (bounds.Dy()-side)/2))
+	src := flattenImage(img, image.Rectangle{Min: offset, Max: offset.Add(image.Pt(side, side))})
+	size := min(side, avatarSize)
+	return scaleImage(src, size, size)
+}
+
+// updateAvatarHandler sets the authenticated user's avatar. The upload is
+// cropped to a square, resized and re-encoded as JPEG before it's stored, so
+// only images the API produced are ever served. The URL changes with the
+// image, so it can be cached indefinitely.
+func (app *application) updateAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	b, err := app.readImageUpload(w, r, "avatar", maxAvatarBytes)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	img := decodeImage(v, "avatar", b)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	avatar, err := encodeJPEG(resizeAvatar(img))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	key := fmt.Sprintf("avatars/%d.jpg", user.ID)
+	err = app.storage.Put(r.Context(), key, avatar, "image/jpeg")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	sum := sha256.Sum256(avatar)
+	user.AvatarURL = app.storage.URL(key) + "?v=" + hex.EncodeToString(sum[:6])
+	app.saveAvatar(w, r, user)
+}
+
+// deleteAvatarHandler removes the authenticated user's avatar.
+func (app *application) deleteAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	err := app.storage.Delete(r.Context(), fmt.Sprintf("avatars/%d.jpg", user.ID))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	user.AvatarURL = ""
+	app.saveAvatar(w, r, user)
+}
+
+func (app *application) saveAvatar(w http.ResponseWriter, r *http.Request, user *data.User) {
+	err := app.models.Users.Update(r.Context(), user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.tokenCache.invalidateUser(user.ID)
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// filesHandler serves the files kept by the local storage backend. The URLs
+// stored for avatars carry a ?v= which changes with the file, so responses to
+// them can be cached for good.
+func (app *application) filesHandler(local *storage.Local) http.HandlerFunc {
+	files := http.StripPrefix("/v1/files", local.Handler())
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("v") {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		}
+		files.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"github.com/ezechidc/greenlight/internal/storage"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			// Red down the middle, transparent either side.
+			if x >= (width-height)/2 && x < (width+height)/2 {
+				img.Set(x, y, color.NRGBA{R: 255, A: 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestResizeAvatar(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		wantSize      int
+	}{
+		{name: "large", width: 1200, height: 800, wantSize: avatarSize},
+		{name: "small", width: 150, height: 100, wantSize: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, _, err := image.Decode(bytes.NewReader(testPNG(t, tt.width, tt.height)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := resizeAvatar(img)
+			if got.Bounds().Dx() != tt.wantSize || got.Bounds().Dy() != tt.wantSize {
+				t.Fatalf("got %v; want %dx%d", got.Bounds(), tt.wantSize, tt.wantSize)
+			}
+			// The crop keeps only the red square in the middle.
+			for _, p := range []image.Point{{0, 0}, {tt.wantSize - 1, tt.wantSize - 1}} {
+				if c := got.RGBAAt(p.X, p.Y); c != (color.RGBA{R: 255, A: 255}) {
+					t.Errorf("got %v at %v; want red", c, p)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateAvatarHandler(t *testing.T) {
+	multipartBody := func(t *testing.T, field string, file []byte) (string, string) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		fw, err := mw.CreateFormFile(field, "me.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(file)
+		mw.Close()
+		return buf.String(), mw.FormDataContentType()
+	}
+
+	tests := []struct {
+		name        string
+		body        func(t *testing.T) (string, string)
+		wantStatus  int
+		wantAvatars int
+	}{
+		{name: "raw", body: func(t *testing.T) (string, string) { return string(testPNG(t, 600, 400)), "image/png" }, wantStatus: http.StatusOK, wantAvatars: 1},
+		{name: "multipart", body: func(t *testing.T) (string, string) { return multipartBody(t, "avatar", testPNG(t, 600, 400)) }, wantStatus: http.StatusOK, wantAvatars: 1},
+		{name: "multipart without avatar", body: func(t *testing.T) (string, string) { return multipartBody(t, "photo", testPNG(t, 60, 40)) }, wantStatus: http.StatusBadRequest},
+		{name: "not an image", body: func(t *testing.T) (string, string) { return "hello", "text/plain" }, wantStatus: http.StatusUnprocessableEntity},
+		{name: "too large", body: func(t *testing.T) (string, string) { return strings.Repeat("x", maxAvatarBytes+1), "image/png" }, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			models.Users.(*mocks.UserStore).UpdateFunc = func(ctx context.Context, user *data.User) error {
+				return nil
+			}
+			app := newTestApplication(t, models)
+			dir := t.TempDir()
+			app.storage, _ = storage.New(storage.BackendLocal, dir, "http://localhost:4000/v1/files")
+
+			body, contentType := tt.body(t)
+			user := *testUser
+			r := newTestRequest(t, http.MethodPut, "/v1/users/me/avatar", body, &user, nil)
+			r.Header.Set("Content-Type", contentType)
+			status, _, resp := serve(t, app.updateAvatarHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, resp)
+			}
+			avatars, _ := filepath.Glob(filepath.Join(dir, "avatars", "*"))
+			if len(avatars) != tt.wantAvatars {
+				t.Fatalf("got %d stored avatars; want %d", len(avatars), tt.wantAvatars)
+			}
+			if status != http.StatusOK {
+				return
+			}
+
+			avatarURL, _ := resp["user"].(map[string]any)["avatar_url"].(string)
+			if !strings.HasPrefix(avatarURL, "http://localhost:4000/v1/files/avatars/1.jpg?v=") {
+				t.Errorf("got avatar_url %q", avatarURL)
+			}
+			f, err := os.Open(avatars[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			config, err := jpeg.DecodeConfig(f)
+			if err != nil || config.Width != avatarSize || config.Height != avatarSize {
+				t.Errorf("got %+v (err %v); want a %dx%d JPEG", config, err, avatarSize, avatarSize)
+			}
+		})
+	}
+}
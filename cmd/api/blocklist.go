@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/ezechidc/greenlight/internal/validator"
+	"net/http"
+	"strings"
+)
+
+func (app *application) showDisposableDomainsHandler(w http.ResponseWriter, r *http.Request) {
+	env := envelope{
+		"embedded_count": app.disposable.EmbeddedLen(),
+		"extra":          app.disposable.Extra(),
+	}
+	err := app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateDisposableDomainsHandler replaces the domains blocked in addition to
+// the embedded list. The change lasts until the next restart; use
+// -disposable-domains-file to make it permanent.
+func (app *application) updateDisposableDomainsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Extra []string `json:"extra"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.CheckCode(input.Extra != nil, "extra", "required", "must be provided")
+	for _, domain := range input.Extra {
+		valid := len(domain) <= 253 && strings.Contains(domain, ".") && !strings.ContainsAny(domain, "@ \t")
+		if !valid {
+			v.AddErrorCode("extra", "invalid_value", "must only contain domain names")
+			break
+		}
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	app.disposable.SetExtra(input.Extra)
+	app.showDisposableDomainsHandler(w, r)
+}
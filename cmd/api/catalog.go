@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// refreshCatalogStats recomputes the statistics served by
+// showCatalogStatsHandler. Computing them scans every movie, so they're
+// cached and refreshed on a schedule rather than per request.
+func (app *application) refreshCatalogStats(ctx context.Context) {
+	stats, err := app.models.Movies.GetCatalogStats(ctx)
+	if err != nil {
+		app.logger.Error(err.Error())
+		return
+	}
+	app.catalogStats.Store(stats)
+}
+
+// showCatalogStatsHandler returns the cached catalog statistics, which are at
+// most -catalog-stats-interval old. computed_at says when they were computed.
+func (app *application) showCatalogStatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats := app.catalogStats.Load()
+	if stats == nil {
+		// Nothing has been cached since startup yet.
+		var err error
+		stats, err = app.models.Movies.GetCatalogStats(r.Context())
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.catalogStats.Store(stats)
+	}
+	err := app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
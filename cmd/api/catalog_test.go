@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"github.com/lib/pq"
+	"net/http"
+	"testing"
+)
+
+func TestShowCatalogStatsHandlerCaches(t *testing.T) {
+	models := mocks.NewModels()
+	movies := movieMocks(models)
+	movies.GetCatalogStatsFunc = func(ctx context.Context) (*data.CatalogStats, error) {
+		return &data.CatalogStats{Movies: 3, Genres: []*data.GenreCount{}, Decades: []*data.DecadeCount{}}, nil
+	}
+	app := newTestApplication(t, models)
+
+	for range 2 {
+		r := newTestRequest(t, http.MethodGet, "/v1/catalog/stats", "", testUser, nil)
+		status, _, body := serve(t, app.showCatalogStatsHandler, r)
+		if status != http.StatusOK {
+			t.Fatalf("got status %d; want %d (%v)", status, http.StatusOK, body)
+		}
+		stats, _ := body["stats"].(map[string]any)
+		if stats["movies"] != float64(3) {
+			t.Errorf("got stats %v; want 3 movies", stats)
+		}
+	}
+	if got := len(movies.GetCatalogStatsCalls()); got != 1 {
+		t.Errorf("got %d computations; want 1", got)
+	}
+}
+
+func TestMovieChangedClearsCatalogStats(t *testing.T) {
+	for _, n := range []*pq.Notification{{Channel: movieChangesChannel, Extra: "INSERT"}, nil} {
+		app := newTestApplication(t, mocks.NewModels())
+		app.catalogStats.Store(&data.CatalogStats{Movies: 3})
+		app.movieChanged(n)
+		if app.catalogStats.Load() != nil {
+			t.Errorf("catalog stats still cached after notification %v", n)
+		}
+	}
+}
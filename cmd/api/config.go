@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/captcha"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/embedding"
+	"github.com/ezechidc/greenlight/internal/mailer"
+	"github.com/ezechidc/greenlight/internal/storage"
+	"github.com/lib/pq"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// validateConfig returns a description of each problem with cfg, or nil if
+// there are none.
+func validateConfig(cfg config) []string {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(cfg.port > 0 && cfg.port <= 65535, "port %d is out of range", cfg.port)
+	check(slices.Contains([]string{"development", "staging", "production"}, cfg.env), "invalid environment %q", cfg.env)
+	check(slices.Contains([]string{emailRedactMask, emailRedactFull, emailRedactOff}, cfg.log.redactEmails), "invalid email redaction mode %q", cfg.log.redactEmails)
+	check(slices.Contains([]string{"auto", "on", "off"}, cfg.security.headers), "invalid security headers mode %q", cfg.security.headers)
+	check(!cfg.stats.enabled || (cfg.stats.flushInterval > 0 && cfg.stats.interval > 0 && cfg.stats.bufferSize >= 1), "stats intervals and buffer size must be positive")
+	check(!cfg.usage.enabled || (cfg.usage.flushInterval > 0 && cfg.usage.retention > 0 && cfg.usage.bufferSize >= 1), "usage flush interval, retention and buffer size must be positive")
+	_, err := data.NewPasswordParams(cfg.password.scheme, cfg.password.bcryptCost, cfg.password.argon2Time, cfg.password.argon2Memory, cfg.password.argon2Threads)
+	check(err == nil, "%v", err)
+
+	if !cfg.demo {
+		check(cfg.db.dsn != "", "no database DSN given")
+		if cfg.db.dsn != "" {
+			_, err := pq.NewConnector(cfg.db.dsn)
+			check(err == nil, "invalid database DSN: %v", err)
+		}
+	}
+	check(cfg.db.maxOpenConns >= 0 && cfg.db.maxIdleConns >= 0, "database connection limits must not be negative")
+
+	if cfg.limiter.enabled {
+		check(cfg.limiter.rps > 0 && cfg.limiter.burst >= 1, "limiter rps and burst must be positive")
+	}
+	if cfg.anonymous.read {
+		check(cfg.anonymous.rps > 0 && cfg.anonymous.burst >= 1, "anonymous limiter rps and burst must be positive")
+	}
+	if cfg.authLimiter.enabled {
+		check(cfg.authLimiter.rps > 0 && cfg.authLimiter.burst >= 1, "auth limiter rps and burst must be positive")
+		check(cfg.authLimiter.ban > 0 && cfg.authLimiter.maxBan >= cfg.authLimiter.ban, "auth limiter ban must be positive and no longer than the maximum ban")
+	}
+
+	check(cfg.jobs.workers >= 1 && cfg.jobs.queueSize >= 1, "job workers and queue size must be positive")
+	if cfg.panicWebhookURL != "" {
+		u, err := url.Parse(cfg.panicWebhookURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "invalid panic webhook URL %q", cfg.panicWebhookURL)
+	}
+	check(cfg.timeout.request >= 0 && cfg.timeout.bulk >= 0, "request timeouts must not be negative")
+	check(cfg.inflight.max >= 0 && cfg.inflight.queue >= 0 && cfg.inflight.wait >= 0, "in-flight limits must not be negative")
+	check(cfg.search.similarity > 0 && cfg.search.similarity <= 1, "search similarity %g must be above 0 and at most 1", cfg.search.similarity)
+	check(cfg.catalogStats.interval > 0, "catalog stats interval must be positive")
+	check(cfg.tokens.pruneInterval > 0 && cfg.tokens.pruneBatch >= 1, "token prune interval and batch must be positive")
+	check(cfg.tokens.cacheTTL >= 0 && cfg.tokens.cacheSize >= 1, "token cache TTL must not be negative and its size must be positive")
+	check(cfg.shutdown.delay >= 0, "shutdown delay must not be negative")
+
+	check(slices.Contains([]string{mailer.ModeLive, mailer.ModeLog, mailer.ModeDiscard}, cfg.smtp.mode), "invalid SMTP mode %q", cfg.smtp.mode)
+	if cfg.smtp.mode == mailer.ModeLive {
+		check(cfg.smtp.host != "", "no SMTP host given")
+		check(cfg.smtp.port > 0 && cfg.smtp.port <= 65535, "SMTP port %d is out of range", cfg.smtp.port)
+		check(cfg.smtp.poolSize >= 0, "SMTP pool size must not be negative")
+		check(slices.Contains([]string{mailer.TLSMandatory, mailer.TLSOpportunistic, mailer.TLSNone}, cfg.smtp.tls), "invalid SMTP TLS policy %q", cfg.smtp.tls)
+	}
+
+	if cfg.captcha.provider != "" {
+		_, err := captcha.New(cfg.captcha.provider, cfg.captcha.secret)
+		check(err == nil, "%v", err)
+	}
+	if cfg.embedding.provider != "" {
+		_, err := embedding.New(cfg.embedding.provider, cfg.embedding.url, cfg.embedding.model, cfg.embedding.apiKey)
+		check(err == nil, "%v", err)
+		check(cfg.embedding.interval > 0 && cfg.embedding.batch >= 1, "embedding interval and batch must be positive")
+	}
+	_, err = storage.New(cfg.storage.backend, cfg.storage.dir, cfg.storage.url)
+	check(err == nil, "%v", err)
+	return problems
+}
+
+// maskFlagValue returns the value of a flag as it's safe to print.
+func maskFlagValue(name, value string) string {
+	key := strings.ReplaceAll(name, "-", "_")
+	switch {
+	case value == "":
+		return value
+	case name == "db-dsn":
+		if u, err := url.Parse(value); err == nil && u.User != nil {
+			return u.Redacted()
+		}
+		return queryRX.ReplaceAllString(value, "$1="+redacted)
+	case name == "token-peppers" || name == "embedding-api-key" || isSensitiveKey(key):
+		return redacted
+	}
+	return value
+}
+
+// printConfig writes the effective value of every flag in fs to w, with
+// secrets masked.
+func printConfig(w io.Writer, fs *flag.FlagSet) {
+	fs.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(w, "%s=%s\n", f.Name, maskFlagValue(f.Name, f.Value.String()))
+	})
+}
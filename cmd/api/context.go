@@ -8,9 +8,20 @@ import (
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey      = contextKey("user")
+	requestIDContextKey = contextKey("requestID")
+
+	allowUnknownFieldsContextKey = contextKey("allowUnknownFields")
+	debugContextKey              = contextKey("debug")
+	routeContextKey              = contextKey("route")
+	logUserContextKey            = contextKey("logUser")
+)
 
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
+	if slot, ok := r.Context().Value(logUserContextKey).(**data.User); ok {
+		*slot = user
+	}
 	ctx := context.WithValue(r.Context(), userContextKey, user)
 	return r.WithContext(ctx)
 }
@@ -23,6 +34,64 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 	return user
 }
 
+func (app *application) contextSetRequestID(r *http.Request, requestID string) *http.Request {
+	ctx := context.WithValue(r.Context(), requestIDContextKey, requestID)
+	return r.WithContext(ctx)
+}
+
+// contextGetRequestID returns the request ID, or an empty string for requests
+// which haven't passed through the requestID middleware.
+func (app *application) contextGetRequestID(r *http.Request) string {
+	requestID, _ := r.Context().Value(requestIDContextKey).(string)
+	return requestID
+}
+
+func (app *application) contextGetAllowUnknownFields(r *http.Request) bool {
+	allow, _ := r.Context().Value(allowUnknownFieldsContextKey).(bool)
+	return allow
+}
+
+func (app *application) contextSetDebug(r *http.Request, info *debugInfo) *http.Request {
+	ctx := context.WithValue(r.Context(), debugContextKey, info)
+	return r.WithContext(ctx)
+}
+
+// contextGetDebug returns nil unless the request is in debug mode.
+func (app *application) contextGetDebug(r *http.Request) *debugInfo {
+	info, _ := r.Context().Value(debugContextKey).(*debugInfo)
+	return info
+}
+
+// contextSetRoute stores where the matched route template should be recorded.
+func (app *application) contextSetRoute(r *http.Request, route *string) *http.Request {
+	ctx := context.WithValue(r.Context(), routeContextKey, route)
+	return r.WithContext(ctx)
+}
+
+// contextSetLogUser stores where the user set by contextSetUser should also be
+// recorded, so the access log written by outer middleware can include them.
+func (app *application) contextSetLogUser(r *http.Request, user **data.User) *http.Request {
+	ctx := context.WithValue(r.Context(), logUserContextKey, user)
+	return r.WithContext(ctx)
+}
+
+// contextGetRoute returns nil for requests which haven't passed through the
+// metrics middleware.
+// contextGetLogUser returns the user recorded in the slot set by
+// contextSetLogUser, or nil if there's no slot or no user has been set yet.
+func (app *application) contextGetLogUser(r *http.Request) *data.User {
+	slot, ok := r.Context().Value(logUserContextKey).(**data.User)
+	if !ok || *slot == nil || (*slot).IsAnonymous() {
+		return nil
+	}
+	return *slot
+}
+
+func (app *application) contextGetRoute(r *http.Request) *string {
+	route, _ := r.Context().Value(routeContextKey).(*string)
+	return route
+}
+
 func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
 	message := "you must be authenticated to access this resource"
 	app.errorResponse(w, r, http.StatusUnauthorized, message)
@@ -31,3 +100,13 @@ func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.R
 	message := "your user account must be activated to access this resource"
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
+
+func (app *application) deactivatedAccountResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account has been deactivated"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account doesn't have the necessary permissions to access this resource"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"embed"
+	"expvar"
+	"io/fs"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+)
+
+//go:embed "admin"
+var adminFiles embed.FS
+
+// dashboardCSP lets the dashboard load its own script and stylesheet and call
+// the API, which the API's default policy forbids.
+const dashboardCSP = "default-src 'none'; script-src 'self'; style-src 'self'; connect-src 'self'; img-src 'self'; form-action 'none'; base-uri 'none'; frame-ancestors 'none'"
+
+// recentError is an error logged while serving a request.
+type recentError struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id,omitempty"`
+	Method    string    `json:"method"`
+	URI       string    `json:"uri"`
+	Message   string    `json:"message"`
+}
+
+// errorRing keeps the most recent errors logged by logError for the admin
+// dashboard. Its zero value is ready to use.
+type errorRing struct {
+	mu      sync.Mutex
+	entries [50]recentError
+	next    int
+	count   int
+}
+
+func (er *errorRing) add(e recentError) {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	er.entries[er.next] = e
+	er.next = (er.next + 1) % len(er.entries)
+	er.count = min(er.count+1, len(er.entries))
+}
+
+// recent returns the kept errors, newest first.
+func (er *errorRing) recent() []recentError {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	errs := make([]recentError, 0, er.count)
+	for i := 1; i <= er.count; i++ {
+		errs = append(errs, er.entries[(er.next-i+len(er.entries))%len(er.entries)])
+	}
+	return errs
+}
+
+// expvarValue returns the value of the named expvar variable as JSON, or nil
+// if it isn't published, such as when the metrics middleware isn't in use.
+func expvarValue(name string) any {
+	v := expvar.Get(name)
+	if v == nil {
+		return nil
+	}
+	return rawJSON(v.String())
+}
+
+// rawJSON is JSON which is written as is.
+type rawJSON string
+
+func (j rawJSON) MarshalJSON() ([]byte, error) {
+	return []byte(j), nil
+}
+
+// showDashboardHandler returns everything the admin dashboard displays in a
+// single response, so that it can be polled.
+func (app *application) showDashboardHandler(w http.ResponseWriter, r *http.Request) {
+	users, err := app.models.Users.GetCounts(r.Context())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	stats := app.catalogStats.Load()
+	if stats == nil {
+		stats, err = app.models.Movies.GetCatalogStats(r.Context())
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.catalogStats.Store(stats)
+	}
+	status, components := app.health.status()
+
+	dashboard := envelope{
+		"status":     status,
+		"components": components,
+		"counts": envelope{
+			"users":  users,
+			"movies": stats.Movies,
+		},
+		"metrics": envelope{
+			"total_requests_received": expvarValue("total_requests_received"),
+			"total_responses_sent":    expvarValue("total_responses_sent"),
+			"requests_in_flight":      expvarValue("requests_in_flight"),
+			"requests_shed":           expvarValue("requests_shed"),
+			"responses_by_status":     expvarValue("route_responses_by_status_class"),
+			"panics_recovered":        expvarValue("panics_recovered"),
+			"db_slow_queries":         expvarValue("db_slow_queries"),
+			"jobs_queued":             len(app.jobs.jobs),
+			"token_cache_hit_rate":    fraction(tokenCacheHits.Value(), tokenCacheHits.Value()+tokenCacheMisses.Value()),
+			"goroutines":              runtime.NumGoroutine(),
+		},
+		"recent_errors": app.recentErrors.recent(),
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"dashboard": dashboard}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminUIHandler serves the embedded admin dashboard. The assets hold no
+// data, so they're served without authentication; the dashboard signs in and
+// fetches GET /v1/admin/dashboard with an admin's token.
+func (app *application) adminUIHandler() http.HandlerFunc {
+	sub, err := fs.Sub(adminFiles, "admin")
+	if err != nil {
+		panic(err)
+	}
+	files := http.StripPrefix("/admin", http.FileServerFS(sub))
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", dashboardCSP)
+		w.Header().Set("Cache-Control", "no-cache")
+		files.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorRing(t *testing.T) {
+	var er errorRing
+	if got := er.recent(); len(got) != 0 {
+		t.Fatalf("got %d errors from an empty ring; want 0", len(got))
+	}
+	total := len(er.entries) + 5
+	for i := range total {
+		er.add(recentError{Message: fmt.Sprint(i)})
+	}
+	got := er.recent()
+	if len(got) != len(er.entries) {
+		t.Fatalf("got %d errors; want %d", len(got), len(er.entries))
+	}
+	if got[0].Message != fmt.Sprint(total-1) || got[len(got)-1].Message != "5" {
+		t.Errorf("got newest %q and oldest %q; want %d and 5", got[0].Message, got[len(got)-1].Message, total-1)
+	}
+}
+
+func TestShowDashboardHandler(t *testing.T) {
+	models := mocks.NewModels()
+	movieMocks(models).GetCatalogStatsFunc = func(ctx context.Context) (*data.CatalogStats, error) {
+		return &data.CatalogStats{Movies: 7}, nil
+	}
+	models.Users.(*mocks.UserStore).GetCountsFunc = func(ctx context.Context) (*data.UserCounts, error) {
+		return &data.UserCounts{Total: 3, Activated: 2, Pending: 1}, nil
+	}
+	app := newTestApplication(t, models)
+	app.config.log.redactEmails = emailRedactFull
+	app.health.report("database", healthHealthy, "")
+	app.logError(httptest.NewRequest(http.MethodGet, "/v1/movies?token=abc", nil), errors.New("lookup failed for alice@example.com"))
+
+	r := newTestRequest(t, http.MethodGet, "/v1/admin/dashboard", "", testUser, nil)
+	status, _, body := serve(t, app.showDashboardHandler, r)
+	if status != http.StatusOK {
+		t.Fatalf("got status %d; want %d (%v)", status, http.StatusOK, body)
+	}
+	dashboard, _ := body["dashboard"].(map[string]any)
+	if dashboard["status"] != healthHealthy {
+		t.Errorf("got status %v; want %q", dashboard["status"], healthHealthy)
+	}
+	counts, _ := dashboard["counts"].(map[string]any)
+	users, _ := counts["users"].(map[string]any)
+	if counts["movies"] != float64(7) || users["total"] != float64(3) {
+		t.Errorf("got counts %v", counts)
+	}
+	errs, _ := dashboard["recent_errors"].([]any)
+	if len(errs) != 1 {
+		t.Fatalf("got recent errors %v; want 1", dashboard["recent_errors"])
+	}
+	recent, _ := errs[0].(map[string]any)
+	for _, leaked := range []string{"abc", "alice"} {
+		if strings.Contains(fmt.Sprint(recent), leaked) {
+			t.Errorf("recent error %v contains %q", recent, leaked)
+		}
+	}
+}
+
+func TestAdminUIHandler(t *testing.T) {
+	app := newTestApplication(t, mocks.NewModels())
+	handler := app.adminUIHandler()
+
+	for _, path := range []string{"/admin/", "/admin/dashboard.js", "/admin/dashboard.css"} {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != http.StatusOK {
+			t.Errorf("got status %d for %s; want %d", rr.Code, path, http.StatusOK)
+		}
+		if !strings.Contains(rr.Header().Get("Content-Security-Policy"), "script-src 'self'") {
+			t.Errorf("got CSP %q for %s", rr.Header().Get("Content-Security-Policy"), path)
+		}
+	}
+}
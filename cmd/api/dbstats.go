@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"expvar"
+	"time"
+)
+
+// dbWaitMonitorInterval is how often dbWaitMonitor runs.
+const dbWaitMonitorInterval = time.Minute
+
+// dbStats is the JSON form of the connection pool's sql.DBStats, published as
+// the "database" expvar and in the verbose healthcheck.
+type dbStats struct {
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+	MaxIdleClosed      int64  `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64  `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64  `json:"max_lifetime_closed"`
+}
+
+func newDBStats(s sql.DBStats) dbStats {
+	return dbStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       s.WaitDuration.String(),
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
+
+// publishDBStats publishes the pool statistics at /debug/vars. It must only be
+// called once.
+func (app *application) publishDBStats() {
+	expvar.Publish("database", expvar.Func(func() any {
+		return newDBStats(app.db.Stats())
+	}))
+}
+
+// dbWaitMonitor returns a scheduled job which logs a warning when requests
+// have spent longer than -db-wait-warning in total waiting for a free
+// connection since it last ran, which suggests -db-max-open-conns is too low.
+func (app *application) dbWaitMonitor() func(ctx context.Context) {
+	last := app.db.Stats()
+	return func(ctx context.Context) {
+		stats := app.db.Stats()
+		waited := stats.WaitDuration - last.WaitDuration
+		if waited > app.config.db.waitWarning {
+			app.logger.Warn("database connection waits",
+				"waited", waited.String(),
+				"waits", stats.WaitCount-last.WaitCount,
+				"in_use", stats.InUse,
+				"max_open_connections", stats.MaxOpenConnections,
+			)
+		}
+		last = stats
+	}
+}
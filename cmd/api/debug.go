@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/ezechidc/greenlight/internal/data"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// debugInfo is collected for requests made with ?debug=true and returned in
+// the "debug" member of the response envelope.
+type debugInfo struct {
+	start  time.Time
+	trace  *data.QueryTrace
+	params any
+}
+
+// debugRecorder buffers the response so the debug member can be added to the
+// envelope once the handler has finished.
+type debugRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *debugRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *debugRecorder) Write(b []byte) (int, error) {
+	return rec.body.Write(b)
+}
+
+func (rec *debugRecorder) WriteHeader(status int) {
+	rec.status = status
+}
+
+// debug enables the debug member for requests with ?debug=true. It has to be
+// switched on with -debug-enabled and is then only available to users with the
+// admin:read permission, whatever the environment, since it exposes SQL. It
+// must run after authenticate.
+func (app *application) debug(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.config.debug.enabled || r.URL.Query().Get("debug") != "true" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user := app.contextGetUser(r)
+		if user.IsAnonymous() {
+			app.auditDenial(r, user, "authentication required for debug mode")
+			app.authenticationRequiredResponse(w, r)
+			return
+		}
+		permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !permissions.Include("admin:read") {
+			app.auditDenial(r, user, "missing permission admin:read for debug mode")
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		info := &debugInfo{start: time.Now()}
+		ctx, trace := data.WithQueryTrace(r.Context())
+		info.trace = trace
+		r = app.contextSetDebug(r.WithContext(ctx), info)
+
+		rec := &debugRecorder{header: w.Header(), status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		body := rec.body.Bytes()
+		if strings.Contains(rec.header.Get("Content-Type"), "json") {
+			var env map[string]any
+			dec := json.NewDecoder(bytes.NewReader(body))
+			dec.UseNumber()
+			if dec.Decode(&env) == nil {
+				env["debug"] = info.summary()
+				js, err := json.MarshalIndent(env, "", "\t")
+				if err == nil {
+					body = append(js, '\n')
+					rec.header.Del("Content-Length")
+				}
+			}
+		}
+		w.WriteHeader(rec.status)
+		w.Write(body)
+	})
+}
+
+// debugParams records the parsed query parameters of a request in debug mode.
+// It does nothing for other requests.
+func (app *application) debugParams(r *http.Request, params any) {
+	if info := app.contextGetDebug(r); info != nil {
+		info.params = params
+	}
+}
+
+func (info *debugInfo) summary() envelope {
+	type query struct {
+		data.TracedQuery
+		Duration string `json:"duration"`
+	}
+	queries := []query{}
+	var dbTime time.Duration
+	for _, q := range info.trace.Queries() {
+		dbTime += q.Duration
+		queries = append(queries, query{TracedQuery: q, Duration: q.Duration.String()})
+	}
+	total := time.Since(info.start)
+	return envelope{
+		"params":  info.params,
+		"queries": queries,
+		"timing": map[string]string{
+			"total":   total.String(),
+			"db":      dbTime.String(),
+			"handler": (total - dbTime).String(),
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"github.com/ezechidc/greenlight/internal/data"
+	"time"
+)
+
+const digestPeriod = 7 * 24 * time.Hour
+
+// sendDigests queues the weekly digest for every opted-in user who hasn't
+// received one in the last week. It runs on every scheduler tick rather than
+// sleeping for a week so that restarts don't delay or skip a digest.
+func (app *application) sendDigests(ctx context.Context) {
+	recipients, err := app.models.Preferences.GetDueForDigest(ctx, time.Now().Add(-digestPeriod))
+	if err != nil {
+		app.logger.Error(err.Error())
+		return
+	}
+
+	for _, recipient := range recipients {
+		userID := recipient.User.ID
+		if _, queued := app.pendingDigests.LoadOrStore(userID, true); queued {
+			continue
+		}
+		ok := app.jobs.enqueue(ctx, "weekly_digest", func(ctx context.Context) error {
+			defer app.pendingDigests.Delete(userID)
+			return app.sendDigest(ctx, recipient)
+		})
+		if !ok {
+			app.pendingDigests.Delete(userID)
+			return
+		}
+	}
+}
+
+// sendDigest emails one user their digest. The digest is only marked as sent
+// once the email has gone, so a failed send is retried on the next tick.
+func (app *application) sendDigest(ctx context.Context, recipient *data.DigestRecipient) error {
+	movies, err := app.models.Movies.GetAddedSince(ctx, time.Now().Add(-digestPeriod), recipient.FavoriteGenres, 20)
+	if err != nil {
+		return err
+	}
+	if len(movies) > 0 {
+		templateData := map[string]any{
+			"name":   recipient.User.Name,
+			"movies": movies,
+		}
+		err = app.sendNotification(recipient.User, data.NotificationWeeklyDigest, "weekly_digest.tmpl", templateData)
+		if err != nil {
+			return err
+		}
+	}
+	return app.models.Preferences.MarkDigestSent(ctx, recipient.User.ID)
+}
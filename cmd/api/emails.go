@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// sendEmail delivers a templated email and records its delivery status in the
+// emails table. Failures are logged as well as returned, so callers running it
+// inside app.background() can ignore the error.
+func (app *application) sendEmail(recipient, locale, templateFile string, templateData any) error {
+	email := &data.Email{
+		Recipient: recipient,
+		Template:  templateFile,
+		Status:    data.EmailStatusPending,
+	}
+	err := app.models.Emails.Insert(context.Background(), email)
+	if err != nil {
+		app.logger.Error(err.Error(), "recipient", recipient, "template", templateFile)
+		return err
+	}
+
+	messageID, sendErr := app.mailer.Send(recipient, locale, templateFile, templateData)
+	if sendErr != nil {
+		app.logger.Error(sendErr.Error(), "email_id", email.ID, "template", templateFile)
+		app.health.report("mailer", healthUnhealthy, sendErr.Error())
+		email.Status = data.EmailStatusFailed
+		email.Error = sendErr.Error()
+	} else {
+		app.health.report("mailer", healthHealthy, "")
+		email.Status = data.EmailStatusSent
+		email.MessageID = messageID
+	}
+
+	err = app.models.Emails.UpdateStatus(context.Background(), email)
+	if err != nil {
+		app.logger.Error(err.Error(), "email_id", email.ID)
+	}
+	return sendErr
+}
+
+// sendNotification sends an optional email, honouring the user's notification
+// preferences and adding a one-click unsubscribe link to the template data.
+// Users who have opted out are skipped without an error.
+func (app *application) sendNotification(user *data.User, notification, templateFile string, templateData map[string]any) error {
+	preferences, err := app.models.Preferences.Get(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	if !preferences.Allows(notification) {
+		return nil
+	}
+
+	token, err := app.models.Tokens.New(context.Background(), user.ID, 90*24*time.Hour, data.UnsubscribeScope(notification))
+	if err != nil {
+		return err
+	}
+	qs := url.Values{"token": {token.Plaintext}, "notification": {notification}}
+	templateData["unsubscribeURL"] = fmt.Sprintf("%s/v1/users/unsubscribe?%s", app.config.baseURL, qs.Encode())
+
+	return app.sendEmail(user.Email, user.Language, templateFile, templateData)
+}
+
+func (app *application) listEmailsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Recipient string
+		Status    string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Recipient = app.readString(qs, "recipient", "")
+	input.Status = app.readString(qs, "status", "")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
+	input.Filters.SortSafelist = []string{"id", "created_at", "status", "-id", "-created_at", "-status"}
+	app.debugParams(r, envelope{"recipient": input.Recipient, "status": input.Status, "filters": input.Filters})
+	data.ValidateEmailStatus(v, input.Status)
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	emails, metadata, err := app.models.Emails.GetAll(r.Context(), input.Recipient, input.Status, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	metadata.SetLinks(app.config.baseURL+r.URL.Path, r.URL.Query())
+	err = app.writeJSON(w, http.StatusOK, envelope{"emails": emails, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
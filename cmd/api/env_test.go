@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestBindEnv(t *testing.T) {
+	t.Setenv("GREENLIGHT_LIMITER_RPS", "4")
+	t.Setenv("GREENLIGHT_LIMITER_BURST", "8")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	rps := fs.Float64("limiter-rps", 2, "")
+	burst := fs.Int("limiter-burst", 4, "")
+	port := fs.Int("port", 4000, "")
+	err := fs.Parse([]string{"-limiter-burst", "16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = bindEnv(fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *rps != 4 {
+		t.Errorf("got limiter-rps %v; want 4 from the environment", *rps)
+	}
+	if *burst != 16 {
+		t.Errorf("got limiter-burst %d; want 16 from the command line", *burst)
+	}
+	if *port != 4000 {
+		t.Errorf("got port %d; want the default 4000", *port)
+	}
+
+	t.Setenv("GREENLIGHT_PORT", "not a number")
+	err = bindEnv(fs)
+	if err == nil {
+		t.Error("got no error for an invalid value")
+	}
+}
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/ezechidc/greenlight/internal/i18n"
+	"github.com/ezechidc/greenlight/internal/validator"
 	"net/http"
+	"strings"
+	"time"
 )
 
 func (app *application) logError(r *http.Request, err error) {
@@ -11,10 +17,57 @@ func (app *application) logError(r *http.Request, err error) {
 		uri    = r.URL.RequestURI()
 	)
 	app.logger.Error(err.Error(), "method", method, "uri", uri)
+	redactor := RedactHandler{Emails: app.config.log.redactEmails}
+	app.recentErrors.add(recentError{
+		Time:      time.Now(),
+		RequestID: app.contextGetRequestID(r),
+		Method:    method,
+		URI:       redactor.redactString(uri),
+		Message:   redactor.redactString(err.Error()),
+	})
+}
+
+// translateMessage localizes an error message, a map of validation errors or a
+// list of structured field errors into the language negotiated for the request.
+func (app *application) translateMessage(locale string, message any) any {
+	switch message := message.(type) {
+	case string:
+		return i18n.Translate(locale, message)
+	case map[string]string:
+		translated := make(map[string]string, len(message))
+		for key, value := range message {
+			translated[key] = i18n.Translate(locale, value)
+		}
+		return translated
+	case []validator.FieldError:
+		translated := make([]validator.FieldError, len(message))
+		for i, fieldError := range message {
+			fieldError.Message = i18n.Translate(locale, fieldError.Message)
+			translated[i] = fieldError
+		}
+		return translated
+	default:
+		return message
+	}
 }
 
 func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
-	env := envelope{"error": message}
+	app.errorEnvelopeResponse(w, r, status, envelope{"error": message})
+}
+
+// errorEnvelopeResponse sends an error response whose envelope carries members
+// in addition to "error". Every member is localized before being written.
+func (app *application) errorEnvelopeResponse(w http.ResponseWriter, r *http.Request, status int, env envelope) {
+	locale := app.readLanguage(r)
+	w.Header().Set("Content-Language", locale)
+	w.Header().Add("Vary", "Accept-Language")
+	for key, value := range env {
+		env[key] = app.translateMessage(locale, value)
+	}
+	if app.wantsProblemJSON(r) {
+		app.problemResponse(w, r, status, env)
+		return
+	}
 	// Write the response using the writeJSON() helper. If this happens to return an
 	// error then log it, and fall back to sending the client an empty response with a
 	// 500 Internal Server Error status code.
@@ -27,10 +80,21 @@ func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, st
 
 func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
 	app.logError(r, err)
+	// Whatever failed most likely did so because the route's timeout
+	// cancelled the request context.
+	if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		app.timeoutResponse(w, r)
+		return
+	}
 	message := "the server encountered a problem and could not process your request"
 	app.errorResponse(w, r, http.StatusInternalServerError, message)
 }
 
+func (app *application) timeoutResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the server took too long to process your request"
+	app.errorResponse(w, r, http.StatusGatewayTimeout, message)
+}
+
 func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request) {
 	message := "the requested resource could not be found"
 	app.errorResponse(w, r, http.StatusNotFound, message)
@@ -45,8 +109,9 @@ func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Reques
 	app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 }
 
-func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
-	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
+func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, v *validator.Validator) {
+	env := envelope{"error": v.Errors, "field_errors": v.FieldErrors}
+	app.errorEnvelopeResponse(w, r, http.StatusUnprocessableEntity, env)
 }
 
 func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
@@ -54,11 +119,39 @@ func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Requ
 	app.errorResponse(w, r, http.StatusConflict, message)
 }
 
+// editConflictCurrentResponse is editConflictResponse with the current state
+// of the resource, so the client can merge its changes rather than retry
+// blindly.
+func (app *application) editConflictCurrentResponse(w http.ResponseWriter, r *http.Request, current envelope) {
+	message := "unable to update the record due to an edit conflict, please try again"
+	app.errorEnvelopeResponse(w, r, http.StatusConflict, envelope{"error": message, "current": current})
+}
+
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
 	message := "rate limit exceeded, please try again"
 	app.errorResponse(w, r, http.StatusTooManyRequests, message)
 }
 
+func (app *application) serviceUnavailableResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", "30")
+	message := "the service is temporarily unavailable, please try again later"
+	app.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
+
+// overloadedResponse is sent when a request is shed under load. Unlike
+// serviceUnavailableResponse it asks clients to retry almost immediately, as
+// spikes are usually short.
+func (app *application) overloadedResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", "1")
+	message := "the server is too busy to handle your request, please try again shortly"
+	app.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
+
+func (app *application) ipDeniedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "access from your IP address is not permitted"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
 func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
 	message := "invalid authentication credentials"
 	app.errorResponse(w, r, http.StatusUnauthorized, message)
@@ -69,3 +162,43 @@ func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter
 	message := "invalid or missing authentication token"
 	app.errorResponse(w, r, http.StatusUnauthorized, message)
 }
+
+// wantsProblemJSON reports whether the error should be sent as an RFC 7807
+// problem document, either because it's enabled for every response or because
+// the client asked for it in the Accept header.
+func (app *application) wantsProblemJSON(r *http.Request) bool {
+	return app.config.errors.problemJSON || strings.Contains(r.Header.Get("Accept"), "application/problem+json")
+}
+
+// problemResponse converts an error envelope into an RFC 7807 problem document.
+// A string "error" member becomes the detail; any other members, such as the
+// validation errors, are carried over as extension members.
+func (app *application) problemResponse(w http.ResponseWriter, r *http.Request, status int, env envelope) {
+	title := http.StatusText(status)
+	problem := envelope{
+		"type":   fmt.Sprintf("%s/problems/%s", app.config.baseURL, strings.ToLower(strings.ReplaceAll(title, " ", "-"))),
+		"title":  title,
+		"status": status,
+	}
+	if requestID := app.contextGetRequestID(r); requestID != "" {
+		problem["instance"] = "urn:request:" + requestID
+	}
+	for key, value := range env {
+		if detail, ok := value.(string); ok && key == "error" {
+			problem["detail"] = detail
+			continue
+		}
+		if key == "error" {
+			key = "errors"
+		}
+		problem[key] = value
+	}
+
+	headers := make(http.Header)
+	headers.Set("Content-Type", "application/problem+json")
+	err := app.writeJSON(w, status, problem, headers)
+	if err != nil {
+		app.logError(r, err)
+		w.WriteHeader(500)
+	}
+}
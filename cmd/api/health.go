@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Health statuses, from best to worst.
+const (
+	healthHealthy   = "healthy"
+	healthDegraded  = "degraded"
+	healthUnhealthy = "unhealthy"
+)
+
+var healthRank = map[string]int{healthHealthy: 0, healthDegraded: 1, healthUnhealthy: 2}
+
+// healthCheckInterval is how often checkHealth runs.
+const healthCheckInterval = 15 * time.Second
+
+// componentHealth is the last status reported by a subsystem.
+type componentHealth struct {
+	Status    string    `json:"status"`
+	Detail    string    `json:"detail,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// healthRegistry holds the status each subsystem last reported. The API is
+// unhealthy when a critical component is, and otherwise degraded when any
+// component isn't healthy.
+type healthRegistry struct {
+	mu         sync.Mutex
+	critical   map[string]bool
+	components map[string]componentHealth
+}
+
+func newHealthRegistry(critical ...string) *healthRegistry {
+	h := &healthRegistry{
+		critical:   map[string]bool{},
+		components: map[string]componentHealth{},
+	}
+	for _, name := range critical {
+		h.critical[name] = true
+	}
+	return h
+}
+
+func (h *healthRegistry) report(name, status, detail string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.components[name] = componentHealth{Status: status, Detail: detail, UpdatedAt: time.Now()}
+}
+
+// status returns the overall status and a copy of each component's.
+func (h *healthRegistry) status() (string, map[string]componentHealth) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	overall := healthHealthy
+	components := make(map[string]componentHealth, len(h.components))
+	for name, component := range h.components {
+		components[name] = component
+		status := component.Status
+		if status == healthUnhealthy && !h.critical[name] {
+			status = healthDegraded
+		}
+		if healthRank[status] > healthRank[overall] {
+			overall = status
+		}
+	}
+	return overall, components
+}
+
+// checkHealth reports the status of the subsystems which have to be polled.
+// The mailer reports its own status as emails are sent.
+func (app *application) checkHealth(ctx context.Context) {
+	if app.db == nil {
+		app.health.report("database", healthHealthy, "in-memory")
+	} else {
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		err := app.db.PingContext(ctx)
+		if err != nil {
+			app.health.report("database", healthUnhealthy, err.Error())
+		} else {
+			stats := app.db.Stats()
+			// Every connection in use with requests waiting means queries are
+			// queueing for the pool.
+			if stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections && stats.WaitCount > 0 {
+				app.health.report("database", healthDegraded, "connection pool exhausted")
+			} else {
+				app.health.report("database", healthHealthy, "")
+			}
+		}
+	}
+
+	queued, size := len(app.jobs.jobs), cap(app.jobs.jobs)
+	detail := fmt.Sprintf("%d of %d queued", queued, size)
+	if queued*10 >= size*9 {
+		app.health.report("jobs", healthDegraded, detail)
+	} else {
+		app.health.report("jobs", healthHealthy, detail)
+	}
+}
@@ -1,20 +1,56 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"time"
 )
 
+// healthcheckHandler reports whether the API is healthy, degraded or
+// unhealthy, with the status of each component. It always responds 200, so
+// that a liveness probe doesn't restart the API over a database outage. With
+// ?verbose=true it also reports the database connection pool statistics, for
+// tuning the pool flags.
 func (app *application) healthcheckHandler(w http.ResponseWriter, r *http.Request) {
+	status, components := app.health.status()
 	data := envelope{
-		"status": "available",
+		"status":     status,
+		"components": components,
 		"system_info": map[string]string{
 			"environment": app.config.env,
 			"version":     version,
 		},
 	}
+	if app.readString(r.URL.Query(), "verbose", "false") == "true" && app.db != nil {
+		data["database"] = newDBStats(app.db.Stats())
+	}
 
 	err := app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// readyzHandler reports whether the API should receive traffic: it fails once
+// shutdown has begun, and when the database can't be reached.
+func (app *application) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	data := envelope{"status": "ready"}
+	if app.shuttingDown.Load() {
+		status = http.StatusServiceUnavailable
+		data["status"] = "shutting down"
+	} else if app.db != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), time.Second)
+		defer cancel()
+		err := app.db.PingContext(ctx)
+		if err != nil {
+			status = http.StatusServiceUnavailable
+			data["status"] = "database unavailable"
+		}
+	}
+
+	err := app.writeJSON(w, status, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"database/sql"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"net/http"
+	"testing"
+)
+
+func TestHealthcheckHandlerVerbose(t *testing.T) {
+	// sql.Open doesn't connect, and the pool statistics don't need to.
+	db, err := sql.Open("postgres", "postgres://localhost/greenlight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(25)
+
+	tests := []struct {
+		name         string
+		query        string
+		wantDatabase bool
+	}{
+		{name: "default", query: ""},
+		{name: "verbose", query: "?verbose=true", wantDatabase: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, mocks.NewModels())
+			app.db = db
+
+			r := newTestRequest(t, http.MethodGet, "/v1/healthcheck"+tt.query, "", testUser, nil)
+			status, _, body := serve(t, app.healthcheckHandler, r)
+			if status != http.StatusOK {
+				t.Fatalf("got status %d; want %d", status, http.StatusOK)
+			}
+			database, ok := body["database"].(map[string]any)
+			if ok != tt.wantDatabase {
+				t.Fatalf("got database %v; want it included: %t", body["database"], tt.wantDatabase)
+			}
+			if ok && database["max_open_connections"] != float64(25) {
+				t.Errorf("got max_open_connections %v; want 25", database["max_open_connections"])
+			}
+		})
+	}
+}
+
+func TestReadyzHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		shuttingDown bool
+		wantStatus   int
+	}{
+		{name: "serving", wantStatus: http.StatusOK},
+		{name: "shutting down", shuttingDown: true, wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, mocks.NewModels())
+			app.shuttingDown.Store(tt.shuttingDown)
+
+			r := newTestRequest(t, http.MethodGet, "/v1/readyz", "", nil, nil)
+			status, _, body := serve(t, app.readyzHandler, r)
+			if status != tt.wantStatus {
+				t.Errorf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+		})
+	}
+}
+
+func TestHealthRegistry(t *testing.T) {
+	tests := []struct {
+		name    string
+		reports [][2]string
+		want    string
+	}{
+		{name: "nothing reported", want: healthHealthy},
+		{name: "all healthy", reports: [][2]string{{"database", healthHealthy}, {"mailer", healthHealthy}}, want: healthHealthy},
+		{name: "degraded component", reports: [][2]string{{"database", healthHealthy}, {"jobs", healthDegraded}}, want: healthDegraded},
+		{name: "non-critical unhealthy", reports: [][2]string{{"database", healthHealthy}, {"mailer", healthUnhealthy}}, want: healthDegraded},
+		{name: "critical unhealthy", reports: [][2]string{{"database", healthUnhealthy}, {"mailer", healthHealthy}}, want: healthUnhealthy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHealthRegistry("database")
+			for _, report := range tt.reports {
+				h.report(report[0], report[1], "")
+			}
+			got, components := h.status()
+			if got != tt.want {
+				t.Errorf("got status %q; want %q", got, tt.want)
+			}
+			if len(components) != len(tt.reports) {
+				t.Errorf("got %d components; want %d", len(components), len(tt.reports))
+			}
+		})
+	}
+}
+
+func TestHealthcheckHandlerStatus(t *testing.T) {
+	app := newTestApplication(t, mocks.NewModels())
+	app.health.report("database", healthUnhealthy, "connection refused")
+
+	r := newTestRequest(t, http.MethodGet, "/v1/healthcheck", "", testUser, nil)
+	status, _, body := serve(t, app.healthcheckHandler, r)
+	// An unhealthy API still responds 200, so liveness probes don't restart it.
+	if status != http.StatusOK {
+		t.Fatalf("got status %d; want %d", status, http.StatusOK)
+	}
+	if body["status"] != healthUnhealthy {
+		t.Errorf("got status %v; want %q", body["status"], healthUnhealthy)
+	}
+	components, _ := body["components"].(map[string]any)
+	database, _ := components["database"].(map[string]any)
+	if database["detail"] != "connection refused" {
+		t.Errorf("got database component %v", components["database"])
+	}
+}
@@ -1,14 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/i18n"
 	"github.com/ezechidc/greenlight/internal/validator"
 	"github.com/julienschmidt/httprouter"
+	"golang.org/x/text/language"
 	"io"
+	"maps"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -32,28 +38,54 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data any, h
 	}
 
 	js = append(js, '\n')
+	w.Header().Set("Content-Type", "application/json")
 	for key, value := range headers {
 		w.Header()[key] = value
 	}
-	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	w.Write(js)
 	return nil
 }
 
+// writeMessage writes env like writeJSON, with its "message" member localized
+// in the same way as error messages.
+func (app *application) writeMessage(w http.ResponseWriter, r *http.Request, status int, env envelope) error {
+	locale := app.readLanguage(r)
+	w.Header().Set("Content-Language", locale)
+	w.Header().Add("Vary", "Accept-Language")
+	env["message"] = app.translateMessage(locale, env["message"])
+	return app.writeJSON(w, status, env, nil)
+}
+
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 	r.Body = http.MaxBytesReader(w, r.Body, 1_048_576)
-	dec := json.NewDecoder(r.Body)
-	dec.DisallowUnknownFields()
-	err := dec.Decode(dst)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		}
+		return err
+	}
+
+	err = app.checkJSONStructure(body)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if !app.contextGetAllowUnknownFields(r) {
+		dec.DisallowUnknownFields()
+	}
+	err = dec.Decode(dst)
 	if err != nil {
 		var syntaxError *json.SyntaxError
 		var unmarshalTypeError *json.UnmarshalTypeError
 		var invalidUnmarshalError *json.InvalidUnmarshalError
-		var maxBytesError *http.MaxBytesError
 		switch {
 		case errors.As(err, &syntaxError):
-			return fmt.Errorf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+			line, column := jsonPosition(body, syntaxError.Offset)
+			return fmt.Errorf("body contains badly-formed JSON (at line %d, column %d)", line, column)
 
 		case errors.Is(err, io.ErrUnexpectedEOF):
 			return errors.New("body contains badly-formed JSON")
@@ -62,7 +94,8 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 			if unmarshalTypeError.Field != "" {
 				return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
 			}
-			return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
+			line, column := jsonPosition(body, unmarshalTypeError.Offset)
+			return fmt.Errorf("body contains incorrect JSON type (at line %d, column %d)", line, column)
 
 		case errors.Is(err, io.EOF):
 			return errors.New("body must not be empty")
@@ -71,9 +104,6 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
 			return fmt.Errorf("body contains unknown key %s", fieldName)
 
-		case errors.As(err, &maxBytesError):
-			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
-
 		case errors.As(err, &invalidUnmarshalError):
 			panic(err)
 		default:
@@ -87,6 +117,79 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 	return nil
 }
 
+// checkJSONStructure walks the tokens of the first JSON value in body,
+// enforcing the configured maximum nesting depth and, if enabled, rejecting
+// objects which repeat a key. Syntax errors are left for the decoder to report.
+func (app *application) checkJSONStructure(body []byte) error {
+	type frame struct {
+		object    bool
+		expectKey bool
+		keys      map[string]bool
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	var stack []*frame
+	for {
+		offset := dec.InputOffset()
+		token, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		// InputOffset points before any separator preceding the token, so skip
+		// past it to report the position of the token itself.
+		for offset < int64(len(body)) && strings.IndexByte(" \t\r\n,:", body[offset]) >= 0 {
+			offset++
+		}
+
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			if top != nil && top.object {
+				top.expectKey = true
+			}
+			if len(stack) >= app.config.json.maxDepth {
+				line, column := jsonPosition(body, offset)
+				return fmt.Errorf("body must not be nested more than %d levels deep (at line %d, column %d)", app.config.json.maxDepth, line, column)
+			}
+			object := token == json.Delim('{')
+			stack = append(stack, &frame{object: object, expectKey: object, keys: make(map[string]bool)})
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		default:
+			if key, ok := token.(string); ok && top != nil && top.object && top.expectKey {
+				if app.config.json.rejectDuplicateKeys && top.keys[key] {
+					line, column := jsonPosition(body, offset)
+					return fmt.Errorf("body contains duplicate key %q (at line %d, column %d)", key, line, column)
+				}
+				top.keys[key] = true
+				top.expectKey = false
+				continue
+			}
+			if top != nil && top.object {
+				top.expectKey = true
+			}
+		}
+
+		if len(stack) == 0 {
+			return nil
+		}
+	}
+}
+
+// jsonPosition converts a byte offset in body into a 1-based line and column.
+func jsonPosition(body []byte, offset int64) (int, int) {
+	offset = min(offset, int64(len(body)))
+	before := body[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
 func (app *application) readString(qs url.Values, key string, defaultValue string) string {
 	s := qs.Get(key)
 	if s == "" {
@@ -111,21 +214,103 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	}
 	i, err := strconv.Atoi(s)
 	if err != nil {
-		v.AddError(key, "must be an integer value")
+		v.AddErrorCode(key, "must_be_integer", "must be an integer value")
 		return defaultValue
 	}
 	return i
 }
 
+// readDate returns the ISO 8601 date in the query string value for key, or nil
+// if there isn't one.
+func (app *application) readDate(qs url.Values, key string, v *validator.Validator) *data.Date {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+	d, err := data.ParseDate(s)
+	if err != nil {
+		v.AddErrorCode(key, "invalid_date", "must be a date in YYYY-MM-DD format")
+		return nil
+	}
+	return &d
+}
+
+var languageMatcher = language.NewMatcher(func() []language.Tag {
+	tags := make([]language.Tag, len(data.SupportedLanguages))
+	for i, lang := range data.SupportedLanguages {
+		tags[i] = language.Make(lang)
+	}
+	return tags
+}())
+
+// readLanguage returns the language to respond in: the authenticated user's
+// language if they have one, otherwise the supported language which best
+// matches the request's Accept-Language header, defaulting to the first
+// supported language.
+func (app *application) readLanguage(r *http.Request) string {
+	user, ok := r.Context().Value(userContextKey).(*data.User)
+	if ok && !user.IsAnonymous() && slices.Contains(data.SupportedLanguages, user.Language) {
+		return user.Language
+	}
+	tags, _, _ := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	_, index, _ := languageMatcher.Match(tags...)
+	return data.SupportedLanguages[index]
+}
+
+// localizeMovies replaces each movie's title with its translation in the
+// client's preferred language, if it has one: the authenticated user's
+// language, then those of the Accept-Language header in order of preference.
+// The default-language title stays available under titles.
+func (app *application) localizeMovies(w http.ResponseWriter, r *http.Request, movies ...*data.Movie) {
+	w.Header().Add("Vary", "Accept-Language")
+	var preferred []string
+	user, ok := r.Context().Value(userContextKey).(*data.User)
+	if ok && !user.IsAnonymous() && user.Language != "" {
+		preferred = append(preferred, user.Language)
+	}
+	tags, _, _ := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	for _, tag := range tags {
+		preferred = append(preferred, tag.String())
+	}
+
+	for _, movie := range movies {
+		title, ok := movie.Titles.Best(preferred, i18n.DefaultLocale)
+		if !ok || title == movie.Title {
+			continue
+		}
+		titles := maps.Clone(movie.Titles)
+		titles[i18n.DefaultLocale] = movie.Title
+		movie.Title, movie.Titles = title, titles
+	}
+}
+
 func (app *application) background(fn func()) {
 	app.wg.Add(1)
 	go func() {
 		defer app.wg.Done()
 		defer func() {
 			if err := recover(); err != nil {
-				app.logger.Error(fmt.Sprintf("%v", err))
+				app.reportPanic(panicReport{Source: "background", Panic: fmt.Sprint(err), Stack: panicStack()})
 			}
 		}()
 		fn() // Execute the arbitrary function that we passed as the parameter.
 	}()
 }
+
+// checkPwnedPassword adds a validation error to v if the password appears in
+// a known breach. Lookup failures are logged and, unless -pwned-fail-open is
+// off, the password is accepted.
+func (app *application) checkPwnedPassword(r *http.Request, v *validator.Validator, password string) {
+	if !app.config.pwned.enabled {
+		return
+	}
+	compromised, err := app.pwned.Compromised(r.Context(), password)
+	if err != nil {
+		app.logError(r, fmt.Errorf("pwned passwords lookup: %w", err))
+		if !app.config.pwned.failOpen {
+			v.AddErrorCode("password", "unverified_password", "unable to check this password, please try again later")
+		}
+		return
+	}
+	v.CheckCode(!compromised, "password", "compromised_password", "this password has appeared in a data breach, please choose another")
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"net/http"
+	"testing"
+)
+
+func TestReadLanguage(t *testing.T) {
+	french := &data.User{ID: 3, Activated: true, Language: "fr"}
+
+	tests := []struct {
+		name           string
+		user           *data.User
+		acceptLanguage string
+		want           string
+	}{
+		{name: "default", user: data.AnonymousUser, want: "en"},
+		{name: "accept-language", user: data.AnonymousUser, acceptLanguage: "fr-CA, en;q=0.5", want: "fr"},
+		{name: "unsupported accept-language", user: data.AnonymousUser, acceptLanguage: "de", want: "en"},
+		{name: "user language", user: french, want: "fr"},
+		{name: "user language over accept-language", user: french, acceptLanguage: "en", want: "fr"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, mocks.NewModels())
+			r := newTestRequest(t, http.MethodGet, "/v1/movies", "", tt.user, nil)
+			if tt.acceptLanguage != "" {
+				r.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			if got := app.readLanguage(r); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
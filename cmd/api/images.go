@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+)
+
+// maxImagePixels limits the decoded size of uploaded images, since a small
+// file can declare huge dimensions.
+const maxImagePixels = 25_000_000
+
+// imageFormats are the formats accepted for uploaded images, as named by
+// image.Decode.
+var imageFormats = []string{"jpeg", "png", "gif"}
+
+// readImageUpload returns an uploaded image of at most maxBytes, sent either
+// as the request body or as the named file of a multipart/form-data form.
+func (app *application) readImageUpload(w http.ResponseWriter, r *http.Request, field string, maxBytes int) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes)+1024)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	body := r.Body
+	if mediaType == "multipart/form-data" {
+		err := r.ParseMultipartForm(int64(maxBytes))
+		if err != nil {
+			return nil, tooLargeError(err, maxBytes)
+		}
+		file, _, err := r.FormFile(field)
+		if err != nil {
+			return nil, fmt.Errorf("body must contain a file named %q", field)
+		}
+		defer file.Close()
+		body = file
+	}
+	b, err := io.ReadAll(io.LimitReader(body, int64(maxBytes)+1))
+	if err != nil {
+		return nil, tooLargeError(err, maxBytes)
+	}
+	if len(b) > maxBytes {
+		return nil, fmt.Errorf("body must not be larger than %d bytes", maxBytes)
+	}
+	return b, nil
+}
+
+func tooLargeError(err error, maxBytes int) error {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return fmt.Errorf("body must not be larger than %d bytes", maxBytes)
+	}
+	return err
+}
+
+// checkImage adds a validation error for field to v if b isn't one of
+// imageFormats or is too large to decode, without decoding it.
+func checkImage(v *validator.Validator, field string, b []byte) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil || !slices.Contains(imageFormats, format) {
+		v.AddErrorCode(field, "invalid_image", "must be a JPEG, PNG or GIF image")
+		return
+	}
+	if config.Width*config.Height > maxImagePixels {
+		v.AddErrorCode(field, "too_large", "must not be more than 25 megapixels")
+	}
+}
+
+// decodeImage decodes an uploaded image, adding a validation error for field
+// to v if checkImage rejects it or it can't be decoded.
+func decodeImage(v *validator.Validator, field string, b []byte) image.Image {
+	checkImage(v, field, b)
+	if !v.Valid() {
+		return nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		v.AddErrorCode(field, "invalid_image", "must be a JPEG, PNG or GIF image")
+		return nil
+	}
+	return img
+}
+
+// flattenImage copies the rect part of img onto a white background, as JPEG
+// has no alpha channel.
+func flattenImage(img image.Image, rect image.Rectangle) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, rect.Min, draw.Over)
+	return dst
+}
+
+// scaleImage scales src down to width by height, averaging the source pixels
+// behind each pixel of the result. It never scales up.
+func scaleImage(src *image.RGBA, width, height int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if width >= sw || height >= sh {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		y0, y1 := y*sh/height, (y+1)*sh/height
+		for x := range width {
+			x0, x1 := x*sw/width, (x+1)*sw/width
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			i := y*dst.Stride + x*4
+			for c := range sum {
+				dst.Pix[i+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
+
+func encodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
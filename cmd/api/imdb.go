@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The imdb subcommand bootstraps the catalog from the public IMDb datasets at
+// https://datasets.imdbws.com. The files are streamed, plain or gzipped, and
+// movies are added in batches with COPY:
+//
+//	go run ./cmd/api imdb -basics title.basics.tsv.gz -ratings title.ratings.tsv.gz -min-votes 1000
+//
+// Imported movies record their IMDb ID, and title.basics is sorted by it, so
+// an interrupted import resumes after the last movie it added. Ratings aren't
+// stored, as movies have nowhere to keep them; title.ratings is only used to
+// leave out titles with fewer than -min-votes votes.
+
+type imdbConfig struct {
+	dsn      string
+	basics   string
+	ratings  string
+	types    []string
+	minVotes int
+	batch    int
+	resume   bool
+}
+
+// imdbCounts tallies what happened to the rows of title.basics.
+type imdbCounts struct {
+	read     int
+	imported int
+	skipped  map[string]int
+}
+
+// runIMDb implements the imdb subcommand. args are the command line arguments
+// following "imdb".
+func runIMDb(args []string) error {
+	var (
+		cfg   imdbConfig
+		types string
+	)
+	fs := flag.NewFlagSet("imdb", flag.ExitOnError)
+	fs.StringVar(&cfg.dsn, "db-dsn", "", "PostgreSQL DSN")
+	fs.StringVar(&cfg.basics, "basics", "", "Path of title.basics.tsv, optionally gzipped")
+	fs.StringVar(&cfg.ratings, "ratings", "", "Path of title.ratings.tsv, optionally gzipped (needed for -min-votes)")
+	fs.StringVar(&types, "types", "movie", "Comma-separated title types to import, such as movie,tvMovie")
+	fs.IntVar(&cfg.minVotes, "min-votes", 0, "Skip titles with fewer IMDb votes than this")
+	fs.IntVar(&cfg.batch, "batch", 5000, "Movies added per transaction")
+	fs.BoolVar(&cfg.resume, "resume", true, "Skip titles up to the last one imported")
+	fs.Parse(args)
+	err := bindEnv(fs)
+	if err != nil {
+		return err
+	}
+	cfg.types = strings.Split(types, ",")
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	return importIMDb(cfg, logger)
+}
+
+func importIMDb(cfg imdbConfig, logger *slog.Logger) error {
+	switch {
+	case cfg.basics == "":
+		return errors.New("-basics must be given")
+	case cfg.batch < 1 || cfg.batch > 100000:
+		return errors.New("-batch must be between 1 and 100000")
+	case cfg.minVotes < 0:
+		return errors.New("-min-votes must not be negative")
+	case cfg.minVotes > 0 && cfg.ratings == "":
+		return errors.New("-min-votes needs -ratings")
+	}
+
+	db, err := sql.Open("postgres", cfg.dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	var after int64
+	if cfg.resume {
+		after, err = data.LastIMDbID(ctx, db)
+		if err != nil {
+			return err
+		}
+		if after > 0 {
+			logger.Info("resuming", "after", fmt.Sprintf("tt%07d", after))
+		}
+	}
+
+	var votes map[int64]int
+	if cfg.ratings != "" {
+		f, err := openTSV(cfg.ratings)
+		if err != nil {
+			return err
+		}
+		votes, err = readIMDbVotes(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", cfg.ratings, err)
+		}
+		logger.Info("read ratings", "titles", len(votes))
+	}
+
+	f, err := openTSV(cfg.basics)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	start := time.Now()
+	batch := make([]*data.IMDbMovie, 0, cfg.batch)
+	var counts imdbCounts
+	flush := func() error {
+		added, err := data.CopyIMDbMovies(ctx, db, batch)
+		if err != nil {
+			return err
+		}
+		counts.imported += int(added)
+		batch = batch[:0]
+		logger.Info("progress", "read", counts.read, "imported", counts.imported, "rate", fmt.Sprintf("%.0f/s", float64(counts.read)/time.Since(start).Seconds()))
+		return nil
+	}
+	err = readIMDbBasics(f, cfg, after, votes, &counts, func(movie *data.IMDbMovie) error {
+		batch = append(batch, movie)
+		if len(batch) < cfg.batch {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", cfg.basics, err)
+	}
+	if len(batch) > 0 {
+		err = flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	args := []any{"read", counts.read, "imported", counts.imported, "duration", time.Since(start).Round(time.Millisecond).String()}
+	for reason, n := range counts.skipped {
+		args = append(args, "skipped_"+reason, n)
+	}
+	logger.Info("done", args...)
+	return nil
+}
+
+// tsvFile is an open dataset file, decompressed if it was gzipped.
+type tsvFile struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (f *tsvFile) Close() error {
+	var errs []error
+	for _, c := range slices.Backward(f.closers) {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func openTSV(path string) (*tsvFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return &tsvFile{Reader: f, closers: []io.Closer{f}}, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &tsvFile{Reader: gz, closers: []io.Closer{f, gz}}, nil
+}
+
+// tsvReader reads the rows of an IMDb dataset. The datasets aren't quite TSV
+// as encoding/csv understands it: fields are never quoted, so quotes are
+// literal, and \N marks a missing value.
+type tsvReader struct {
+	scanner *bufio.Scanner
+	columns map[string]int
+	line    int
+}
+
+// newTSVReader reads the header row and checks it has every column in want.
+func newTSVReader(r io.Reader, want ...string) (*tsvReader, error) {
+	tr := &tsvReader{scanner: bufio.NewScanner(r)}
+	tr.scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	header, err := tr.next()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("empty file")
+		}
+		return nil, err
+	}
+	tr.columns = make(map[string]int, len(header))
+	for i, name := range header {
+		tr.columns[name] = i
+	}
+	for _, name := range want {
+		if _, ok := tr.columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+	return tr, nil
+}
+
+// next returns the fields of the next row, or io.EOF after the last.
+func (tr *tsvReader) next() ([]string, error) {
+	if !tr.scanner.Scan() {
+		if err := tr.scanner.Err(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", tr.line+1, err)
+		}
+		return nil, io.EOF
+	}
+	tr.line++
+	fields := strings.Split(tr.scanner.Text(), "\t")
+	if tr.columns != nil && len(fields) != len(tr.columns) {
+		return nil, fmt.Errorf("line %d: got %d fields; want %d", tr.line, len(fields), len(tr.columns))
+	}
+	return fields, nil
+}
+
+// field returns the named column of a row, or "" if the value is missing.
+func (tr *tsvReader) field(fields []string, name string) string {
+	value := fields[tr.columns[name]]
+	if value == `\N` {
+		return ""
+	}
+	return value
+}
+
+// parseIMDbID returns the number in a tconst such as tt1375666.
+func parseIMDbID(tconst string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(tconst, "tt"), 10, 64)
+	if err != nil || !strings.HasPrefix(tconst, "tt") || id < 1 {
+		return 0, fmt.Errorf("invalid tconst %q", tconst)
+	}
+	return id, nil
+}
+
+// readIMDbVotes reads title.ratings and returns the number of votes of each
+// title by IMDb ID.
+func readIMDbVotes(r io.Reader) (map[int64]int, error) {
+	tr, err := newTSVReader(r, "tconst", "numVotes")
+	if err != nil {
+		return nil, err
+	}
+	votes := map[int64]int{}
+	for {
+		fields, err := tr.next()
+		if errors.Is(err, io.EOF) {
+			return votes, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		id, err := parseIMDbID(tr.field(fields, "tconst"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", tr.line, err)
+		}
+		n, err := strconv.Atoi(tr.field(fields, "numVotes"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid numVotes: %w", tr.line, err)
+		}
+		votes[id] = n
+	}
+}
+
+// readIMDbBasics reads title.basics and calls fn with each title to import:
+// those of the configured types with an ID above after, enough votes and the
+// fields a movie needs. The titles which aren't imported are counted in
+// counts.skipped by reason.
+func readIMDbBasics(r io.Reader, cfg imdbConfig, after int64, votes map[int64]int, counts *imdbCounts, fn func(*data.IMDbMovie) error) error {
+	tr, err := newTSVReader(r, "tconst", "titleType", "primaryTitle", "isAdult", "startYear", "runtimeMinutes", "genres")
+	if err != nil {
+		return err
+	}
+	counts.skipped = map[string]int{}
+	for {
+		fields, err := tr.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		counts.read++
+
+		id, err := parseIMDbID(tr.field(fields, "tconst"))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", tr.line, err)
+		}
+		movie, reason := imdbMovie(tr, fields)
+		switch {
+		case id <= after:
+			reason = "imported"
+		case !slices.Contains(cfg.types, tr.field(fields, "titleType")):
+			reason = "type"
+		case tr.field(fields, "isAdult") == "1":
+			reason = "adult"
+		case cfg.minVotes > 0 && votes[id] < cfg.minVotes:
+			reason = "votes"
+		}
+		if reason != "" {
+			counts.skipped[reason]++
+			continue
+		}
+		err = fn(&data.IMDbMovie{IMDbID: id, Movie: movie})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// imdbMovie converts a row of title.basics to a movie. If the row can't be
+// imported it returns why instead.
+func imdbMovie(tr *tsvReader, fields []string) (*data.Movie, string) {
+	year, err := strconv.Atoi(tr.field(fields, "startYear"))
+	if err != nil {
+		return nil, "no_year"
+	}
+	runtime, err := strconv.Atoi(tr.field(fields, "runtimeMinutes"))
+	if err != nil {
+		return nil, "no_runtime"
+	}
+	genres := []string{}
+	if value := tr.field(fields, "genres"); value != "" {
+		genres = strings.Split(strings.ToLower(value), ",")
+	}
+	movie := &data.Movie{
+		Title:   tr.field(fields, "primaryTitle"),
+		Year:    int32(year),
+		Runtime: data.Runtime(runtime),
+		Genres:  genres,
+	}
+	v := validator.New()
+	if data.ValidateMovie(v, movie); !v.Valid() {
+		return nil, "invalid"
+	}
+	return movie, ""
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"github.com/ezechidc/greenlight/internal/data"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testIMDbBasics = "tconst\ttitleType\tprimaryTitle\toriginalTitle\tisAdult\tstartYear\tendYear\truntimeMinutes\tgenres\n" +
+	"tt0000001\tmovie\tAlready Imported\tAlready Imported\t0\t1999\t\\N\t90\tDrama\n" +
+	"tt0000002\tmovie\tThe \"Quoted\" Film\tThe \"Quoted\" Film\t0\t2001\t\\N\t101\tAction,Sci-Fi\n" +
+	"tt0000003\tshort\tA Short\tA Short\t0\t2001\t\\N\t7\tShort\n" +
+	"tt0000004\tmovie\tNo Runtime\tNo Runtime\t0\t2005\t\\N\t\\N\tComedy\n" +
+	"tt0000005\tmovie\tObscure\tObscure\t0\t2010\t\\N\t80\t\\N\n" +
+	"tt0000006\tmovie\tUngenred\tUngenred\t0\t2011\t\\N\t95\t\\N\n" +
+	"tt0000007\tmovie\tAdult\tAdult\t1\t2012\t\\N\t95\tDrama\n"
+
+const testIMDbRatings = "tconst\taverageRating\tnumVotes\n" +
+	"tt0000002\t7.5\t2400\n" +
+	"tt0000004\t6.0\t300\n" +
+	"tt0000005\t6.1\t12\n" +
+	"tt0000006\t5.0\t500\n"
+
+func TestReadIMDbBasics(t *testing.T) {
+	votes, err := readIMDbVotes(strings.NewReader(testIMDbRatings))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if votes[2] != 2400 || len(votes) != 4 {
+		t.Fatalf("got votes %v", votes)
+	}
+
+	cfg := imdbConfig{types: []string{"movie"}, minVotes: 100}
+	var (
+		counts   imdbCounts
+		imported []*data.IMDbMovie
+	)
+	err = readIMDbBasics(strings.NewReader(testIMDbBasics), cfg, 1, votes, &counts, func(movie *data.IMDbMovie) error {
+		imported = append(imported, movie)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(imported) != 2 {
+		t.Fatalf("got %d movies; want 2", len(imported))
+	}
+	first := imported[0]
+	if first.IMDbID != 2 || first.Movie.Title != `The "Quoted" Film` || first.Movie.Year != 2001 || first.Movie.Runtime != 101 {
+		t.Errorf("got %d %+v", first.IMDbID, first.Movie)
+	}
+	if !slices.Equal(first.Movie.Genres, []string{"action", "sci-fi"}) {
+		t.Errorf("got genres %v; want [action sci-fi]", first.Movie.Genres)
+	}
+	if genres := imported[1].Movie.Genres; genres == nil || len(genres) != 0 {
+		t.Errorf("got genres %#v for a title without any; want an empty list", genres)
+	}
+
+	if counts.read != 7 || counts.imported != 0 {
+		t.Errorf("got %d read and %d imported; want 7 and 0", counts.read, counts.imported)
+	}
+	wantSkipped := map[string]int{"imported": 1, "type": 1, "no_runtime": 1, "votes": 1, "adult": 1}
+	for reason, n := range wantSkipped {
+		if counts.skipped[reason] != n {
+			t.Errorf("got %d skipped for %s; want %d", counts.skipped[reason], reason, n)
+		}
+	}
+}
+
+func TestReadIMDbBasicsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "empty", input: "", want: "empty file"},
+		{name: "missing column", input: "tconst\ttitleType\n", want: `missing column "primaryTitle"`},
+		{name: "short row", input: strings.SplitAfter(testIMDbBasics, "\n")[0] + "tt0000001\tmovie\n", want: "line 2: got 2 fields; want 9"},
+		{name: "bad tconst", input: strings.SplitAfter(testIMDbBasics, "\n")[0] + "nm0000001\tmovie\tA\tA\t0\t2000\t\\N\t90\tDrama\n", want: `line 2: invalid tconst "nm0000001"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var counts imdbCounts
+			err := readIMDbBasics(strings.NewReader(tt.input), imdbConfig{types: []string{"movie"}}, 0, nil, &counts, func(*data.IMDbMovie) error { return nil })
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("got error %v; want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+//go:build integration
+
+// The integration tests run the API in-process against a throwaway PostgreSQL
+// container started with testcontainers-go, delivering emails to an in-process
+// SMTP server. They need Docker, and run with:
+//
+//	go test -tags integration ./cmd/api
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/blocklist"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/mailer"
+	"github.com/ezechidc/greenlight/internal/pwned"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var activationTokenRX = regexp.MustCompile(`"token": "([A-Z0-9]{26})"`)
+
+// smtpServer is a minimal in-process SMTP server which accepts every message
+// and hands it to the test on messages.
+type smtpServer struct {
+	listener net.Listener
+	messages chan string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{listener: listener, messages: make(chan string, 100)}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		fmt.Fprintf(conn, "%s\r\n", line)
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"):
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case command == "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			var message strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				message.WriteString(strings.TrimPrefix(line, "."))
+			}
+			s.messages <- message.String()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+// waitForMatch returns the first submatch of rx in the next email which
+// matches it, skipping any others.
+func (s *smtpServer) waitForMatch(t *testing.T, rx *regexp.Regexp) string {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case message := <-s.messages:
+			// Undo quoted-printable soft line breaks so long lines match.
+			message = strings.ReplaceAll(message, "=\r\n", "")
+			if match := rx.FindStringSubmatch(message); match != nil {
+				return match[1]
+			}
+		case <-timeout:
+			t.Fatalf("no email matching %s", rx)
+		}
+	}
+}
+
+// newPostgres starts a PostgreSQL container, applies the migrations and
+// returns a connection pool to it.
+func newPostgres(t *testing.T) *sql.DB {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+	ctx := context.Background()
+	container, err := postgres.Run(ctx, "postgres:17",
+		postgres.WithDatabase("greenlight"),
+		postgres.WithUsername("greenlight"),
+		postgres.WithPassword("pa55word"),
+		postgres.BasicWaitStrategies(),
+	)
+	testcontainers.CleanupContainer(t, container)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsn, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, extension := range []string{"citext", "pg_trgm", "vector"} {
+		_, err = db.Exec("CREATE EXTENSION IF NOT EXISTS " + extension)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := filepath.Glob("../../migrations/*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no migrations found")
+	}
+	slices.Sort(files)
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.Exec(string(b))
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+	}
+	return db
+}
+
+// newIntegrationServer runs the API with models, sending email to smtp.
+func newIntegrationServer(t *testing.T, models data.Models, smtp *smtpServer) *httptest.Server {
+	t.Helper()
+	m, err := mailer.New("127.0.0.1", smtp.port(), "", "", "Greenlight <no-reply@greenlight.example>", mailer.TLSNone, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg config
+	cfg.env = "development"
+	cfg.security.headers = "off"
+	cfg.json.maxDepth = 32
+	cfg.json.rejectDuplicateKeys = true
+	cfg.jobs.workers = 2
+	cfg.jobs.queueSize = 100
+
+	app := &application{
+		config:         cfg,
+		logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models:         models,
+		mailer:         m,
+		authLimiter:    newAuthLimiter(2, 4, time.Minute, time.Hour),
+		limiter:        newClientLimiter(limiterSettings{}),
+		health:         newHealthRegistry("database"),
+		tokenCache:     newTokenCache(0, 1),
+		disposable:     blocklist.NewDisposable(),
+		pwned:          pwned.New(time.Second),
+		jobs:           newJobQueue(cfg.jobs.queueSize),
+		views:          newViewBuffer(100),
+		usage:          newUsageBuffer(100),
+		passwordParams: data.DefaultPasswordParams(),
+	}
+	app.startJobWorkers(cfg.jobs.workers)
+
+	srv := httptest.NewServer(app.rooutes())
+	app.config.baseURL = srv.URL
+	t.Cleanup(func() {
+		srv.Close()
+		app.jobs.close()
+		app.wg.Wait()
+		m.Close()
+	})
+	return srv
+}
+
+type client struct {
+	t     *testing.T
+	url   string
+	token string
+}
+
+// expect makes a request and fails the test unless it gets the given status.
+// It returns the decoded response body.
+func (c *client) expect(method, path string, body any, status int) map[string]any {
+	c.t.Helper()
+	var reqBody io.Reader
+	if body != nil {
+		js, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		reqBody = bytes.NewReader(js)
+	}
+	req, err := http.NewRequest(method, c.url+path, reqBody)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if resp.StatusCode != status {
+		c.t.Fatalf("%s %s: got status %d; want %d: %s", method, path, resp.StatusCode, status, respBody)
+	}
+	var decoded map[string]any
+	_ = json.Unmarshal(respBody, &decoded)
+	return decoded
+}
+
+func TestIntegration(t *testing.T) {
+	smtp := newSMTPServer(t)
+	srv := newIntegrationServer(t, data.NewModels(newPostgres(t)), smtp)
+	c := &client{t: t, url: srv.URL}
+
+	user := map[string]any{"name": "Integration", "email": "integration@example.com", "password": "pa55word"}
+	c.expect(http.MethodPost, "/v1/users", user, http.StatusCreated)
+	activationToken := smtp.waitForMatch(t, activationTokenRX)
+	c.expect(http.MethodPut, "/v1/users/activated", map[string]any{"token": activationToken}, http.StatusOK)
+
+	body := c.expect(http.MethodPost, "/v1/tokens/authentication", map[string]any{"email": user["email"], "password": user["password"]}, http.StatusCreated)
+	token, _ := body["authentication_token"].(map[string]any)
+	c.expect(http.MethodGet, "/v1/movies", nil, http.StatusUnauthorized)
+	c.token, _ = token["Plaintext"].(string)
+
+	movie := map[string]any{"title": "Moana", "year": 2016, "runtime": "107 mins", "genres": []string{"animation", "adventure"}}
+	body = c.expect(http.MethodPost, "/v1/movies", movie, http.StatusCreated)
+	created, _ := body["movie"].(map[string]any)
+	id, _ := created["id"].(float64)
+	path := "/v1/movies/" + strconv.FormatInt(int64(id), 10)
+
+	checks := []struct {
+		method string
+		path   string
+		body   any
+		status int
+	}{
+		{http.MethodGet, path, nil, http.StatusOK},
+		{http.MethodPatch, path, map[string]any{"year": 2017}, http.StatusOK},
+		{http.MethodPatch, path, map[string]any{"genres": []string{}}, http.StatusOK},
+		{http.MethodGet, "/v1/movies?title=moana", nil, http.StatusOK},
+		{http.MethodPost, "/v1/movies", map[string]any{"title": ""}, http.StatusUnprocessableEntity},
+		{http.MethodDelete, path, nil, http.StatusOK},
+		{http.MethodGet, path, nil, http.StatusNotFound},
+	}
+	for _, check := range checks {
+		c.expect(check.method, check.path, check.body, check.status)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"net/http"
+	"net/url"
+)
+
+// createInvitationHandler invites someone to create an account with a given
+// role. Users can only invite into roles whose permissions they already hold.
+func (app *application) createInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	inviter := app.contextGetUser(r)
+	invitation := &data.Invitation{Email: input.Email, Role: input.Role}
+	v := validator.New()
+	if data.ValidateInvitation(v, invitation); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), inviter.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !invitation.CanBeGrantedBy(permissions) {
+		app.auditDenial(r, inviter, "cannot grant role "+invitation.Role)
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	_, err = app.models.Users.GetByEmail(r.Context(), invitation.Email)
+	switch {
+	case err == nil:
+		v.AddErrorCode("email", "duplicate_email", "a user with this email address already exists")
+		app.failedValidationResponse(w, r, v)
+		return
+	case !errors.Is(err, data.ErrRecordNotFound):
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	invitation, err = app.models.Invitations.New(r.Context(), invitation.Email, invitation.Role, inviter.ID, app.config.invitations.ttl)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.background(func() {
+		templateData := map[string]any{
+			"inviterName":     inviter.Name,
+			"role":            invitation.Role,
+			"invitationToken": invitation.Plaintext,
+			"expiry":          invitation.Expiry.Format("2 January 2006"),
+		}
+		if app.config.invitations.signupURL != "" {
+			templateData["signupURL"] = app.config.invitations.signupURL + "?" + url.Values{"token": {invitation.Plaintext}}.Encode()
+		}
+		app.sendEmail(invitation.Email, inviter.Language, "invitation.tmpl", templateData)
+	})
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/invitations/%d", invitation.ID))
+	err = app.writeJSON(w, http.StatusCreated, envelope{"invitation": invitation}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
+	input.Filters.SortSafelist = []string{"id", "created_at", "email", "expiry", "-id", "-created_at", "-email", "-expiry"}
+	app.debugParams(r, envelope{"filters": input.Filters})
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	invitations, metadata, err := app.models.Invitations.GetAllPending(r.Context(), input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	metadata.SetLinks(app.config.baseURL+r.URL.Path, r.URL.Query())
+	err = app.writeJSON(w, http.StatusOK, envelope{"invitations": invitations, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// revokeInvitationHandler withdraws a pending invitation. Like creating one,
+// it's only allowed for roles whose permissions the user already holds.
+func (app *application) revokeInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	invitation, err := app.models.Invitations.GetPending(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	user := app.contextGetUser(r)
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !invitation.CanBeGrantedBy(permissions) {
+		app.auditDenial(r, user, "cannot revoke role "+invitation.Role)
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	err = app.models.Invitations.Revoke(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeMessage(w, r, http.StatusOK, envelope{"message": "invitation successfully revoked"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// acceptInvitationHandler creates the invited user's account. The invitation
+// email proves they own the address, so the account is activated straight
+// away and given the permissions of the invited role.
+func (app *application) acceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TokenPlaintext string `json:"token"`
+		Name           string `json:"name"`
+		Password       string `json:"password"`
+		Language       string `json:"language"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	invitation, err := app.models.Invitations.GetForToken(r.Context(), input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddErrorCode("token", "invalid_token", "invalid or expired invitation token")
+			app.failedValidationResponse(w, r, v)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := &data.User{
+		Name:      input.Name,
+		Email:     invitation.Email,
+		Activated: true,
+		Language:  input.Language,
+	}
+	if user.Language == "" {
+		user.Language = app.readLanguage(r)
+	}
+	data.ValidateUserDetails(v, user)
+	if data.ValidatePasswordPlaintext(v, input.Password); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	if app.checkPwnedPassword(r, v, input.Password); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	err = user.Password.Set(input.Password, app.passwordParams)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.WithTx(r.Context(), func(m data.Models) error {
+		err := m.Users.Insert(r.Context(), user)
+		if err != nil {
+			return err
+		}
+		if codes := data.Roles[invitation.Role]; len(codes) > 0 {
+			err = m.Permissions.AddForUser(r.Context(), user.ID, codes...)
+			if err != nil {
+				return err
+			}
+		}
+		return m.Invitations.MarkAccepted(r.Context(), invitation.ID, user.ID)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateEmail):
+			v.AddErrorCode("email", "duplicate_email", "a user with this email address already exists")
+			app.failedValidationResponse(w, r, v)
+		case errors.Is(err, data.ErrEditConflict):
+			v.AddErrorCode("token", "invalid_token", "invalid or expired invitation token")
+			app.failedValidationResponse(w, r, v)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"testing"
+)
+
+func TestRevokeInvitationHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		role       string
+		wantStatus int
+	}{
+		{name: "grantable role", role: "editor", wantStatus: http.StatusOK},
+		{name: "role the user can't grant", role: "admin", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			invitations := models.Invitations.(*mocks.InvitationStore)
+			invitations.GetPendingFunc = func(ctx context.Context, id int64) (*data.Invitation, error) {
+				return &data.Invitation{ID: id, Email: "bob@example.com", Role: tt.role}, nil
+			}
+			invitations.RevokeFunc = func(ctx context.Context, id int64) error {
+				return nil
+			}
+			models.Permissions.(*mocks.PermissionStore).GetAllForUserFunc = func(ctx context.Context, userID int64) (data.Permissions, error) {
+				return data.Roles["editor"], nil
+			}
+			models.Audit.(*mocks.AuditStore).InsertFunc = func(ctx context.Context, event *data.AuditEvent) error {
+				return nil
+			}
+			app := newTestApplication(t, models)
+
+			r := newTestRequest(t, http.MethodDelete, "/v1/invitations/7", "", testUser, httprouter.Params{{Key: "id", Value: "7"}})
+			status, _, resp := serve(t, app.revokeInvitationHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, resp)
+			}
+			if revoked := len(invitations.RevokeCalls()) == 1; revoked != (status == http.StatusOK) {
+				t.Errorf("got revoked %t after status %d", revoked, status)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var droppedJobs = expvar.NewMap("jobs_dropped")
+
+// job is a unit of background work run by the job queue workers.
+type job struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// jobQueue is a bounded queue of background work drained by a fixed pool of
+// workers, so bursts of requests or a large batch of scheduled work can't
+// start an unbounded number of goroutines. Once closed, enqueueing fails and
+// the workers exit after finishing the jobs already queued.
+type jobQueue struct {
+	mu     sync.RWMutex
+	closed bool
+	jobs   chan job
+}
+
+func newJobQueue(size int) *jobQueue {
+	return &jobQueue{jobs: make(chan job, size)}
+}
+
+// enqueue waits for room in the queue, giving up if ctx is done or the queue
+// is closed. It is meant for scheduled work, which should slow down rather
+// than be dropped when the workers fall behind.
+func (q *jobQueue) enqueue(ctx context.Context, name string, fn func(ctx context.Context) error) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return false
+	}
+	select {
+	case q.jobs <- job{name: name, fn: fn}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// tryEnqueue queues the job only if there's room, counting it in the
+// jobs_dropped expvar otherwise. It is meant for work triggered by requests,
+// which mustn't block the response or let clients grow the backlog.
+func (q *jobQueue) tryEnqueue(name string, fn func(ctx context.Context) error) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if !q.closed {
+		select {
+		case q.jobs <- job{name: name, fn: fn}:
+			return true
+		default:
+		}
+	}
+	droppedJobs.Add(name, 1)
+	return false
+}
+
+func (q *jobQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+}
+
+// startJobWorkers starts the workers draining app.jobs. They are tracked by
+// app.wg, so shutdown waits for queued jobs once the queue is closed.
+func (app *application) startJobWorkers(n int) {
+	for range n {
+		app.wg.Add(1)
+		go func() {
+			defer app.wg.Done()
+			for j := range app.jobs.jobs {
+				app.runJob(j)
+			}
+		}()
+	}
+}
+
+func (app *application) runJob(j job) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.reportPanic(panicReport{Source: "job", Panic: fmt.Sprint(err), Stack: panicStack(), Job: j.name})
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	err := j.fn(ctx)
+	if err != nil {
+		app.logger.Error(err.Error(), "job", j.name)
+	}
+}
+
+// schedule calls fn every interval until ctx is cancelled. The scheduler is
+// tracked by app.schedulers so shutdown can wait for it to stop before closing
+// the job queue it feeds.
+func (app *application) schedule(ctx context.Context, interval time.Duration, fn func(ctx context.Context)) {
+	app.schedulers.Add(1)
+	go func() {
+		defer app.schedulers.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				app.runScheduled(ctx, fn)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// runScheduled calls fn, recovering from a panic so that the scheduler keeps
+// running.
+func (app *application) runScheduled(ctx context.Context, fn func(ctx context.Context)) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.reportPanic(panicReport{Source: "scheduler", Panic: fmt.Sprint(err), Stack: panicStack()})
+		}
+	}()
+	fn(ctx)
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"golang.org/x/time/rate"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tomasen/realip"
+)
+
+// authLimiter is a strict limiter for the authentication and registration
+// routes. Client IPs go through allow: an IP which exceeds its limit is banned,
+// and each further offence doubles the ban up to maxBan. Email addresses go
+// through throttled, fail and reset instead, which count only failed
+// credential checks and never ban, so nobody can lock a user out just by
+// knowing their address.
+type authLimiter struct {
+	mu      sync.Mutex
+	limit   rate.Limit
+	burst   int
+	ban     time.Duration
+	maxBan  time.Duration
+	clients map[string]*authClient
+}
+
+type authClient struct {
+	limiter     *rate.Limiter
+	strikes     int
+	bannedUntil time.Time
+	lastSeen    time.Time
+}
+
+func newAuthLimiter(rps float64, burst int, ban, maxBan time.Duration) *authLimiter {
+	l := &authLimiter{
+		limit:   rate.Limit(rps),
+		burst:   burst,
+		ban:     ban,
+		maxBan:  maxBan,
+		clients: make(map[string]*authClient),
+	}
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+			l.mu.Lock()
+			for key, client := range l.clients {
+				if time.Since(client.lastSeen) > l.maxBan && time.Now().After(client.bannedUntil) {
+					delete(l.clients, key)
+				}
+			}
+			l.mu.Unlock()
+		}
+	}()
+	return l
+}
+
+// allow records an attempt for key. When the attempt is refused it returns
+// how long the key is banned for, and whether the ban was imposed just now.
+func (l *authLimiter) allow(key string) (ok bool, retryAfter time.Duration, banned bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	client, exists := l.clients[key]
+	if !exists {
+		client = &authClient{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = client
+	}
+	client.lastSeen = now
+
+	if now.Before(client.bannedUntil) {
+		return false, client.bannedUntil.Sub(now), false
+	}
+	if client.limiter.Allow() {
+		return true, 0, false
+	}
+
+	client.strikes++
+	ban := l.ban * time.Duration(math.Pow(2, float64(min(client.strikes-1, 30))))
+	client.bannedUntil = now.Add(min(ban, l.maxBan))
+	return false, client.bannedUntil.Sub(now), true
+}
+
+// throttled reports whether key has used up its allowance of failures, and if
+// so how long until another attempt is allowed. Unlike allow it doesn't count
+// as an attempt.
+func (l *authLimiter) throttled(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	client, exists := l.clients[key]
+	if !exists {
+		return 0, false
+	}
+	tokens := client.limiter.Tokens()
+	if tokens >= 1 {
+		return 0, false
+	}
+	return time.Duration((1 - tokens) / float64(l.limit) * float64(time.Second)), true
+}
+
+// fail records a failed attempt for key.
+func (l *authLimiter) fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	client, exists := l.clients[key]
+	if !exists {
+		client = &authClient{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = client
+	}
+	client.lastSeen = time.Now()
+	client.limiter.Allow()
+}
+
+// reset forgets the failures recorded for key.
+func (l *authLimiter) reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.clients, key)
+}
+
+// allowAuthAttempt checks key against the auth limiter, sending a 429
+// response and returning false if the attempt is refused.
+func (app *application) allowAuthAttempt(w http.ResponseWriter, r *http.Request, key string) bool {
+	if !app.config.authLimiter.enabled {
+		return true
+	}
+	ok, retryAfter, banned := app.authLimiter.allow(key)
+	if ok {
+		return true
+	}
+	if banned {
+		app.logger.Warn("auth limiter ban", "key", key, "duration", retryAfter.String(), "method", r.Method, "url", r.URL.String())
+	}
+	w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(retryAfter.Seconds()))))
+	app.rateLimitExceededResponse(w, r)
+	return false
+}
+
+// allowAuthEmail sends a 429 response and returns false if there have been too
+// many failed credential checks for the email address recently. The request
+// itself isn't counted: handlers report the outcome of the check with
+// authEmailFailed or authEmailSucceeded.
+func (app *application) allowAuthEmail(w http.ResponseWriter, r *http.Request, email string) bool {
+	if !app.config.authLimiter.enabled {
+		return true
+	}
+	retryAfter, throttled := app.authLimiter.throttled(authEmailKey(email))
+	if !throttled {
+		return true
+	}
+	w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(retryAfter.Seconds()))))
+	app.rateLimitExceededResponse(w, r)
+	return false
+}
+
+func (app *application) authEmailFailed(email string) {
+	app.authLimiter.fail(authEmailKey(email))
+}
+
+func (app *application) authEmailSucceeded(email string) {
+	app.authLimiter.reset(authEmailKey(email))
+}
+
+func authEmailKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// anonymousLimiter is the per-IP limiter for unauthenticated reads when
+// anonymous read access is enabled. It's stricter than the global limiter, as
+// anonymous clients can't be told apart or held to account.
+type anonymousLimiter struct {
+	mu      sync.Mutex
+	limit   rate.Limit
+	burst   int
+	clients map[string]*anonymousClient
+}
+
+type anonymousClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newAnonymousLimiter(rps float64, burst int) *anonymousLimiter {
+	l := &anonymousLimiter{
+		limit:   rate.Limit(rps),
+		burst:   burst,
+		clients: make(map[string]*anonymousClient),
+	}
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+			l.mu.Lock()
+			for ip, client := range l.clients {
+				if time.Since(client.lastSeen) > 3*time.Minute {
+					delete(l.clients, ip)
+				}
+			}
+			l.mu.Unlock()
+		}
+	}()
+	return l
+}
+
+func (l *anonymousLimiter) allow(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	client, exists := l.clients[ip]
+	if !exists {
+		client = &anonymousClient{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[ip] = client
+	}
+	client.lastSeen = time.Now()
+	return client.limiter.Allow()
+}
+
+func (app *application) authRateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !app.allowAuthAttempt(w, r, "ip:"+realip.FromRequest(r)) {
+			return
+		}
+		next(w, r)
+	}
+}
+
+// limiterSettings are the global rate limiter settings which can be changed
+// at runtime through the admin API.
+type limiterSettings struct {
+	Enabled      bool    `json:"enabled"`
+	RPS          float64 `json:"rps"`
+	Burst        int     `json:"burst"`
+	TrustedRPS   float64 `json:"trusted_rps"`
+	TrustedBurst int     `json:"trusted_burst"`
+}
+
+// clientLimiter is the global per-IP rate limiter applied by rateLimit.
+type clientLimiter struct {
+	mu       sync.Mutex
+	settings limiterSettings
+	clients  map[string]*limiterClient
+}
+
+type limiterClient struct {
+	limiter  *rate.Limiter
+	trusted  bool
+	lastSeen time.Time
+}
+
+// limiterClientStatus describes a client tracked by the global limiter.
+type limiterClientStatus struct {
+	IP       string    `json:"ip"`
+	Trusted  bool      `json:"trusted"`
+	Tokens   float64   `json:"tokens"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+func newClientLimiter(settings limiterSettings) *clientLimiter {
+	l := &clientLimiter{
+		settings: settings,
+		clients:  make(map[string]*limiterClient),
+	}
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+			l.mu.Lock()
+			for ip, client := range l.clients {
+				if time.Since(client.lastSeen) > 3*time.Minute {
+					delete(l.clients, ip)
+				}
+			}
+			l.mu.Unlock()
+		}
+	}()
+	return l
+}
+
+func (l *clientLimiter) limits(trusted bool) (rate.Limit, int) {
+	if trusted {
+		return rate.Limit(l.settings.TrustedRPS), l.settings.TrustedBurst
+	}
+	return rate.Limit(l.settings.RPS), l.settings.Burst
+}
+
+func (l *clientLimiter) allow(ip string, trusted bool) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.settings.Enabled {
+		return true
+	}
+	client, exists := l.clients[ip]
+	if !exists {
+		client = &limiterClient{limiter: rate.NewLimiter(l.limits(trusted)), trusted: trusted}
+		l.clients[ip] = client
+	}
+	client.lastSeen = time.Now()
+	return client.limiter.Allow()
+}
+
+func (l *clientLimiter) getSettings() limiterSettings {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.settings
+}
+
+// update replaces the settings, applying the new limits to the clients
+// already being tracked as well as new ones.
+func (l *clientLimiter) update(settings limiterSettings) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.settings = settings
+	for _, client := range l.clients {
+		limit, burst := l.limits(client.trusted)
+		client.limiter.SetLimit(limit)
+		client.limiter.SetBurst(burst)
+	}
+}
+
+// status returns the clients being tracked, most recently seen first.
+func (l *clientLimiter) status() []limiterClientStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	clients := make([]limiterClientStatus, 0, len(l.clients))
+	for ip, client := range l.clients {
+		clients = append(clients, limiterClientStatus{
+			IP:       ip,
+			Trusted:  client.trusted,
+			Tokens:   client.limiter.Tokens(),
+			LastSeen: client.lastSeen,
+		})
+	}
+	slices.SortFunc(clients, func(a, b limiterClientStatus) int {
+		return b.LastSeen.Compare(a.LastSeen)
+	})
+	return clients
+}
+
+// clear forgets the client with the given IP, or every client if ip is
+// empty, restoring their full burst. It returns the number of clients cleared.
+func (l *clientLimiter) clear(ip string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if ip == "" {
+		n := len(l.clients)
+		clear(l.clients)
+		return n
+	}
+	if _, exists := l.clients[ip]; !exists {
+		return 0
+	}
+	delete(l.clients, ip)
+	return 1
+}
+
+func (app *application) showLimiterHandler(w http.ResponseWriter, r *http.Request) {
+	env := envelope{
+		"limiter": app.limiter.getSettings(),
+		"clients": app.limiter.status(),
+	}
+	err := app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateLimiterHandler changes the global rate limiter settings. The change
+// lasts until the next restart; use the -limiter-* flags to make it permanent.
+func (app *application) updateLimiterHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Enabled      *bool    `json:"enabled"`
+		RPS          *float64 `json:"rps"`
+		Burst        *int     `json:"burst"`
+		TrustedRPS   *float64 `json:"trusted_rps"`
+		TrustedBurst *int     `json:"trusted_burst"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	settings := app.limiter.getSettings()
+	if input.Enabled != nil {
+		settings.Enabled = *input.Enabled
+	}
+	if input.RPS != nil {
+		settings.RPS = *input.RPS
+	}
+	if input.Burst != nil {
+		settings.Burst = *input.Burst
+	}
+	if input.TrustedRPS != nil {
+		settings.TrustedRPS = *input.TrustedRPS
+	}
+	if input.TrustedBurst != nil {
+		settings.TrustedBurst = *input.TrustedBurst
+	}
+
+	v := validator.New()
+	v.CheckCode(settings.RPS > 0, "rps", "too_small", "must be greater than zero")
+	v.CheckCode(settings.Burst > 0, "burst", "too_small", "must be greater than zero")
+	v.CheckCode(settings.TrustedRPS > 0, "trusted_rps", "too_small", "must be greater than zero")
+	v.CheckCode(settings.TrustedBurst > 0, "trusted_burst", "too_small", "must be greater than zero")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	app.limiter.update(settings)
+	app.logger.Info("limiter updated",
+		"enabled", settings.Enabled,
+		"rps", settings.RPS,
+		"burst", settings.Burst,
+		"trusted_rps", settings.TrustedRPS,
+		"trusted_burst", settings.TrustedBurst,
+		"user_id", app.contextGetUser(r).ID,
+	)
+	app.showLimiterHandler(w, r)
+}
+
+// clearLimiterClientsHandler forgets the clients tracked by the global rate
+// limiter, or only the one given by ?ip=, so they can make requests again.
+func (app *application) clearLimiterClientsHandler(w http.ResponseWriter, r *http.Request) {
+	ip := app.readString(r.URL.Query(), "ip", "")
+	cleared := app.limiter.clear(ip)
+	app.logger.Info("limiter clients cleared", "ip", ip, "cleared", cleared, "user_id", app.contextGetUser(r).ID)
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"cleared": cleared}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
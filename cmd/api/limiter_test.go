@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"net/http"
+	"testing"
+)
+
+func TestUpdateLimiterHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBurst  int
+	}{
+		{name: "raise burst", body: `{"burst": 10}`, wantStatus: http.StatusOK, wantBurst: 10},
+		{name: "disable", body: `{"enabled": false}`, wantStatus: http.StatusOK, wantBurst: 1},
+		{name: "zero rps", body: `{"rps": 0}`, wantStatus: http.StatusUnprocessableEntity, wantBurst: 1},
+		{name: "unknown field", body: `{"limit": 5}`, wantStatus: http.StatusBadRequest, wantBurst: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, mocks.NewModels())
+			app.limiter = newClientLimiter(limiterSettings{Enabled: true, RPS: 0.001, Burst: 1, TrustedRPS: 0.001, TrustedBurst: 1})
+			app.limiter.allow("192.0.2.1", false)
+
+			r := newTestRequest(t, http.MethodPatch, "/v1/admin/limiter", tt.body, testUser, nil)
+			status, _, body := serve(t, app.updateLimiterHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+			if got := app.limiter.getSettings().Burst; got != tt.wantBurst {
+				t.Errorf("got burst %d; want %d", got, tt.wantBurst)
+			}
+			// The new settings apply to clients already being limited.
+			if got := app.limiter.clients["192.0.2.1"].limiter.Burst(); got != tt.wantBurst {
+				t.Errorf("got existing client burst %d; want %d", got, tt.wantBurst)
+			}
+		})
+	}
+}
+
+func TestClearLimiterClientsHandler(t *testing.T) {
+	app := newTestApplication(t, mocks.NewModels())
+	app.limiter = newClientLimiter(limiterSettings{Enabled: true, RPS: 0.001, Burst: 1})
+	app.limiter.allow("192.0.2.1", false)
+	app.limiter.allow("192.0.2.2", false)
+
+	r := newTestRequest(t, http.MethodDelete, "/v1/admin/limiter/clients?ip=192.0.2.1", "", testUser, nil)
+	status, _, body := serve(t, app.clearLimiterClientsHandler, r)
+	if status != http.StatusOK || body["cleared"] != float64(1) {
+		t.Fatalf("got status %d, body %v; want 200 with one client cleared", status, body)
+	}
+	if !app.limiter.allow("192.0.2.1", false) {
+		t.Error("cleared client still limited")
+	}
+	if app.limiter.allow("192.0.2.2", false) {
+		t.Error("other client no longer limited")
+	}
+}
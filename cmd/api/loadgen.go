@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The loadgen subcommand fills the movies table with generated data for load
+// and index testing. The shape of the data can be tuned so queries can be
+// checked against realistic cardinalities:
+//
+//	go run ./cmd/api loadgen -count 1000000 -titles 50000 -title-skew 1.2 -genres 12
+//
+// The same -seed always generates the same movies.
+
+var (
+	genrePool = []string{
+		"drama", "comedy", "action", "adventure", "animation", "crime", "documentary",
+		"family", "fantasy", "history", "horror", "music", "mystery", "romance",
+		"sci-fi", "thriller", "war", "western", "biography", "sport",
+	}
+	titleAdjectives = []string{
+		"Silent", "Last", "Broken", "Golden", "Hidden", "Dark", "Eternal", "Lost",
+		"Crimson", "Distant", "Wild", "Frozen", "Secret", "Burning", "Little", "Final",
+	}
+	titleNouns = []string{
+		"River", "Empire", "Garden", "Storm", "Promise", "Kingdom", "Journey", "Shadow",
+		"Harbor", "Summer", "Machine", "Letter", "Frontier", "Dream", "Island", "Witness",
+	}
+)
+
+type loadgenConfig struct {
+	dsn       string
+	count     int
+	batch     int
+	workers   int
+	seed      uint64
+	yearFrom  int
+	yearTo    int
+	genres    int
+	maxGenres int
+	titles    int
+	titleSkew float64
+}
+
+// runLoadgen implements the loadgen subcommand. args are the command line
+// arguments following "loadgen".
+func runLoadgen(args []string) error {
+	var cfg loadgenConfig
+	fs := flag.NewFlagSet("loadgen", flag.ExitOnError)
+	fs.StringVar(&cfg.dsn, "db-dsn", "", "PostgreSQL DSN")
+	fs.IntVar(&cfg.count, "count", 100000, "Number of movies to insert")
+	fs.IntVar(&cfg.batch, "batch", 1000, "Movies inserted per transaction")
+	fs.IntVar(&cfg.workers, "workers", 4, "Number of concurrent inserting workers")
+	fs.Uint64Var(&cfg.seed, "seed", 1, "Random seed")
+	fs.IntVar(&cfg.yearFrom, "year-from", 1920, "Earliest release year")
+	fs.IntVar(&cfg.yearTo, "year-to", time.Now().Year(), "Latest release year")
+	fs.IntVar(&cfg.genres, "genres", len(genrePool), fmt.Sprintf("Number of distinct genres to use (max %d)", len(genrePool)))
+	fs.IntVar(&cfg.maxGenres, "max-genres", 3, "Maximum genres per movie (1-5)")
+	fs.IntVar(&cfg.titles, "titles", 0, "Number of distinct titles (default: every title unique)")
+	fs.Float64Var(&cfg.titleSkew, "title-skew", 0, "Zipf exponent for title popularity, must be > 1 to skew (default: uniform)")
+	fs.Parse(args)
+	err := bindEnv(fs)
+	if err != nil {
+		return err
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	return loadMovies(cfg, logger)
+}
+
+func loadMovies(cfg loadgenConfig, logger *slog.Logger) error {
+	switch {
+	case cfg.count < 1 || cfg.batch < 1 || cfg.workers < 1:
+		return errors.New("-count, -batch and -workers must be positive")
+	case cfg.batch > 9000:
+		return errors.New("-batch must be at most 9000")
+	case cfg.yearFrom < 1888 || cfg.yearTo > time.Now().Year() || cfg.yearFrom > cfg.yearTo:
+		return errors.New("invalid year range")
+	case cfg.genres < 1 || cfg.genres > len(genrePool):
+		return fmt.Errorf("-genres must be between 1 and %d", len(genrePool))
+	case cfg.maxGenres < 1 || cfg.maxGenres > 5 || cfg.maxGenres > cfg.genres:
+		return errors.New("-max-genres must be between 1 and min(5, -genres)")
+	case cfg.titleSkew != 0 && cfg.titleSkew <= 1:
+		return errors.New("-title-skew must be greater than 1")
+	}
+
+	db, err := sql.Open("postgres", cfg.dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(cfg.workers)
+
+	// The first failing worker cancels ctx, which stops the other workers and
+	// the producer rather than letting them keep inserting.
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	batches := make(chan int)
+	go func() {
+		defer close(batches)
+		for start := 0; start < cfg.count; start += cfg.batch {
+			select {
+			case batches <- start:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		wg       sync.WaitGroup
+		inserted atomic.Int64
+	)
+	start := time.Now()
+	for range cfg.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batchStart := range batches {
+				n := min(cfg.batch, cfg.count-batchStart)
+				// Seeding from the batch offset keeps the output independent
+				// of how batches are spread across workers.
+				gen := newGenerator(cfg, uint64(batchStart))
+				err := insertBatch(ctx, db, gen, n)
+				if err != nil {
+					cancel(err)
+					return
+				}
+				total := inserted.Add(int64(n))
+				elapsed := time.Since(start)
+				logger.Info("progress", "inserted", total, "rate", fmt.Sprintf("%.0f/s", float64(total)/elapsed.Seconds()))
+			}
+		}()
+	}
+	wg.Wait()
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
+	logger.Info("done", "inserted", inserted.Load(), "duration", time.Since(start).Round(time.Millisecond).String())
+	return nil
+}
+
+// insertBatch generates n movies and inserts them with one statement in their
+// own transaction.
+func insertBatch(ctx context.Context, db *sql.DB, gen *generator, n int) error {
+	movies := make([]*data.Movie, 0, n)
+	for range n {
+		movie := gen.movie()
+		v := validator.New()
+		if data.ValidateMovie(v, movie); !v.Valid() {
+			return fmt.Errorf("generated invalid movie %+v: %v", movie, v.Errors)
+		}
+		movies = append(movies, movie)
+	}
+
+	return data.NewModels(db).WithTx(ctx, func(m data.Models) error {
+		return m.Movies.InsertMany(ctx, movies)
+	})
+}
+
+type generator struct {
+	cfg  loadgenConfig
+	rand *rand.Rand
+	zipf *rand.Zipf
+}
+
+func newGenerator(cfg loadgenConfig, offset uint64) *generator {
+	g := &generator{
+		cfg:  cfg,
+		rand: rand.New(rand.NewPCG(cfg.seed, offset)),
+	}
+	if cfg.titles > 0 && cfg.titleSkew > 1 {
+		g.zipf = rand.NewZipf(g.rand, cfg.titleSkew, 1, uint64(cfg.titles-1))
+	}
+	return g
+}
+
+func (g *generator) movie() *data.Movie {
+	genres := make([]string, 0, g.cfg.maxGenres)
+	for _, i := range g.rand.Perm(g.cfg.genres)[:1+g.rand.IntN(g.cfg.maxGenres)] {
+		genres = append(genres, genrePool[i])
+	}
+	return &data.Movie{
+		Title:   g.title(),
+		Year:    int32(g.cfg.yearFrom + g.rand.IntN(g.cfg.yearTo-g.cfg.yearFrom+1)),
+		Runtime: data.Runtime(70 + g.rand.IntN(111)),
+		Genres:  genres,
+	}
+}
+
+// title picks from a fixed set of -titles titles, following a Zipf
+// distribution when -title-skew is set, or generates a unique title otherwise.
+func (g *generator) title() string {
+	var n uint64
+	switch {
+	case g.zipf != nil:
+		n = g.zipf.Uint64()
+	case g.cfg.titles > 0:
+		n = uint64(g.rand.IntN(g.cfg.titles))
+	default:
+		n = g.rand.Uint64()
+	}
+	words := uint64(len(titleAdjectives) * len(titleNouns))
+	title := fmt.Sprintf("The %s %s", titleAdjectives[n%uint64(len(titleAdjectives))], titleNouns[(n/uint64(len(titleAdjectives)))%uint64(len(titleNouns))])
+	if suffix := n / words; suffix > 0 {
+		title = fmt.Sprintf("%s %d", title, suffix+1)
+	}
+	return title
+}
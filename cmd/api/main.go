@@ -5,8 +5,13 @@ import (
 	"database/sql"
 	"flag"
 	"fmt"
+	"github.com/ezechidc/greenlight/internal/blocklist"
+	"github.com/ezechidc/greenlight/internal/captcha"
 	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/embedding"
 	"github.com/ezechidc/greenlight/internal/mailer"
+	"github.com/ezechidc/greenlight/internal/pwned"
+	"github.com/ezechidc/greenlight/internal/storage"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 	"log"
@@ -15,40 +20,232 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const version = "1.0.0"
 
+// Credentials of the admin user seeded in demo mode.
+const (
+	demoEmail    = "demo@greenlight.example"
+	demoPassword = "pa55word"
+)
+
 type config struct {
-	port int
-	env  string
-	db   struct {
+	port    int
+	env     string
+	demo    bool
+	baseURL string
+	errors  struct {
+		problemJSON bool
+	}
+	debug struct {
+		enabled bool
+	}
+	log struct {
+		redactEmails string
+	}
+	security struct {
+		headers    string
+		hstsMaxAge time.Duration
+		csp        string
+	}
+	json struct {
+		maxDepth            int
+		rejectDuplicateKeys bool
+	}
+	tokenPeppers        string
+	editConflictRetries int
+	panicWebhookURL     string
+	password            struct {
+		scheme        string
+		bcryptCost    int
+		argon2Time    uint
+		argon2Memory  uint
+		argon2Threads uint
+	}
+	db struct {
 		dsn          string
 		maxOpenConns int
 		maxIdleConns int
 		maxIdleTime  time.Duration
+		slowQuery    time.Duration
+		waitWarning  time.Duration
+		listen       bool
+	}
+	pwned struct {
+		enabled  bool
+		timeout  time.Duration
+		failOpen bool
+	}
+	disposable struct {
+		enabled bool
+		file    string
+	}
+	captcha struct {
+		provider string
+		secret   string
+		failOpen bool
+	}
+	audit struct {
+		db bool
+	}
+	ip struct {
+		allow      cidrList
+		deny       cidrList
+		adminAllow cidrList
+		adminDeny  cidrList
 	}
 	limiter struct {
+		rps          float64
+		burst        int
+		enabled      bool
+		exempt       cidrList
+		trusted      cidrList
+		trustedRPS   float64
+		trustedBurst int
+	}
+	anonymous struct {
+		read  bool
+		rps   float64
+		burst int
+	}
+	authLimiter struct {
 		rps     float64
 		burst   int
+		ban     time.Duration
+		maxBan  time.Duration
 		enabled bool
 	}
+	stats struct {
+		enabled       bool
+		interval      time.Duration
+		retention     time.Duration
+		flushInterval time.Duration
+		bufferSize    int
+	}
+	shutdown struct {
+		delay time.Duration
+	}
+	timeout struct {
+		request time.Duration
+		bulk    time.Duration
+	}
+	inflight struct {
+		max   int
+		queue int
+		wait  time.Duration
+	}
+	jobs struct {
+		workers   int
+		queueSize int
+	}
+	invitations struct {
+		ttl       time.Duration
+		signupURL string
+	}
+	digest struct {
+		enabled  bool
+		interval time.Duration
+	}
+	usage struct {
+		enabled       bool
+		flushInterval time.Duration
+		retention     time.Duration
+		bufferSize    int
+	}
+	catalogStats struct {
+		interval time.Duration
+	}
+	search struct {
+		similarity float64
+	}
+	embedding struct {
+		provider string
+		url      string
+		model    string
+		apiKey   string
+		interval time.Duration
+		batch    int
+	}
+	storage struct {
+		backend string
+		dir     string
+		url     string
+	}
+	tokens struct {
+		pruneInterval time.Duration
+		pruneBatch    int
+		cacheTTL      time.Duration
+		cacheSize     int
+	}
 	smtp struct {
+		mode     string
+		logDir   string
 		host     string
 		port     int
 		username string
 		password string
+		tls      string
 		sender   string
+		poolSize int
 	}
 }
 
 type application struct {
-	config config
-	logger *slog.Logger
-	models data.Models
-	mailer *mailer.Mailer
-	wg     sync.WaitGroup
+	config      config
+	logger      *slog.Logger
+	models      data.Models
+	mailer      *mailer.Mailer
+	authLimiter *authLimiter
+	captcha     captcha.Verifier
+	disposable  *blocklist.Domains
+	pwned       *pwned.Checker
+	// passwordParams are the settings used for new password hashes.
+	passwordParams data.PasswordParams
+	jobs           *jobQueue
+	views          *viewBuffer
+	usage          *usageBuffer
+	wg             sync.WaitGroup
+	schedulers     sync.WaitGroup
+
+	// db is the connection pool behind models, or nil in demo mode.
+	db *sql.DB
+
+	// embedder computes the embeddings behind semantic similarity, or is nil
+	// if no -embedding-provider is configured.
+	embedder embedding.Provider
+
+	// storage keeps uploaded files such as avatars.
+	storage storage.Store
+
+	// pendingDigests holds the IDs of users whose weekly digest is queued, so
+	// a slow queue doesn't lead to the same digest being queued twice.
+	pendingDigests sync.Map
+
+	// limiter is the global per-IP rate limiter, whose settings start as the
+	// -limiter-* flags and can be changed through the admin API.
+	limiter *clientLimiter
+
+	// anonymousLimiter limits anonymous reads when -anonymous-read is set.
+	anonymousLimiter *anonymousLimiter
+
+	// tokenCache caches the users of authentication tokens for authenticate.
+	tokenCache *tokenCache
+
+	// health holds the status reported by each subsystem.
+	health *healthRegistry
+
+	// recentErrors keeps the latest request errors for the admin dashboard.
+	recentErrors errorRing
+
+	// catalogStats caches the statistics served by /v1/catalog/stats.
+	catalogStats atomic.Pointer[data.CatalogStats]
+
+	// shuttingDown is set when a shutdown signal arrives, so /v1/readyz
+	// fails while traffic is still being served.
+	shuttingDown atomic.Bool
 }
 
 type FlatSourceHandler struct {
@@ -89,43 +286,280 @@ func main() {
 	if err != nil {
 		log.Print(".env file not found or failed to load")
 	}
+	if len(os.Args) > 1 && os.Args[1] == "loadgen" {
+		err := runLoadgen(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "imdb" {
+		err := runIMDb(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	var cfg config
 
 	flag.IntVar(&cfg.port, "port", 4000, "API server port")
 	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
-	flag.StringVar(&cfg.db.dsn, "db-dsn", os.Getenv("GREENLIGHT_DB_DSN"), "PostgreSQL DSN")
+	flag.BoolVar(&cfg.demo, "demo", false, "Run with seeded in-memory storage instead of PostgreSQL (data is lost on exit)")
+	flag.StringVar(&cfg.baseURL, "base-url", "http://localhost:4000", "Public base URL used in links sent to users")
+	flag.StringVar(&cfg.log.redactEmails, "log-redact-emails", emailRedactMask, "Redact email addresses in logs (mask|full|off); mask keeps the first letter and domain")
+	flag.BoolVar(&cfg.debug.enabled, "debug-enabled", false, "Allow users with admin:read to add ?debug=true to requests for params, SQL and timings")
+	flag.IntVar(&cfg.editConflictRetries, "edit-conflict-retries", 0, "Times to retry a movie update server-side after an edit conflict which changed none of the same fields, before returning 409")
+	flag.StringVar(&cfg.panicWebhookURL, "panic-webhook-url", "", "URL to POST a JSON report of each recovered panic to, for alerting")
+	flag.BoolVar(&cfg.errors.problemJSON, "errors-problem-json", false, "Send all error responses as application/problem+json (RFC 7807)")
+	flag.StringVar(&cfg.security.headers, "security-headers", "auto", "Send security headers (auto|on|off); auto enables them outside development")
+	flag.DurationVar(&cfg.security.hstsMaxAge, "security-hsts-max-age", 365*24*time.Hour, "Strict-Transport-Security max-age")
+	flag.StringVar(&cfg.security.csp, "security-csp", "default-src 'none'; frame-ancestors 'none'", "Content-Security-Policy header value (empty to omit)")
+	flag.IntVar(&cfg.json.maxDepth, "json-max-depth", 32, "Maximum nesting depth of JSON request bodies")
+	flag.BoolVar(&cfg.json.rejectDuplicateKeys, "json-reject-duplicate-keys", true, "Reject JSON request bodies containing duplicate object keys")
+
+	flag.StringVar(&cfg.tokenPeppers, "token-peppers", "", "Comma-separated token hashing peppers; the first hashes new tokens, all are accepted (an empty entry accepts unpeppered tokens)")
+	defaultPassword := data.DefaultPasswordParams()
+	flag.StringVar(&cfg.password.scheme, "password-hash", defaultPassword.Scheme, "Password hashing scheme for new hashes (bcrypt|argon2id); existing hashes are upgraded at login")
+	flag.IntVar(&cfg.password.bcryptCost, "password-bcrypt-cost", defaultPassword.BcryptCost, "bcrypt cost")
+	flag.UintVar(&cfg.password.argon2Time, "password-argon2-time", uint(defaultPassword.Argon2Time), "argon2id iterations")
+	flag.UintVar(&cfg.password.argon2Memory, "password-argon2-memory", uint(defaultPassword.Argon2Memory), "argon2id memory in KiB")
+	flag.UintVar(&cfg.password.argon2Threads, "password-argon2-threads", uint(defaultPassword.Argon2Threads), "argon2id parallelism")
+
+	flag.StringVar(&cfg.db.dsn, "db-dsn", "", "PostgreSQL DSN")
 	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
 	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
 	flag.DurationVar(&cfg.db.maxIdleTime, "db-max-idle-time", 15*time.Minute, "PostgreSQL max connection idle time")
+	flag.DurationVar(&cfg.db.slowQuery, "db-slow-query-threshold", 200*time.Millisecond, "Log queries which take longer than this (0 to disable)")
+	flag.DurationVar(&cfg.db.waitWarning, "db-wait-warning", time.Second, "Warn when requests wait longer than this in total for a free connection in a minute")
+	flag.BoolVar(&cfg.db.listen, "db-listen", true, "Listen for movie changes made by other instances, to drop cached catalog statistics straight away")
+
+	flag.BoolVar(&cfg.pwned.enabled, "pwned-check", false, "Reject passwords found in the Have I Been Pwned breach corpus")
+	flag.DurationVar(&cfg.pwned.timeout, "pwned-timeout", 2*time.Second, "Timeout for Have I Been Pwned lookups")
+	flag.BoolVar(&cfg.pwned.failOpen, "pwned-fail-open", true, "Accept the password if the Have I Been Pwned lookup fails")
+
+	flag.BoolVar(&cfg.disposable.enabled, "disposable-block", true, "Reject registrations from disposable email domains")
+	flag.StringVar(&cfg.disposable.file, "disposable-domains-file", "", "File of extra disposable domains to block, one per line")
+
+	flag.StringVar(&cfg.captcha.provider, "captcha-provider", "", "CAPTCHA provider required on registration (hcaptcha|recaptcha|turnstile, default: none)")
+	flag.StringVar(&cfg.captcha.secret, "captcha-secret", "", "CAPTCHA provider secret key")
+	flag.BoolVar(&cfg.captcha.failOpen, "captcha-fail-open", false, "Skip the CAPTCHA check if the provider can't be reached")
+
+	flag.BoolVar(&cfg.audit.db, "audit-db", false, "Also record audit events in the audit_events table")
+
+	flag.Var(&cfg.ip.allow, "ip-allow", "Comma-separated CIDRs allowed to use the API (default: all)")
+	flag.Var(&cfg.ip.deny, "ip-deny", "Comma-separated CIDRs denied access to the API")
+	flag.Var(&cfg.ip.adminAllow, "admin-ip-allow", "Comma-separated CIDRs allowed to use the admin routes (default: all)")
+	flag.Var(&cfg.ip.adminDeny, "admin-ip-deny", "Comma-separated CIDRs denied access to the admin routes")
 
 	flag.Float64Var(&cfg.limiter.rps, "limiter-rps", 2, "Rate limiter maximum requests per second")
 	flag.IntVar(&cfg.limiter.burst, "limiter-burst", 4, "Rate limiter maximum burst")
 	flag.BoolVar(&cfg.limiter.enabled, "limiter-enabled", true, "Enable rate limiter")
+	flag.Var(&cfg.limiter.exempt, "limiter-exempt", "Comma-separated CIDRs exempt from the request rate limits, such as monitoring probes")
+	flag.Var(&cfg.limiter.trusted, "limiter-trusted", "Comma-separated CIDRs given the trusted rate limits, such as internal batch tools")
+	flag.Float64Var(&cfg.limiter.trustedRPS, "limiter-trusted-rps", 50, "Rate limiter maximum requests per second for trusted CIDRs")
+	flag.IntVar(&cfg.limiter.trustedBurst, "limiter-trusted-burst", 100, "Rate limiter maximum burst for trusted CIDRs")
+
+	flag.BoolVar(&cfg.anonymous.read, "anonymous-read", false, "Allow unauthenticated users to list and show movies")
+	flag.Float64Var(&cfg.anonymous.rps, "anonymous-limiter-rps", 0.5, "Anonymous read limiter requests per second per IP")
+	flag.IntVar(&cfg.anonymous.burst, "anonymous-limiter-burst", 2, "Anonymous read limiter maximum burst")
+
+	flag.Float64Var(&cfg.authLimiter.rps, "auth-limiter-rps", 0.1, "Authentication limiter requests per second per IP, and failed credential checks per second per email")
+	flag.IntVar(&cfg.authLimiter.burst, "auth-limiter-burst", 5, "Authentication limiter maximum burst")
+	flag.DurationVar(&cfg.authLimiter.ban, "auth-limiter-ban", time.Minute, "Authentication limiter initial IP ban, doubled on each repeat offence")
+	flag.DurationVar(&cfg.authLimiter.maxBan, "auth-limiter-max-ban", time.Hour, "Authentication limiter maximum IP ban")
+	flag.BoolVar(&cfg.authLimiter.enabled, "auth-limiter-enabled", true, "Enable authentication limiter")
+
+	flag.BoolVar(&cfg.stats.enabled, "stats-enabled", true, "Record movie views and roll them up into daily stats")
+	flag.DurationVar(&cfg.stats.interval, "stats-rollup-interval", 15*time.Minute, "How often to roll movie views up into daily stats")
+	flag.DurationVar(&cfg.stats.retention, "stats-raw-retention", 7*24*time.Hour, "How long to keep raw movie views after they're rolled up")
+	flag.DurationVar(&cfg.stats.flushInterval, "stats-flush-interval", 10*time.Second, "How often to write buffered movie views to the database")
+	flag.IntVar(&cfg.stats.bufferSize, "stats-buffer-size", 100000, "Maximum movie and viewer pairs to buffer between flushes")
+
+	flag.BoolVar(&cfg.usage.enabled, "usage-enabled", true, "Record each user's daily requests, errors and bytes, served by /v1/users/me/usage")
+	flag.DurationVar(&cfg.usage.flushInterval, "usage-flush-interval", 10*time.Second, "How often to write buffered usage to the database")
+	flag.DurationVar(&cfg.usage.retention, "usage-retention", 90*24*time.Hour, "How long to keep daily usage")
+	flag.IntVar(&cfg.usage.bufferSize, "usage-buffer-size", 10000, "Maximum users whose usage is buffered between flushes")
+
+	flag.IntVar(&cfg.jobs.workers, "job-workers", 4, "Number of background job workers")
+	flag.DurationVar(&cfg.timeout.request, "request-timeout", 10*time.Second, "Time after which a request's database queries are cancelled and it gets a 504 (0 for no timeout)")
+	flag.DurationVar(&cfg.timeout.bulk, "request-timeout-bulk", 45*time.Second, "-request-timeout for bulk admin routes; keep it under the server's 1m write timeout")
+	flag.IntVar(&cfg.inflight.max, "inflight-max", 200, "Maximum requests handled at once (0 for no limit)")
+	flag.IntVar(&cfg.inflight.queue, "inflight-queue", 100, "Maximum requests waiting for -inflight-max before new ones get a 503")
+	flag.DurationVar(&cfg.inflight.wait, "inflight-wait", time.Second, "How long a request waits for -inflight-max before getting a 503")
+	flag.DurationVar(&cfg.shutdown.delay, "shutdown-delay", 0, "How long to keep serving, with /v1/readyz failing, after SIGTERM before draining connections (e.g. 5s behind a Kubernetes Service)")
+	flag.IntVar(&cfg.jobs.queueSize, "job-queue-size", 1000, "Maximum number of queued background jobs")
+
+	flag.DurationVar(&cfg.invitations.ttl, "invitation-ttl", 7*24*time.Hour, "How long an invitation can be accepted for")
+	flag.StringVar(&cfg.invitations.signupURL, "invitation-signup-url", "", "Signup page linked from invitation emails, given the token as ?token= (default: explain the API request instead)")
+
+	flag.Float64Var(&cfg.search.similarity, "search-similarity", 0.4, "Minimum trigram similarity (0-1) for a title to match a search despite typos; higher is more precise, lower finds more")
+	flag.StringVar(&cfg.embedding.provider, "embedding-provider", "", "Embedding provider for semantic similar movies (openai|ollama, default: none)")
+	flag.StringVar(&cfg.embedding.url, "embedding-url", "", "Embedding endpoint, for OpenAI-compatible services or a remote Ollama (default: the provider's)")
+	flag.StringVar(&cfg.embedding.model, "embedding-model", "", "Embedding model, e.g. text-embedding-3-small or nomic-embed-text")
+	flag.StringVar(&cfg.embedding.apiKey, "embedding-api-key", "", "Embedding provider API key")
+	flag.DurationVar(&cfg.embedding.interval, "embedding-interval", time.Minute, "How often to embed new and changed movies")
+	flag.IntVar(&cfg.embedding.batch, "embedding-batch", 100, "Movies embedded per request to the embedding provider")
+	flag.StringVar(&cfg.storage.backend, "storage-backend", storage.BackendLocal, "Where uploaded files such as avatars are kept (local)")
+	flag.StringVar(&cfg.storage.dir, "storage-dir", "./uploads", "Directory uploaded files are kept in by the local storage backend")
+	flag.StringVar(&cfg.storage.url, "storage-url", "", "Public URL uploaded files are served from, such as a CDN in front of -storage-dir (default: served by the API under <base-url>/v1/files)")
+	flag.DurationVar(&cfg.catalogStats.interval, "catalog-stats-interval", 5*time.Minute, "How often to recompute the catalog statistics served by /v1/catalog/stats")
+
+	flag.DurationVar(&cfg.tokens.pruneInterval, "tokens-prune-interval", 10*time.Minute, "How often to delete expired tokens")
+	flag.IntVar(&cfg.tokens.pruneBatch, "tokens-prune-batch", 5000, "Expired tokens deleted per statement when pruning")
+	flag.DurationVar(&cfg.tokens.cacheTTL, "token-cache-ttl", 30*time.Second, "How long to cache authentication token lookups; revocations reach other instances within this (0 to disable)")
+	flag.IntVar(&cfg.tokens.cacheSize, "token-cache-size", 10000, "Maximum authentication tokens cached")
 
+	flag.BoolVar(&cfg.digest.enabled, "digest-enabled", true, "Enable the weekly digest email")
+	flag.DurationVar(&cfg.digest.interval, "digest-interval", time.Hour, "How often to check for users due a weekly digest")
+
+	flag.StringVar(&cfg.smtp.mode, "smtp-mode", mailer.ModeLive, "SMTP delivery mode (live|log|discard)")
+	flag.StringVar(&cfg.smtp.logDir, "smtp-log-dir", "", "Directory to write emails to in log mode (default: write to the logger)")
 	flag.StringVar(&cfg.smtp.host, "smtp-host", "sandbox.smtp.mailtrap.io", "SMTP host")
 	flag.IntVar(&cfg.smtp.port, "smtp-port", 2525, "SMTP port")
-	flag.StringVar(&cfg.smtp.username, "smtp-username", os.Getenv("MAIL_TRAP_USERNAME"), "SMTP username")
-	flag.StringVar(&cfg.smtp.password, "smtp-password", os.Getenv("MAIL_TRAP_PASSWORD"), "SMTP password")
+	flag.StringVar(&cfg.smtp.username, "smtp-username", "", "SMTP username")
+	flag.StringVar(&cfg.smtp.password, "smtp-password", "", "SMTP password")
+	flag.StringVar(&cfg.smtp.tls, "smtp-tls", mailer.TLSMandatory, "SMTP STARTTLS policy (mandatory|opportunistic|none)")
+	flag.IntVar(&cfg.smtp.poolSize, "smtp-pool-size", 2, "SMTP connections kept open between emails (0 to dial for each email)")
 	flag.StringVar(&cfg.smtp.sender, "smtp-sender", "Greenlight <no-reply@denco.greenlight.net>", "SMTP sender")
 
+	var checkConfig bool
+	flag.BoolVar(&checkConfig, "check-config", false, "Validate the configuration, print it with secrets masked and exit, non-zero if it's invalid")
+
 	flag.Parse()
+	err = bindEnv(flag.CommandLine)
+	if err != nil {
+		log.Fatal(err)
+	}
 	base := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{})
-	logger := slog.New(&FlatSourceHandler{Handler: base})
+	logger := slog.New(&RedactHandler{Handler: &FlatSourceHandler{Handler: base}, Emails: cfg.log.redactEmails})
 
-	db, err := openDB(cfg)
+	problems := validateConfig(cfg)
+	if checkConfig {
+		printConfig(os.Stdout, flag.CommandLine)
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, problem)
+		}
+		if len(problems) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	for _, problem := range problems {
+		logger.Error(problem)
+	}
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+	passwordParams, err := data.NewPasswordParams(cfg.password.scheme, cfg.password.bcryptCost, cfg.password.argon2Time, cfg.password.argon2Memory, cfg.password.argon2Threads)
 	if err != nil {
 		logger.Error(err.Error())
 		os.Exit(1)
 	}
+	data.SlowQueryThreshold = cfg.db.slowQuery
+	data.OnSlowQuery = func(ctx context.Context, q data.SlowQuery) {
+		requestID, _ := ctx.Value(requestIDContextKey).(string)
+		logger.Warn("slow query", "query", q.Name, "args", q.Args, "duration", q.Duration.String(), "request_id", requestID)
+		slowQueries.Add(q.Name, 1)
+	}
+	data.TokenPeppers = nil
+	for pepper := range strings.SplitSeq(cfg.tokenPeppers, ",") {
+		data.TokenPeppers = append(data.TokenPeppers, []byte(pepper))
+	}
 
-	defer db.Close()
-	logger.Info("database connection pool established")
-	mailerApp, err := mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender)
+	var (
+		models data.Models
+		db     *sql.DB
+	)
+	if cfg.demo {
+		models, err = data.NewMemoryModels(demoEmail, demoPassword)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		// There's no point dialing SMTP for throwaway demo accounts.
+		cfg.smtp.mode = mailer.ModeLog
+		// The demo credentials are public, so they skip redaction.
+		slog.New(&FlatSourceHandler{Handler: base}).Info("running in demo mode with in-memory storage", "email", demoEmail, "password", demoPassword)
+	} else {
+		db, err = openDB(cfg)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+
+		defer db.Close()
+		logger.Info("database connection pool established")
+		models = data.NewModels(db)
+	}
+
+	mailerApp, err := newMailer(cfg, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	var captchaVerifier captcha.Verifier
+	if cfg.captcha.provider != "" {
+		captchaVerifier, err = captcha.New(cfg.captcha.provider, cfg.captcha.secret)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+	var embedder embedding.Provider
+	if cfg.embedding.provider != "" {
+		embedder, err = embedding.New(cfg.embedding.provider, cfg.embedding.url, cfg.embedding.model, cfg.embedding.apiKey)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+	storageURL := cfg.storage.url
+	if storageURL == "" {
+		storageURL = cfg.baseURL + "/v1/files"
+	}
+	store, err := storage.New(cfg.storage.backend, cfg.storage.dir, storageURL)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	disposable := blocklist.NewDisposable()
+	if cfg.disposable.file != "" {
+		b, err := os.ReadFile(cfg.disposable.file)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		disposable.SetExtra(strings.Split(string(b), "\n"))
+	}
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
-		mailer: mailerApp,
+		config:           cfg,
+		logger:           logger,
+		models:           models,
+		mailer:           mailerApp,
+		authLimiter:      newAuthLimiter(cfg.authLimiter.rps, cfg.authLimiter.burst, cfg.authLimiter.ban, cfg.authLimiter.maxBan),
+		anonymousLimiter: newAnonymousLimiter(cfg.anonymous.rps, cfg.anonymous.burst),
+		health:           newHealthRegistry("database"),
+		tokenCache:       newTokenCache(cfg.tokens.cacheTTL, cfg.tokens.cacheSize),
+		limiter: newClientLimiter(limiterSettings{
+			Enabled:      cfg.limiter.enabled,
+			RPS:          cfg.limiter.rps,
+			Burst:        cfg.limiter.burst,
+			TrustedRPS:   cfg.limiter.trustedRPS,
+			TrustedBurst: cfg.limiter.trustedBurst,
+		}),
+		captcha:        captchaVerifier,
+		embedder:       embedder,
+		storage:        store,
+		disposable:     disposable,
+		pwned:          pwned.New(cfg.pwned.timeout),
+		jobs:           newJobQueue(cfg.jobs.queueSize),
+		views:          newViewBuffer(cfg.stats.bufferSize),
+		usage:          newUsageBuffer(cfg.usage.bufferSize),
+		passwordParams: passwordParams,
+		db:             db,
+	}
+	if db != nil {
+		app.publishDBStats()
 	}
 
 	err = app.serve()
@@ -135,6 +569,19 @@ func main() {
 	}
 }
 
+func newMailer(cfg config, logger *slog.Logger) (*mailer.Mailer, error) {
+	switch cfg.smtp.mode {
+	case mailer.ModeLive:
+		return mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender, cfg.smtp.tls, cfg.smtp.poolSize)
+	case mailer.ModeLog:
+		return mailer.NewLogMailer(logger, cfg.smtp.logDir, cfg.smtp.sender), nil
+	case mailer.ModeDiscard:
+		return mailer.NewDiscardMailer(cfg.smtp.sender), nil
+	default:
+		return nil, fmt.Errorf("invalid smtp mode %q", cfg.smtp.mode)
+	}
+}
+
 func openDB(cfg config) (*sql.DB, error) {
 	db, err := sql.Open("postgres", cfg.db.dsn)
 	if err != nil {
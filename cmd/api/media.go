@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// includeMedia is the include query parameter value which adds each movie's
+// media links to movie responses.
+const includeMedia = "media"
+
+// readInclude returns the related resources named by the include query
+// parameter, such as include=media.
+func (app *application) readInclude(qs url.Values, v *validator.Validator) []string {
+	include := app.readCSV(qs, "include", []string{})
+	for _, name := range include {
+		v.CheckCode(validator.PermittedValue(name, includeMedia), "include", "invalid_value", "must only contain media")
+	}
+	return include
+}
+
+// loadMedia sets the Media of each movie with a single query.
+func (app *application) loadMedia(ctx context.Context, movies ...*data.Movie) error {
+	if len(movies) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+	links, err := app.models.Media.GetForMovies(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byMovie := make(map[int64][]*data.Media, len(movies))
+	for _, media := range links {
+		byMovie[media.MovieID] = append(byMovie[media.MovieID], media)
+	}
+	for _, movie := range movies {
+		movie.Media = byMovie[movie.ID]
+		if movie.Media == nil {
+			movie.Media = []*data.Media{}
+		}
+	}
+	return nil
+}
+
+func (app *application) readMediaIDParam(r *http.Request) (int64, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.ParseInt(params.ByName("media_id"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("invalid media_id parameter")
+	}
+	return id, nil
+}
+
+// readMedia reads the movie's media link named in the URL, sending a 404 or
+// 500 response and returning false if it can't be read.
+func (app *application) readMedia(w http.ResponseWriter, r *http.Request) (*data.Media, bool) {
+	movieID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+	id, err := app.readMediaIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+	media, err := app.models.Media.Get(r.Context(), movieID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return media, true
+}
+
+// listMovieMediaHandler returns a movie's trailers, clips and alternative
+// posters, in the order they were added.
+func (app *application) listMovieMediaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	links, err := app.models.Media.GetForMovies(r.Context(), []int64{id})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"media": links}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createMovieMediaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		Type     string `json:"type"`
+		Provider string `json:"provider"`
+		URL      string `json:"url"`
+		Language string `json:"language"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	media := &data.Media{
+		MovieID:  id,
+		Type:     input.Type,
+		Provider: input.Provider,
+		URL:      input.URL,
+		Language: input.Language,
+	}
+	v := validator.New()
+	if data.ValidateMedia(v, media); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	err = app.models.Media.Insert(r.Context(), media)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/movies/%d/media/%d", id, media.ID))
+	err = app.writeJSON(w, http.StatusCreated, envelope{"media": media}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateMovieMediaHandler(w http.ResponseWriter, r *http.Request) {
+	media, ok := app.readMedia(w, r)
+	if !ok {
+		return
+	}
+	var input struct {
+		Type     *string `json:"type"`
+		Provider *string `json:"provider"`
+		URL      *string `json:"url"`
+		Language *string `json:"language"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if input.Type != nil {
+		media.Type = *input.Type
+	}
+	if input.Provider != nil {
+		media.Provider = *input.Provider
+	}
+	if input.URL != nil {
+		media.URL = *input.URL
+	}
+	if input.Language != nil {
+		media.Language = *input.Language
+	}
+
+	v := validator.New()
+	if data.ValidateMedia(v, media); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	err = app.models.Media.Update(r.Context(), media)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"media": media}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteMovieMediaHandler(w http.ResponseWriter, r *http.Request) {
+	movieID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	id, err := app.readMediaIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	err = app.models.Media.Delete(r.Context(), movieID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeMessage(w, r, http.StatusOK, envelope{"message": "media successfully deleted"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
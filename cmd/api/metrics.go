@@ -0,0 +1,240 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// slowQueries counts the statements logged as slow, by query name.
+var slowQueries = expvar.NewMap("db_slow_queries")
+
+// panicsRecovered counts recovered panics, by where they were recovered.
+var panicsRecovered = expvar.NewMap("panics_recovered")
+
+// shedRequests counts the requests refused by shedLoad.
+var shedRequests = expvar.NewInt("requests_shed")
+
+// prunedTokens counts the expired tokens deleted by pruneTokens.
+var prunedTokens = expvar.NewInt("tokens_pruned")
+
+// tokenTableRows and tokenTableBytes are the size of the tokens table, as of
+// the last time pruneTokens ran.
+var (
+	tokenTableRows  = expvar.NewInt("tokens_table_rows")
+	tokenTableBytes = expvar.NewInt("tokens_table_bytes")
+)
+
+// tokenCacheHits and tokenCacheMisses count authentication token lookups
+// answered by the token cache and those which went to the database.
+var (
+	tokenCacheHits   = expvar.NewInt("token_cache_hits")
+	tokenCacheMisses = expvar.NewInt("token_cache_misses")
+)
+
+// embeddedMovies counts the movies embedded by embedMovies.
+var embeddedMovies = expvar.NewInt("movies_embedded")
+
+// requestHistory counts responses per minute, for the request and error rates
+// reported by /v1/admin/stats.
+var requestHistory = newRequestSeries(7 * 24 * time.Hour)
+
+// requestCounts are the responses sent over some period.
+type requestCounts struct {
+	Total        int64 `json:"total"`
+	ClientErrors int64 `json:"client_errors"`
+	ServerErrors int64 `json:"server_errors"`
+}
+
+type requestMinute struct {
+	minute int64
+	requestCounts
+}
+
+// requestSeries is a ring of per-minute response counts covering a fixed
+// span. Counts older than the span are overwritten.
+type requestSeries struct {
+	mu      sync.Mutex
+	start   time.Time
+	minutes []requestMinute
+}
+
+func newRequestSeries(span time.Duration) *requestSeries {
+	return &requestSeries{start: time.Now(), minutes: make([]requestMinute, int(span/time.Minute))}
+}
+
+func (rs *requestSeries) record(at time.Time, status int) {
+	minute := at.Unix() / 60
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	m := &rs.minutes[minute%int64(len(rs.minutes))]
+	if m.minute != minute {
+		*m = requestMinute{minute: minute}
+	}
+	m.Total++
+	switch {
+	case status >= 500:
+		m.ServerErrors++
+	case status >= 400:
+		m.ClientErrors++
+	}
+}
+
+// since returns the counts from since until now. The counts only go back to
+// the series' start, which is returned when it's later than since, so that
+// rates aren't diluted by minutes before the process started.
+func (rs *requestSeries) since(since time.Time) (requestCounts, time.Time) {
+	if rs.start.After(since) {
+		since = rs.start
+	}
+	from := since.Unix() / 60
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	var counts requestCounts
+	for _, m := range rs.minutes {
+		if m.minute >= from {
+			counts.Total += m.Total
+			counts.ClientErrors += m.ClientErrors
+			counts.ServerErrors += m.ServerErrors
+		}
+	}
+	return counts, since
+}
+
+// latencyBuckets are the upper bounds of the latency histogram buckets.
+var latencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// histogram is a cumulative latency histogram published through expvar. The
+// last count is the +Inf bucket.
+type histogram struct {
+	counts [11]atomic.Int64
+	count  atomic.Int64
+	sumUS  atomic.Int64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := 0
+	for i < len(latencyBuckets) && d > latencyBuckets[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	h.sumUS.Add(d.Microseconds())
+}
+
+func (h *histogram) String() string {
+	var b strings.Builder
+	b.WriteString(`{"buckets":{`)
+	cumulative := int64(0)
+	for i := range h.counts {
+		cumulative += h.counts[i].Load()
+		le := "+Inf"
+		if i < len(latencyBuckets) {
+			le = strconv.FormatFloat(latencyBuckets[i].Seconds(), 'f', -1, 64)
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%q:%d", le, cumulative)
+	}
+	fmt.Fprintf(&b, `},"count":%d,"sum_seconds":%g}`, h.count.Load(), float64(h.sumUS.Load())/1e6)
+	return b.String()
+}
+
+// recordRoute wraps a route's handler to record its template, such as
+// "GET /v1/movies/:id", for the metrics middleware, so that metrics are
+// aggregated per route rather than per URL.
+func (app *application) recordRoute(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if slot := app.contextGetRoute(r); slot != nil {
+			*slot = route
+		}
+		next(w, r)
+	}
+}
+
+type metricsRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	bytes       int64
+}
+
+func (mr *metricsRecorder) WriteHeader(status int) {
+	if !mr.wroteHeader {
+		mr.status = status
+		mr.wroteHeader = true
+	}
+	mr.ResponseWriter.WriteHeader(status)
+}
+
+func (mr *metricsRecorder) Write(b []byte) (int, error) {
+	mr.wroteHeader = true
+	n, err := mr.ResponseWriter.Write(b)
+	mr.bytes += int64(n)
+	return n, err
+}
+
+func (mr *metricsRecorder) Unwrap() http.ResponseWriter {
+	return mr.ResponseWriter
+}
+
+// metrics records per-route latency histograms and status class counters,
+// published at /debug/vars alongside the standard expvar memory stats.
+// Requests which don't reach a route registered through recordRoute, because
+// nothing matched or a middleware rejected them first, are grouped together as
+// "unmatched".
+func (app *application) metrics(next http.Handler) http.Handler {
+	var (
+		totalRequests  = expvar.NewInt("total_requests_received")
+		totalResponses = expvar.NewInt("total_responses_sent")
+		inFlight       = expvar.NewInt("requests_in_flight")
+		latency        = expvar.NewMap("route_latency_seconds")
+		statuses       = expvar.NewMap("route_responses_by_status_class")
+		mu             sync.Mutex
+	)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		totalRequests.Add(1)
+		inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		route := "unmatched"
+		mr := &metricsRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(mr, app.contextSetRoute(r, &route))
+
+		mu.Lock()
+		h, ok := latency.Get(route).(*histogram)
+		if !ok {
+			h = &histogram{}
+			latency.Set(route, h)
+		}
+		classes, ok := statuses.Get(route).(*expvar.Map)
+		if !ok {
+			classes = new(expvar.Map).Init()
+			statuses.Set(route, classes)
+		}
+		mu.Unlock()
+
+		requestHistory.record(start, mr.status)
+		h.observe(time.Since(start))
+		classes.Add(fmt.Sprintf("%dxx", mr.status/100), 1)
+		totalResponses.Add(1)
+	})
+}
@@ -1,16 +1,17 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/ezechidc/greenlight/internal/data"
 	"github.com/ezechidc/greenlight/internal/validator"
-	"golang.org/x/time/rate"
 	"net/http"
-	"runtime/debug"
+	"net/netip"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/tomasen/realip"
@@ -35,12 +36,61 @@ func (r *statusRecorder) WriteHeader(code int) {
 	r.ResponseWriter.WriteHeader(code)
 }
 
+// requestID tags each request with an ID, reusing a well-formed X-Request-ID
+// header from an upstream proxy when there is one, and echoes it back.
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" || len(id) > 128 {
+			id = rand.Text()
+		}
+		w.Header().Set("X-Request-ID", id)
+		r = app.contextSetRequestID(r, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// secureHeaders sets the standard security response headers. They are off by
+// default in development, where the API is usually served over plain HTTP.
+func (app *application) secureHeaders(next http.Handler) http.Handler {
+	enabled := app.config.security.headers == "on" ||
+		(app.config.security.headers == "auto" && app.config.env != "development")
+	if !enabled {
+		return next
+	}
+	hsts := fmt.Sprintf("max-age=%d; includeSubDomains", int(app.config.security.hstsMaxAge.Seconds()))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Strict-Transport-Security", hsts)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		if app.config.security.csp != "" {
+			w.Header().Set("Content-Security-Policy", app.config.security.csp)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
+				report := panicReport{
+					Source:    "request",
+					Panic:     fmt.Sprint(err),
+					Stack:     panicStack(),
+					Method:    r.Method,
+					URL:       r.URL.String(),
+					RequestID: app.contextGetRequestID(r),
+				}
+				if user := app.contextGetLogUser(r); user != nil {
+					report.UserID = user.ID
+				}
+				app.reportPanic(report)
+
 				w.Header().Set("Connection", "close")
-				app.serverErrorResponse(w, r, fmt.Errorf("%s", err))
+				message := "the server encountered a problem and could not process your request"
+				app.errorResponse(w, r, http.StatusInternalServerError, message)
 			}
 		}()
 		next.ServeHTTP(w, r)
@@ -52,7 +102,8 @@ func (app *application) logRequestDuration(next http.Handler) http.Handler {
 		start := time.Now()
 		ip := realip.FromRequest(r)
 		rec := &statusRecorder{ResponseWriter: w, status: 200}
-		next.ServeHTTP(rec, r)
+		var user *data.User
+		next.ServeHTTP(rec, app.contextSetLogUser(r, &user))
 		duration := time.Since(start)
 
 		msg := "request complete"
@@ -62,11 +113,14 @@ func (app *application) logRequestDuration(next http.Handler) http.Handler {
 			"status", rec.status,
 			"source_ip", ip,
 			"duration", fmt.Sprintf("%d ms", duration.Milliseconds()),
+			"request_id", app.contextGetRequestID(r),
+		}
+		if user != nil && !user.IsAnonymous() {
+			fields = append(fields, "user_id", user.ID, "user_email", user.Email)
 		}
 
 		if rec.status >= 500 {
 			msg = "internal server error"
-			fields = append(fields, "stack", string(debug.Stack()))
 			app.logger.Error(msg, fields...)
 		} else if rec.status >= 400 {
 			msg = "client error"
@@ -92,45 +146,183 @@ func (app *application) logRequestDuration(next http.Handler) http.Handler {
 	})
 }
 
+// cidrList is a flag.Value holding a comma-separated list of CIDRs. Bare IP
+// addresses are accepted as single-host prefixes.
+type cidrList []netip.Prefix
+
+func (l *cidrList) String() string {
+	var parts []string
+	for _, prefix := range *l {
+		parts = append(parts, prefix.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *cidrList) Set(value string) error {
+	for part := range strings.SplitSeq(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(part)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(part)
+			if addrErr != nil {
+				return err
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		*l = append(*l, prefix.Masked())
+	}
+	return nil
+}
+
+func (l cidrList) contains(addr netip.Addr) bool {
+	for _, prefix := range l {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ipPermitted reports whether ip passes the deny and allow lists. A denied
+// address is always refused; otherwise an empty allow list admits everyone.
+func ipPermitted(ip string, allow, deny cidrList) bool {
+	if len(allow) == 0 && len(deny) == 0 {
+		return true
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	if deny.contains(addr) {
+		return false
+	}
+	return len(allow) == 0 || allow.contains(addr)
+}
+
+func (app *application) ipFilter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := realip.FromRequest(r)
+		if !ipPermitted(ip, app.config.ip.allow, app.config.ip.deny) {
+			app.logger.Warn("ip denied", "scope", "global", "source_ip", ip, "method", r.Method, "url", r.URL.String())
+			app.ipDeniedResponse(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) adminIPFilter(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := realip.FromRequest(r)
+		if !ipPermitted(ip, app.config.ip.adminAllow, app.config.ip.adminDeny) {
+			app.logger.Warn("ip denied", "scope", "admin", "source_ip", ip, "method", r.Method, "url", r.URL.String())
+			app.ipDeniedResponse(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
+
+const (
+	limiterDefault = iota
+	limiterTrusted
+	limiterExempt
+)
+
+// limiterClass returns how the global rate limiter treats ip: exempt clients
+// aren't limited at all and trusted ones get the elevated trusted limits.
+func (app *application) limiterClass(ip string) int {
+	if len(app.config.limiter.exempt) == 0 && len(app.config.limiter.trusted) == 0 {
+		return limiterDefault
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return limiterDefault
+	}
+	addr = addr.Unmap()
+	switch {
+	case app.config.limiter.exempt.contains(addr):
+		return limiterExempt
+	case app.config.limiter.trusted.contains(addr):
+		return limiterTrusted
+	default:
+		return limiterDefault
+	}
+}
+
 func (app *application) rateLimit(next http.Handler) http.Handler {
-	type client struct {
-		limiter  *rate.Limiter
-		lastSeen time.Time
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := realip.FromRequest(r)
+		class := app.limiterClass(ip)
+		if class != limiterExempt && !app.limiter.allow(ip, class == limiterTrusted) {
+			app.rateLimitExceededResponse(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// timeout cancels the request context after d, which cancels any database
+// query in progress. serverErrorResponse then sends a 504 rather than a 500.
+func (app *application) timeout(d time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	if d <= 0 {
+		return next
 	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
+}
 
+// shedLoad limits the number of requests handled at once to -inflight-max.
+// Up to -inflight-queue more wait up to -inflight-wait for a slot, and the
+// rest get a 503, so that a spike can't queue unbounded work on the database
+// pool. Health probes are never shed, so an overloaded instance isn't also
+// restarted.
+func (app *application) shedLoad(next http.Handler) http.Handler {
+	if app.config.inflight.max <= 0 {
+		return next
+	}
 	var (
-		mu      sync.Mutex
-		clients = make(map[string]*client)
+		slots   = make(chan struct{}, app.config.inflight.max)
+		waiting atomic.Int64
 	)
-	go func() {
-		for {
-			time.Sleep(time.Minute)
-			mu.Lock()
-			for ip, client := range clients {
-				if time.Since(client.lastSeen) > 3*time.Minute {
-					delete(clients, ip)
-				}
-			}
-			mu.Unlock()
-		}
-	}()
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if app.config.limiter.enabled {
-			ip := realip.FromRequest(r)
-			mu.Lock()
-			defer mu.Unlock()
-			if _, ok := clients[ip]; !ok {
-				clients[ip] = &client{
-					limiter: rate.NewLimiter(rate.Limit(app.config.limiter.rps), app.config.limiter.burst),
-				}
+		if r.URL.Path == "/v1/healthcheck" || r.URL.Path == "/v1/readyz" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		select {
+		case slots <- struct{}{}:
+		default:
+			if waiting.Add(1) > int64(app.config.inflight.queue) {
+				waiting.Add(-1)
+				shedRequests.Add(1)
+				app.overloadedResponse(w, r)
+				return
 			}
-			clients[ip].lastSeen = time.Now()
-			if !clients[ip].limiter.Allow() {
-
-				app.rateLimitExceededResponse(w, r)
+			timer := time.NewTimer(app.config.inflight.wait)
+			select {
+			case slots <- struct{}{}:
+				timer.Stop()
+				waiting.Add(-1)
+			case <-timer.C:
+				waiting.Add(-1)
+				shedRequests.Add(1)
+				app.overloadedResponse(w, r)
+				return
+			case <-r.Context().Done():
+				timer.Stop()
+				waiting.Add(-1)
 				return
 			}
 		}
+		defer func() { <-slots }()
 		next.ServeHTTP(w, r)
 	})
 }
@@ -159,15 +351,20 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		user, err := app.models.Users.GetForToken(data.ScopeAuthentication, token)
-		if err != nil {
-			switch {
-			case errors.Is(err, data.ErrRecordNotFound):
-				app.invalidAuthenticationTokenResponse(w, r)
-			default:
-				app.serverErrorResponse(w, r, err)
+		user, ok := app.tokenCache.get(token)
+		if !ok {
+			var err error
+			user, err = app.models.Users.GetForToken(r.Context(), data.ScopeAuthentication, token)
+			if err != nil {
+				switch {
+				case errors.Is(err, data.ErrRecordNotFound):
+					app.invalidAuthenticationTokenResponse(w, r)
+				default:
+					app.serverErrorResponse(w, r, err)
+				}
+				return
 			}
-			return
+			app.tokenCache.put(token, user)
 		}
 		r = app.contextSetUser(r, user)
 		next.ServeHTTP(w, r)
@@ -178,6 +375,7 @@ func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.Han
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := app.contextGetUser(r)
 		if user.IsAnonymous() {
+			app.auditDenial(r, user, "authentication required")
 			app.authenticationRequiredResponse(w, r)
 			return
 		}
@@ -191,6 +389,7 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 		user := app.contextGetUser(r)
 		// Check that a user is activated.
 		if !user.Activated {
+			app.auditDenial(r, user, "account not activated")
 			app.inactiveAccountResponse(w, r)
 			return
 		}
@@ -199,3 +398,81 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 	// Wrap fn with the requireAuthenticatedUser() middleware before returning it.
 	return app.requireAuthenticatedUser(fn)
 }
+
+// requireReadAccess lets anonymous users through to read-only routes when
+// anonymous read access is enabled, subject to the anonymous rate limit.
+// Otherwise, and for authenticated users, it's requireActivatedUser.
+func (app *application) requireReadAccess(next http.HandlerFunc) http.HandlerFunc {
+	activated := app.requireActivatedUser(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !app.config.anonymous.read || !app.contextGetUser(r).IsAnonymous() {
+			activated(w, r)
+			return
+		}
+		ip := realip.FromRequest(r)
+		if app.limiter.getSettings().Enabled && app.limiterClass(ip) != limiterExempt && !app.anonymousLimiter.allow(ip) {
+			app.rateLimitExceededResponse(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+		permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !permissions.Include(code) {
+			app.auditDenial(r, user, "missing permission "+code)
+			app.notPermittedResponse(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+	return app.requireActivatedUser(fn)
+}
+
+// auditDenial records a request rejected by an authorization check as an audit
+// log event and, if enabled, an audit_events row.
+func (app *application) auditDenial(r *http.Request, user *data.User, reason string) {
+	event := &data.AuditEvent{
+		UserID:    user.ID,
+		Action:    data.AuditActionAuthorizationDenied,
+		Reason:    reason,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		SourceIP:  realip.FromRequest(r),
+		RequestID: app.contextGetRequestID(r),
+	}
+	app.logger.Warn("audit",
+		"action", event.Action,
+		"reason", event.Reason,
+		"user_id", event.UserID,
+		"method", event.Method,
+		"path", event.Path,
+		"source_ip", event.SourceIP,
+		"request_id", event.RequestID,
+	)
+	if !app.config.audit.db {
+		return
+	}
+	// Clients decide how many requests get denied, so the rows are written
+	// through the bounded job queue and dropped, with the log line above kept,
+	// when it's full.
+	app.jobs.tryEnqueue("audit", func(ctx context.Context) error {
+		return app.models.Audit.Insert(ctx, event)
+	})
+}
+
+// allowUnknownFields lets readJSON ignore unknown keys in the request body for
+// routes where forward-compatible clients may send fields we don't know yet.
+func (app *application) allowUnknownFields(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), allowUnknownFieldsContextKey, true)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequirePermission(t *testing.T) {
+	inactive := &data.User{ID: 2, Email: "inactive@example.com", Language: "en"}
+
+	tests := []struct {
+		name        string
+		user        *data.User
+		permissions data.Permissions
+		wantStatus  int
+	}{
+		{name: "anonymous", user: data.AnonymousUser, wantStatus: http.StatusUnauthorized},
+		{name: "not activated", user: inactive, permissions: data.Permissions{"admin:read"}, wantStatus: http.StatusForbidden},
+		{name: "missing permission", user: testUser, permissions: data.Permissions{"movies:read"}, wantStatus: http.StatusForbidden},
+		{name: "has permission", user: testUser, permissions: data.Permissions{"admin:read"}, wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			models.Permissions.(*mocks.PermissionStore).GetAllForUserFunc = func(ctx context.Context, userID int64) (data.Permissions, error) {
+				return tt.permissions, nil
+			}
+			app := newTestApplication(t, models)
+			next := func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}
+
+			r := newTestRequest(t, http.MethodGet, "/v1/admin/emails", "", tt.user, nil)
+			status, _, body := serve(t, app.requirePermission("admin:read", next), r)
+			if status != tt.wantStatus {
+				t.Errorf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+		})
+	}
+}
+
+func TestRequireReadAccess(t *testing.T) {
+	tests := []struct {
+		name          string
+		anonymousRead bool
+		user          *data.User
+		requests      int
+		wantStatus    int
+	}{
+		{name: "anonymous, disabled", user: data.AnonymousUser, requests: 1, wantStatus: http.StatusUnauthorized},
+		{name: "anonymous, enabled", anonymousRead: true, user: data.AnonymousUser, requests: 1, wantStatus: http.StatusNoContent},
+		{name: "anonymous, over the limit", anonymousRead: true, user: data.AnonymousUser, requests: 3, wantStatus: http.StatusTooManyRequests},
+		{name: "activated, over the anonymous limit", anonymousRead: true, user: testUser, requests: 3, wantStatus: http.StatusNoContent},
+		{name: "not activated, enabled", anonymousRead: true, user: &data.User{ID: 2, Language: "en"}, requests: 1, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, mocks.NewModels())
+			app.config.anonymous.read = tt.anonymousRead
+			app.limiter = newClientLimiter(limiterSettings{Enabled: true})
+			app.anonymousLimiter = newAnonymousLimiter(0.001, 2)
+			next := func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}
+
+			var status int
+			var body map[string]any
+			for range tt.requests {
+				r := newTestRequest(t, http.MethodGet, "/v1/movies", "", tt.user, nil)
+				status, _, body = serve(t, app.requireReadAccess(next), r)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+		})
+	}
+}
+
+func TestRateLimitTrustedClients(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		wantStatus int
+	}{
+		{name: "default", remoteAddr: "192.0.2.1:1234", wantStatus: http.StatusTooManyRequests},
+		{name: "trusted", remoteAddr: "10.0.0.1:1234", wantStatus: http.StatusNoContent},
+		{name: "exempt", remoteAddr: "10.1.0.1:1234", wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, mocks.NewModels())
+			app.limiter = newClientLimiter(limiterSettings{Enabled: true, RPS: 0.001, Burst: 2, TrustedRPS: 0.001, TrustedBurst: 3})
+			app.config.limiter.trusted.Set("10.0.0.0/16")
+			app.config.limiter.exempt.Set("10.1.0.0/16")
+			handler := app.rateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			var status int
+			for range 3 {
+				r := newTestRequest(t, http.MethodGet, "/v1/healthcheck", "", data.AnonymousUser, nil)
+				r.RemoteAddr = tt.remoteAddr
+				status, _, _ = serve(t, handler.ServeHTTP, r)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("got status %d; want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestLogRequestDurationUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     *data.User
+		wantUser bool
+	}{
+		{name: "anonymous", user: data.AnonymousUser},
+		{name: "authenticated", user: testUser, wantUser: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			app := newTestApplication(t, mocks.NewModels())
+			app.logger = slog.New(slog.NewJSONHandler(&buf, nil))
+			// Like authenticate, the inner handler sets the user on its
+			// own copy of the request.
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				app.contextSetUser(r, tt.user)
+				w.WriteHeader(http.StatusNoContent)
+			})
+			handler := app.requestID(app.logRequestDuration(next))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/movies", nil))
+
+			var entry map[string]any
+			err := json.Unmarshal(buf.Bytes(), &entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry["request_id"] == "" || entry["request_id"] == nil {
+				t.Errorf("log entry %v has no request_id", entry)
+			}
+			_, hasUser := entry["user_id"]
+			if hasUser != tt.wantUser {
+				t.Errorf("got user_id in %v: %t; want %t", entry, hasUser, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestShedLoad(t *testing.T) {
+	app := newTestApplication(t, mocks.NewModels())
+	app.config.inflight.max = 1
+	app.config.inflight.queue = 1
+	app.config.inflight.wait = 50 * time.Millisecond
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	handler := app.shedLoad(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	serveAsync := func(path string) chan int {
+		status := make(chan int, 1)
+		go func() {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+			status <- rr.Code
+		}()
+		return status
+	}
+
+	// The first request takes the only slot and the second queues behind it.
+	first := serveAsync("/slow")
+	<-started
+	second := serveAsync("/slow")
+	time.Sleep(10 * time.Millisecond)
+
+	// The queue is full, so the third is shed straight away.
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/movies", nil))
+	if rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("got status %d, Retry-After %q; want 503 with Retry-After", rr.Code, rr.Header().Get("Retry-After"))
+	}
+	// Health probes are never shed.
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/readyz", nil))
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("got readyz status %d; want %d", rr.Code, http.StatusNoContent)
+	}
+
+	// The queued request times out waiting.
+	if status := <-second; status != http.StatusServiceUnavailable {
+		t.Errorf("got queued status %d; want %d", status, http.StatusServiceUnavailable)
+	}
+	close(release)
+	if status := <-first; status != http.StatusNoContent {
+		t.Errorf("got first status %d; want %d", status, http.StatusNoContent)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		wantStatus int
+	}{
+		{name: "timed out", timeout: time.Millisecond, wantStatus: http.StatusGatewayTimeout},
+		{name: "in time", timeout: time.Second, wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, mocks.NewModels())
+			// The handler stands in for a query taking 50ms, or until the
+			// context is cancelled.
+			handler := app.timeout(tt.timeout, func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+					app.serverErrorResponse(w, r, r.Context().Err())
+				case <-time.After(50 * time.Millisecond):
+					w.WriteHeader(http.StatusNoContent)
+				}
+			})
+
+			r := newTestRequest(t, http.MethodGet, "/v1/movies", "", testUser, nil)
+			status, _, body := serve(t, handler, r)
+			if status != tt.wantStatus {
+				t.Errorf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+		})
+	}
+}
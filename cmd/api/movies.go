@@ -1,19 +1,33 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/jsonpatch"
 	"github.com/ezechidc/greenlight/internal/validator"
+	"maps"
+	"mime"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title   string       `json:"title"`
-		Year    int32        `json:"year"`
-		Runtime data.Runtime `json:"runtime"`
-		Genres  []string     `json:"genres"`
+		Title               string            `json:"title"`
+		OriginalTitle       string            `json:"original_title"`
+		Titles              map[string]string `json:"titles"`
+		Year                int32             `json:"year"`
+		Runtime             data.Runtime      `json:"runtime"`
+		Genres              []string          `json:"genres"`
+		ReleaseDate         *data.Date        `json:"release_date"`
+		CertificationSystem string            `json:"certification_system"`
+		Certification       string            `json:"certification"`
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
@@ -22,20 +36,25 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	movie := &data.Movie{
-		Title:   input.Title,
-		Year:    input.Year,
-		Runtime: input.Runtime,
-		Genres:  input.Genres,
+		Title:               input.Title,
+		OriginalTitle:       input.OriginalTitle,
+		Titles:              input.Titles,
+		Year:                input.Year,
+		Runtime:             input.Runtime,
+		Genres:              input.Genres,
+		ReleaseDate:         input.ReleaseDate,
+		CertificationSystem: input.CertificationSystem,
+		Certification:       input.Certification,
 	}
 
 	v := validator.New()
 
 	if data.ValidateMovie(v, movie); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	err = app.models.Movies.Insert(movie)
+	err = app.models.Movies.Insert(r.Context(), movie)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -57,8 +76,14 @@ func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request)
 		http.NotFound(w, r)
 		return
 	}
+	v := validator.New()
+	include := app.readInclude(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
 
-	movie, err := app.models.Movies.Get(id)
+	movie, err := app.models.Movies.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -68,6 +93,15 @@ func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request)
 		}
 		return
 	}
+	app.recordView(r, movie.ID)
+	if slices.Contains(include, includeMedia) {
+		err = app.loadMedia(r.Context(), movie)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	app.localizeMovies(w, r, movie)
 
 	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
 	if err != nil {
@@ -82,7 +116,7 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	movie, err := app.models.Movies.Get(id)
+	movie, err := app.models.Movies.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -92,49 +126,267 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		}
 		return
 	}
-	var input struct {
-		Title   *string       `json:"title"`
-		Year    *int32        `json:"year"`
-		Runtime *data.Runtime `json:"runtime"`
-		Genres  []string      `json:"genres"`
-	}
-	err = app.readJSON(w, r, &input)
+	apply, err := app.readMoviePatch(w, r)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
 	}
-	if input.Title != nil {
-		movie.Title = *input.Title
+
+	for attempt := 0; ; attempt++ {
+		before := *movie
+		err = apply(movie)
+		if err != nil {
+			switch {
+			case errors.Is(err, jsonpatch.ErrTestFailed):
+				app.editConflictCurrentResponse(w, r, envelope{"movie": movie})
+			default:
+				app.badRequestResponse(w, r, err)
+			}
+			return
+		}
+
+		v := validator.New()
+		if data.ValidateMovie(v, movie); !v.Valid() {
+			app.failedValidationResponse(w, r, v)
+			return
+		}
+		err = app.models.Movies.Update(r.Context(), movie)
+		if !errors.Is(err, data.ErrEditConflict) {
+			break
+		}
+
+		// Someone else updated the movie since we read it. Re-read it, and
+		// either apply the same changes again or show the client the
+		// current state so it can merge. Reapplying is only safe when the
+		// other update left the fields this one changes alone, otherwise one
+		// of the two would be silently lost.
+		patched := movieFieldsChanged(&before, movie)
+		movie, err = app.models.Movies.Get(r.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		concurrent := movieFieldsChanged(&before, movie)
+		if attempt >= app.config.editConflictRetries || slices.ContainsFunc(patched, func(field string) bool {
+			return slices.Contains(concurrent, field)
+		}) {
+			app.editConflictCurrentResponse(w, r, envelope{"movie": movie})
+			return
+		}
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// movieFieldsChanged returns the names of the editable fields which differ
+// between a and b.
+func movieFieldsChanged(a, b *data.Movie) []string {
+	var fields []string
+	if a.Title != b.Title {
+		fields = append(fields, "title")
+	}
+	if a.OriginalTitle != b.OriginalTitle {
+		fields = append(fields, "original_title")
+	}
+	if !maps.Equal(a.Titles, b.Titles) {
+		fields = append(fields, "titles")
+	}
+	if a.Year != b.Year {
+		fields = append(fields, "year")
+	}
+	if a.Runtime != b.Runtime {
+		fields = append(fields, "runtime")
+	}
+	if !slices.Equal(a.Genres, b.Genres) {
+		fields = append(fields, "genres")
 	}
-	if input.Year != nil {
-		movie.Year = *input.Year
+	if !equalDates(a.ReleaseDate, b.ReleaseDate) {
+		fields = append(fields, "release_date")
 	}
-	if input.Runtime != nil {
-		movie.Runtime = *input.Runtime
+	if a.CertificationSystem != b.CertificationSystem {
+		fields = append(fields, "certification_system")
 	}
-	if input.Genres != nil {
-		movie.Genres = input.Genres // Note that we don't need to dereference a slice.
+	if a.Certification != b.Certification {
+		fields = append(fields, "certification")
 	}
+	return fields
+}
 
-	v := validator.New()
-	if data.ValidateMovie(v, movie); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
-		return
+func equalDates(a, b *data.Date) bool {
+	if a == nil || b == nil {
+		return a == b
 	}
-	err = app.models.Movies.Update(movie)
-	if err != nil {
-		switch {
-		case errors.Is(err, data.ErrEditConflict):
-			app.editConflictResponse(w, r)
-		default:
-			app.serverErrorResponse(w, r, err)
+	return a.Equal(b.Time)
+}
+
+// moviePatchDocument is the JSON document which JSON Patch and JSON Merge Patch
+// bodies are applied to. Members removed by the patch are cleared.
+type moviePatchDocument struct {
+	Title               string            `json:"title"`
+	OriginalTitle       string            `json:"original_title"`
+	Titles              map[string]string `json:"titles"`
+	Year                int32             `json:"year"`
+	Runtime             data.Runtime      `json:"runtime"`
+	Genres              []string          `json:"genres"`
+	ReleaseDate         *data.Date        `json:"release_date"`
+	CertificationSystem string            `json:"certification_system"`
+	Certification       string            `json:"certification"`
+}
+
+// readMoviePatch reads the body of a movie update and returns a function
+// which applies it to a movie. Plain JSON bodies update the fields they
+// contain. application/merge-patch+json (RFC 7386) and
+// application/json-patch+json (RFC 6902) bodies can also clear fields, which
+// plain JSON can't express.
+func (app *application) readMoviePatch(w http.ResponseWriter, r *http.Request) (func(movie *data.Movie) error, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/merge-patch+json":
+		var patch any
+		err := app.readJSON(w, r, &patch)
+		if err != nil {
+			return nil, err
 		}
-		return
+		return func(movie *data.Movie) error {
+			return patchMovie(movie, func(doc any) (any, error) {
+				return jsonpatch.MergePatch(doc, patch), nil
+			})
+		}, nil
+
+	case "application/json-patch+json":
+		var ops []jsonpatch.Operation
+		err := app.readJSON(w, r, &ops)
+		if err != nil {
+			return nil, err
+		}
+		return func(movie *data.Movie) error {
+			return patchMovie(movie, func(doc any) (any, error) {
+				return jsonpatch.Apply(doc, ops)
+			})
+		}, nil
+
+	default:
+		var input struct {
+			Title               *string           `json:"title"`
+			OriginalTitle       *string           `json:"original_title"`
+			Titles              map[string]string `json:"titles"`
+			Year                *int32            `json:"year"`
+			Runtime             *data.Runtime     `json:"runtime"`
+			Genres              []string          `json:"genres"`
+			ReleaseDate         *data.Date        `json:"release_date"`
+			CertificationSystem *string           `json:"certification_system"`
+			Certification       *string           `json:"certification"`
+		}
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			return nil, err
+		}
+		return func(movie *data.Movie) error {
+			if input.Title != nil {
+				movie.Title = *input.Title
+			}
+			if input.OriginalTitle != nil {
+				movie.OriginalTitle = *input.OriginalTitle
+			}
+			if input.Titles != nil {
+				movie.Titles = input.Titles // Replaces every translation.
+			}
+			if input.Year != nil {
+				movie.Year = *input.Year
+			}
+			if input.Runtime != nil {
+				movie.Runtime = *input.Runtime
+			}
+			if input.Genres != nil {
+				movie.Genres = input.Genres // Note that we don't need to dereference a slice.
+			}
+			if input.ReleaseDate != nil {
+				movie.ReleaseDate = input.ReleaseDate
+			}
+			if input.CertificationSystem != nil {
+				movie.CertificationSystem = *input.CertificationSystem
+			}
+			if input.Certification != nil {
+				movie.Certification = *input.Certification
+			}
+			return nil
+		}, nil
 	}
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+}
+
+// patchMovie runs patch over the movie's patch document and copies the result
+// back. The movie is left untouched if the patch fails.
+func patchMovie(movie *data.Movie, patch func(doc any) (any, error)) error {
+	// Titles is never null in the document, so JSON Patch can add a
+	// translation to a movie without any.
+	titles := maps.Clone(movie.Titles)
+	if titles == nil {
+		titles = map[string]string{}
+	}
+	js, err := json.Marshal(moviePatchDocument{
+		Title:               movie.Title,
+		OriginalTitle:       movie.OriginalTitle,
+		Titles:              titles,
+		Year:                movie.Year,
+		Runtime:             movie.Runtime,
+		Genres:              movie.Genres,
+		ReleaseDate:         movie.ReleaseDate,
+		CertificationSystem: movie.CertificationSystem,
+		Certification:       movie.Certification,
+	})
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		return err
+	}
+	var doc any
+	err = json.Unmarshal(js, &doc)
+	if err != nil {
+		return err
+	}
+
+	doc, err = patch(doc)
+	if err != nil {
+		return err
+	}
+
+	js, err = json.Marshal(doc)
+	if err != nil {
+		return err
 	}
+	var result moviePatchDocument
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&result)
+	if err != nil {
+		if fieldName, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return fmt.Errorf("body contains unknown key %s", fieldName)
+		}
+		return err
+	}
+
+	movie.Title = result.Title
+	movie.OriginalTitle = result.OriginalTitle
+	movie.Titles = result.Titles
+	movie.Year = result.Year
+	movie.Runtime = result.Runtime
+	movie.Genres = result.Genres
+	movie.ReleaseDate = result.ReleaseDate
+	movie.CertificationSystem = result.CertificationSystem
+	movie.Certification = result.Certification
+	if movie.Genres == nil {
+		movie.Genres = []string{}
+	}
+	return nil
 }
 
 func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Request) {
@@ -142,7 +394,13 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 	if err != nil || id < 1 {
 		app.notFoundResponse(w, r)
 	}
-	err = app.models.Movies.Delete(id)
+	// The movie is looked up first for its poster, whose files are removed
+	// along with it. A poster still processing also removes the one it was
+	// replacing once it finds the movie gone.
+	movie, err := app.models.Movies.Get(r.Context(), id)
+	if err == nil {
+		err = app.models.Movies.Delete(r.Context(), id)
+	}
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -152,7 +410,13 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 		}
 		return
 	}
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie successfully deleted"}, nil)
+	if movie.Poster.Version != "" {
+		err = app.deletePosterFiles(r.Context(), id, movie.Poster.Version)
+		if err != nil {
+			app.logger.Error(err.Error(), "movie_id", id)
+		}
+	}
+	err = app.writeMessage(w, r, http.StatusOK, envelope{"message": "movie successfully deleted"})
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -160,30 +424,115 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 
 func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title  string
-		Genres []string
+		Title               string
+		Genres              []string
+		CertificationSystem string
+		Certification       string
+		ReleasedAfter       *data.Date
+		ReleasedBefore      *data.Date
 		data.Filters
 	}
 
 	v := validator.New()
 	qs := r.URL.Query()
+	if qs.Has("ids") {
+		app.listMoviesByIDHandler(w, r, qs, v)
+		return
+	}
 	input.Title = app.readString(qs, "title", "")
 	input.Genres = app.readCSV(qs, "genres", []string{})
+	input.CertificationSystem = app.readString(qs, "certification_system", "")
+	input.Certification = app.readString(qs, "certification", "")
+	data.ValidateCertification(v, input.CertificationSystem, input.Certification)
+	input.ReleasedAfter = app.readDate(qs, "released_after", v)
+	input.ReleasedBefore = app.readDate(qs, "released_before", v)
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 	input.Filters.Sort = app.readString(qs, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime", "-id", "-title", "-year", "-runtime"}
+	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime", "release_date", "-id", "-title", "-year", "-runtime", "-release_date"}
+	include := app.readInclude(qs, v)
+	app.debugParams(r, envelope{"title": input.Title, "genres": input.Genres, "certification_system": input.CertificationSystem, "certification": input.Certification, "released_after": input.ReleasedAfter, "released_before": input.ReleasedBefore, "filters": input.Filters})
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
-	movies, metadata, err := app.models.Movies.GetAll(input.Title, input.Genres, input.Filters)
+	movies, metadata, err := app.models.Movies.GetAll(r.Context(), input.Title, input.Genres, input.CertificationSystem, input.Certification, input.ReleasedAfter, input.ReleasedBefore, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+	metadata.SetLinks(app.config.baseURL+r.URL.Path, r.URL.Query())
+	if slices.Contains(include, includeMedia) {
+		err = app.loadMedia(r.Context(), movies...)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	app.localizeMovies(w, r, movies...)
 	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// maxMovieIDs caps the ids list so a single request stays one cheap query.
+const maxMovieIDs = 100
+
+// listMoviesByIDHandler serves GET /v1/movies?ids=12,15,99, returning the
+// movies in the order they were asked for along with any IDs that don't
+// exist, so clients can render a watchlist without a request per movie.
+func (app *application) listMoviesByIDHandler(w http.ResponseWriter, r *http.Request, qs url.Values, v *validator.Validator) {
+	var ids []int64
+	for _, s := range app.readCSV(qs, "ids", []string{}) {
+		id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil || id < 1 {
+			v.AddErrorCode("ids", "must_be_positive", "must be a positive integer")
+			break
+		}
+		ids = append(ids, id)
+	}
+	v.CheckCode(len(ids) > 0, "ids", "required", "must be provided")
+	v.CheckCode(len(ids) <= maxMovieIDs, "ids", "too_large", "must be a maximum of 100")
+	v.CheckCode(validator.Unique(ids), "ids", "duplicate_values", "must not contain duplicate values")
+	include := app.readInclude(qs, v)
+	for _, key := range []string{"title", "genres", "certification_system", "certification", "released_after", "released_before", "page", "page_size", "sort"} {
+		v.CheckCode(!qs.Has(key), "ids", "conflict", "cannot be combined with other filters")
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	found, err := app.models.Movies.GetMany(r.Context(), ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	byID := make(map[int64]*data.Movie, len(found))
+	for _, movie := range found {
+		byID[movie.ID] = movie
+	}
+	movies := make([]*data.Movie, 0, len(found))
+	missing := []int64{}
+	for _, id := range ids {
+		if movie, ok := byID[id]; ok {
+			movies = append(movies, movie)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if slices.Contains(include, includeMedia) {
+		err = app.loadMedia(r.Context(), movies...)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	app.localizeMovies(w, r, movies...)
+	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies, "missing": missing}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"github.com/ezechidc/greenlight/internal/storage"
+	"github.com/julienschmidt/httprouter"
+	"maps"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestShowMovieHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		err        error
+		wantStatus int
+	}{
+		{name: "valid id", id: "1", wantStatus: http.StatusOK},
+		{name: "missing movie", id: "2", err: data.ErrRecordNotFound, wantStatus: http.StatusNotFound},
+		{name: "database error", id: "1", err: errors.New("connection refused"), wantStatus: http.StatusInternalServerError},
+		{name: "negative id", id: "-1", wantStatus: http.StatusNotFound},
+		{name: "non-numeric id", id: "foo", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			movieMocks(models).GetFunc = func(ctx context.Context, id int64) (*data.Movie, error) {
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return &data.Movie{ID: id, Title: "Moana", Year: 2016, Runtime: 107, Genres: []string{"animation"}, Version: 1}, nil
+			}
+			app := newTestApplication(t, models)
+
+			r := newTestRequest(t, http.MethodGet, "/v1/movies/"+tt.id, "", testUser, httprouter.Params{{Key: "id", Value: tt.id}})
+			status, _, body := serve(t, app.showMovieHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+			if status == http.StatusOK {
+				movie, _ := body["movie"].(map[string]any)
+				if movie["title"] != "Moana" {
+					t.Errorf("got movie %v; want Moana", movie)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateMovieHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantInsert bool
+	}{
+		{
+			name:       "valid movie",
+			body:       `{"title": "Moana", "year": 2016, "runtime": "107 mins", "genres": ["animation"]}`,
+			wantStatus: http.StatusCreated,
+			wantInsert: true,
+		},
+		{
+			name:       "localized titles",
+			body:       `{"title": "Moana", "original_title": "Moana", "titles": {"fr": "Vaiana", "pt-BR": "Moana: Um Mar de Aventuras"}, "year": 2016, "runtime": "107 mins", "genres": ["animation"]}`,
+			wantStatus: http.StatusCreated,
+			wantInsert: true,
+		},
+		{
+			name:       "certification",
+			body:       `{"title": "Moana", "year": 2016, "runtime": "107 mins", "genres": ["animation"], "certification_system": "MPA", "certification": "PG"}`,
+			wantStatus: http.StatusCreated,
+			wantInsert: true,
+		},
+		{
+			name:       "certification from another system",
+			body:       `{"title": "Moana", "year": 2016, "runtime": "107 mins", "genres": ["animation"], "certification_system": "BBFC", "certification": "12A"}`,
+			wantStatus: http.StatusCreated,
+			wantInsert: true,
+		},
+		{
+			name:       "invalid certification",
+			body:       `{"title": "Moana", "year": 2016, "runtime": "107 mins", "genres": ["animation"], "certification_system": "MPA", "certification": "12A"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "certification without a system",
+			body:       `{"title": "Moana", "year": 2016, "runtime": "107 mins", "genres": ["animation"], "certification": "PG"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invalid title locale",
+			body:       `{"title": "Moana", "titles": {"French": "Vaiana"}, "year": 2016, "runtime": "107 mins", "genres": ["animation"]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "empty localized title",
+			body:       `{"title": "Moana", "titles": {"fr": ""}, "year": 2016, "runtime": "107 mins", "genres": ["animation"]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "no genres",
+			body:       `{"title": "Moana", "year": 2016, "runtime": "107 mins", "genres": []}`,
+			wantStatus: http.StatusCreated,
+			wantInsert: true,
+		},
+		{
+			name:       "runtime in minutes",
+			body:       `{"title": "Moana", "year": 2016, "runtime": 107, "genres": []}`,
+			wantStatus: http.StatusCreated,
+			wantInsert: true,
+		},
+		{
+			name:       "release date",
+			body:       `{"title": "Moana", "year": 2016, "runtime": "107 mins", "genres": [], "release_date": "2016-11-23"}`,
+			wantStatus: http.StatusCreated,
+			wantInsert: true,
+		},
+		{
+			name:       "invalid release date",
+			body:       `{"title": "Moana", "year": 2016, "runtime": "107 mins", "genres": [], "release_date": "23/11/2016"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing title",
+			body:       `{"year": 2016, "runtime": "107 mins", "genres": ["animation"]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "unknown field",
+			body:       `{"title": "Moana", "rating": 5}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "malformed JSON",
+			body:       `{"title": "Moana"`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			movies := movieMocks(models)
+			movies.InsertFunc = func(ctx context.Context, movie *data.Movie) error {
+				movie.ID = 42
+				movie.Version = 1
+				return nil
+			}
+			app := newTestApplication(t, models)
+
+			r := newTestRequest(t, http.MethodPost, "/v1/movies", tt.body, testUser, nil)
+			status, headers, body := serve(t, app.createMovieHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+			if inserted := len(movies.InsertCalls()) == 1; inserted != tt.wantInsert {
+				t.Errorf("got inserted %t; want %t", inserted, tt.wantInsert)
+			}
+			if tt.wantInsert && headers.Get("Location") != "/v1/movies/42" {
+				t.Errorf("got Location %q; want /v1/movies/42", headers.Get("Location"))
+			}
+		})
+	}
+}
+
+func TestDeleteMovieHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		poster     data.Poster
+		wantStatus int
+	}{
+		{name: "with a poster", id: "1", poster: data.Poster{Status: data.PosterReady, Version: "0123456789ab"}, wantStatus: http.StatusOK},
+		{name: "without a poster", id: "1", wantStatus: http.StatusOK},
+		{name: "missing movie", id: "2", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			movies := movieMocks(models)
+			movies.GetFunc = func(ctx context.Context, id int64) (*data.Movie, error) {
+				if id != 1 {
+					return nil, data.ErrRecordNotFound
+				}
+				return &data.Movie{ID: 1, Title: "Moana", Poster: tt.poster}, nil
+			}
+			movies.DeleteFunc = func(ctx context.Context, id int64) error {
+				return nil
+			}
+			app := newTestApplication(t, models)
+			dir := t.TempDir()
+			app.storage, _ = storage.New(storage.BackendLocal, dir, "http://localhost:4000/v1/files")
+			writePosterFiles(t, dir, 1, "0123456789ab")
+
+			r := newTestRequest(t, http.MethodDelete, "/v1/movies/"+tt.id, "", testUser, httprouter.Params{{Key: "id", Value: tt.id}})
+			status, _, body := serve(t, app.deleteMovieHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+			wantFiles := len(posterSizes)
+			if tt.poster.Version != "" {
+				wantFiles = 0
+			}
+			if files := storedPosterFiles(t, dir, 1); len(files) != wantFiles {
+				t.Errorf("got stored files %v; want %d", files, wantFiles)
+			}
+		})
+	}
+}
+
+func TestUpdateMovieHandlerEditConflict(t *testing.T) {
+	tests := []struct {
+		name        string
+		retries     int
+		concurrent  func(movie *data.Movie)
+		wantStatus  int
+		wantUpdates int
+	}{
+		{
+			name:        "no retries",
+			concurrent:  func(movie *data.Movie) { movie.Year = 2017 },
+			wantStatus:  http.StatusConflict,
+			wantUpdates: 1,
+		},
+		{
+			name:        "retry after a change to another field",
+			retries:     1,
+			concurrent:  func(movie *data.Movie) { movie.Year = 2017 },
+			wantStatus:  http.StatusOK,
+			wantUpdates: 2,
+		},
+		{
+			name:        "no retry after a change to the same field",
+			retries:     1,
+			concurrent:  func(movie *data.Movie) { movie.Title = "Moana 2" },
+			wantStatus:  http.StatusConflict,
+			wantUpdates: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := &data.Movie{ID: 1, Title: "Moana", Year: 2016, Runtime: 107, Genres: []string{"animation"}, Version: 1}
+			models := mocks.NewModels()
+			movies := movieMocks(models)
+			movies.GetFunc = func(ctx context.Context, id int64) (*data.Movie, error) {
+				movie := *stored
+				return &movie, nil
+			}
+			movies.UpdateFunc = func(ctx context.Context, movie *data.Movie) error {
+				if len(movies.UpdateCalls()) == 1 {
+					// Another request updates the movie first.
+					tt.concurrent(stored)
+					stored.Version++
+				}
+				if movie.Version != stored.Version {
+					return data.ErrEditConflict
+				}
+				movie.Version++
+				*stored = *movie
+				return nil
+			}
+			app := newTestApplication(t, models)
+			app.config.editConflictRetries = tt.retries
+
+			r := newTestRequest(t, http.MethodPatch, "/v1/movies/1", `{"title": "Vaiana"}`, testUser, httprouter.Params{{Key: "id", Value: "1"}})
+			status, _, body := serve(t, app.updateMovieHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+			if got := len(movies.UpdateCalls()); got != tt.wantUpdates {
+				t.Errorf("got %d updates; want %d", got, tt.wantUpdates)
+			}
+			// A conflict returns the current movie so the client can merge.
+			if status == http.StatusConflict {
+				body, _ = body["current"].(map[string]any)
+			}
+			if _, ok := body["movie"]; !ok {
+				t.Errorf("response %v has no movie", body)
+			}
+		})
+	}
+}
+
+func TestUpdateMovieHandlerClearGenres(t *testing.T) {
+	models := mocks.NewModels()
+	movies := movieMocks(models)
+	movies.GetFunc = func(ctx context.Context, id int64) (*data.Movie, error) {
+		return &data.Movie{ID: 1, Title: "Moana", Year: 2016, Runtime: 107, Genres: []string{"animation"}, Version: 1}, nil
+	}
+	app := newTestApplication(t, models)
+
+	r := newTestRequest(t, http.MethodPatch, "/v1/movies/1", `{"genres": null}`, testUser, httprouter.Params{{Key: "id", Value: "1"}})
+	r.Header.Set("Content-Type", "application/merge-patch+json")
+	status, _, body := serve(t, app.updateMovieHandler, r)
+	if status != http.StatusOK {
+		t.Fatalf("got status %d; want %d (%v)", status, http.StatusOK, body)
+	}
+	calls := movies.UpdateCalls()
+	if len(calls) != 1 || calls[0].Movie.Genres == nil || len(calls[0].Movie.Genres) != 0 {
+		t.Errorf("got update calls %+v; want one with empty genres", calls)
+	}
+}
+
+func TestListMoviesByID(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantStatus  int
+		wantIDs     []float64
+		wantMissing []float64
+	}{
+		{name: "request order", query: "ids=3,1,2", wantStatus: http.StatusOK, wantIDs: []float64{3, 1, 2}, wantMissing: []float64{}},
+		{name: "missing ids", query: "ids=1,99,2", wantStatus: http.StatusOK, wantIDs: []float64{1, 2}, wantMissing: []float64{99}},
+		{name: "empty", query: "ids=", wantStatus: http.StatusUnprocessableEntity},
+		{name: "non-numeric", query: "ids=1,foo", wantStatus: http.StatusUnprocessableEntity},
+		{name: "zero", query: "ids=0", wantStatus: http.StatusUnprocessableEntity},
+		{name: "duplicates", query: "ids=1,1", wantStatus: http.StatusUnprocessableEntity},
+		{name: "combined with filters", query: "ids=1&title=moana", wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			movieMocks(models).GetManyFunc = func(ctx context.Context, ids []int64) ([]*data.Movie, error) {
+				var movies []*data.Movie
+				for _, id := range []int64{1, 2, 3} {
+					if slices.Contains(ids, id) {
+						movies = append(movies, &data.Movie{ID: id, Title: "Moana", Version: 1})
+					}
+				}
+				return movies, nil
+			}
+			app := newTestApplication(t, models)
+
+			r := newTestRequest(t, http.MethodGet, "/v1/movies?"+tt.query, "", testUser, nil)
+			status, _, body := serve(t, app.listMoviesHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			movies, _ := body["movies"].([]any)
+			var ids []float64
+			for _, movie := range movies {
+				ids = append(ids, movie.(map[string]any)["id"].(float64))
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("got ids %v; want %v", ids, tt.wantIDs)
+			}
+			missing := []float64{}
+			for _, id := range body["missing"].([]any) {
+				missing = append(missing, id.(float64))
+			}
+			if !slices.Equal(missing, tt.wantMissing) {
+				t.Errorf("got missing %v; want %v", missing, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestShowMovieHandlerLocalizedTitle(t *testing.T) {
+	tests := []struct {
+		name           string
+		language       string
+		acceptLanguage string
+		wantTitle      string
+		wantTitles     map[string]any
+	}{
+		{name: "default", wantTitle: "Spirited Away", wantTitles: map[string]any{"fr": "Le Voyage de Chihiro"}},
+		{name: "accept-language", acceptLanguage: "fr-CA, en;q=0.5", wantTitle: "Le Voyage de Chihiro", wantTitles: map[string]any{"en": "Spirited Away", "fr": "Le Voyage de Chihiro"}},
+		{name: "english preferred", acceptLanguage: "en, fr;q=0.5", wantTitle: "Spirited Away", wantTitles: map[string]any{"fr": "Le Voyage de Chihiro"}},
+		{name: "untranslated", acceptLanguage: "de", wantTitle: "Spirited Away", wantTitles: map[string]any{"fr": "Le Voyage de Chihiro"}},
+		{name: "user language", language: "fr", acceptLanguage: "en", wantTitle: "Le Voyage de Chihiro", wantTitles: map[string]any{"en": "Spirited Away", "fr": "Le Voyage de Chihiro"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			movieMocks(models).GetFunc = func(ctx context.Context, id int64) (*data.Movie, error) {
+				return &data.Movie{ID: id, Title: "Spirited Away", OriginalTitle: "Sen to Chihiro no kamikakushi", Titles: data.Titles{"fr": "Le Voyage de Chihiro"}, Version: 1}, nil
+			}
+			app := newTestApplication(t, models)
+
+			user := *testUser
+			user.Language = tt.language
+			r := newTestRequest(t, http.MethodGet, "/v1/movies/1", "", &user, httprouter.Params{{Key: "id", Value: "1"}})
+			r.Header.Set("Accept-Language", tt.acceptLanguage)
+			status, headers, body := serve(t, app.showMovieHandler, r)
+			if status != http.StatusOK {
+				t.Fatalf("got status %d; want %d (%v)", status, http.StatusOK, body)
+			}
+			movie, _ := body["movie"].(map[string]any)
+			if movie["title"] != tt.wantTitle || !maps.Equal(movie["titles"].(map[string]any), tt.wantTitles) {
+				t.Errorf("got title %v, titles %v; want %q, %v", movie["title"], movie["titles"], tt.wantTitle, tt.wantTitles)
+			}
+			if movie["original_title"] != "Sen to Chihiro no kamikakushi" {
+				t.Errorf("got original_title %v", movie["original_title"])
+			}
+			if !slices.Contains(headers.Values("Vary"), "Accept-Language") {
+				t.Errorf("got Vary %q; want Accept-Language", headers.Values("Vary"))
+			}
+		})
+	}
+}
+
+func TestUpdateMovieHandlerTitles(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantTitles  data.Titles
+	}{
+		{name: "json", contentType: "application/json", body: `{"titles": {"de": "Vaiana"}}`, wantTitles: data.Titles{"de": "Vaiana"}},
+		{name: "merge patch", contentType: "application/merge-patch+json", body: `{"titles": {"de": "Vaiana", "fr": null}}`, wantTitles: data.Titles{"de": "Vaiana"}},
+		{name: "json patch", contentType: "application/json-patch+json", body: `[{"op": "add", "path": "/titles/de", "value": "Vaiana"}]`, wantTitles: data.Titles{"de": "Vaiana", "fr": "Vaiana, la légende du bout du monde"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			movies := movieMocks(models)
+			movies.GetFunc = func(ctx context.Context, id int64) (*data.Movie, error) {
+				return &data.Movie{ID: 1, Title: "Moana", Titles: data.Titles{"fr": "Vaiana, la légende du bout du monde"}, Year: 2016, Runtime: 107, Genres: []string{"animation"}, Version: 1}, nil
+			}
+			app := newTestApplication(t, models)
+
+			r := newTestRequest(t, http.MethodPatch, "/v1/movies/1", tt.body, testUser, httprouter.Params{{Key: "id", Value: "1"}})
+			r.Header.Set("Content-Type", tt.contentType)
+			status, _, body := serve(t, app.updateMovieHandler, r)
+			if status != http.StatusOK {
+				t.Fatalf("got status %d; want %d (%v)", status, http.StatusOK, body)
+			}
+			calls := movies.UpdateCalls()
+			if len(calls) != 1 || !maps.Equal(calls[0].Movie.Titles, tt.wantTitles) {
+				t.Errorf("got update calls %+v; want titles %v", calls, tt.wantTitles)
+			}
+		})
+	}
+}
+
+func TestListMoviesHandlerCertification(t *testing.T) {
+	tests := []struct {
+		name              string
+		query             string
+		wantStatus        int
+		wantSystem        string
+		wantCertification string
+	}{
+		{name: "none", query: "", wantStatus: http.StatusOK},
+		{name: "valid", query: "certification=PG-13", wantStatus: http.StatusOK, wantCertification: "PG-13"},
+		{name: "system", query: "certification_system=BBFC", wantStatus: http.StatusOK, wantSystem: "BBFC"},
+		{name: "system and rating", query: "certification_system=FSK&certification=16", wantStatus: http.StatusOK, wantSystem: "FSK", wantCertification: "16"},
+		{name: "unknown", query: "certification=PG-16", wantStatus: http.StatusUnprocessableEntity},
+		{name: "rating from another system", query: "certification_system=MPA&certification=12A", wantStatus: http.StatusUnprocessableEntity},
+		{name: "unknown system", query: "certification_system=CBFC", wantStatus: http.StatusUnprocessableEntity},
+		{name: "with ids", query: "ids=1&certification=PG", wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			movies := movieMocks(models)
+			movies.GetAllFunc = func(ctx context.Context, title string, genres []string, certificationSystem, certification string, releasedAfter, releasedBefore *data.Date, filters data.Filters) ([]*data.Movie, data.Metadata, error) {
+				return []*data.Movie{}, data.Metadata{}, nil
+			}
+			app := newTestApplication(t, models)
+
+			r := newTestRequest(t, http.MethodGet, "/v1/movies?"+tt.query, "", testUser, nil)
+			status, _, body := serve(t, app.listMoviesHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			calls := movies.GetAllCalls()
+			if len(calls) != 1 || calls[0].CertificationSystem != tt.wantSystem || calls[0].Certification != tt.wantCertification {
+				t.Errorf("got calls %+v; want certification %q %q", calls, tt.wantSystem, tt.wantCertification)
+			}
+		})
+	}
+}
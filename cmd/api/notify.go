@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+// movieChangesChannel is notified by a trigger on the movies table whenever a
+// statement changes it, with the operation as the payload.
+const movieChangesChannel = "movie_changes"
+
+// listenForMovieChanges clears the catalog statistics cache whenever any
+// instance changes the movies, so that every instance serves fresh
+// statistics rather than waiting for -catalog-stats-interval. It returns when
+// ctx is cancelled.
+func (app *application) listenForMovieChanges(ctx context.Context) {
+	listener := pq.NewListener(app.config.db.dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventConnected, pq.ListenerEventReconnected:
+			app.health.report("listener", healthHealthy, "")
+		case pq.ListenerEventDisconnected, pq.ListenerEventConnectionAttemptFailed:
+			app.health.report("listener", healthUnhealthy, fmt.Sprint(err))
+		}
+	})
+	defer listener.Close()
+
+	err := listener.Listen(movieChangesChannel)
+	if err != nil {
+		app.logger.Error(err.Error())
+		app.health.report("listener", healthUnhealthy, err.Error())
+		return
+	}
+	for {
+		select {
+		case n := <-listener.Notify:
+			app.movieChanged(n)
+		case <-time.After(90 * time.Second):
+			// A ping notices a dead connection which has gone quiet.
+			go listener.Ping()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// movieChanged handles a notification on movieChangesChannel. A nil
+// notification means the listener reconnected and may have missed some, so
+// it's treated as a change too.
+func (app *application) movieChanged(n *pq.Notification) {
+	operation := "reconnected"
+	if n != nil {
+		operation = n.Extra
+	}
+	app.logger.Debug("movies changed", "operation", operation)
+	app.catalogStats.Store(nil)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// panicReport describes a recovered panic, as logged and sent to
+// -panic-webhook-url.
+type panicReport struct {
+	Time time.Time `json:"time"`
+	// Source is where the panic was recovered: request, job, background or
+	// scheduler.
+	Source    string   `json:"source"`
+	Panic     string   `json:"panic"`
+	Stack     []string `json:"stack"`
+	Method    string   `json:"method,omitempty"`
+	URL       string   `json:"url,omitempty"`
+	RequestID string   `json:"request_id,omitempty"`
+	UserID    int64    `json:"user_id,omitempty"`
+	Job       string   `json:"job,omitempty"`
+}
+
+// panicStack returns the stack of the panicking goroutine, one
+// "function file:line" entry per frame. Called from a deferred recover, the
+// frames start inside the runtime's panic handling, so those are skipped.
+func panicStack() []string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	stack := []string{}
+	seenPanic := false
+	for {
+		frame, more := frames.Next()
+		if seenPanic {
+			stack = append(stack, fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line))
+		} else if frame.Function == "runtime.gopanic" {
+			seenPanic = true
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// reportPanic logs a recovered panic with its stack as structured fields,
+// counts it and, if -panic-webhook-url is set, posts the report there.
+func (app *application) reportPanic(report panicReport) {
+	report.Time = time.Now()
+	panicsRecovered.Add(report.Source, 1)
+	app.logger.Error("panic",
+		"source", report.Source,
+		"panic", report.Panic,
+		"stack", report.Stack,
+		"method", report.Method,
+		"url", report.URL,
+		"request_id", report.RequestID,
+		"user_id", report.UserID,
+		"job", report.Job,
+	)
+	if app.config.panicWebhookURL == "" {
+		return
+	}
+	// Not app.background: a panic while shutting down would otherwise add to
+	// a WaitGroup that's already being waited on.
+	go func() {
+		err := app.postPanicReport(report)
+		if err != nil {
+			app.logger.Error(err.Error(), "webhook", "panic")
+		}
+	}()
+}
+
+func (app *application) postPanicReport(report panicReport) error {
+	js, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, app.config.panicWebhookURL, bytes.NewReader(js))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("panic webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	reports := make(chan panicReport, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report panicReport
+		err := json.NewDecoder(r.Body).Decode(&report)
+		if err != nil {
+			t.Error(err)
+		}
+		reports <- report
+	}))
+	defer webhook.Close()
+
+	var buf bytes.Buffer
+	app := newTestApplication(t, mocks.NewModels())
+	app.logger = slog.New(slog.NewJSONHandler(&buf, nil))
+	app.config.panicWebhookURL = webhook.URL
+	handler := app.recoverPanic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	r := newTestRequest(t, http.MethodGet, "/v1/movies", "", testUser, nil)
+	status, headers, _ := serve(t, handler.ServeHTTP, r)
+	if status != http.StatusInternalServerError || headers.Get("Connection") != "close" {
+		t.Errorf("got status %d, Connection %q; want 500 and close", status, headers.Get("Connection"))
+	}
+
+	var entry struct {
+		Msg   string   `json:"msg"`
+		Panic string   `json:"panic"`
+		Stack []string `json:"stack"`
+	}
+	err := json.Unmarshal(buf.Bytes(), &entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Msg != "panic" || entry.Panic != "boom" {
+		t.Errorf("got log entry %+v; want a panic entry for boom", entry)
+	}
+	// The stack starts at the function which panicked.
+	if len(entry.Stack) == 0 || !strings.Contains(entry.Stack[0], "TestRecoverPanic") {
+		t.Errorf("got stack %q; want it to start in the handler", entry.Stack)
+	}
+
+	select {
+	case report := <-reports:
+		if report.Source != "request" || report.Panic != "boom" || report.URL != "/v1/movies" {
+			t.Errorf("got report %+v; want a request panic for /v1/movies", report)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no report sent to the webhook")
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"image"
+	"net/http"
+	"os"
+)
+
+// maxPosterBytes is the largest poster upload accepted.
+const maxPosterBytes = 10 << 20
+
+// posterSizes are the sizes posters are stored at, with the width each is
+// scaled down to. The original keeps the upload's size, re-encoded like the
+// others so only images the API produced are ever served.
+var posterSizes = []struct {
+	name  string
+	width int
+}{
+	{name: "thumb", width: 185},
+	{name: "medium", width: 500},
+	{name: "original"},
+}
+
+// posterKey returns the storage key of one size of a poster upload, such as
+// posters/12/3f2a9c1b04de/thumb.jpg.
+func posterKey(movieID int64, version, size string) string {
+	return fmt.Sprintf("posters/%d/%s/%s.jpg", movieID, version, size)
+}
+
+// updatePosterHandler sets a movie's poster. The upload is only checked
+// here; it's resized in the background by the job queue, so the response is
+// 202 Accepted with the poster still processing. Clients see the sizes and
+// dominant color on the movie once it's ready. Until then the upload waits in
+// a temporary file, so queued jobs don't hold every upload in memory.
+func (app *application) updatePosterHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	b, err := app.readImageUpload(w, r, "poster", maxPosterBytes)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	if checkImage(v, "poster", b); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	movie, err := app.models.Movies.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	upload, err := writeTempFile(b)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	sum := sha256.Sum256(b)
+	poster := &data.Poster{Status: data.PosterProcessing, Version: hex.EncodeToString(sum[:6])}
+	err = app.models.Movies.SetPoster(r.Context(), id, poster)
+	if err != nil {
+		os.Remove(upload)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	previous := movie.Poster
+	ok := app.jobs.tryEnqueue("poster_sizes", func(ctx context.Context) error {
+		defer os.Remove(upload)
+		b, err := os.ReadFile(upload)
+		if err != nil {
+			return err
+		}
+		return app.processPoster(ctx, id, poster.Version, previous.Version, b)
+	})
+	if !ok {
+		os.Remove(upload)
+		// Only undo this upload; one made since has replaced it anyway.
+		err = app.models.Movies.RevertPoster(r.Context(), id, poster.Version, &previous)
+		if err != nil && !errors.Is(err, data.ErrEditConflict) {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.overloadedResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"poster": poster}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// writeTempFile writes b to a new temporary file, returning its name.
+func writeTempFile(b []byte) (string, error) {
+	f, err := os.CreateTemp("", "greenlight-upload-*")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// processPoster stores each of posterSizes of an upload and marks the poster
+// ready, then removes the files of the poster it replaced. If a newer upload
+// has replaced it in the meantime, or the movie has been deleted, the files
+// of both are removed instead, unless one is the movie's poster again. An
+// upload that fails is marked failed, and any sizes already stored removed.
+func (app *application) processPoster(ctx context.Context, movieID int64, version, previousVersion string, b []byte) error {
+	poster := &data.Poster{Status: data.PosterReady, Version: version, Sizes: make(map[string]data.PosterImage)}
+	err := app.storePosterSizes(ctx, movieID, poster, b)
+	if err != nil {
+		if err := app.deletePosterFiles(ctx, movieID, version); err != nil {
+			app.logger.Error(err.Error(), "job", "poster_sizes")
+		}
+		failed := &data.Poster{Status: data.PosterFailed, Version: version}
+		if err := app.models.Movies.UpdatePoster(ctx, movieID, failed); err != nil && !errors.Is(err, data.ErrEditConflict) {
+			app.logger.Error(err.Error(), "job", "poster_sizes")
+		}
+		return err
+	}
+
+	err = app.models.Movies.UpdatePoster(ctx, movieID, poster)
+	switch {
+	case errors.Is(err, data.ErrEditConflict):
+		return app.deleteReplacedPosterFiles(ctx, movieID, version, previousVersion)
+	case err != nil:
+		return err
+	}
+	if previousVersion != "" && previousVersion != version {
+		return app.deletePosterFiles(ctx, movieID, previousVersion)
+	}
+	return nil
+}
+
+func (app *application) storePosterSizes(ctx context.Context, movieID int64, poster *data.Poster, b []byte) error {
+	img, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	src := flattenImage(img, img.Bounds())
+	for _, size := range posterSizes {
+		scaled := src
+		if size.width > 0 {
+			height := max(1, src.Bounds().Dy()*size.width/src.Bounds().Dx())
+			scaled = scaleImage(src, size.width, height)
+		}
+		jpg, err := encodeJPEG(scaled)
+		if err != nil {
+			return err
+		}
+		key := posterKey(movieID, poster.Version, size.name)
+		err = app.storage.Put(ctx, key, jpg, "image/jpeg")
+		if err != nil {
+			return err
+		}
+		poster.Sizes[size.name] = data.PosterImage{
+			URL:    app.storage.URL(key),
+			Width:  scaled.Bounds().Dx(),
+			Height: scaled.Bounds().Dy(),
+		}
+		if size.name == "thumb" {
+			poster.Color = dominantColor(scaled)
+		}
+	}
+	return nil
+}
+
+func (app *application) deleteReplacedPosterFiles(ctx context.Context, movieID int64, versions ...string) error {
+	var current string
+	movie, err := app.models.Movies.Get(ctx, movieID)
+	switch {
+	case err == nil:
+		current = movie.Poster.Version
+	case !errors.Is(err, data.ErrRecordNotFound):
+		return err
+	}
+	for _, version := range versions {
+		if version == "" || version == current {
+			continue
+		}
+		err := app.deletePosterFiles(ctx, movieID, version)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (app *application) deletePosterFiles(ctx context.Context, movieID int64, version string) error {
+	for _, size := range posterSizes {
+		err := app.storage.Delete(ctx, posterKey(movieID, version, size.name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dominantColor returns the most common color of img as #rrggbb, for clients
+// to show while the poster loads. Pixels are grouped into buckets of similar
+// colors, and the average of the largest bucket returned.
+func dominantColor(img *image.RGBA) string {
+	type bucket struct{ n, r, g, b int }
+	buckets := make(map[int]*bucket)
+	var best *bucket
+	for i := 0; i < len(img.Pix); i += 4 {
+		r, g, b := int(img.Pix[i]), int(img.Pix[i+1]), int(img.Pix[i+2])
+		key := r>>4<<8 | g>>4<<4 | b>>4
+		bk := buckets[key]
+		if bk == nil {
+			bk = &bucket{}
+			buckets[key] = bk
+		}
+		bk.n++
+		bk.r += r
+		bk.g += g
+		bk.b += b
+		if best == nil || bk.n > best.n {
+			best = bk
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return fmt.Sprintf("#%02x%02x%02x", best.r/best.n, best.g/best.n, best.b/best.n)
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"github.com/ezechidc/greenlight/internal/storage"
+	"github.com/julienschmidt/httprouter"
+	"image"
+	"image/color"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// writePosterFiles stores placeholder files for every size of a poster
+// upload in dir, as the local storage backend would.
+func writePosterFiles(t *testing.T, dir string, movieID int64, version string) {
+	t.Helper()
+	for _, size := range posterSizes {
+		path := filepath.Join(dir, filepath.FromSlash(posterKey(movieID, version, size.name)))
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte("old"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// storedPosterFiles returns the poster files stored in dir for movieID.
+func storedPosterFiles(t *testing.T, dir string, movieID int64) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "posters", strconv.FormatInt(movieID, 10), "*", "*.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// failingStore fails to store any file whose key has the given suffix.
+type failingStore struct {
+	storage.Store
+	suffix string
+}
+
+func (s failingStore) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	if strings.HasSuffix(key, s.suffix) {
+		return errors.New("storage unavailable")
+	}
+	return s.Store.Put(ctx, key, body, contentType)
+}
+
+func TestDominantColor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for y := range 10 {
+		for x := range 10 {
+			c := color.RGBA{R: 200, G: 20, B: 30, A: 255}
+			if x < 3 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	if got := dominantColor(img); got != "#c8141e" {
+		t.Errorf("got %q; want #c8141e", got)
+	}
+}
+
+func TestUpdatePosterHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		movieID    string
+		previous   data.Poster
+		wantStatus int
+	}{
+		{name: "first poster", body: "png", movieID: "1", wantStatus: http.StatusAccepted},
+		{name: "replaced poster", body: "png", movieID: "1", previous: data.Poster{Status: data.PosterReady, Version: "0123456789ab"}, wantStatus: http.StatusAccepted},
+		{name: "not an image", body: "hello", movieID: "1", wantStatus: http.StatusUnprocessableEntity},
+		{name: "missing movie", body: "png", movieID: "2", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			movies := models.Movies.(*mocks.MovieStore)
+			movies.GetFunc = func(ctx context.Context, id int64) (*data.Movie, error) {
+				if id != 1 {
+					return nil, data.ErrRecordNotFound
+				}
+				return &data.Movie{ID: 1, Title: "Moana", Poster: tt.previous}, nil
+			}
+			var current data.Poster
+			movies.SetPosterFunc = func(ctx context.Context, movieID int64, poster *data.Poster) error {
+				current = *poster
+				return nil
+			}
+			movies.UpdatePosterFunc = func(ctx context.Context, movieID int64, poster *data.Poster) error {
+				if poster.Version != current.Version {
+					return data.ErrEditConflict
+				}
+				current = *poster
+				return nil
+			}
+			app := newTestApplication(t, models)
+			dir := t.TempDir()
+			app.storage, _ = storage.New(storage.BackendLocal, dir, "http://localhost:4000/v1/files")
+			if tt.previous.Version != "" {
+				writePosterFiles(t, dir, 1, tt.previous.Version)
+			}
+			tmp := t.TempDir()
+			t.Setenv("TMPDIR", tmp)
+
+			body := tt.body
+			if body == "png" {
+				body = string(testPNG(t, 600, 900))
+			}
+			r := newTestRequest(t, http.MethodPut, "/v1/movies/"+tt.movieID+"/poster", body, testUser, httprouter.Params{{Key: "id", Value: tt.movieID}})
+			r.Header.Set("Content-Type", "image/png")
+			status, _, resp := serve(t, app.updatePosterHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, resp)
+			}
+			if status != http.StatusAccepted {
+				if len(app.jobs.jobs) != 0 {
+					t.Errorf("got %d queued jobs; want none", len(app.jobs.jobs))
+				}
+				return
+			}
+			poster, _ := resp["poster"].(map[string]any)
+			if poster["status"] != data.PosterProcessing || poster["version"] != current.Version {
+				t.Fatalf("got poster %v; want it processing as version %q", poster, current.Version)
+			}
+
+			if uploads, _ := os.ReadDir(tmp); len(uploads) != 1 {
+				t.Errorf("got %d uploads waiting; want 1", len(uploads))
+			}
+			app.runJob(<-app.jobs.jobs)
+			if uploads, _ := os.ReadDir(tmp); len(uploads) != 0 {
+				t.Errorf("got %d uploads left after processing; want none", len(uploads))
+			}
+			if current.Status != data.PosterReady || current.Color == "" {
+				t.Fatalf("got poster %+v; want it ready with a color", current)
+			}
+			want := map[string][2]int{"thumb": {185, 277}, "medium": {500, 750}, "original": {600, 900}}
+			for name, size := range want {
+				got := current.Sizes[name]
+				wantURL := "http://localhost:4000/v1/files/" + posterKey(1, current.Version, name)
+				if got.URL != wantURL || got.Width != size[0] || got.Height != size[1] {
+					t.Errorf("got %s %+v; want %s at %dx%d", name, got, wantURL, size[0], size[1])
+				}
+			}
+			if files := storedPosterFiles(t, dir, 1); len(files) != len(posterSizes) {
+				t.Errorf("got stored files %v; want only the %d new sizes", files, len(posterSizes))
+			}
+		})
+	}
+}
+
+func TestUpdatePosterHandlerOverloaded(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous data.Poster
+	}{
+		{name: "first poster"},
+		{name: "replaced poster", previous: data.Poster{Status: data.PosterReady, Version: "0123456789ab"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			movies := models.Movies.(*mocks.MovieStore)
+			movies.GetFunc = func(ctx context.Context, id int64) (*data.Movie, error) {
+				return &data.Movie{ID: 1, Title: "Moana", Poster: tt.previous}, nil
+			}
+			movies.SetPosterFunc = func(ctx context.Context, movieID int64, poster *data.Poster) error {
+				return nil
+			}
+			movies.RevertPosterFunc = func(ctx context.Context, movieID int64, version string, previous *data.Poster) error {
+				return nil
+			}
+			app := newTestApplication(t, models)
+			for range cap(app.jobs.jobs) {
+				app.jobs.tryEnqueue("test", func(ctx context.Context) error { return nil })
+			}
+			tmp := t.TempDir()
+			t.Setenv("TMPDIR", tmp)
+
+			r := newTestRequest(t, http.MethodPut, "/v1/movies/1/poster", string(testPNG(t, 60, 90)), testUser, httprouter.Params{{Key: "id", Value: "1"}})
+			r.Header.Set("Content-Type", "image/png")
+			status, _, resp := serve(t, app.updatePosterHandler, r)
+			if status != http.StatusServiceUnavailable {
+				t.Fatalf("got status %d; want 503 (%v)", status, resp)
+			}
+			set, reverted := movies.SetPosterCalls(), movies.RevertPosterCalls()
+			if len(reverted) != 1 || reverted[0].Version != set[0].Poster.Version || reverted[0].Previous.Version != tt.previous.Version {
+				t.Errorf("got reverts %+v; want the upload reverted to %+v", reverted, tt.previous)
+			}
+			if uploads, _ := os.ReadDir(tmp); len(uploads) != 0 {
+				t.Errorf("got %d uploads left; want none", len(uploads))
+			}
+		})
+	}
+}
+
+func TestProcessPosterSuperseded(t *testing.T) {
+	tests := []struct {
+		name      string
+		current   string
+		deleted   bool
+		wantFiles int
+	}{
+		{name: "newer upload", current: "fedcba987654"},
+		{name: "movie deleted", deleted: true},
+		{name: "replaced poster restored", current: "ba9876543210", wantFiles: len(posterSizes)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			movies := models.Movies.(*mocks.MovieStore)
+			movies.GetFunc = func(ctx context.Context, id int64) (*data.Movie, error) {
+				if tt.deleted {
+					return nil, data.ErrRecordNotFound
+				}
+				return &data.Movie{ID: 1, Poster: data.Poster{Status: data.PosterReady, Version: tt.current}}, nil
+			}
+			movies.UpdatePosterFunc = func(ctx context.Context, movieID int64, poster *data.Poster) error {
+				return data.ErrEditConflict
+			}
+			app := newTestApplication(t, models)
+			dir := t.TempDir()
+			app.storage, _ = storage.New(storage.BackendLocal, dir, "http://localhost:4000/v1/files")
+			writePosterFiles(t, dir, 1, "ba9876543210")
+
+			err := app.processPoster(context.Background(), 1, "0123456789ab", "ba9876543210", testPNG(t, 300, 450))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if files := storedPosterFiles(t, dir, 1); len(files) != tt.wantFiles {
+				t.Errorf("got stored files %v; want %d", files, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestProcessPosterFailed(t *testing.T) {
+	models := mocks.NewModels()
+	movies := models.Movies.(*mocks.MovieStore)
+	movies.UpdatePosterFunc = func(ctx context.Context, movieID int64, poster *data.Poster) error {
+		return nil
+	}
+	app := newTestApplication(t, models)
+	dir := t.TempDir()
+	local, _ := storage.New(storage.BackendLocal, dir, "http://localhost:4000/v1/files")
+	app.storage = failingStore{Store: local, suffix: "/original.jpg"}
+	writePosterFiles(t, dir, 1, "ba9876543210")
+
+	err := app.processPoster(context.Background(), 1, "0123456789ab", "ba9876543210", testPNG(t, 300, 450))
+	if err == nil {
+		t.Fatal("got no error; want the storage error")
+	}
+	calls := movies.UpdatePosterCalls()
+	if len(calls) != 1 || calls[0].Poster.Status != data.PosterFailed {
+		t.Errorf("got updates %+v; want the poster marked failed", calls)
+	}
+	// The sizes stored before the failure are removed, and the poster it
+	// would have replaced is kept.
+	want, _ := filepath.Glob(filepath.Join(dir, "posters", "1", "ba9876543210", "*.jpg"))
+	if files := storedPosterFiles(t, dir, 1); len(files) != len(posterSizes) || len(want) != len(posterSizes) {
+		t.Errorf("got stored files %v; want only the replaced poster's", files)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"net/http"
+)
+
+func (app *application) showPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	preferences, err := app.models.Preferences.Get(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"preferences": preferences}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updatePreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	preferences, err := app.models.Preferences.Get(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		WeeklyDigest      *bool    `json:"weekly_digest"`
+		FavoriteGenres    []string `json:"favorite_genres"`
+		ReviewReplies     *bool    `json:"review_replies"`
+		ModerationUpdates *bool    `json:"moderation_updates"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if input.WeeklyDigest != nil {
+		preferences.WeeklyDigest = *input.WeeklyDigest
+	}
+	if input.FavoriteGenres != nil {
+		preferences.FavoriteGenres = input.FavoriteGenres
+	}
+	if input.ReviewReplies != nil {
+		preferences.ReviewReplies = *input.ReviewReplies
+	}
+	if input.ModerationUpdates != nil {
+		preferences.ModerationUpdates = *input.ModerationUpdates
+	}
+
+	v := validator.New()
+	if data.ValidatePreferences(v, preferences); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	err = app.models.Preferences.Update(r.Context(), preferences)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"preferences": preferences}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showUnsubscribeHandler is where the unsubscribe links in notification emails
+// land. Mail scanners and link prefetchers fetch those links too, so it only
+// checks the token and asks for the change to be confirmed with a POST to the
+// same URL, which is also what RFC 8058 one-click clients send.
+func (app *application) showUnsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	_, notification, ok := app.readUnsubscribeToken(w, r)
+	if !ok {
+		return
+	}
+	env := envelope{
+		"message":      "send a POST request to this URL to confirm you want to unsubscribe",
+		"notification": notification,
+	}
+	err := app.writeMessage(w, r, http.StatusOK, env)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// unsubscribeHandler turns off the notification an unsubscribe link was sent
+// for. Like showUnsubscribeHandler it reads its input from the query string, so
+// the link from the email can be POSTed as-is.
+func (app *application) unsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	user, notification, ok := app.readUnsubscribeToken(w, r)
+	if !ok {
+		return
+	}
+
+	preferences, err := app.models.Preferences.Get(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	preferences.Disable(notification)
+	err = app.models.Preferences.Update(r.Context(), preferences)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeMessage(w, r, http.StatusOK, envelope{"message": "you have been unsubscribed"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readUnsubscribeToken looks up the user an unsubscribe link was sent to. The
+// token is only valid for the notification it was issued for, so a link can't
+// be edited to unsubscribe from something else.
+func (app *application) readUnsubscribeToken(w http.ResponseWriter, r *http.Request) (*data.User, string, bool) {
+	qs := r.URL.Query()
+	tokenPlaintext := app.readString(qs, "token", "")
+	notification := app.readString(qs, "notification", "")
+
+	v := validator.New()
+	data.ValidateTokenPlaintext(v, tokenPlaintext)
+	v.CheckCode(validator.PermittedValue(notification, data.Notifications...), "notification", "invalid_value", "invalid notification value")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return nil, "", false
+	}
+
+	user, err := app.models.Users.GetForToken(r.Context(), data.UnsubscribeScope(notification), tokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddErrorCode("token", "invalid_token", "invalid or expired unsubscribe token")
+			app.failedValidationResponse(w, r, v)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, "", false
+	}
+	return user, notification, true
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Email redaction modes for -log-redact-emails.
+const (
+	emailRedactMask = "mask"
+	emailRedactFull = "full"
+	emailRedactOff  = "off"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are the attribute, query parameter and JSON member names whose
+// values are never logged. A key also matches with a prefix, so user_password
+// and activation_token are redacted too.
+var sensitiveKeys = []string{"password", "token", "plaintext", "authorization", "secret", "cookie"}
+
+var (
+	bearerRX    = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`)
+	queryRX     = regexp.MustCompile(`(?i)\b([a-z_]*(?:password|token|plaintext|secret))=[^&\s"]*`)
+	jsonFieldRX = regexp.MustCompile(`(?i)"([a-z_]*(?:password|token|plaintext|authorization|secret))"\s*:\s*"(?:[^"\\]|\\.)*"`)
+	emailRX     = regexp.MustCompile(`([A-Za-z0-9._%+-])[A-Za-z0-9._%+-]*@([A-Za-z0-9.-]+\.[A-Za-z]{2,})`)
+)
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range sensitiveKeys {
+		if key == k || strings.HasSuffix(key, "_"+k) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactHandler masks credentials and, depending on Emails, email addresses in
+// log messages and attributes before passing records on to Handler. Values of
+// sensitive attributes are dropped entirely, while other strings, such as
+// URLs and captured response bodies, have credentials in query parameters,
+// JSON members and Authorization schemes masked.
+type RedactHandler struct {
+	slog.Handler
+	Emails string
+}
+
+func (h *RedactHandler) Handle(ctx context.Context, r slog.Record) error {
+	newRec := slog.NewRecord(r.Time, r.Level, h.redactString(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		newRec.AddAttrs(h.redactAttr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, newRec)
+}
+
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redactedAttrs := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redactedAttrs[i] = h.redactAttr(a)
+	}
+	return &RedactHandler{Handler: h.Handler.WithAttrs(redactedAttrs), Emails: h.Emails}
+}
+
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	return &RedactHandler{Handler: h.Handler.WithGroup(name), Emails: h.Emails}
+}
+
+func (h *RedactHandler) redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if isSensitiveKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(h.redactString(a.Value.String()))
+	case slog.KindGroup:
+		group := a.Value.Group()
+		redactedGroup := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			redactedGroup[i] = h.redactAttr(ga)
+		}
+		a.Value = slog.GroupValue(redactedGroup...)
+	case slog.KindAny:
+		// Errors are the only values logged as Any whose text may carry user
+		// input.
+		if err, ok := a.Value.Any().(error); ok {
+			a.Value = slog.StringValue(h.redactString(err.Error()))
+		}
+	}
+	return a
+}
+
+func (h *RedactHandler) redactString(s string) string {
+	s = bearerRX.ReplaceAllString(s, "$1 "+redacted)
+	s = queryRX.ReplaceAllString(s, "$1="+redacted)
+	s = jsonFieldRX.ReplaceAllString(s, `"$1":"`+redacted+`"`)
+	switch h.Emails {
+	case emailRedactMask:
+		s = emailRX.ReplaceAllString(s, "$1***@$2")
+	case emailRedactFull:
+		s = emailRX.ReplaceAllString(s, redacted)
+	}
+	return s
+}
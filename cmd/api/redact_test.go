@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		emails  string
+		msg     string
+		args    []any
+		want    map[string]any
+		wantMsg string
+	}{
+		{
+			name: "sensitive keys",
+			args: []any{"password", "pa55word", "activation_token", "ABCDEF", "tokens", 3},
+			want: map[string]any{"password": redacted, "activation_token": redacted, "tokens": float64(3)},
+		},
+		{
+			name: "query parameters",
+			args: []any{"url", "/v1/preferences?token=ABCDEF&notification=digest"},
+			want: map[string]any{"url": "/v1/preferences?token=" + redacted + "&notification=digest"},
+		},
+		{
+			name: "captured error body",
+			args: []any{"error_message", `{"password": "pa55word", "name": "Alice"}`},
+			want: map[string]any{"error_message": `{"password":"` + redacted + `", "name": "Alice"}`},
+		},
+		{
+			name: "authorization scheme",
+			args: []any{"error", errors.New("bad header Bearer ABCDEF")},
+			want: map[string]any{"error": "bad header Bearer " + redacted},
+		},
+		{
+			name:    "masked email",
+			emails:  emailRedactMask,
+			msg:     "no user alice@example.com",
+			args:    []any{"user_email", "alice@example.com"},
+			want:    map[string]any{"user_email": "a***@example.com"},
+			wantMsg: "no user a***@example.com",
+		},
+		{
+			name:   "fully redacted email",
+			emails: emailRedactFull,
+			args:   []any{slog.Group("request", "user_email", "alice@example.com")},
+			want:   map[string]any{"request": map[string]any{"user_email": redacted}},
+		},
+		{
+			name:   "emails kept",
+			emails: emailRedactOff,
+			args:   []any{"user_email", "alice@example.com"},
+			want:   map[string]any{"user_email": "alice@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(&RedactHandler{Handler: slog.NewJSONHandler(&buf, nil), Emails: tt.emails})
+			logger.Info(tt.msg, tt.args...)
+
+			var entry map[string]any
+			err := json.Unmarshal(buf.Bytes(), &entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, want := range tt.want {
+				got, _ := json.Marshal(entry[key])
+				wantJSON, _ := json.Marshal(want)
+				if !bytes.Equal(got, wantJSON) {
+					t.Errorf("got %s %s; want %s", key, got, wantJSON)
+				}
+			}
+			if tt.wantMsg != "" && entry["msg"] != tt.wantMsg {
+				t.Errorf("got msg %q; want %q", entry["msg"], tt.wantMsg)
+			}
+			if strings.Contains(buf.String(), "pa55word") || strings.Contains(buf.String(), "ABCDEF") {
+				t.Errorf("log entry %s contains a credential", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"github.com/julienschmidt/httprouter"
+	"maps"
+	"net/http"
+	"strconv"
+)
+
+// fieldChange is one field which differs between a revision and the current
+// version of a movie.
+type fieldChange struct {
+	Field    string `json:"field"`
+	Revision any    `json:"revision"`
+	Current  any    `json:"current"`
+}
+
+// movieFieldValue returns the value of one of the fields named by
+// movieFieldsChanged.
+func movieFieldValue(movie *data.Movie, field string) (any, error) {
+	switch field {
+	case "title":
+		return movie.Title, nil
+	case "original_title":
+		return movie.OriginalTitle, nil
+	case "titles":
+		return movie.Titles, nil
+	case "year":
+		return movie.Year, nil
+	case "runtime":
+		return movie.Runtime, nil
+	case "genres":
+		return movie.Genres, nil
+	case "release_date":
+		return movie.ReleaseDate, nil
+	case "certification_system":
+		return movie.CertificationSystem, nil
+	case "certification":
+		return movie.Certification, nil
+	default:
+		return nil, fmt.Errorf("unknown movie field %q", field)
+	}
+}
+
+func (app *application) readRevisionParam(r *http.Request) (int32, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	rev, err := strconv.ParseInt(params.ByName("rev"), 10, 32)
+	if err != nil || rev < 1 {
+		return 0, errors.New("invalid rev parameter")
+	}
+	return int32(rev), nil
+}
+
+// readRevision reads the movie and revision named in the URL, sending a 404 or
+// 500 response and returning false if either can't be read.
+func (app *application) readRevision(w http.ResponseWriter, r *http.Request) (*data.Movie, *data.MovieRevision, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, nil, false
+	}
+	rev, err := app.readRevisionParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, nil, false
+	}
+
+	movie, err := app.models.Movies.Get(r.Context(), id)
+	if err == nil {
+		var revision *data.MovieRevision
+		revision, err = app.models.Movies.GetRevision(r.Context(), id, rev)
+		if err == nil {
+			return movie, revision, true
+		}
+	}
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		app.notFoundResponse(w, r)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+	return nil, nil, false
+}
+
+// listMovieRevisionsHandler returns every recorded version of a movie, newest
+// first.
+func (app *application) listMovieRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	revisions, err := app.models.Movies.GetRevisions(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// Every movie has at least the revision recorded when it was created.
+	if len(revisions) == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"revisions": revisions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showMovieRevisionDiffHandler returns the fields which differ between a
+// revision and the current version of a movie.
+func (app *application) showMovieRevisionDiffHandler(w http.ResponseWriter, r *http.Request) {
+	movie, revision, ok := app.readRevision(w, r)
+	if !ok {
+		return
+	}
+	changes := []fieldChange{}
+	for _, field := range movieFieldsChanged(&revision.Movie, movie) {
+		before, err := movieFieldValue(&revision.Movie, field)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		after, err := movieFieldValue(movie, field)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		changes = append(changes, fieldChange{Field: field, Revision: before, Current: after})
+	}
+	env := envelope{
+		"revision":        revision.Version,
+		"current_version": movie.Version,
+		"changes":         changes,
+	}
+	err := app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// revertMovieHandler restores a movie's fields to a revision. The restored
+// movie is saved as a new version, so the revert shows up in the history and
+// can itself be reverted.
+func (app *application) revertMovieHandler(w http.ResponseWriter, r *http.Request) {
+	movie, revision, ok := app.readRevision(w, r)
+	if !ok {
+		return
+	}
+	movie.Title = revision.Title
+	movie.OriginalTitle = revision.OriginalTitle
+	movie.Titles = maps.Clone(revision.Titles)
+	movie.Year = revision.Year
+	movie.Runtime = revision.Runtime
+	movie.Genres = revision.Genres
+	movie.ReleaseDate = revision.ReleaseDate
+	movie.CertificationSystem = revision.CertificationSystem
+	movie.Certification = revision.Certification
+
+	// Old revisions may predate the current validation rules.
+	v := validator.New()
+	if data.ValidateMovie(v, movie); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	err := app.models.Movies.Update(r.Context(), movie)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
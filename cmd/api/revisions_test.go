@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestRevertMovieHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		rev         string
+		wantStatus  int
+		wantUpdates int
+	}{
+		{name: "revert", rev: "1", wantStatus: http.StatusOK, wantUpdates: 1},
+		{name: "missing revision", rev: "5", wantStatus: http.StatusNotFound},
+		{name: "invalid revision", rev: "x", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			movies := movieMocks(models)
+			movies.GetFunc = func(ctx context.Context, id int64) (*data.Movie, error) {
+				return &data.Movie{ID: 1, Title: "Vaiana", Year: 2016, Runtime: 107, Genres: []string{}, Version: 2}, nil
+			}
+			movies.GetRevisionFunc = func(ctx context.Context, movieID int64, version int32) (*data.MovieRevision, error) {
+				if version != 1 {
+					return nil, data.ErrRecordNotFound
+				}
+				return &data.MovieRevision{Movie: data.Movie{ID: 1, Title: "Moana", OriginalTitle: "Moana", Titles: data.Titles{"fr": "Vaiana"}, Year: 2016, Runtime: 107, Genres: []string{"animation"}, Version: 1}}, nil
+			}
+			movies.UpdateFunc = func(ctx context.Context, movie *data.Movie) error {
+				movie.Version++
+				return nil
+			}
+			app := newTestApplication(t, models)
+
+			params := httprouter.Params{{Key: "id", Value: "1"}, {Key: "rev", Value: tt.rev}}
+			r := newTestRequest(t, http.MethodPost, "/v1/movies/1/history/"+tt.rev+"/revert", "", testUser, params)
+			status, _, body := serve(t, app.revertMovieHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+			calls := movies.UpdateCalls()
+			if len(calls) != tt.wantUpdates {
+				t.Fatalf("got %d updates; want %d", len(calls), tt.wantUpdates)
+			}
+			if tt.wantUpdates > 0 {
+				movie := calls[0].Movie
+				if movie.Title != "Moana" || movie.OriginalTitle != "Moana" || movie.Titles["fr"] != "Vaiana" || len(movie.Genres) != 1 || movie.Version != 3 {
+					t.Errorf("got %+v; want revision 1 saved as version 3", movie)
+				}
+			}
+		})
+	}
+}
+
+func TestShowMovieRevisionDiffHandler(t *testing.T) {
+	models := mocks.NewModels()
+	movies := movieMocks(models)
+	movies.GetFunc = func(ctx context.Context, id int64) (*data.Movie, error) {
+		return &data.Movie{ID: 1, Title: "Moana", OriginalTitle: "Moana", Titles: data.Titles{"fr": "Vaiana"}, Year: 2016, Runtime: 107, Genres: []string{"animation"}, Version: 2}, nil
+	}
+	movies.GetRevisionFunc = func(ctx context.Context, movieID int64, version int32) (*data.MovieRevision, error) {
+		return &data.MovieRevision{Movie: data.Movie{ID: 1, Title: "Vaiana", Year: 2016, Runtime: 107, Genres: []string{"animation"}, Version: 1}}, nil
+	}
+	app := newTestApplication(t, models)
+
+	params := httprouter.Params{{Key: "id", Value: "1"}, {Key: "rev", Value: "1"}}
+	r := newTestRequest(t, http.MethodGet, "/v1/movies/1/history/1/diff", "", testUser, params)
+	status, _, body := serve(t, app.showMovieRevisionDiffHandler, r)
+	if status != http.StatusOK {
+		t.Fatalf("got status %d; want %d (%v)", status, http.StatusOK, body)
+	}
+	changes, _ := body["changes"].([]any)
+	var fields []string
+	for _, change := range changes {
+		fields = append(fields, change.(map[string]any)["field"].(string))
+	}
+	if want := []string{"title", "original_title", "titles"}; !slices.Equal(fields, want) {
+		t.Errorf("got changes %v; want %v", changes, want)
+	}
+}
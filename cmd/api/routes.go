@@ -1,27 +1,95 @@
 package main
 
 import (
+	"expvar"
+	"github.com/ezechidc/greenlight/internal/storage"
 	"github.com/julienschmidt/httprouter"
 	"net/http"
+	"time"
 )
 
 func (app *application) rooutes() http.Handler {
 	router := httprouter.New()
 	router.NotFound = http.HandlerFunc(app.notFoundResponse)
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
-	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/movies", app.requireActivatedUser(app.createMovieHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", app.requireActivatedUser(app.showMovieHandler))
-	router.HandlerFunc(http.MethodPatch, "/v1/movies/:id", app.requireActivatedUser(app.updateMovieHandler))
-	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id", app.requireActivatedUser(app.deleteMovieHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/movies", app.requireActivatedUser(app.listMoviesHandler))
+	withTimeout := func(timeout time.Duration) func(method, path string, handler http.HandlerFunc) {
+		return func(method, path string, handler http.HandlerFunc) {
+			router.HandlerFunc(method, path, app.recordRoute(method+" "+path, app.timeout(timeout, handler)))
+		}
+	}
+	handle := withTimeout(app.config.timeout.request)
+	// handleBulk registers routes which work on many rows at once.
+	handleBulk := withTimeout(app.config.timeout.bulk)
+	handle(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
+	handle(http.MethodGet, "/v1/readyz", app.readyzHandler)
+	handle(http.MethodPost, "/v1/movies", app.requireActivatedUser(app.createMovieHandler))
+	handle(http.MethodGet, "/v1/movies/:id", app.requireReadAccess(app.showMovieHandler))
+	handle(http.MethodPatch, "/v1/movies/:id", app.requireActivatedUser(app.updateMovieHandler))
+	handle(http.MethodDelete, "/v1/movies/:id", app.requireActivatedUser(app.deleteMovieHandler))
+	handle(http.MethodGet, "/v1/movies", app.requireReadAccess(app.listMoviesHandler))
+	handle(http.MethodGet, "/v1/search", app.requireReadAccess(app.searchHandler))
+	handle(http.MethodGet, "/v1/catalog/stats", app.requireReadAccess(app.showCatalogStatsHandler))
+	handle(http.MethodGet, "/v1/movies/:id/history", app.requireActivatedUser(app.listMovieRevisionsHandler))
+	handle(http.MethodGet, "/v1/movies/:id/history/:rev/diff", app.requireActivatedUser(app.showMovieRevisionDiffHandler))
+	handle(http.MethodPost, "/v1/movies/:id/history/:rev/revert", app.requireActivatedUser(app.revertMovieHandler))
+	handle(http.MethodGet, "/v1/movies/:id/similar", app.requireReadAccess(app.showSimilarMoviesHandler))
+	handle(http.MethodGet, "/v1/movies/:id/suggestions", app.requireReadAccess(app.listMovieSuggestionsHandler))
+	handle(http.MethodPost, "/v1/movies/:id/suggestions", app.requireActivatedUser(app.createSuggestionHandler))
+	handle(http.MethodPut, "/v1/movies/:id/poster", app.requireActivatedUser(app.updatePosterHandler))
+	handle(http.MethodGet, "/v1/movies/:id/media", app.requireReadAccess(app.listMovieMediaHandler))
+	handle(http.MethodPost, "/v1/movies/:id/media", app.requireActivatedUser(app.createMovieMediaHandler))
+	handle(http.MethodPatch, "/v1/movies/:id/media/:media_id", app.requireActivatedUser(app.updateMovieMediaHandler))
+	handle(http.MethodDelete, "/v1/movies/:id/media/:media_id", app.requireActivatedUser(app.deleteMovieMediaHandler))
+	handle(http.MethodGet, "/v1/movies/:id/stats", app.requirePermission("admin:read", app.showMovieStatsHandler))
 
 	// Add the route for the POST /v1/users endpoint.
-	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
-	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
+	handle(http.MethodPost, "/v1/users", app.authRateLimit(app.registerUserHandler))
+	handle(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
+	handle(http.MethodPut, "/v1/users/reactivated", app.authRateLimit(app.reactivateUserHandler))
+	handle(http.MethodPatch, "/v1/users/me", app.requireActivatedUser(app.updateUserHandler))
+	handle(http.MethodDelete, "/v1/users/me", app.requireAuthenticatedUser(app.deactivateUserHandler))
+	handle(http.MethodGet, "/v1/users/me/preferences", app.requireActivatedUser(app.showPreferencesHandler))
+	handle(http.MethodPatch, "/v1/users/me/preferences", app.requireActivatedUser(app.allowUnknownFields(app.updatePreferencesHandler)))
+	handle(http.MethodPut, "/v1/users/me/avatar", app.requireActivatedUser(app.updateAvatarHandler))
+	handle(http.MethodDelete, "/v1/users/me/avatar", app.requireActivatedUser(app.deleteAvatarHandler))
+	handle(http.MethodGet, "/v1/users/me/usage", app.requireActivatedUser(app.showUsageHandler))
+	handle(http.MethodGet, "/v1/users/unsubscribe", app.showUnsubscribeHandler)
+	handle(http.MethodPost, "/v1/users/unsubscribe", app.unsubscribeHandler)
 
-	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	handle(http.MethodPost, "/v1/invitations", app.requirePermission("invitations:write", app.createInvitationHandler))
+	handle(http.MethodGet, "/v1/invitations", app.requirePermission("invitations:write", app.listInvitationsHandler))
+	handle(http.MethodDelete, "/v1/invitations/:id", app.requirePermission("invitations:write", app.revokeInvitationHandler))
+	handle(http.MethodPut, "/v1/invitations/accepted", app.authRateLimit(app.acceptInvitationHandler))
 
-	return app.logRequestDuration(app.recoverPanic(app.rateLimit(app.authenticate(router))))
+	handle(http.MethodGet, "/v1/suggestions", app.requirePermission("movies:review", app.listSuggestionsHandler))
+	handle(http.MethodPost, "/v1/suggestions/:id/approve", app.requirePermission("movies:review", app.approveSuggestionHandler))
+	handle(http.MethodPost, "/v1/suggestions/:id/reject", app.requirePermission("movies:review", app.rejectSuggestionHandler))
+
+	if local, ok := app.storage.(*storage.Local); ok {
+		handle(http.MethodGet, "/v1/files/*key", app.filesHandler(local))
+	}
+
+	handle(http.MethodPost, "/v1/tokens/authentication", app.authRateLimit(app.createAuthenticationTokenHandler))
+
+	handle(http.MethodGet, "/v1/admin/emails", app.adminIPFilter(app.requirePermission("admin:read", app.listEmailsHandler)))
+	handleBulk(http.MethodPost, "/v1/admin/activations", app.adminIPFilter(app.requirePermission("admin:write", app.bulkActivateUsersHandler)))
+	handle(http.MethodPost, "/v1/admin/users/:id/deactivate", app.adminIPFilter(app.requirePermission("admin:write", app.adminDeactivateUserHandler)))
+	handle(http.MethodPost, "/v1/admin/users/:id/reactivate", app.adminIPFilter(app.requirePermission("admin:write", app.adminReactivateUserHandler)))
+	handle(http.MethodGet, "/v1/admin/disposable-domains", app.adminIPFilter(app.requirePermission("admin:read", app.showDisposableDomainsHandler)))
+	handle(http.MethodPut, "/v1/admin/disposable-domains", app.adminIPFilter(app.requirePermission("admin:write", app.updateDisposableDomainsHandler)))
+	handle(http.MethodGet, "/v1/admin/limiter", app.adminIPFilter(app.requirePermission("admin:read", app.showLimiterHandler)))
+	handle(http.MethodPatch, "/v1/admin/limiter", app.adminIPFilter(app.requirePermission("admin:write", app.updateLimiterHandler)))
+	handle(http.MethodDelete, "/v1/admin/limiter/clients", app.adminIPFilter(app.requirePermission("admin:write", app.clearLimiterClientsHandler)))
+
+	handle(http.MethodGet, "/v1/admin/usage", app.adminIPFilter(app.requirePermission("admin:read", app.listUsageHandler)))
+	handle(http.MethodGet, "/v1/admin/stats", app.adminIPFilter(app.requirePermission("admin:read", app.showAdminStatsHandler)))
+	handle(http.MethodGet, "/v1/admin/dashboard", app.adminIPFilter(app.requirePermission("admin:read", app.showDashboardHandler)))
+	handle(http.MethodGet, "/admin/*filepath", app.adminIPFilter(app.adminUIHandler()))
+
+	// expvar publishes the command line, which can hold secrets passed as
+	// flags, so the IP filter alone isn't enough.
+	handle(http.MethodGet, "/debug/vars", app.adminIPFilter(app.requirePermission("admin:read", expvar.Handler().ServeHTTP)))
+
+	return app.metrics(app.requestID(app.secureHeaders(app.logRequestDuration(app.recoverPanic(app.ipFilter(app.rateLimit(app.shedLoad(app.authenticate(app.trackUsage(app.debug(router)))))))))))
 
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"net/http"
+	"slices"
+)
+
+// searchTypes are the resource types searchHandler can return, in the order
+// they're searched.
+var searchTypes = []string{"movies", "genres"}
+
+type movieHit struct {
+	Type string `json:"type"`
+	*data.TitleMatch
+}
+
+type genreHit struct {
+	Type string `json:"type"`
+	*data.GenreCount
+}
+
+// searchHandler searches movie titles and genres for q, returning up to limit
+// hits of each type, grouped by type. Titles are matched with the same
+// full-text search as listMoviesHandler and then, to tolerate typos, by
+// trigram similarity of at least -search-similarity, with each hit's score.
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+	q := app.readString(qs, "q", "")
+	types := app.readCSV(qs, "types", searchTypes)
+	limit := app.readInt(qs, "limit", 5, v)
+
+	v.CheckCode(q != "", "q", "required", "must be provided")
+	v.CheckCode(len(q) <= 500, "q", "too_long", "must not be more than 500 bytes long")
+	v.CheckCode(limit > 0, "limit", "too_small", "must be greater than zero")
+	v.CheckCode(limit <= 20, "limit", "too_large", "must be a maximum of 20")
+	for _, t := range types {
+		v.CheckCode(validator.PermittedValue(t, searchTypes...), "types", "invalid_value", "invalid type value")
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	results := envelope{}
+	if slices.Contains(types, "movies") {
+		var matches []*data.TitleMatch
+		err := app.models.WithTx(r.Context(), func(m data.Models) error {
+			var err error
+			matches, err = m.Movies.SearchTitles(r.Context(), q, app.config.search.similarity, limit)
+			return err
+		})
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		hits := make([]movieHit, len(matches))
+		movies := make([]*data.Movie, len(matches))
+		for i, match := range matches {
+			hits[i] = movieHit{Type: "movie", TitleMatch: match}
+			movies[i] = match.Movie
+		}
+		app.localizeMovies(w, r, movies...)
+		results["movies"] = hits
+	}
+	if slices.Contains(types, "genres") {
+		genres, err := app.models.Movies.SearchGenres(r.Context(), q, limit)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		hits := make([]genreHit, len(genres))
+		for i, genre := range genres {
+			hits[i] = genreHit{Type: "genre", GenreCount: genre}
+		}
+		results["genres"] = hits
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"query": q, "results": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
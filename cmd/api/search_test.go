@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"net/http"
+	"testing"
+)
+
+func TestSearchHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantTypes  []string
+	}{
+		{name: "all types", query: "?q=drama", wantStatus: http.StatusOK, wantTypes: []string{"movies", "genres"}},
+		{name: "genres only", query: "?q=drama&types=genres", wantStatus: http.StatusOK, wantTypes: []string{"genres"}},
+		{name: "missing query", query: "", wantStatus: http.StatusUnprocessableEntity},
+		{name: "unknown type", query: "?q=drama&types=people", wantStatus: http.StatusUnprocessableEntity},
+		{name: "limit too large", query: "?q=drama&limit=21", wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			movies := movieMocks(models)
+			movies.SearchTitlesFunc = func(ctx context.Context, search string, threshold float64, limit int) ([]*data.TitleMatch, error) {
+				if threshold != 0.3 {
+					t.Errorf("got similarity threshold %g; want 0.3", threshold)
+				}
+				return []*data.TitleMatch{{Movie: &data.Movie{ID: 1, Title: "Drama Queen", Version: 1}, Score: 0.8}}, nil
+			}
+			movies.SearchGenresFunc = func(ctx context.Context, search string, limit int) ([]*data.GenreCount, error) {
+				return []*data.GenreCount{{Name: "drama", Movies: 3}}, nil
+			}
+			app := newTestApplication(t, models)
+			app.config.search.similarity = 0.3
+
+			r := newTestRequest(t, http.MethodGet, "/v1/search"+tt.query, "", testUser, nil)
+			status, _, body := serve(t, app.searchHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+			results, _ := body["results"].(map[string]any)
+			if len(results) != len(tt.wantTypes) {
+				t.Errorf("got results %v; want types %v", results, tt.wantTypes)
+			}
+			for _, typ := range tt.wantTypes {
+				hits, _ := results[typ].([]any)
+				if len(hits) != 1 {
+					t.Fatalf("got %s hits %v; want 1", typ, hits)
+				}
+				if hit := hits[0].(map[string]any); hit["type"] == nil {
+					t.Errorf("hit %v has no type", hit)
+				}
+			}
+		})
+	}
+}
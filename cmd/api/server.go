@@ -23,6 +23,9 @@ func (app *application) serve() error {
 	}
 
 	shutdownError := make(chan error)
+	// schedulerCtx is cancelled on shutdown to stop the periodic jobs before
+	// the job queue they feed is closed.
+	schedulerCtx, stopSchedulers := context.WithCancel(context.Background())
 
 	go func() {
 		quit := make(chan os.Signal, 1)
@@ -30,6 +33,16 @@ func (app *application) serve() error {
 		s := <-quit
 
 		app.logger.Info("shutting down server", "signal", s.String())
+		// Endpoints behind a load balancer, such as a Kubernetes Service, are
+		// only removed once a readiness probe fails, and until then new
+		// connections keep arriving. Fail readiness and keep serving for the
+		// delay so they aren't refused.
+		app.shuttingDown.Store(true)
+		if app.config.shutdown.delay > 0 {
+			srv.SetKeepAlivesEnabled(false)
+			app.logger.Info("waiting before draining connections", "delay", app.config.shutdown.delay.String())
+			time.Sleep(app.config.shutdown.delay)
+		}
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 		err := srv.Shutdown(ctx)
@@ -37,10 +50,50 @@ func (app *application) serve() error {
 			shutdownError <- err
 		}
 		app.logger.Info("completing background tasks", "addr", srv.Addr)
+		stopSchedulers()
+		app.schedulers.Wait()
+		if app.config.stats.enabled {
+			app.flushViews(ctx)
+		}
+		if app.config.usage.enabled {
+			app.flushUsage(ctx)
+		}
+		app.jobs.close()
 		app.wg.Wait()
+		app.mailer.Close()
 		shutdownError <- nil
 	}()
 
+	app.startJobWorkers(app.config.jobs.workers)
+	if app.config.stats.enabled {
+		app.schedule(schedulerCtx, app.config.stats.flushInterval, app.flushViews)
+		app.schedule(schedulerCtx, app.config.stats.interval, app.rollupStats)
+	}
+	if app.config.usage.enabled {
+		app.schedule(schedulerCtx, app.config.usage.flushInterval, app.flushUsage)
+		app.schedule(schedulerCtx, usagePruneInterval, app.pruneUsage)
+	}
+	if app.config.digest.enabled {
+		app.schedule(schedulerCtx, app.config.digest.interval, app.sendDigests)
+	}
+	app.checkHealth(schedulerCtx)
+	app.schedule(schedulerCtx, healthCheckInterval, app.checkHealth)
+	app.schedule(schedulerCtx, app.config.catalogStats.interval, app.refreshCatalogStats)
+	app.schedule(schedulerCtx, app.config.tokens.pruneInterval, app.pruneTokens)
+	if app.embedder != nil {
+		app.schedule(schedulerCtx, app.config.embedding.interval, app.embedMovies)
+	}
+	if app.db != nil {
+		app.schedule(schedulerCtx, dbWaitMonitorInterval, app.dbWaitMonitor())
+	}
+	if app.db != nil && app.config.db.listen {
+		app.schedulers.Add(1)
+		go func() {
+			defer app.schedulers.Done()
+			app.listenForMovieChanges(schedulerCtx)
+		}()
+	}
+
 	app.logger.Info("starting server", "addr", srv.Addr, "env", app.config.env)
 	err := srv.ListenAndServe()
 	if !errors.Is(err, http.ErrServerClosed) {
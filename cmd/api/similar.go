@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"net/http"
+	"strings"
+)
+
+const (
+	similarGenres   = "genres"
+	similarSemantic = "semantic"
+)
+
+// showSimilarMoviesHandler returns up to limit movies like the one in the URL.
+// By default they're the movies sharing the most genres with it; with
+// ?mode=semantic they're the nearest by embedding, which needs an
+// -embedding-provider and for the movie to have been embedded by embedMovies.
+func (app *application) showSimilarMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+	mode := app.readString(qs, "mode", similarGenres)
+	limit := app.readInt(qs, "limit", 10, v)
+
+	v.CheckCode(validator.PermittedValue(mode, similarGenres, similarSemantic), "mode", "invalid_value", "invalid mode value")
+	v.CheckCode(mode != similarSemantic || app.embedder != nil, "mode", "unavailable", "semantic similarity is not enabled")
+	v.CheckCode(limit > 0, "limit", "too_small", "must be greater than zero")
+	v.CheckCode(limit <= 20, "limit", "too_large", "must be a maximum of 20")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	movie, err := app.models.Movies.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var similar []*data.SimilarMovie
+	if mode == similarSemantic {
+		similar, err = app.models.Movies.GetSimilarByEmbedding(r.Context(), movie.ID, app.embedder.Model(), limit)
+	} else {
+		similar, err = app.models.Movies.GetSimilarByGenre(r.Context(), movie.ID, limit)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoEmbedding):
+			app.errorResponse(w, r, http.StatusConflict, "this movie hasn't been indexed for semantic similarity yet, please try again later")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	movies := []*data.Movie{movie}
+	for _, s := range similar {
+		movies = append(movies, s.Movie)
+	}
+	app.localizeMovies(w, r, movies...)
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie, "mode": mode, "similar": similar}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// embedMovies embeds the movies added or changed since they were last
+// embedded, in batches of -embedding-batch, until none are left.
+func (app *application) embedMovies(ctx context.Context) {
+	model := app.embedder.Model()
+	for ctx.Err() == nil {
+		movies, err := app.models.Movies.GetUnembedded(ctx, model, app.config.embedding.batch)
+		if err != nil {
+			app.logger.Error(err.Error())
+			return
+		}
+		if len(movies) == 0 {
+			return
+		}
+
+		texts := make([]string, len(movies))
+		for i, movie := range movies {
+			texts[i] = embeddingText(movie)
+		}
+		vectors, err := app.embedder.Embed(ctx, texts)
+		if err != nil {
+			app.health.report("embedding", healthUnhealthy, err.Error())
+			app.logger.Error(fmt.Errorf("embed movies: %w", err).Error())
+			return
+		}
+		app.health.report("embedding", healthHealthy, "")
+
+		for i, movie := range movies {
+			err = app.models.Movies.SetEmbedding(ctx, movie.ID, movie.Version, model, vectors[i])
+			if err != nil {
+				app.logger.Error(err.Error())
+				return
+			}
+		}
+		embeddedMovies.Add(int64(len(movies)))
+		if len(movies) < app.config.embedding.batch {
+			return
+		}
+	}
+}
+
+// embeddingText describes a movie for the embedding model. Movies have no
+// plot summary, so the title, year and genres are all there is to go on.
+func embeddingText(movie *data.Movie) string {
+	text := fmt.Sprintf("%s (%d)", movie.Title, movie.Year)
+	if len(movie.Genres) > 0 {
+		text += ". Genres: " + strings.Join(movie.Genres, ", ")
+	}
+	return text
+}
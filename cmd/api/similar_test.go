@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fakeEmbedder embeds a text as how often each of a few words appears in it,
+// so texts sharing words are similar.
+type fakeEmbedder struct {
+	calls int
+}
+
+func (e *fakeEmbedder) Model() string { return "fake" }
+
+func (e *fakeEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	words := []string{"action", "adventure", "animation", "comedy", "drama", "romance", "sci-fi", "war"}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(words))
+		for j, word := range words {
+			vectors[i][j] = float32(strings.Count(text, word))
+		}
+	}
+	return vectors, nil
+}
+
+func TestShowSimilarMoviesHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		embedder   bool
+		err        error
+		wantStatus int
+		wantMode   string
+	}{
+		{name: "genres by default", wantStatus: http.StatusOK, wantMode: "genres"},
+		{name: "semantic", query: "?mode=semantic", embedder: true, wantStatus: http.StatusOK, wantMode: "semantic"},
+		{name: "semantic disabled", query: "?mode=semantic", wantStatus: http.StatusUnprocessableEntity},
+		{name: "not embedded yet", query: "?mode=semantic", embedder: true, err: data.ErrNoEmbedding, wantStatus: http.StatusConflict},
+		{name: "invalid mode", query: "?mode=plot", wantStatus: http.StatusUnprocessableEntity},
+		{name: "limit too large", query: "?limit=21", wantStatus: http.StatusUnprocessableEntity},
+		{name: "missing movie", err: data.ErrRecordNotFound, wantStatus: http.StatusNotFound},
+		{name: "database error", err: errors.New("connection refused"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			movies := movieMocks(models)
+			movies.GetFunc = func(ctx context.Context, id int64) (*data.Movie, error) {
+				if errors.Is(tt.err, data.ErrRecordNotFound) {
+					return nil, tt.err
+				}
+				return &data.Movie{ID: id, Title: "Moana", Genres: []string{"animation"}, Version: 1}, nil
+			}
+			similar := func() ([]*data.SimilarMovie, error) {
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return []*data.SimilarMovie{{Movie: &data.Movie{ID: 2, Title: "Frozen"}, Score: 0.5}}, nil
+			}
+			movies.GetSimilarByGenreFunc = func(ctx context.Context, id int64, limit int) ([]*data.SimilarMovie, error) {
+				return similar()
+			}
+			movies.GetSimilarByEmbeddingFunc = func(ctx context.Context, id int64, model string, limit int) ([]*data.SimilarMovie, error) {
+				return similar()
+			}
+			app := newTestApplication(t, models)
+			if tt.embedder {
+				app.embedder = &fakeEmbedder{}
+			}
+
+			r := newTestRequest(t, http.MethodGet, "/v1/movies/1/similar"+tt.query, "", testUser, httprouter.Params{{Key: "id", Value: "1"}})
+			status, _, body := serve(t, app.showSimilarMoviesHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			if body["mode"] != tt.wantMode {
+				t.Errorf("got mode %v; want %s", body["mode"], tt.wantMode)
+			}
+			hits, _ := body["similar"].([]any)
+			if len(hits) != 1 || hits[0].(map[string]any)["score"] != 0.5 {
+				t.Errorf("got similar %v; want Frozen with score 0.5", hits)
+			}
+		})
+	}
+}
+
+func TestEmbedMovies(t *testing.T) {
+	models, err := data.NewMemoryModels("admin@example.com", "pa55word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := newTestApplication(t, models)
+	embedder := &fakeEmbedder{}
+	app.embedder = embedder
+	app.config.embedding.batch = 4
+
+	// The six demo movies are embedded in batches of 4, then nothing is left.
+	app.embedMovies(context.Background())
+	if embedder.calls != 2 {
+		t.Errorf("got %d embedding requests; want 2", embedder.calls)
+	}
+	unembedded, err := models.Movies.GetUnembedded(context.Background(), embedder.Model(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unembedded) != 0 {
+		t.Errorf("got %d movies left unembedded; want 0", len(unembedded))
+	}
+
+	// Deadpool (action, comedy) is nearest to Black Panther (action,
+	// adventure, sci-fi) and The Breakfast Club (comedy, drama).
+	movies, _, err := models.Movies.GetAll(context.Background(), "Deadpool", nil, "", "", nil, nil, data.Filters{Page: 1, PageSize: 1, Sort: "id", SortSafelist: []string{"id"}})
+	if err != nil || len(movies) != 1 {
+		t.Fatalf("couldn't find Deadpool: %v", err)
+	}
+	similar, err := models.Movies.GetSimilarByEmbedding(context.Background(), movies[0].ID, embedder.Model(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, movie := range similar {
+		titles = append(titles, movie.Title)
+	}
+	if strings.Join(titles, ", ") != "The Breakfast Club, Black Panther" {
+		t.Errorf("got %v; want The Breakfast Club, Black Panther", titles)
+	}
+
+	// Changing a movie makes it due to be embedded again.
+	movies[0].Genres = []string{"action"}
+	err = models.Movies.Update(context.Background(), movies[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.embedMovies(context.Background())
+	if embedder.calls != 3 {
+		t.Errorf("got %d embedding requests after an update; want 3", embedder.calls)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"expvar"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tomasen/realip"
+)
+
+var droppedViews = expvar.NewInt("views_dropped")
+
+// viewKey identifies the views of one movie by one viewer on one (UTC) day.
+type viewKey struct {
+	movieID int64
+	viewer  string
+	day     time.Time
+}
+
+// viewBuffer counts movie views in memory between flushes, so serving a movie
+// doesn't cost a database write. Repeat views by the same viewer on the same
+// day share an entry, and once max entries are buffered, views by new viewers
+// are dropped until the next flush.
+type viewBuffer struct {
+	mu     sync.Mutex
+	max    int
+	counts map[viewKey]int
+}
+
+func newViewBuffer(max int) *viewBuffer {
+	return &viewBuffer{max: max, counts: make(map[viewKey]int)}
+}
+
+func (b *viewBuffer) add(movieID int64, viewer string, at time.Time) {
+	key := viewKey{movieID: movieID, viewer: viewer, day: at.UTC().Truncate(24 * time.Hour)}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.counts[key]; !ok && len(b.counts) >= b.max {
+		droppedViews.Add(1)
+		return
+	}
+	b.counts[key]++
+}
+
+// len returns the number of buffered movie, viewer and day combinations.
+func (b *viewBuffer) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.counts)
+}
+
+// take empties the buffer and returns what it held.
+func (b *viewBuffer) take() []data.ViewCount {
+	b.mu.Lock()
+	counts := b.counts
+	b.counts = make(map[viewKey]int)
+	b.mu.Unlock()
+
+	views := make([]data.ViewCount, 0, len(counts))
+	for key, hits := range counts {
+		views = append(views, data.ViewCount{MovieID: key.movieID, Viewer: key.viewer, At: key.day, Hits: hits})
+	}
+	return views
+}
+
+// recordView counts a view of the movie in the view buffer. Viewers are
+// identified by user ID, or by a hash of their IP address when anonymous, so
+// repeat views on the same day count once towards unique viewers.
+func (app *application) recordView(r *http.Request, movieID int64) {
+	if !app.config.stats.enabled {
+		return
+	}
+	viewer := ""
+	if user := app.contextGetUser(r); !user.IsAnonymous() {
+		viewer = fmt.Sprintf("user:%d", user.ID)
+	} else {
+		sum := sha256.Sum256([]byte(realip.FromRequest(r)))
+		viewer = "ip:" + hex.EncodeToString(sum[:8])
+	}
+	app.views.add(movieID, viewer, time.Now())
+}
+
+// flushViews writes the buffered views to the database in one batch. It runs
+// on a schedule and once more on shutdown.
+func (app *application) flushViews(ctx context.Context) {
+	views := app.views.take()
+	if len(views) == 0 {
+		return
+	}
+	err := app.models.Views.RecordMany(ctx, views)
+	if err != nil {
+		app.logger.Error(err.Error(), "views_lost", len(views))
+	}
+}
+
+// rollupStats rolls raw views up into daily stats. Yesterday is included each
+// time so that views recorded around midnight aren't lost, and raw rows are
+// deleted once they're past the retention period.
+func (app *application) rollupStats(ctx context.Context) {
+	err := app.models.Views.Rollup(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		app.logger.Error(err.Error())
+		return
+	}
+	deleted, err := app.models.Views.DeleteRawBefore(ctx, time.Now().Add(-app.config.stats.retention))
+	if err != nil {
+		app.logger.Error(err.Error())
+		return
+	}
+	app.logger.Info("movie stats rolled up", "raw_rows_deleted", deleted)
+}
+
+// showMovieStatsHandler returns a movie's daily views. Movies have no owner in
+// this schema, so there is no narrower audience than admin:read to give the
+// stats to.
+func (app *application) showMovieStatsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	days := app.readInt(r.URL.Query(), "days", 30, v)
+	v.CheckCode(days >= 1, "days", "too_small", "must be greater than zero")
+	v.CheckCode(days <= 365, "days", "too_large", "must be a maximum of 365")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	_, err = app.models.Movies.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	daily, err := app.models.Views.GetDaily(r.Context(), id, since)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Fill in the days without views so clients can plot the trend directly.
+	trend := make([]*data.DailyViews, 0, days)
+	totalViews := 0
+	for day := since; len(trend) < days; day = day.AddDate(0, 0, 1) {
+		entry := &data.DailyViews{Day: day, Date: day.Format(time.DateOnly)}
+		if len(daily) > 0 && daily[0].Day.Equal(day) {
+			entry = daily[0]
+			daily = daily[1:]
+		}
+		totalViews += entry.Views
+		trend = append(trend, entry)
+	}
+
+	stats := envelope{"movie_id": id, "total_views": totalViews, "days": trend}
+	err = app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
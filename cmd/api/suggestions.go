@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/jsonpatch"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"net/http"
+	"slices"
+)
+
+// suggestionFields are the movie fields a suggestion may change.
+var suggestionFields = []string{"title", "original_title", "titles", "year", "runtime", "genres", "release_date", "certification_system", "certification"}
+
+// errInvalidSuggestion and errSuggestionReviewed are returned from inside
+// approveSuggestionHandler's transaction when the suggested changes no longer
+// produce a valid movie, or someone else reviewed the suggestion first.
+var (
+	errInvalidSuggestion  = errors.New("invalid suggestion")
+	errSuggestionReviewed = errors.New("suggestion already reviewed")
+)
+
+// applySuggestion applies a suggestion's changes, a JSON Merge Patch, to the
+// movie.
+func applySuggestion(movie *data.Movie, changes json.RawMessage) error {
+	var patch any
+	err := json.Unmarshal(changes, &patch)
+	if err != nil {
+		return err
+	}
+	return patchMovie(movie, func(doc any) (any, error) {
+		return jsonpatch.MergePatch(doc, patch), nil
+	})
+}
+
+// createSuggestionHandler lets any activated user propose changes to a movie
+// for someone with the movies:review permission to approve. The changes are
+// checked against the movie as it is now, so suggestions which wouldn't
+// change anything or would make the movie invalid are refused up front.
+func (app *application) createSuggestionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Changes map[string]json.RawMessage `json:"changes"`
+		Comment string                     `json:"comment"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.CheckCode(len(input.Changes) > 0, "changes", "required", "must be provided")
+	for field := range input.Changes {
+		v.CheckCode(slices.Contains(suggestionFields, field), "changes", "invalid_value", "must only contain editable movie fields")
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	movie, err := app.models.Movies.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	changes, err := json.Marshal(input.Changes)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	suggestion := &data.Suggestion{
+		MovieID:      movie.ID,
+		MovieVersion: movie.Version,
+		UserID:       app.contextGetUser(r).ID,
+		Changes:      changes,
+		Comment:      input.Comment,
+	}
+
+	before := *movie
+	err = applySuggestion(movie, suggestion.Changes)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	data.ValidateMovie(v, movie)
+	v.CheckCode(len(movieFieldsChanged(&before, movie)) > 0, "changes", "no_changes", "must change at least one field")
+	if data.ValidateSuggestion(v, suggestion); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	err = app.models.Suggestions.Insert(r.Context(), suggestion)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/suggestions/%d", suggestion.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"suggestion": suggestion}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listMovieSuggestionsHandler returns the suggestions made for a movie. It's
+// open to anyone who can read movies, so only reviewers see who made and
+// reviewed them.
+func (app *application) listMovieSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+	user := app.contextGetUser(r)
+	reviewer := false
+	if !user.IsAnonymous() {
+		permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		reviewer = permissions.Include("movies:review")
+	}
+	app.listSuggestions(w, r, id, reviewer)
+}
+
+// listSuggestionsHandler is the review queue: the suggestions for every
+// movie, pending only by default.
+func (app *application) listSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	app.listSuggestions(w, r, 0, true)
+}
+
+// listSuggestions lists the suggestions for the movie, or for every movie if
+// movieID is 0. Unless the user is a reviewer, who made and reviewed each
+// suggestion and why it was rejected are left out, except on their own.
+func (app *application) listSuggestions(w http.ResponseWriter, r *http.Request, movieID int64, reviewer bool) {
+	var input struct {
+		Status string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+	defaultStatus := ""
+	if movieID == 0 {
+		defaultStatus = data.SuggestionPending
+	}
+	input.Status = app.readString(qs, "status", defaultStatus)
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "created_at")
+	input.Filters.SortSafelist = []string{"id", "created_at", "-id", "-created_at"}
+	data.ValidateSuggestionStatus(v, input.Status)
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	suggestions, metadata, err := app.models.Suggestions.GetAll(r.Context(), movieID, input.Status, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !reviewer {
+		user := app.contextGetUser(r)
+		for _, suggestion := range suggestions {
+			if user.IsAnonymous() || suggestion.UserID != user.ID {
+				suggestion.UserID = 0
+				suggestion.ReviewedBy = 0
+				suggestion.Reason = ""
+			}
+		}
+	}
+	metadata.SetLinks(app.config.baseURL+r.URL.Path, r.URL.Query())
+	err = app.writeJSON(w, http.StatusOK, envelope{"suggestions": suggestions, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readPendingSuggestion reads the suggestion named in the URL, writing an
+// error response and returning false if it doesn't exist or has already been
+// reviewed.
+func (app *application) readPendingSuggestion(w http.ResponseWriter, r *http.Request) (*data.Suggestion, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+	suggestion, err := app.models.Suggestions.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	if suggestion.Status != data.SuggestionPending {
+		app.errorResponse(w, r, http.StatusConflict, "this suggestion has already been reviewed")
+		return nil, false
+	}
+	return suggestion, true
+}
+
+// approveSuggestionHandler applies a suggestion's changes to the movie as it
+// is now and marks it approved, recording who reviewed it and the version of
+// the movie it produced. Both happen in one transaction, so a suggestion is
+// never applied twice.
+func (app *application) approveSuggestionHandler(w http.ResponseWriter, r *http.Request) {
+	suggestion, ok := app.readPendingSuggestion(w, r)
+	if !ok {
+		return
+	}
+	suggestion.Status = data.SuggestionApproved
+	suggestion.ReviewedBy = app.contextGetUser(r).ID
+
+	v := validator.New()
+	var movie *data.Movie
+	err := app.models.WithTx(r.Context(), func(m data.Models) error {
+		var err error
+		movie, err = m.Movies.Get(r.Context(), suggestion.MovieID)
+		if err != nil {
+			return err
+		}
+		// The movie may have been edited since the suggestion was made, so
+		// check the changes still make sense.
+		before := *movie
+		err = applySuggestion(movie, suggestion.Changes)
+		if err != nil {
+			return err
+		}
+		data.ValidateMovie(v, movie)
+		v.CheckCode(len(movieFieldsChanged(&before, movie)) > 0, "changes", "no_changes", "must change at least one field")
+		if !v.Valid() {
+			return errInvalidSuggestion
+		}
+
+		err = m.Movies.Update(r.Context(), movie)
+		if err != nil {
+			return err
+		}
+		suggestion.AppliedVersion = movie.Version
+		err = m.Suggestions.Review(r.Context(), suggestion)
+		if errors.Is(err, data.ErrEditConflict) {
+			return errSuggestionReviewed
+		}
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, errInvalidSuggestion):
+			app.failedValidationResponse(w, r, v)
+		case errors.Is(err, errSuggestionReviewed):
+			app.errorResponse(w, r, http.StatusConflict, "this suggestion has already been reviewed")
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.notifySuggester(suggestion, movie.Title)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"suggestion": suggestion, "movie": movie}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// rejectSuggestionHandler marks a suggestion rejected. A reason is required,
+// and is passed on to the suggester.
+func (app *application) rejectSuggestionHandler(w http.ResponseWriter, r *http.Request) {
+	suggestion, ok := app.readPendingSuggestion(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	suggestion.Status = data.SuggestionRejected
+	suggestion.ReviewedBy = app.contextGetUser(r).ID
+	suggestion.Reason = input.Reason
+
+	v := validator.New()
+	if data.ValidateSuggestion(v, suggestion); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	err = app.models.Suggestions.Review(r.Context(), suggestion)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.errorResponse(w, r, http.StatusConflict, "this suggestion has already been reviewed")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	movie, err := app.models.Movies.Get(r.Context(), suggestion.MovieID)
+	if err == nil {
+		app.notifySuggester(suggestion, movie.Title)
+	} else if !errors.Is(err, data.ErrRecordNotFound) {
+		app.logger.Error(err.Error(), "suggestion_id", suggestion.ID)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"suggestion": suggestion}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// notifySuggester emails the user who made a suggestion the outcome of its
+// review, unless they've opted out of moderation updates.
+func (app *application) notifySuggester(suggestion *data.Suggestion, movieTitle string) {
+	app.background(func() {
+		user, err := app.models.Users.Get(context.Background(), suggestion.UserID)
+		if err != nil {
+			if !errors.Is(err, data.ErrRecordNotFound) {
+				app.logger.Error(err.Error(), "suggestion_id", suggestion.ID)
+			}
+			return
+		}
+		templateData := map[string]any{
+			"name":       user.Name,
+			"movieTitle": movieTitle,
+			"approved":   suggestion.Status == data.SuggestionApproved,
+			"reason":     suggestion.Reason,
+		}
+		err = app.sendNotification(user, data.NotificationModerationUpdates, "suggestion_reviewed.tmpl", templateData)
+		if err != nil {
+			app.logger.Error(err.Error(), "suggestion_id", suggestion.ID)
+		}
+	})
+}
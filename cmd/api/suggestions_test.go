@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"testing"
+)
+
+// suggestionMocks returns models whose only movie is Casablanca at version 1
+// and whose suggester can't be found, so no notification is sent.
+func suggestionMocks() data.Models {
+	models := mocks.NewModels()
+	movieMocks(models).GetFunc = func(ctx context.Context, id int64) (*data.Movie, error) {
+		if id != 1 {
+			return nil, data.ErrRecordNotFound
+		}
+		return &data.Movie{ID: 1, Title: "Casablanca", Year: 1942, Runtime: 102, Genres: []string{"drama"}, Version: 1}, nil
+	}
+	movieMocks(models).UpdateFunc = func(ctx context.Context, movie *data.Movie) error {
+		movie.Version++
+		return nil
+	}
+	models.Users.(*mocks.UserStore).GetFunc = func(ctx context.Context, id int64) (*data.User, error) {
+		return nil, data.ErrRecordNotFound
+	}
+	return models
+}
+
+func TestCreateSuggestionHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		body       string
+		wantStatus int
+	}{
+		{name: "valid", id: "1", body: `{"changes": {"year": 1943}, "comment": "It premiered in 1942 but was released in 1943"}`, wantStatus: http.StatusCreated},
+		{name: "no changes", id: "1", body: `{"comment": "Wrong"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "unchanged", id: "1", body: `{"changes": {"title": "Casablanca"}}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "unknown field", id: "1", body: `{"changes": {"version": 7}}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "invalid movie", id: "1", body: `{"changes": {"title": null}}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "wrong type", id: "1", body: `{"changes": {"year": "1943"}}`, wantStatus: http.StatusBadRequest},
+		{name: "missing movie", id: "2", body: `{"changes": {"year": 1943}}`, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := suggestionMocks()
+			suggestions := models.Suggestions.(*mocks.SuggestionStore)
+			suggestions.InsertFunc = func(ctx context.Context, suggestion *data.Suggestion) error {
+				suggestion.ID = 5
+				suggestion.Status = data.SuggestionPending
+				return nil
+			}
+			app := newTestApplication(t, models)
+
+			r := newTestRequest(t, http.MethodPost, "/v1/movies/"+tt.id+"/suggestions", tt.body, testUser, httprouter.Params{{Key: "id", Value: tt.id}})
+			status, headers, body := serve(t, app.createSuggestionHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+			if status != http.StatusCreated {
+				if len(suggestions.InsertCalls()) != 0 {
+					t.Error("got a suggestion inserted for a refused request")
+				}
+				return
+			}
+			if headers.Get("Location") != "/v1/suggestions/5" {
+				t.Errorf("got Location %q; want /v1/suggestions/5", headers.Get("Location"))
+			}
+			inserted := suggestions.InsertCalls()[0].Suggestion
+			if inserted.MovieVersion != 1 || inserted.UserID != testUser.ID || string(inserted.Changes) != `{"year":1943}` {
+				t.Errorf("got %+v; want year 1943 suggested by user %d against version 1", inserted, testUser.ID)
+			}
+		})
+	}
+}
+
+func TestApproveSuggestionHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		changes    string
+		reviewErr  error
+		wantStatus int
+	}{
+		{name: "approved", status: data.SuggestionPending, changes: `{"year":1943}`, wantStatus: http.StatusOK},
+		{name: "already reviewed", status: data.SuggestionRejected, changes: `{"year":1943}`, wantStatus: http.StatusConflict},
+		{name: "reviewed concurrently", status: data.SuggestionPending, changes: `{"year":1943}`, reviewErr: data.ErrEditConflict, wantStatus: http.StatusConflict},
+		{name: "already applied", status: data.SuggestionPending, changes: `{"year":1942}`, wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := suggestionMocks()
+			suggestions := models.Suggestions.(*mocks.SuggestionStore)
+			suggestions.GetFunc = func(ctx context.Context, id int64) (*data.Suggestion, error) {
+				return &data.Suggestion{ID: id, MovieID: 1, MovieVersion: 1, UserID: 2, Changes: []byte(tt.changes), Status: tt.status}, nil
+			}
+			suggestions.ReviewFunc = func(ctx context.Context, suggestion *data.Suggestion) error {
+				return tt.reviewErr
+			}
+			app := newTestApplication(t, models)
+
+			r := newTestRequest(t, http.MethodPost, "/v1/suggestions/5/approve", "", testUser, httprouter.Params{{Key: "id", Value: "5"}})
+			status, _, body := serve(t, app.approveSuggestionHandler, r)
+			app.wg.Wait()
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			if updates := movieMocks(models).UpdateCalls(); len(updates) != 1 || updates[0].Movie.Year != 1943 {
+				t.Errorf("got updates %+v; want the year changed to 1943", updates)
+			}
+			reviewed := suggestions.ReviewCalls()[0].Suggestion
+			if reviewed.Status != data.SuggestionApproved || reviewed.ReviewedBy != testUser.ID || reviewed.AppliedVersion != 2 {
+				t.Errorf("got %+v; want approved by user %d as version 2", reviewed, testUser.ID)
+			}
+		})
+	}
+}
+
+func TestRejectSuggestionHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "rejected", body: `{"reason": "It was released in 1942"}`, wantStatus: http.StatusOK},
+		{name: "no reason", body: `{}`, wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := suggestionMocks()
+			suggestions := models.Suggestions.(*mocks.SuggestionStore)
+			suggestions.GetFunc = func(ctx context.Context, id int64) (*data.Suggestion, error) {
+				return &data.Suggestion{ID: id, MovieID: 1, UserID: 2, Changes: []byte(`{"year":1943}`), Status: data.SuggestionPending}, nil
+			}
+			suggestions.ReviewFunc = func(ctx context.Context, suggestion *data.Suggestion) error {
+				return nil
+			}
+			app := newTestApplication(t, models)
+
+			r := newTestRequest(t, http.MethodPost, "/v1/suggestions/5/reject", tt.body, testUser, httprouter.Params{{Key: "id", Value: "5"}})
+			status, _, body := serve(t, app.rejectSuggestionHandler, r)
+			app.wg.Wait()
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			reviewed := suggestions.ReviewCalls()[0].Suggestion
+			if reviewed.Status != data.SuggestionRejected || reviewed.Reason != "It was released in 1942" {
+				t.Errorf("got %+v; want rejected with the reason given", reviewed)
+			}
+			if len(movieMocks(models).UpdateCalls()) != 0 {
+				t.Error("got the movie updated for a rejected suggestion")
+			}
+		})
+	}
+}
+
+func TestListMovieSuggestionsHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		user        *data.User
+		permissions data.Permissions
+		wantHidden  []bool
+	}{
+		{name: "anonymous", user: data.AnonymousUser, wantHidden: []bool{true, true}},
+		{name: "suggester", user: testUser, wantHidden: []bool{false, true}},
+		{name: "reviewer", user: testUser, permissions: data.Permissions{"movies:review"}, wantHidden: []bool{false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := suggestionMocks()
+			models.Suggestions.(*mocks.SuggestionStore).GetAllFunc = func(ctx context.Context, movieID int64, status string, filters data.Filters) ([]*data.Suggestion, data.Metadata, error) {
+				return []*data.Suggestion{
+					{ID: 5, MovieID: 1, UserID: testUser.ID, Status: data.SuggestionRejected, ReviewedBy: 3, Reason: "Wrong year"},
+					{ID: 6, MovieID: 1, UserID: 2, Status: data.SuggestionRejected, ReviewedBy: 3, Reason: "Spam"},
+				}, data.Metadata{}, nil
+			}
+			models.Permissions.(*mocks.PermissionStore).GetAllForUserFunc = func(ctx context.Context, userID int64) (data.Permissions, error) {
+				return tt.permissions, nil
+			}
+			app := newTestApplication(t, models)
+
+			r := newTestRequest(t, http.MethodGet, "/v1/movies/1/suggestions", "", tt.user, httprouter.Params{{Key: "id", Value: "1"}})
+			status, _, body := serve(t, app.listMovieSuggestionsHandler, r)
+			if status != http.StatusOK {
+				t.Fatalf("got status %d; want 200 (%v)", status, body)
+			}
+			suggestions, _ := body["suggestions"].([]any)
+			if len(suggestions) != len(tt.wantHidden) {
+				t.Fatalf("got %v; want %d suggestions", suggestions, len(tt.wantHidden))
+			}
+			for i, s := range suggestions {
+				suggestion := s.(map[string]any)
+				for _, field := range []string{"user_id", "reviewed_by", "reason"} {
+					if _, shown := suggestion[field]; shown == tt.wantHidden[i] {
+						t.Errorf("suggestion %v: got %s shown %t; want %t", suggestion["id"], field, shown, !tt.wantHidden[i])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"github.com/julienschmidt/httprouter"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestApplication returns an application backed by models, which are
+// usually built with mocks.NewModels, with logging discarded.
+func newTestApplication(t *testing.T, models data.Models) *application {
+	t.Helper()
+	app := &application{
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models:     models,
+		jobs:       newJobQueue(10),
+		views:      newViewBuffer(10),
+		usage:      newUsageBuffer(10),
+		limiter:    newClientLimiter(limiterSettings{}),
+		health:     newHealthRegistry("database"),
+		tokenCache: newTokenCache(0, 1),
+	}
+	app.config.json.maxDepth = 32
+	return app
+}
+
+// testUser is the activated user requests are made as unless a test says
+// otherwise.
+var testUser = &data.User{ID: 1, Name: "Test", Email: "test@example.com", Activated: true, Language: "en"}
+
+// newTestRequest builds a request as user, with the given router params, as
+// httprouter would pass it to a handler.
+func newTestRequest(t *testing.T, method, target, body string, user *data.User, params httprouter.Params) *http.Request {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	r := httptest.NewRequest(method, target, reader)
+	r = r.WithContext(context.WithValue(r.Context(), httprouter.ParamsKey, params))
+	return (&application{}).contextSetUser(r, user)
+}
+
+// serve runs handler for r and returns the response status, headers and
+// decoded body. Bodies which aren't JSON are left undecoded.
+func serve(t *testing.T, handler http.HandlerFunc, r *http.Request) (int, http.Header, map[string]any) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	handler(rr, r)
+
+	var body map[string]any
+	if strings.HasPrefix(rr.Header().Get("Content-Type"), "application/json") {
+		err := json.Unmarshal(rr.Body.Bytes(), &body)
+		if err != nil {
+			t.Fatalf("decode response %q: %v", rr.Body.String(), err)
+		}
+	}
+	return rr.Code, rr.Header(), body
+}
+
+func movieMocks(models data.Models) *mocks.MovieStore {
+	return models.Movies.(*mocks.MovieStore)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/sha256"
+	"github.com/ezechidc/greenlight/internal/data"
+	"sync"
+	"time"
+)
+
+// tokenCache remembers the user each recently seen authentication token
+// belongs to, so that authenticate doesn't query the database on every
+// request. Entries are keyed by a hash of the token, so plaintext tokens
+// aren't kept in memory, and expire after ttl. Revoking a user's tokens or
+// changing the user must invalidate their entries; other instances only see
+// the change once their entries expire, so ttl should be short.
+type tokenCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	max     int
+	entries map[[sha256.Size]byte]tokenCacheEntry
+	byUser  map[int64]map[[sha256.Size]byte]bool
+}
+
+type tokenCacheEntry struct {
+	user    *data.User
+	expires time.Time
+}
+
+// newTokenCache returns a cache holding up to max tokens for ttl. A ttl of 0
+// disables it.
+func newTokenCache(ttl time.Duration, max int) *tokenCache {
+	return &tokenCache{
+		ttl:     ttl,
+		max:     max,
+		entries: map[[sha256.Size]byte]tokenCacheEntry{},
+		byUser:  map[int64]map[[sha256.Size]byte]bool{},
+	}
+}
+
+func (c *tokenCache) enabled() bool {
+	return c.ttl > 0
+}
+
+// get returns a copy of the user token was cached for, so that handlers can
+// modify it freely.
+func (c *tokenCache) get(token string) (*data.User, bool) {
+	if !c.enabled() {
+		return nil, false
+	}
+	key := sha256.Sum256([]byte(token))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		tokenCacheMisses.Add(1)
+		return nil, false
+	}
+	tokenCacheHits.Add(1)
+	user := *entry.user
+	return &user, true
+}
+
+func (c *tokenCache) put(token string, user *data.User) {
+	if !c.enabled() {
+		return
+	}
+	key := sha256.Sum256([]byte(token))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.max {
+		c.evictExpired()
+		if len(c.entries) >= c.max {
+			return
+		}
+	}
+	cached := *user
+	c.entries[key] = tokenCacheEntry{user: &cached, expires: time.Now().Add(c.ttl)}
+	if c.byUser[user.ID] == nil {
+		c.byUser[user.ID] = map[[sha256.Size]byte]bool{}
+	}
+	c.byUser[user.ID][key] = true
+}
+
+// invalidateUser drops every token cached for the user.
+func (c *tokenCache) invalidateUser(userID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.byUser[userID] {
+		delete(c.entries, key)
+	}
+	delete(c.byUser, userID)
+}
+
+// evictExpired drops the expired entries. c.mu must be held.
+func (c *tokenCache) evictExpired() {
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+			delete(c.byUser[entry.user.ID], key)
+			if len(c.byUser[entry.user.ID]) == 0 {
+				delete(c.byUser, entry.user.ID)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testTokenPlaintext = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+func TestTokenCache(t *testing.T) {
+	c := newTokenCache(time.Minute, 2)
+	alice := &data.User{ID: 1, Name: "Alice"}
+	c.put("token-a", alice)
+
+	got, ok := c.get("token-a")
+	if !ok || got.Name != "Alice" {
+		t.Fatalf("got %v, %t; want Alice", got, ok)
+	}
+	got.Name = "Mallory"
+	if again, _ := c.get("token-a"); again.Name != "Alice" {
+		t.Errorf("changing a returned user changed the cached one to %q", again.Name)
+	}
+
+	c.put("token-b", alice)
+	c.put("token-c", &data.User{ID: 2})
+	if _, ok := c.get("token-c"); ok {
+		t.Error("cached a token beyond the maximum size")
+	}
+
+	c.invalidateUser(alice.ID)
+	for _, token := range []string{"token-a", "token-b"} {
+		if _, ok := c.get(token); ok {
+			t.Errorf("%s still cached after invalidating its user", token)
+		}
+	}
+
+	c.ttl = -time.Second
+	c.put("token-d", alice)
+	c.ttl = time.Minute
+	if _, ok := c.get("token-d"); ok {
+		t.Error("got an expired entry")
+	}
+	c.put("token-e", alice)
+	c.put("token-f", alice)
+	if _, ok := c.get("token-f"); !ok {
+		t.Error("expired entries weren't evicted to make room")
+	}
+
+	disabled := newTokenCache(0, 10)
+	disabled.put("token-a", alice)
+	if _, ok := disabled.get("token-a"); ok {
+		t.Error("a disabled cache returned an entry")
+	}
+}
+
+func TestAuthenticateUsesTokenCache(t *testing.T) {
+	models := mocks.NewModels()
+	users := models.Users.(*mocks.UserStore)
+	users.GetForTokenFunc = func(ctx context.Context, tokenScope, tokenPlaintext string) (*data.User, error) {
+		return &data.User{ID: 7, Activated: true}, nil
+	}
+	app := newTestApplication(t, models)
+	app.tokenCache = newTokenCache(time.Minute, 10)
+
+	var seen int64
+	handler := app.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = app.contextGetUser(r).ID
+	}))
+	request := func() {
+		r := httptest.NewRequest(http.MethodGet, "/v1/movies", nil)
+		r.Header.Set("Authorization", "Bearer "+testTokenPlaintext)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		if seen != 7 {
+			t.Fatalf("got user %d; want 7", seen)
+		}
+	}
+
+	request()
+	request()
+	if got := len(users.GetForTokenCalls()); got != 1 {
+		t.Errorf("got %d lookups; want 1", got)
+	}
+	app.tokenCache.invalidateUser(7)
+	request()
+	if got := len(users.GetForTokenCalls()); got != 2 {
+		t.Errorf("got %d lookups after invalidation; want 2", got)
+	}
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/ezechidc/greenlight/internal/data"
 	"github.com/ezechidc/greenlight/internal/validator"
 	"net/http"
@@ -20,19 +22,24 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
+	if !app.allowAuthEmail(w, r, input.Email) {
+		return
+	}
+
 	v := validator.New()
 	data.ValidateEmail(v, input.Email)
 	data.ValidatePasswordPlaintext(v, input.Password)
 
 	if !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	user, err := app.models.Users.GetByEmail(input.Email)
+	user, err := app.models.Users.GetByEmail(r.Context(), input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
+			app.authEmailFailed(input.Email)
 			app.invalidCredentialsResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
@@ -47,11 +54,31 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	}
 
 	if !match {
+		app.authEmailFailed(input.Email)
 		app.invalidCredentialsResponse(w, r)
 		return
 	}
+	app.authEmailSucceeded(input.Email)
+	if user.IsDeactivated() {
+		app.deactivatedAccountResponse(w, r)
+		return
+	}
 
-	token, err := app.models.Tokens.New(user.ID, 24*time.Hour, data.ScopeAuthentication)
+	// Upgrade hashes made with an older scheme or cost while we have the
+	// plaintext. Failing to do so shouldn't stop the user logging in.
+	if user.Password.NeedsRehash(app.passwordParams) {
+		err = user.Password.Set(input.Password, app.passwordParams)
+		if err == nil {
+			err = app.models.Users.Update(r.Context(), user)
+		}
+		if err != nil {
+			app.logError(r, fmt.Errorf("rehash password: %w", err))
+		} else {
+			app.tokenCache.invalidateUser(user.ID)
+		}
+	}
+
+	token, err := app.models.Tokens.New(r.Context(), user.ID, 24*time.Hour, data.ScopeAuthentication)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -63,3 +90,32 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	}
 
 }
+
+// pruneTokens deletes expired tokens in batches of -tokens-prune-batch until
+// none are left, then records the size of the tokens table.
+func (app *application) pruneTokens(ctx context.Context) {
+	var total int64
+	for ctx.Err() == nil {
+		deleted, err := app.models.Tokens.DeleteExpired(ctx, time.Now(), app.config.tokens.pruneBatch)
+		if err != nil {
+			app.logger.Error(err.Error())
+			return
+		}
+		total += deleted
+		prunedTokens.Add(deleted)
+		if deleted < int64(app.config.tokens.pruneBatch) {
+			break
+		}
+	}
+	if total > 0 {
+		app.logger.Info("expired tokens pruned", "deleted", total)
+	}
+
+	stats, err := app.models.Tokens.GetTableStats(ctx)
+	if err != nil {
+		app.logger.Error(err.Error())
+		return
+	}
+	tokenTableRows.Set(stats.Rows)
+	tokenTableBytes.Set(stats.Bytes)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"testing"
+	"time"
+)
+
+func TestPruneTokens(t *testing.T) {
+	models := mocks.NewModels()
+	tokens := models.Tokens.(*mocks.TokenStore)
+	// 12 expired tokens are deleted in batches of 5.
+	remaining := int64(12)
+	tokens.DeleteExpiredFunc = func(ctx context.Context, before time.Time, limit int) (int64, error) {
+		deleted := min(remaining, int64(limit))
+		remaining -= deleted
+		return deleted, nil
+	}
+	tokens.GetTableStatsFunc = func(ctx context.Context) (*data.TokenTableStats, error) {
+		return &data.TokenTableStats{Rows: 40, Bytes: 8192}, nil
+	}
+	app := newTestApplication(t, models)
+	app.config.tokens.pruneBatch = 5
+
+	before := prunedTokens.Value()
+	app.pruneTokens(context.Background())
+
+	if got := len(tokens.DeleteExpiredCalls()); got != 3 {
+		t.Errorf("got %d deletes; want 3", got)
+	}
+	if got := prunedTokens.Value() - before; got != 12 {
+		t.Errorf("got %d tokens counted as pruned; want 12", got)
+	}
+	if tokenTableRows.Value() != 40 || tokenTableBytes.Value() != 8192 {
+		t.Errorf("got table size %d rows and %d bytes; want 40 and 8192", tokenTableRows.Value(), tokenTableBytes.Value())
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// usagePruneInterval is how often usage older than -usage-retention is
+// deleted.
+const usagePruneInterval = time.Hour
+
+var droppedUsage = expvar.NewInt("usage_dropped")
+
+// usageKey identifies one user's usage on one (UTC) day.
+type usageKey struct {
+	userID int64
+	day    time.Time
+}
+
+// usageBuffer totals each user's requests in memory between flushes, the same
+// way viewBuffer does for movie views. Once max users are buffered, requests
+// by other users go uncounted until the next flush.
+type usageBuffer struct {
+	mu     sync.Mutex
+	max    int
+	counts map[usageKey]*data.UsageCount
+}
+
+func newUsageBuffer(max int) *usageBuffer {
+	return &usageBuffer{max: max, counts: make(map[usageKey]*data.UsageCount)}
+}
+
+func (b *usageBuffer) add(userID int64, at time.Time, status int, bytes int64) {
+	key := usageKey{userID: userID, day: at.UTC().Truncate(24 * time.Hour)}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	count, ok := b.counts[key]
+	if !ok {
+		if len(b.counts) >= b.max {
+			droppedUsage.Add(1)
+			return
+		}
+		count = &data.UsageCount{UserID: userID, At: key.day}
+		b.counts[key] = count
+	}
+	count.Requests++
+	switch {
+	case status >= 500:
+		count.ServerErrors++
+	case status >= 400:
+		count.ClientErrors++
+	}
+	count.Bytes += bytes
+}
+
+// take empties the buffer and returns what it held.
+func (b *usageBuffer) take() []data.UsageCount {
+	b.mu.Lock()
+	counts := b.counts
+	b.counts = make(map[usageKey]*data.UsageCount)
+	b.mu.Unlock()
+
+	usage := make([]data.UsageCount, 0, len(counts))
+	for _, count := range counts {
+		usage = append(usage, *count)
+	}
+	return usage
+}
+
+// trackUsage counts each authenticated user's requests, error responses and
+// response bytes. It sits inside authenticate, so requests rejected before
+// the user is known, such as by the rate limiter, aren't counted.
+func (app *application) trackUsage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+		if !app.config.usage.enabled || user.IsAnonymous() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		mr := &metricsRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(mr, r)
+		app.usage.add(user.ID, time.Now(), mr.status, mr.bytes)
+	})
+}
+
+// flushUsage adds the buffered usage to the database in one batch. It runs on
+// a schedule and once more on shutdown.
+func (app *application) flushUsage(ctx context.Context) {
+	usage := app.usage.take()
+	if len(usage) == 0 {
+		return
+	}
+	err := app.models.Usage.RecordMany(ctx, usage)
+	if err != nil {
+		app.logger.Error(err.Error(), "usage_lost", len(usage))
+	}
+}
+
+// pruneUsage deletes usage for days older than -usage-retention.
+func (app *application) pruneUsage(ctx context.Context) {
+	deleted, err := app.models.Usage.DeleteBefore(ctx, time.Now().Add(-app.config.usage.retention))
+	if err != nil {
+		app.logger.Error(err.Error())
+		return
+	}
+	if deleted > 0 {
+		app.logger.Info("old usage pruned", "deleted", deleted)
+	}
+}
+
+// usageDay is a day of usage with its error rate, the share of requests
+// answered with a 4xx or 5xx status.
+type usageDay struct {
+	*data.DailyUsage
+	ErrorRate float64 `json:"error_rate"`
+}
+
+// usageTotal is a user's usage over a period with its error rate.
+type usageTotal struct {
+	*data.UserUsage
+	ErrorRate float64 `json:"error_rate"`
+}
+
+// showUsageHandler returns the user's daily usage over the last days days,
+// today included, and the totals for the period. Usage is written every
+// -usage-flush-interval, so the latest requests may not be counted yet.
+func (app *application) showUsageHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	days := app.readInt(r.URL.Query(), "days", 30, v)
+	v.CheckCode(days >= 1, "days", "too_small", "must be greater than zero")
+	v.CheckCode(days <= 365, "days", "too_large", "must be a maximum of 365")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	daily, err := app.models.Usage.GetDaily(r.Context(), user.ID, since)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Fill in the days without requests so clients can plot the trend
+	// directly.
+	trend := make([]usageDay, 0, days)
+	total := &data.UserUsage{UserID: user.ID, Email: user.Email}
+	for day := since; len(trend) < days; day = day.AddDate(0, 0, 1) {
+		entry := &data.DailyUsage{Day: day, Date: day.Format(time.DateOnly)}
+		if len(daily) > 0 && daily[0].Day.Equal(day) {
+			entry = daily[0]
+			daily = daily[1:]
+		}
+		total.Requests += entry.Requests
+		total.ClientErrors += entry.ClientErrors
+		total.ServerErrors += entry.ServerErrors
+		total.Bytes += entry.Bytes
+		trend = append(trend, usageDay{DailyUsage: entry, ErrorRate: fraction(entry.ClientErrors+entry.ServerErrors, entry.Requests)})
+	}
+
+	usage := envelope{
+		"total": usageTotal{UserUsage: total, ErrorRate: fraction(total.ClientErrors+total.ServerErrors, total.Requests)},
+		"days":  trend,
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"usage": usage}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listUsageHandler returns the heaviest users over the last days days, ranked
+// by requests, errors or bytes, to help operators spot abuse.
+func (app *application) listUsageHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+	days := app.readInt(qs, "days", 1, v)
+	sort := app.readString(qs, "sort", "requests")
+	limit := app.readInt(qs, "limit", 20, v)
+	v.CheckCode(days >= 1, "days", "too_small", "must be greater than zero")
+	v.CheckCode(days <= 365, "days", "too_large", "must be a maximum of 365")
+	v.CheckCode(validator.PermittedValue(sort, data.UsageSortColumns...), "sort", "invalid_value", "invalid sort value")
+	v.CheckCode(limit > 0, "limit", "too_small", "must be greater than zero")
+	v.CheckCode(limit <= 100, "limit", "too_large", "must be a maximum of 100")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	top, err := app.models.Usage.GetTop(r.Context(), since, sort, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	users := make([]usageTotal, len(top))
+	for i, usage := range top {
+		users[i] = usageTotal{UserUsage: usage, ErrorRate: fraction(usage.ClientErrors+usage.ServerErrors, usage.Requests)}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"since": since.Format(time.DateOnly), "sort": sort, "users": users}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTrackUsage(t *testing.T) {
+	app := newTestApplication(t, mocks.NewModels())
+	app.config.usage.enabled = true
+	handler := app.trackUsage(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte("hello"))
+	}))
+
+	for _, path := range []string{"/", "/", "/missing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), newTestRequest(t, http.MethodGet, path, "", testUser, nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), newTestRequest(t, http.MethodGet, "/", "", data.AnonymousUser, nil))
+
+	usage := app.usage.take()
+	if len(usage) != 1 {
+		t.Fatalf("got usage for %d users; want 1", len(usage))
+	}
+	got := usage[0]
+	if got.UserID != testUser.ID || got.Requests != 3 || got.ClientErrors != 1 || got.ServerErrors != 0 || got.Bytes != 15 {
+		t.Errorf("got %+v; want 3 requests, 1 client error and 15 bytes for user %d", got, testUser.ID)
+	}
+}
+
+func TestUsageBufferMax(t *testing.T) {
+	b := newUsageBuffer(1)
+	now := time.Now()
+	b.add(1, now, http.StatusOK, 10)
+	b.add(2, now, http.StatusOK, 10)
+	b.add(1, now, http.StatusInternalServerError, 10)
+
+	usage := b.take()
+	if len(usage) != 1 || usage[0].UserID != 1 || usage[0].Requests != 2 || usage[0].ServerErrors != 1 {
+		t.Errorf("got %+v; want only user 1's two requests", usage)
+	}
+}
+
+func TestShowUsageHandler(t *testing.T) {
+	models := mocks.NewModels()
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	models.Usage.(*mocks.UsageStore).GetDailyFunc = func(ctx context.Context, userID int64, since time.Time) ([]*data.DailyUsage, error) {
+		return []*data.DailyUsage{{Day: today, Date: today.Format(time.DateOnly), Requests: 8, ClientErrors: 1, ServerErrors: 1, Bytes: 400}}, nil
+	}
+	app := newTestApplication(t, models)
+
+	r := newTestRequest(t, http.MethodGet, "/v1/users/me/usage?days=7", "", testUser, nil)
+	status, _, body := serve(t, app.showUsageHandler, r)
+	if status != http.StatusOK {
+		t.Fatalf("got status %d; want 200 (%v)", status, body)
+	}
+	usage := body["usage"].(map[string]any)
+	days := usage["days"].([]any)
+	if len(days) != 7 {
+		t.Fatalf("got %d days; want 7", len(days))
+	}
+	last := days[6].(map[string]any)
+	if last["date"] != today.Format(time.DateOnly) || last["requests"] != float64(8) || last["error_rate"] != 0.25 {
+		t.Errorf("got today %v; want 8 requests with an error rate of 0.25", last)
+	}
+	total := usage["total"].(map[string]any)
+	if total["requests"] != float64(8) || total["bytes"] != float64(400) {
+		t.Errorf("got total %v; want 8 requests and 400 bytes", total)
+	}
+}
+
+func TestListUsageHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantSort   string
+	}{
+		{name: "defaults", wantStatus: http.StatusOK, wantSort: "requests"},
+		{name: "by errors", query: "?sort=errors&days=7", wantStatus: http.StatusOK, wantSort: "errors"},
+		{name: "invalid sort", query: "?sort=email", wantStatus: http.StatusUnprocessableEntity},
+		{name: "limit too large", query: "?limit=101", wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := mocks.NewModels()
+			usage := models.Usage.(*mocks.UsageStore)
+			usage.GetTopFunc = func(ctx context.Context, since time.Time, sort string, limit int) ([]*data.UserUsage, error) {
+				return []*data.UserUsage{{UserID: 2, Email: "heavy@example.com", Requests: 10, ServerErrors: 5}}, nil
+			}
+			app := newTestApplication(t, models)
+
+			r := newTestRequest(t, http.MethodGet, "/v1/admin/usage"+tt.query, "", testUser, nil)
+			status, _, body := serve(t, app.listUsageHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, body)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			if calls := usage.GetTopCalls(); len(calls) != 1 || calls[0].Sort != tt.wantSort {
+				t.Errorf("got calls %+v; want one sorted by %s", calls, tt.wantSort)
+			}
+			users := body["users"].([]any)
+			if len(users) != 1 || users[0].(map[string]any)["error_rate"] != 0.5 {
+				t.Errorf("got users %v; want one with an error rate of 0.5", users)
+			}
+		})
+	}
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"github.com/ezechidc/greenlight/internal/data"
 	"github.com/ezechidc/greenlight/internal/validator"
+	"github.com/tomasen/realip"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -13,6 +16,8 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		Name      string `json:"name"`
 		Email     string `json:"email"`
 		Paassword string `json:"password"`
+		Language  string `json:"language"`
+		Captcha   string `json:"captcha_token"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -25,46 +30,74 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		Name:      input.Name,
 		Email:     input.Email,
 		Activated: false,
+		Language:  input.Language,
+	}
+	if user.Language == "" {
+		user.Language = app.readLanguage(r)
 	}
 
-	err = user.Password.Set(input.Paassword)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
+	// Hashing is deliberately slow, so do it only once the CAPTCHA and the
+	// cheap checks have passed.
+	v := validator.New()
+	if app.captcha != nil {
+		ok, err := app.captcha.Verify(r.Context(), input.Captcha, realip.FromRequest(r))
+		switch {
+		case err == nil:
+			v.CheckCode(ok, "captcha_token", "invalid_captcha", "captcha verification failed")
+		case app.config.captcha.failOpen:
+			app.logError(r, fmt.Errorf("captcha verification: %w", err))
+		default:
+			app.logError(r, fmt.Errorf("captcha verification: %w", err))
+			app.serviceUnavailableResponse(w, r)
+			return
+		}
+	}
+	if app.config.disposable.enabled {
+		v.CheckCode(!app.disposable.Blocked(user.Email), "email", "disposable_email", "disposable email addresses are not allowed")
+	}
+	data.ValidateUserDetails(v, user)
+	if data.ValidatePasswordPlaintext(v, input.Paassword); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	if app.checkPwnedPassword(r, v, input.Paassword); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	v := validator.New()
-	if data.ValidateUser(v, user); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+	err = user.Password.Set(input.Paassword, app.passwordParams)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
 		return
 	}
-	err = app.models.Users.Insert(user)
+	// Create the user and their activation token together, so a failure
+	// can't leave behind an account which can never be activated.
+	var token *data.Token
+	err = app.models.WithTx(r.Context(), func(m data.Models) error {
+		err := m.Users.Insert(r.Context(), user)
+		if err != nil {
+			return err
+		}
+		token, err = m.Tokens.New(r.Context(), user.ID, 3*24*time.Hour, data.ScopeActivation)
+		return err
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
-			v.AddError("email", "a user with this email address already exists")
-			app.failedValidationResponse(w, r, v.Errors)
+			v.AddErrorCode("email", "duplicate_email", "a user with this email address already exists")
+			app.failedValidationResponse(w, r, v)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
-	token, err := app.models.Tokens.New(user.ID, 3*24*time.Hour, data.ScopeActivation)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
-	}
-
 	app.background(func() {
 		data := map[string]any{
 			"activationToken": token.Plaintext,
 			"userID":          user.ID,
 		}
-		err = app.mailer.Send(user.Email, "user_welcome.tmpl", data)
-		if err != nil {
-			app.logger.Error(err.Error())
-		}
+		app.sendEmail(user.Email, user.Language, "user_welcome.tmpl", data)
 	})
 
 	err = app.writeJSON(w, http.StatusCreated, envelope{"user": user}, nil)
@@ -84,16 +117,16 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 	v := validator.New()
 	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	user, err := app.models.Users.GetForToken(data.ScopeActivation, input.TokenPlaintext)
+	user, err := app.models.Users.GetForToken(r.Context(), data.ScopeActivation, input.TokenPlaintext)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
-			v.AddError("token", "invalid or expired activation token")
-			app.failedValidationResponse(w, r, v.Errors)
+			v.AddErrorCode("token", "invalid_token", "invalid or expired activation token")
+			app.failedValidationResponse(w, r, v)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -101,7 +134,7 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	user.Activated = true
-	err = app.models.Users.Update(user)
+	err = app.models.Users.Update(r.Context(), user)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -111,15 +144,291 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		}
 		return
 	}
+	app.tokenCache.invalidateUser(user.ID)
+
+	err = app.models.Tokens.DeleteAllForUser(r.Context(), data.ScopeActivation, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 
-	err = app.models.Tokens.DeleteAllForUser(data.ScopeActivation, user.ID)
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deactivateUserHandler closes the authenticated user's own account. The user
+// row and everything linked to it are kept, so the account can be reactivated
+// later with reactivateUserHandler.
+func (app *application) deactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.setUserDeactivated(w, r, app.contextGetUser(r), true)
+}
+
+// updateUserHandler changes the authenticated user's name and language. The
+// language is used for their emails and takes precedence over Accept-Language
+// for their API responses.
+func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	var input struct {
+		Name     *string `json:"name"`
+		Language *string `json:"language"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
 		return
 	}
+	if input.Name != nil {
+		user.Name = *input.Name
+	}
+	if input.Language != nil {
+		user.Language = *input.Language
+	}
 
+	v := validator.New()
+	if data.ValidateUserDetails(v, user); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	err = app.models.Users.Update(r.Context(), user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.tokenCache.invalidateUser(user.ID)
 	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// reactivateUserHandler lets the owner of a deactivated account reopen it.
+// Deactivated users can't hold authentication tokens, so it takes the
+// account's credentials instead. Accounts suspended by an administrator can
+// only be reopened by one, with adminReactivateUserHandler.
+func (app *application) reactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if !app.allowAuthEmail(w, r, input.Email) {
+		return
+	}
+
+	v := validator.New()
+	data.ValidateEmail(v, input.Email)
+	data.ValidatePasswordPlaintext(v, input.Password)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(r.Context(), input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.authEmailFailed(input.Email)
+			app.invalidCredentialsResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	match, err := user.Password.Matches(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !match {
+		app.authEmailFailed(input.Email)
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+	app.authEmailSucceeded(input.Email)
+	if user.IsDeactivated() && !user.IsSelfDeactivated() {
+		app.deactivatedAccountResponse(w, r)
+		return
+	}
+	app.setUserDeactivated(w, r, user, false)
+}
+
+func (app *application) adminDeactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUserParam(w, r)
+	if ok {
+		app.setUserDeactivated(w, r, user, true)
+	}
+}
+
+func (app *application) adminReactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUserParam(w, r)
+	if ok {
+		app.setUserDeactivated(w, r, user, false)
+	}
+}
+
+// readUserParam loads the user named by the :id route parameter, sending a
+// 404 and returning false if there isn't one.
+func (app *application) readUserParam(w http.ResponseWriter, r *http.Request) (*data.User, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+	user, err := app.models.Users.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return user, true
+}
+
+// setUserDeactivated closes or reopens the user's account. Closing records
+// the authenticated user as the one who closed it, so an administrator
+// suspending an account its owner had already closed takes it over.
+func (app *application) setUserDeactivated(w http.ResponseWriter, r *http.Request, user *data.User, deactivated bool) {
+	changed := deactivated != user.IsDeactivated()
+	switch {
+	case deactivated:
+		by := app.contextGetUser(r).ID
+		changed = changed || user.DeactivatedBy == nil || *user.DeactivatedBy != by
+		if !user.IsDeactivated() {
+			now := time.Now()
+			user.DeactivatedAt = &now
+		}
+		user.DeactivatedBy = &by
+	default:
+		user.DeactivatedAt = nil
+		user.DeactivatedBy = nil
+	}
+	if changed {
+		err := app.models.Users.Update(r.Context(), user)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	if deactivated {
+		err := app.models.Tokens.DeleteAllForUser(r.Context(), data.ScopeAuthentication, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	app.tokenCache.invalidateUser(user.ID)
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// bulkActivateUsersHandler activates a batch of users without their activation
+// tokens, for operators migrating an existing user base. Users are matched by
+// ID or email address; anything which matches no user is reported back rather
+// than failing the whole batch.
+func (app *application) bulkActivateUsersHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		IDs    []int64  `json:"ids"`
+		Emails []string `json:"emails"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.CheckCode(len(input.IDs)+len(input.Emails) > 0, "ids", "required", "must contain at least one user ID or email address")
+	v.CheckCode(len(input.IDs)+len(input.Emails) <= 1000, "ids", "too_large", "must not contain more than 1000 users in total")
+	for _, email := range input.Emails {
+		data.ValidateEmail(v, email)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+	if input.IDs == nil {
+		input.IDs = []int64{}
+	}
+	if input.Emails == nil {
+		input.Emails = []string{}
+	}
+
+	var activations []*data.UserActivation
+	err = app.models.WithTx(r.Context(), func(m data.Models) error {
+		var err error
+		activations, err = m.Users.ActivateMany(r.Context(), input.IDs, input.Emails)
+		if err != nil {
+			return err
+		}
+		for _, activation := range activations {
+			err = m.Tokens.DeleteAllForUser(r.Context(), data.ScopeActivation, activation.ID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	for _, activation := range activations {
+		app.tokenCache.invalidateUser(activation.ID)
+	}
+
+	activated := []*data.UserActivation{}
+	alreadyActivated := []*data.UserActivation{}
+	foundIDs := map[int64]bool{}
+	foundEmails := map[string]bool{}
+	for _, activation := range activations {
+		if activation.AlreadyActivated {
+			alreadyActivated = append(alreadyActivated, activation)
+		} else {
+			activated = append(activated, activation)
+		}
+		foundIDs[activation.ID] = true
+		foundEmails[strings.ToLower(activation.Email)] = true
+	}
+	missingIDs := []int64{}
+	for _, id := range input.IDs {
+		if !foundIDs[id] {
+			missingIDs = append(missingIDs, id)
+		}
+	}
+	missingEmails := []string{}
+	for _, email := range input.Emails {
+		if !foundEmails[strings.ToLower(email)] {
+			missingEmails = append(missingEmails, email)
+		}
+	}
+
+	app.logger.Info("bulk user activation", "admin_id", app.contextGetUser(r).ID, "activated", len(activated))
+	env := envelope{"activated": activated, "already_activated": alreadyActivated, "not_found": envelope{"ids": missingIDs, "emails": missingEmails}}
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
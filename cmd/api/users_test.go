@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/mocks"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestReactivateUserHandler(t *testing.T) {
+	self, admin := int64(2), int64(1)
+	tests := []struct {
+		name          string
+		deactivatedBy *int64
+		wantStatus    int
+	}{
+		{name: "closed by the user", deactivatedBy: &self, wantStatus: http.StatusOK},
+		{name: "suspended by an admin", deactivatedBy: &admin, wantStatus: http.StatusForbidden},
+		{name: "closed by unknown", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deactivatedAt := time.Now().Add(-time.Hour)
+			user := &data.User{ID: self, Email: "alice@example.com", Activated: true, DeactivatedAt: &deactivatedAt, DeactivatedBy: tt.deactivatedBy}
+			params := data.DefaultPasswordParams()
+			params.BcryptCost = 4
+			err := user.Password.Set("pa55word", params)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			models := mocks.NewModels()
+			models.Users.(*mocks.UserStore).GetByEmailFunc = func(ctx context.Context, email string) (*data.User, error) {
+				return user, nil
+			}
+			models.Users.(*mocks.UserStore).UpdateFunc = func(ctx context.Context, user *data.User) error {
+				return nil
+			}
+			app := newTestApplication(t, models)
+			app.authLimiter = newAuthLimiter(2, 4, time.Minute, time.Hour)
+
+			body := `{"email": "alice@example.com", "password": "pa55word"}`
+			r := newTestRequest(t, http.MethodPut, "/v1/users/reactivated", body, data.AnonymousUser, nil)
+			status, _, resp := serve(t, app.reactivateUserHandler, r)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d; want %d (%v)", status, tt.wantStatus, resp)
+			}
+			calls := len(models.Users.(*mocks.UserStore).UpdateCalls())
+			if reactivated := status == http.StatusOK; reactivated != (calls == 1) || user.IsDeactivated() == reactivated {
+				t.Errorf("got %d updates, deactivated %t after status %d", calls, user.IsDeactivated(), status)
+			}
+		})
+	}
+}
+
+func TestDeactivateUserHandlers(t *testing.T) {
+	adminUser := &data.User{ID: 1, Activated: true}
+	user := &data.User{ID: 2, Activated: true}
+
+	models := mocks.NewModels()
+	models.Users.(*mocks.UserStore).GetFunc = func(ctx context.Context, id int64) (*data.User, error) {
+		return user, nil
+	}
+	models.Users.(*mocks.UserStore).UpdateFunc = func(ctx context.Context, user *data.User) error {
+		return nil
+	}
+	models.Tokens.(*mocks.TokenStore).DeleteAllForUserFunc = func(ctx context.Context, scope string, userID int64) error {
+		return nil
+	}
+	app := newTestApplication(t, models)
+
+	r := newTestRequest(t, http.MethodDelete, "/v1/users/me", "", user, nil)
+	if status, _, resp := serve(t, app.deactivateUserHandler, r); status != http.StatusOK {
+		t.Fatalf("got status %d; want 200 (%v)", status, resp)
+	}
+	if !user.IsSelfDeactivated() {
+		t.Fatalf("got deactivated by %v; want the user themselves", user.DeactivatedBy)
+	}
+
+	// An admin suspending an account its owner closed takes it over, so the
+	// owner can't reopen it.
+	r = newTestRequest(t, http.MethodPost, "/v1/admin/users/2/deactivate", "", adminUser, httprouter.Params{{Key: "id", Value: "2"}})
+	if status, _, resp := serve(t, app.adminDeactivateUserHandler, r); status != http.StatusOK {
+		t.Fatalf("got status %d; want 200 (%v)", status, resp)
+	}
+	if user.IsSelfDeactivated() || user.DeactivatedBy == nil || *user.DeactivatedBy != adminUser.ID {
+		t.Errorf("got deactivated by %v; want the admin", user.DeactivatedBy)
+	}
+	if calls := len(models.Users.(*mocks.UserStore).UpdateCalls()); calls != 2 {
+		t.Errorf("got %d updates; want 2", calls)
+	}
+}
@@ -1 +1,3 @@
-CREATE EXTENSION IF NOT EXISTS citext;
\ No newline at end of file
+CREATE EXTENSION IF NOT EXISTS citext;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS vector;
@@ -0,0 +1,92 @@
+// Package blocklist holds the disposable email domain blocklist checked at
+// registration. An embedded list is always in effect, and operators can add
+// extra domains at runtime without a redeploy.
+package blocklist
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+//go:embed disposable_domains.txt
+var disposableDomains string
+
+// Domains is a set of blocked email domains, safe for concurrent use.
+type Domains struct {
+	mu       sync.RWMutex
+	embedded map[string]bool
+	extra    map[string]bool
+}
+
+// NewDisposable returns the embedded disposable domain list.
+func NewDisposable() *Domains {
+	return &Domains{
+		embedded: parse(strings.NewReader(disposableDomains)),
+		extra:    map[string]bool{},
+	}
+}
+
+// Blocked reports whether the domain of email, or any parent domain of it, is
+// on the list.
+func (d *Domains) Blocked(email string) bool {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(strings.TrimSuffix(email[at+1:], "."))
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for {
+		if d.embedded[domain] || d.extra[domain] {
+			return true
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+// Extra returns the runtime additions, sorted.
+func (d *Domains) Extra() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	domains := make([]string, 0, len(d.extra))
+	for domain := range d.extra {
+		domains = append(domains, domain)
+	}
+	slices.Sort(domains)
+	return domains
+}
+
+// EmbeddedLen returns the number of domains on the embedded list.
+func (d *Domains) EmbeddedLen() int {
+	return len(d.embedded)
+}
+
+// SetExtra replaces the runtime additions.
+func (d *Domains) SetExtra(domains []string) {
+	extra := parse(strings.NewReader(strings.Join(domains, "\n")))
+	d.mu.Lock()
+	d.extra = extra
+	d.mu.Unlock()
+}
+
+func parse(r io.Reader) map[string]bool {
+	domains := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[strings.TrimSuffix(line, ".")] = true
+	}
+	return domains
+}
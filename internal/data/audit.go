@@ -0,0 +1,40 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const AuditActionAuthorizationDenied = "authorization_denied"
+
+// AuditEvent records a security-relevant event, such as a request rejected by
+// an authorization check. UserID is zero for anonymous requests.
+type AuditEvent struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    int64     `json:"user_id,omitzero"`
+	Action    string    `json:"action"`
+	Reason    string    `json:"reason"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	SourceIP  string    `json:"source_ip"`
+	RequestID string    `json:"request_id,omitzero"`
+}
+
+type AuditModel struct {
+	DB DBTX
+}
+
+func (m AuditModel) Insert(ctx context.Context, event *AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (user_id, action, reason, method, path, source_ip, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+	userID := sql.NullInt64{Int64: event.UserID, Valid: event.UserID != 0}
+	args := []any{userID, event.Action, event.Reason, event.Method, event.Path, event.SourceIP, event.RequestID}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&event.ID, &event.CreatedAt)
+}
@@ -0,0 +1,94 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// CatalogStats summarizes the movies in the catalog.
+type CatalogStats struct {
+	Movies int `json:"movies"`
+	// AverageRuntime is in minutes, over the movies with a known runtime.
+	AverageRuntime float64        `json:"average_runtime"`
+	Genres         []*GenreCount  `json:"genres"`
+	Decades        []*DecadeCount `json:"decades"`
+	AddedLastWeek  int            `json:"added_last_7_days"`
+	AddedLastMonth int            `json:"added_last_30_days"`
+	ComputedAt     time.Time      `json:"computed_at"`
+}
+
+// DecadeCount is a decade, such as 1990 for the 1990s, and the number of
+// movies released in it.
+type DecadeCount struct {
+	Decade int32 `json:"decade"`
+	Movies int   `json:"movies"`
+}
+
+// GetCatalogStats computes statistics over every movie. It scans the whole
+// table, so callers should cache the result.
+func (m MovieModel) GetCatalogStats(ctx context.Context) (*CatalogStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	stats := CatalogStats{Genres: []*GenreCount{}, Decades: []*DecadeCount{}}
+	query := `
+		SELECT now(), count(*), coalesce(avg(NULLIF(runtime, 0)), 0),
+			count(*) FILTER (WHERE created_at > now() - interval '7 days'),
+			count(*) FILTER (WHERE created_at > now() - interval '30 days')
+		FROM movies`
+	err := m.DB.QueryRowContext(ctx, query).Scan(
+		&stats.ComputedAt,
+		&stats.Movies,
+		&stats.AverageRuntime,
+		&stats.AddedLastWeek,
+		&stats.AddedLastMonth,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT genre, count(*)
+		FROM movies, unnest(genres) AS genre
+		GROUP BY genre
+		ORDER BY count(*) DESC, genre ASC`
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var genre GenreCount
+		err := rows.Scan(&genre.Name, &genre.Movies)
+		if err != nil {
+			return nil, err
+		}
+		stats.Genres = append(stats.Genres, &genre)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT year / 10 * 10 AS decade, count(*)
+		FROM movies
+		GROUP BY decade
+		ORDER BY decade ASC`
+	rows, err = m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var decade DecadeCount
+		err := rows.Scan(&decade.Decade, &decade.Movies)
+		if err != nil {
+			return nil, err
+		}
+		stats.Decades = append(stats.Decades, &decade)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
@@ -0,0 +1,134 @@
+// Package datatest provides fixtures for tests which exercise the data models
+// against a real PostgreSQL database. Tests using it are skipped unless the
+// GREENLIGHT_TEST_DB_DSN environment variable points at a migrated database.
+package datatest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/data"
+	_ "github.com/lib/pq"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Password is the plaintext password given to every user built by NewUser.
+const Password = "pa55word"
+
+// Tables lists every application table, for use with Truncate.
+var Tables = []string{"audit_events", "emails", "invitations", "movie_embeddings", "movie_media", "movie_posters", "movie_revisions", "movie_suggestions", "movie_titles", "movie_view_stats", "movie_views", "movies", "tokens", "user_preferences", "user_usage", "users_permissions", "users"}
+
+var sequence atomic.Int64
+
+// OpenDB connects to the test database, skipping the test if none is
+// configured. The pool is closed when the test finishes.
+func OpenDB(t testing.TB) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("GREENLIGHT_TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("GREENLIGHT_TEST_DB_DSN not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = db.PingContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// Truncate empties the given tables (or all of Tables if none are given) and
+// resets their id sequences.
+func Truncate(t testing.TB, db *sql.DB, tables ...string) {
+	t.Helper()
+	if len(tables) == 0 {
+		tables = Tables
+	}
+	query := fmt.Sprintf("TRUNCATE %s RESTART IDENTITY CASCADE", strings.Join(tables, ", "))
+	_, err := db.Exec(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// WithTx runs fn with models bound to a transaction which is always rolled
+// back afterwards, so tests can share a database without cleaning up.
+func WithTx(t testing.TB, db *sql.DB, fn func(m data.Models)) {
+	t.Helper()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	fn(data.NewModels(tx))
+}
+
+// NewMovie returns a valid, unsaved movie. The optional modifiers are applied
+// in order, so tests only need to spell out the fields they care about.
+func NewMovie(modifiers ...func(*data.Movie)) *data.Movie {
+	movie := &data.Movie{
+		Title:   fmt.Sprintf("Movie %d", sequence.Add(1)),
+		Year:    2020,
+		Runtime: 102,
+		Genres:  []string{"drama"},
+	}
+	for _, modify := range modifiers {
+		modify(movie)
+	}
+	return movie
+}
+
+// NewUser returns a valid, unsaved and unactivated user with a unique email
+// address and the password Password.
+func NewUser(modifiers ...func(*data.User)) *data.User {
+	n := sequence.Add(1)
+	user := &data.User{
+		Name:     fmt.Sprintf("User %d", n),
+		Email:    fmt.Sprintf("user%d@example.com", n),
+		Language: data.SupportedLanguages[0],
+	}
+	err := user.Password.Set(Password, data.DefaultPasswordParams())
+	if err != nil {
+		panic(err)
+	}
+	for _, modify := range modifiers {
+		modify(user)
+	}
+	return user
+}
+
+// NewActivatedUser is like NewUser but the user is already activated.
+func NewActivatedUser(modifiers ...func(*data.User)) *data.User {
+	user := NewUser(modifiers...)
+	user.Activated = true
+	return user
+}
+
+// InsertMovie saves movie using m, failing the test on error.
+func InsertMovie(t testing.TB, m data.Models, movie *data.Movie) *data.Movie {
+	t.Helper()
+	err := m.Movies.Insert(context.Background(), movie)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return movie
+}
+
+// InsertUser saves user using m, failing the test on error.
+func InsertUser(t testing.TB, m data.Models, user *data.User) *data.User {
+	t.Helper()
+	err := m.Users.Insert(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
@@ -0,0 +1,61 @@
+package data
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Date is a calendar date with no time of day, such as a release date. It's
+// written in JSON as an ISO 8601 date, like "2016-11-23".
+type Date struct {
+	time.Time
+}
+
+var ErrInvalidDateFormat = errors.New("invalid date format")
+
+const dateLayout = time.DateOnly
+
+// ParseDate parses an ISO 8601 date.
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return Date{}, ErrInvalidDateFormat
+	}
+	return Date{t}, nil
+}
+
+func (d Date) String() string {
+	return d.Format(dateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+func (d *Date) UnmarshalJSON(jsonValue []byte) error {
+	unquotedJSONValue, err := strconv.Unquote(string(jsonValue))
+	if err != nil {
+		return ErrInvalidDateFormat
+	}
+	*d, err = ParseDate(unquotedJSONValue)
+	return err
+}
+
+// Scan implements sql.Scanner for date columns.
+func (d *Date) Scan(value any) error {
+	t, ok := value.(time.Time)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into Date", value)
+	}
+	*d = Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+	return nil
+}
+
+// Value implements driver.Valuer, sending the date without a time of day so
+// the server's time zone can't shift it.
+func (d Date) Value() (driver.Value, error) {
+	return d.String(), nil
+}
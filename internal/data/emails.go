@@ -0,0 +1,141 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"time"
+)
+
+const (
+	EmailStatusPending = "pending"
+	EmailStatusSent    = "sent"
+	EmailStatusFailed  = "failed"
+)
+
+type Email struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Recipient string    `json:"recipient"`
+	Template  string    `json:"template"`
+	Status    string    `json:"status"`
+	MessageID string    `json:"message_id,omitzero"`
+	Error     string    `json:"error,omitzero"`
+}
+
+type EmailModel struct {
+	DB DBTX
+}
+
+func ValidateEmailStatus(v *validator.Validator, status string) {
+	if status == "" {
+		return
+	}
+	v.CheckCode(validator.PermittedValue(status, EmailStatusPending, EmailStatusSent, EmailStatusFailed), "status", "invalid_value", "invalid status value")
+}
+
+func (m EmailModel) Insert(ctx context.Context, email *Email) error {
+	query := `
+		INSERT INTO emails (recipient, template, status)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at`
+	args := []any{email.Recipient, email.Template, email.Status}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&email.ID, &email.CreatedAt, &email.UpdatedAt)
+}
+
+func (m EmailModel) UpdateStatus(ctx context.Context, email *Email) error {
+	query := `
+		UPDATE emails
+		SET status = $1, message_id = $2, error = $3, updated_at = NOW()
+		WHERE id = $4
+		RETURNING updated_at`
+	args := []any{email.Status, email.MessageID, email.Error, email.ID}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&email.UpdatedAt)
+}
+
+func (m EmailModel) GetAll(ctx context.Context, recipient string, status string, filters Filters) ([]*Email, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, created_at, updated_at, recipient, template, status, message_id, error
+		FROM emails
+		WHERE (recipient = $1 OR $1 = '')
+		AND (status = $2 OR $2 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	args := []any{recipient, status, filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	emails := []*Email{}
+	for rows.Next() {
+		var email Email
+		err := rows.Scan(
+			&totalRecords,
+			&email.ID,
+			&email.CreatedAt,
+			&email.UpdatedAt,
+			&email.Recipient,
+			&email.Template,
+			&email.Status,
+			&email.MessageID,
+			&email.Error,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		emails = append(emails, &email)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return emails, metadata, nil
+}
+
+// CountByStatus returns the number of emails created since since in each
+// status.
+func (m EmailModel) CountByStatus(ctx context.Context, since time.Time) (map[string]int, error) {
+	query := `
+		SELECT status, count(*)
+		FROM emails
+		WHERE created_at >= $1
+		GROUP BY status`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var (
+			status string
+			count  int
+		)
+		err := rows.Scan(&status, &count)
+		if err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
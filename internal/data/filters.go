@@ -2,32 +2,39 @@ package data
 
 import (
 	"github.com/ezechidc/greenlight/internal/validator"
+	"maps"
+	"net/url"
+	"strconv"
 	"strings"
 )
 
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitzero"`
-	PageSize     int `json:"page_size,omitzero"`
-	FirstPage    int `json:"first_page,omitzero"`
-	LastPage     int `json:"last_page,omitzero"`
-	TotalRecords int `json:"total_records,omitzero"`
+	CurrentPage  int    `json:"current_page,omitzero"`
+	PageSize     int    `json:"page_size,omitzero"`
+	FirstPage    int    `json:"first_page,omitzero"`
+	LastPage     int    `json:"last_page,omitzero"`
+	TotalRecords int    `json:"total_records,omitzero"`
+	First        string `json:"first,omitzero"`
+	Prev         string `json:"prev,omitzero"`
+	Next         string `json:"next,omitzero"`
+	Last         string `json:"last,omitzero"`
 }
 
 type Filters struct {
-	Page         int
-	PageSize     int
-	Sort         string
-	SortSafelist []string
+	Page         int      `json:"page"`
+	PageSize     int      `json:"page_size"`
+	Sort         string   `json:"sort"`
+	SortSafelist []string `json:"-"`
 }
 
 func ValidateFilters(v *validator.Validator, f Filters) {
 	// Check that the page and page_size parameters contain sensible values.
-	v.Check(f.Page > 0, "page", "must be greater than zero")
-	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
-	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
-	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
+	v.CheckCode(f.Page > 0, "page", "too_small", "must be greater than zero")
+	v.CheckCode(f.Page <= 10_000_000, "page", "too_large", "must be a maximum of 10 million")
+	v.CheckCode(f.PageSize > 0, "page_size", "too_small", "must be greater than zero")
+	v.CheckCode(f.PageSize <= 100, "page_size", "too_large", "must be a maximum of 100")
 	// Check that the sort parameter matches a value in the safelist.
-	v.Check(validator.PermittedValue(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+	v.CheckCode(validator.PermittedValue(f.Sort, f.SortSafelist...), "sort", "invalid_value", "invalid sort value")
 }
 
 func (f Filters) sortColumn() string {
@@ -53,6 +60,31 @@ func (f Filters) offset() int {
 	return (f.Page - 1) * f.PageSize
 }
 
+// SetLinks fills in the navigation URLs from the URL of the listing endpoint
+// and the query parameters of the current request, so every filter is carried
+// over and only the page changes. Empty metadata gets no links.
+func (m *Metadata) SetLinks(endpoint string, query url.Values) {
+	if m.CurrentPage == 0 {
+		return
+	}
+	link := func(page int) string {
+		q := maps.Clone(query)
+		if q == nil {
+			q = url.Values{}
+		}
+		q.Set("page", strconv.Itoa(page))
+		return endpoint + "?" + q.Encode()
+	}
+	m.First = link(m.FirstPage)
+	m.Last = link(m.LastPage)
+	if m.CurrentPage > m.FirstPage {
+		m.Prev = link(min(m.CurrentPage-1, m.LastPage))
+	}
+	if m.CurrentPage < m.LastPage {
+		m.Next = link(m.CurrentPage + 1)
+	}
+}
+
 func calculateMetadata(totalRecords, page, pageSize int) Metadata {
 	if totalRecords == 0 {
 		// Note that we return an empty Metadata struct if there are no records.
@@ -0,0 +1,97 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+// IMDbMovie is a movie imported from the IMDb datasets. IMDbID is the number
+// in its tconst, so 1375666 for tt1375666.
+type IMDbMovie struct {
+	IMDbID int64
+	Movie  *Movie
+}
+
+// CopyIMDbMovies adds movies in one transaction, streaming them with COPY into
+// a temporary table which is then merged into movies. Movies which were
+// already imported are skipped, so a batch can be retried safely. It returns
+// the number of movies added.
+//
+// COPY needs a prepared statement, which the Models don't offer, so this
+// takes the *sql.DB directly.
+func CopyIMDbMovies(ctx context.Context, db *sql.DB, movies []*IMDbMovie) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		CREATE TEMPORARY TABLE imdb_import (
+			imdb_id integer,
+			title text,
+			year integer,
+			runtime integer,
+			genres text[]
+		) ON COMMIT DROP`
+	_, err = tx.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("imdb_import", "imdb_id", "title", "year", "runtime", "genres"))
+	if err != nil {
+		return 0, err
+	}
+	for _, movie := range movies {
+		_, err = stmt.ExecContext(ctx, movie.IMDbID, movie.Movie.Title, movie.Movie.Year, movie.Movie.Runtime, pq.Array(movie.Movie.Genres))
+		if err != nil {
+			stmt.Close()
+			return 0, err
+		}
+	}
+	// An Exec without arguments flushes the COPY.
+	_, err = stmt.ExecContext(ctx)
+	if err != nil {
+		stmt.Close()
+		return 0, err
+	}
+	err = stmt.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	query = `
+		INSERT INTO movies (imdb_id, title, year, runtime, genres)
+		SELECT imdb_id, title, year, runtime, genres
+		FROM imdb_import
+		ON CONFLICT (imdb_id) DO NOTHING`
+	result, err := tx.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	added, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return added, tx.Commit()
+}
+
+// LastIMDbID returns the highest IMDb ID imported so far, or 0 if none has
+// been.
+func LastIMDbID(ctx context.Context, db *sql.DB) (int64, error) {
+	query := `
+		SELECT coalesce(max(imdb_id), 0)
+		FROM movies`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var id int64
+	err := db.QueryRowContext(ctx, query).Scan(&id)
+	return id, err
+}
@@ -0,0 +1,246 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"github.com/lib/pq"
+	"slices"
+	"time"
+)
+
+// Roles maps the roles an invitation can grant to the permissions given to
+// the new user when it is accepted. Editors and admins hold
+// invitations:write, so they can invite others into the roles they could
+// have granted.
+var Roles = map[string]Permissions{
+	"member":    {},
+	"editor":    {"invitations:write", "movies:review"},
+	"moderator": {"admin:read", "movies:review"},
+	"admin":     {"admin:read", "admin:write", "invitations:write", "movies:review"},
+}
+
+// Invitation is a pending offer for someone to create an account. Like a
+// Token, only the hash of the plaintext is stored; Plaintext is set just
+// after the invitation is created so it can be emailed.
+type Invitation struct {
+	ID         int64      `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	InvitedBy  int64      `json:"invited_by,omitzero"`
+	Expiry     time.Time  `json:"expiry"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	AcceptedBy int64      `json:"accepted_by,omitzero"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Plaintext  string     `json:"-"`
+	Hash       []byte     `json:"-"`
+}
+
+// CanBeGrantedBy reports whether a user holding permissions may invite someone
+// into the role, which is only the case if they already have every permission
+// it grants.
+func (i *Invitation) CanBeGrantedBy(permissions Permissions) bool {
+	return !slices.ContainsFunc(Roles[i.Role], func(code string) bool { return !permissions.Include(code) })
+}
+
+func ValidateInvitation(v *validator.Validator, invitation *Invitation) {
+	ValidateEmail(v, invitation.Email)
+	_, ok := Roles[invitation.Role]
+	v.CheckCode(invitation.Role != "", "role", "required", "must be provided")
+	v.CheckCode(invitation.Role == "" || ok, "role", "invalid_value", "invalid role value")
+}
+
+type InvitationModel struct {
+	DB DBTX
+}
+
+// New creates an invitation for the given email address and role, valid for
+// ttl.
+func (m InvitationModel) New(ctx context.Context, email, role string, invitedBy int64, ttl time.Duration) (*Invitation, error) {
+	invitation := &Invitation{
+		Email:     email,
+		Role:      role,
+		InvitedBy: invitedBy,
+		Expiry:    time.Now().Add(ttl),
+		Plaintext: rand.Text(),
+	}
+	invitation.Hash = tokenHash(invitation.Plaintext)
+
+	query := `
+		INSERT INTO invitations (email, role, hash, invited_by, expiry)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+	invitedByID := sql.NullInt64{Int64: invitedBy, Valid: invitedBy != 0}
+	args := []any{invitation.Email, invitation.Role, invitation.Hash, invitedByID, invitation.Expiry}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&invitation.ID, &invitation.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// GetForToken returns the pending invitation with the given plaintext token.
+// Accepted, revoked and expired invitations are reported as ErrRecordNotFound.
+func (m InvitationModel) GetForToken(ctx context.Context, tokenPlaintext string) (*Invitation, error) {
+	query := `
+		SELECT id, created_at, email, role, COALESCE(invited_by, 0), expiry
+		FROM invitations
+		WHERE hash = ANY($1)
+		AND accepted_at IS NULL
+		AND revoked_at IS NULL
+		AND expiry > $2`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var invitation Invitation
+	err := m.DB.QueryRowContext(ctx, query, pq.ByteaArray(tokenHashes(tokenPlaintext)), time.Now()).Scan(
+		&invitation.ID,
+		&invitation.CreatedAt,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.InvitedBy,
+		&invitation.Expiry,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &invitation, nil
+}
+
+// GetPending returns the pending invitation with the given ID. Accepted,
+// revoked and expired invitations are reported as ErrRecordNotFound.
+func (m InvitationModel) GetPending(ctx context.Context, id int64) (*Invitation, error) {
+	query := `
+		SELECT id, created_at, email, role, COALESCE(invited_by, 0), expiry
+		FROM invitations
+		WHERE id = $1
+		AND accepted_at IS NULL
+		AND revoked_at IS NULL
+		AND expiry > $2`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var invitation Invitation
+	err := m.DB.QueryRowContext(ctx, query, id, time.Now()).Scan(
+		&invitation.ID,
+		&invitation.CreatedAt,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.InvitedBy,
+		&invitation.Expiry,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &invitation, nil
+}
+
+// GetAllPending lists the invitations which can still be accepted.
+func (m InvitationModel) GetAllPending(ctx context.Context, filters Filters) ([]*Invitation, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, created_at, email, role, COALESCE(invited_by, 0), expiry
+		FROM invitations
+		WHERE accepted_at IS NULL
+		AND revoked_at IS NULL
+		AND expiry > $1
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, time.Now(), filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	invitations := []*Invitation{}
+	for rows.Next() {
+		var invitation Invitation
+		err := rows.Scan(
+			&totalRecords,
+			&invitation.ID,
+			&invitation.CreatedAt,
+			&invitation.Email,
+			&invitation.Role,
+			&invitation.InvitedBy,
+			&invitation.Expiry,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		invitations = append(invitations, &invitation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return invitations, metadata, nil
+}
+
+// Revoke withdraws a pending invitation. It returns ErrRecordNotFound if there
+// is no such invitation or it can no longer be accepted.
+func (m InvitationModel) Revoke(ctx context.Context, id int64) error {
+	query := `
+		UPDATE invitations
+		SET revoked_at = NOW()
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expiry > NOW()`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// MarkAccepted records that the invitation was used to create the user with
+// the given ID. It returns ErrEditConflict if the invitation was accepted or
+// revoked in the meantime.
+func (m InvitationModel) MarkAccepted(ctx context.Context, id, userID int64) error {
+	query := `
+		UPDATE invitations
+		SET accepted_at = NOW(), accepted_by = $2
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+	return nil
+}
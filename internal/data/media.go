@@ -0,0 +1,192 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"github.com/lib/pq"
+	"time"
+)
+
+const (
+	MediaTrailer   = "trailer"
+	MediaClip      = "clip"
+	MediaPosterAlt = "poster-alt"
+)
+
+// MediaTypes are the kinds of media a movie can link to.
+var MediaTypes = []string{MediaTrailer, MediaClip, MediaPosterAlt}
+
+// Media is a link from a movie to a trailer, clip or alternative poster
+// hosted elsewhere, such as on YouTube. Language is the language tag of the
+// media's audio or text, if it has one.
+type Media struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	MovieID   int64     `json:"movie_id"`
+	Type      string    `json:"type"`
+	Provider  string    `json:"provider"`
+	URL       string    `json:"url"`
+	Language  string    `json:"language,omitzero"`
+	Version   int32     `json:"version"`
+}
+
+func ValidateMedia(v *validator.Validator, media *Media) {
+	v.CheckCode(media.Type != "", "type", "required", "must be provided")
+	v.CheckCode(validator.PermittedValue(media.Type, MediaTypes...), "type", "invalid_value", "must be one of trailer, clip or poster-alt")
+	v.CheckCode(media.Provider != "", "provider", "required", "must be provided")
+	v.CheckCode(len(media.Provider) <= 100, "provider", "too_long", "must not be more than 100 bytes long")
+	v.CheckCode(media.URL != "", "url", "required", "must be provided")
+	v.CheckCode(len(media.URL) <= 2000, "url", "too_long", "must not be more than 2000 bytes long")
+	v.CheckCode(validator.WebURL(media.URL), "url", "invalid_url", "must be an http or https URL")
+	if media.Language != "" {
+		v.CheckCode(LocaleRX.MatchString(media.Language), "language", "invalid_value", "must be a language tag such as fr or pt-BR")
+	}
+}
+
+type MediaModel struct {
+	DB DBTX
+}
+
+// Insert adds a media link to a movie. It returns ErrRecordNotFound if the
+// movie has been deleted.
+func (m MediaModel) Insert(ctx context.Context, media *Media) error {
+	query := `
+		INSERT INTO movie_media (movie_id, type, provider, url, language)
+		SELECT id, $2, $3, $4, $5 FROM movies WHERE id = $1
+		RETURNING id, created_at, version`
+	args := []any{media.MovieID, media.Type, media.Provider, media.URL, media.Language}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&media.ID, &media.CreatedAt, &media.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns one of the movie's media links.
+func (m MediaModel) Get(ctx context.Context, movieID, id int64) (*Media, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `
+		SELECT id, created_at, movie_id, type, provider, url, language, version
+		FROM movie_media
+		WHERE id = $1 AND movie_id = $2`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var media Media
+	err := m.DB.QueryRowContext(ctx, query, id, movieID).Scan(
+		&media.ID,
+		&media.CreatedAt,
+		&media.MovieID,
+		&media.Type,
+		&media.Provider,
+		&media.URL,
+		&media.Language,
+		&media.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &media, nil
+}
+
+// GetForMovies returns the media links of the given movies, ordered by movie
+// and then in the order they were added.
+func (m MediaModel) GetForMovies(ctx context.Context, movieIDs []int64) ([]*Media, error) {
+	query := `
+		SELECT id, created_at, movie_id, type, provider, url, language, version
+		FROM movie_media
+		WHERE movie_id = ANY($1)
+		ORDER BY movie_id, id`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []*Media{}
+	for rows.Next() {
+		var media Media
+		err := rows.Scan(
+			&media.ID,
+			&media.CreatedAt,
+			&media.MovieID,
+			&media.Type,
+			&media.Provider,
+			&media.URL,
+			&media.Language,
+			&media.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, &media)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (m MediaModel) Update(ctx context.Context, media *Media) error {
+	query := `
+		UPDATE movie_media
+		SET type = $1, provider = $2, url = $3, language = $4, version = version + 1
+		WHERE id = $5 AND movie_id = $6 AND version = $7
+		RETURNING version`
+	args := []any{media.Type, media.Provider, media.URL, media.Language, media.ID, media.MovieID, media.Version}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&media.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m MediaModel) Delete(ctx context.Context, movieID, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `DELETE FROM movie_media WHERE id = $1 AND movie_id = $2`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, movieID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
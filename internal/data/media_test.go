@@ -0,0 +1,72 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/datatest"
+	"testing"
+)
+
+func TestMediaModel(t *testing.T) {
+	db := datatest.OpenDB(t)
+	ctx := context.Background()
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		movie := datatest.InsertMovie(t, m, datatest.NewMovie())
+		other := datatest.InsertMovie(t, m, datatest.NewMovie())
+
+		trailer := &data.Media{MovieID: movie.ID, Type: data.MediaTrailer, Provider: "YouTube", URL: "https://www.youtube.com/watch?v=BkL9l7qovsE", Language: "en"}
+		err := m.Media.Insert(ctx, trailer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trailer.ID == 0 || trailer.Version != 1 {
+			t.Fatalf("got id %d, version %d; want an id and version 1", trailer.ID, trailer.Version)
+		}
+		err = m.Media.Insert(ctx, &data.Media{MovieID: other.ID, Type: data.MediaClip, Provider: "Vimeo", URL: "https://vimeo.com/1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = m.Media.Insert(ctx, &data.Media{MovieID: -1, Type: data.MediaClip, Provider: "Vimeo", URL: "https://vimeo.com/2"})
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("got error %v for a missing movie; want ErrRecordNotFound", err)
+		}
+
+		links, err := m.Media.GetForMovies(ctx, []int64{movie.ID, other.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(links) != 2 || links[0].MovieID != movie.ID || links[1].MovieID != other.ID {
+			t.Errorf("got %+v; want one link for each movie, in movie order", links)
+		}
+
+		_, err = m.Media.Get(ctx, other.ID, trailer.ID)
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("got error %v reading through another movie; want ErrRecordNotFound", err)
+		}
+		got, err := m.Media.Get(ctx, movie.ID, trailer.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stale := *got
+		got.Language = "fr"
+		err = m.Media.Update(ctx, got)
+		if err != nil || got.Version != 2 {
+			t.Fatalf("got error %v, version %d after update; want version 2", err, got.Version)
+		}
+		err = m.Media.Update(ctx, &stale)
+		if !errors.Is(err, data.ErrEditConflict) {
+			t.Errorf("got %v updating a stale link; want ErrEditConflict", err)
+		}
+
+		err = m.Media.Delete(ctx, movie.ID, trailer.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = m.Media.Delete(ctx, movie.ID, trailer.ID)
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("got %v deleting twice; want ErrRecordNotFound", err)
+		}
+	})
+}
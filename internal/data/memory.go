@@ -0,0 +1,1392 @@
+package data
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// memoryDB holds the records for the in-memory stores used by demo mode. All
+// stores share one instance so that, for example, tokens can be joined to
+// users. Records are copied on the way in and out so that callers mutating a
+// returned value behave as they would against PostgreSQL.
+type memoryDB struct {
+	mu          sync.Mutex
+	movies      []*Movie
+	revisions   []*MovieRevision
+	embeddings  map[int64]*memoryEmbedding
+	users       []*User
+	tokens      []*Token
+	permissions map[int64]Permissions
+	preferences map[int64]*Preferences
+	digests     map[int64]time.Time
+	emails      []*Email
+	invitations []*Invitation
+	suggestions []*Suggestion
+	media       []*Media
+	audit       []*AuditEvent
+	views       map[movieViewKey]int
+	viewStats   map[movieViewKey]*DailyViews
+	usage       map[userDayKey]*DailyUsage
+	nextID      int64
+}
+
+func (db *memoryDB) id() int64 {
+	db.nextID++
+	return db.nextID
+}
+
+// NewMemoryModels returns Models backed by in-memory stores, seeded with a
+// handful of movies and an activated admin user with the given credentials.
+// Nothing is persisted between runs.
+func NewMemoryModels(seedEmail, seedPassword string) (Models, error) {
+	db := &memoryDB{
+		permissions: make(map[int64]Permissions),
+		preferences: make(map[int64]*Preferences),
+		embeddings:  make(map[int64]*memoryEmbedding),
+		digests:     make(map[int64]time.Time),
+		views:       make(map[movieViewKey]int),
+		viewStats:   make(map[movieViewKey]*DailyViews),
+		usage:       make(map[userDayKey]*DailyUsage),
+	}
+	models := Models{
+		Audit:       memoryAuditStore{db},
+		Emails:      memoryEmailStore{db},
+		Invitations: memoryInvitationStore{db},
+		Media:       memoryMediaStore{db},
+		Movies:      memoryMovieStore{db},
+		Permissions: memoryPermissionStore{db},
+		Preferences: memoryPreferenceStore{db},
+		Suggestions: memorySuggestionStore{db},
+		Tokens:      memoryTokenStore{db},
+		Usage:       memoryUsageStore{db},
+		Users:       memoryUserStore{db},
+		Views:       memoryViewStore{db},
+	}
+
+	ctx := context.Background()
+	seedMovies := []*Movie{
+		{Title: "Casablanca", Year: 1942, Runtime: 102, Genres: []string{"drama", "romance", "war"}, CertificationSystem: "MPA", Certification: "PG"},
+		{Title: "The Breakfast Club", Year: 1985, Runtime: 97, Genres: []string{"comedy", "drama"}, CertificationSystem: "MPA", Certification: "R"},
+		{Title: "Moana", Year: 2016, Runtime: 107, Genres: []string{"animation", "adventure"}, CertificationSystem: "MPA", Certification: "PG"},
+		{Title: "Black Panther", Year: 2018, Runtime: 134, Genres: []string{"action", "adventure", "sci-fi"}, CertificationSystem: "MPA", Certification: "PG-13"},
+		{Title: "Deadpool", Year: 2016, Runtime: 108, Genres: []string{"action", "comedy"}, CertificationSystem: "MPA", Certification: "R"},
+		{Title: "The Shawshank Redemption", Year: 1994, Runtime: 142, Genres: []string{"drama"}, CertificationSystem: "MPA", Certification: "R"},
+	}
+	for _, movie := range seedMovies {
+		err := models.Movies.Insert(ctx, movie)
+		if err != nil {
+			return Models{}, err
+		}
+	}
+
+	user := &User{Name: "Demo Admin", Email: seedEmail, Activated: true, Language: SupportedLanguages[0]}
+	err := user.Password.Set(seedPassword, DefaultPasswordParams())
+	if err != nil {
+		return Models{}, err
+	}
+	err = models.Users.Insert(ctx, user)
+	if err != nil {
+		return Models{}, err
+	}
+	err = models.Permissions.AddForUser(ctx, user.ID, "admin:read", "admin:write", "invitations:write", "movies:review")
+	if err != nil {
+		return Models{}, err
+	}
+	return models, nil
+}
+
+// paginate sorts records using the filter's sort column, then applies the
+// page and page size, mirroring the ORDER BY/LIMIT/OFFSET of the SQL models.
+func paginate[T any](records []T, filters Filters, columns map[string]func(a, b T) int, id func(T) int64) ([]T, Metadata) {
+	compare := columns[filters.sortColumn()]
+	slices.SortStableFunc(records, func(a, b T) int {
+		c := compare(a, b)
+		if filters.sortDirection() == "DESC" {
+			c = -c
+		}
+		if c == 0 {
+			c = cmp.Compare(id(a), id(b))
+		}
+		return c
+	})
+	metadata := calculateMetadata(len(records), filters.Page, filters.PageSize)
+	start := min(filters.offset(), len(records))
+	end := min(start+filters.limit(), len(records))
+	return records[start:end], metadata
+}
+
+type memoryMovieStore struct{ db *memoryDB }
+
+func copyMovie(movie *Movie) *Movie {
+	c := *movie
+	c.Genres = slices.Clone(movie.Genres)
+	c.Titles = maps.Clone(movie.Titles)
+	c.Poster.Sizes = maps.Clone(movie.Poster.Sizes)
+	if movie.ReleaseDate != nil {
+		releaseDate := *movie.ReleaseDate
+		c.ReleaseDate = &releaseDate
+	}
+	return &c
+}
+
+func (s memoryMovieStore) Insert(ctx context.Context, movie *Movie) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	movie.ID = s.db.id()
+	movie.CreatedAt = time.Now()
+	movie.Version = 1
+	movie.Poster = Poster{}
+	s.db.movies = append(s.db.movies, copyMovie(movie))
+	s.db.recordRevision(movie)
+	return nil
+}
+
+func (s memoryMovieStore) InsertMany(ctx context.Context, movies []*Movie) error {
+	for _, movie := range movies {
+		err := s.Insert(ctx, movie)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s memoryMovieStore) Get(ctx context.Context, id int64) (*Movie, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, movie := range s.db.movies {
+		if movie.ID == id {
+			return copyMovie(movie), nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+func (s memoryMovieStore) GetMany(ctx context.Context, ids []int64) ([]*Movie, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	movies := []*Movie{}
+	for _, movie := range s.db.movies {
+		if slices.Contains(ids, movie.ID) {
+			movies = append(movies, copyMovie(movie))
+		}
+	}
+	return movies, nil
+}
+
+func (s memoryMovieStore) Update(ctx context.Context, movie *Movie) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for i, existing := range s.db.movies {
+		if existing.ID == movie.ID && existing.Version == movie.Version {
+			movie.Version++
+			// Posters are stored apart from the movie's fields, as in
+			// PostgreSQL, so an update from a stale read keeps the current
+			// one.
+			movie.Poster = existing.Poster
+			s.db.movies[i] = copyMovie(movie)
+			s.db.recordRevision(movie)
+			return nil
+		}
+	}
+	return ErrEditConflict
+}
+
+func (s memoryMovieStore) Delete(ctx context.Context, id int64) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for i, movie := range s.db.movies {
+		if movie.ID == id {
+			s.db.movies = slices.Delete(s.db.movies, i, i+1)
+			s.db.revisions = slices.DeleteFunc(s.db.revisions, func(r *MovieRevision) bool { return r.ID == id })
+			delete(s.db.embeddings, id)
+			s.db.suggestions = slices.DeleteFunc(s.db.suggestions, func(su *Suggestion) bool { return su.MovieID == id })
+			s.db.media = slices.DeleteFunc(s.db.media, func(m *Media) bool { return m.MovieID == id })
+			return nil
+		}
+	}
+	return ErrRecordNotFound
+}
+
+// matchesTitle approximates the 'simple' full-text search used by the SQL
+// model: every word of the query must appear as a word in the title.
+func matchesTitle(title, query string) bool {
+	words := strings.Fields(strings.ToLower(title))
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !slices.Contains(words, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// movieTitles returns every title the movie is known by.
+func movieTitles(movie *Movie) []string {
+	titles := []string{movie.Title}
+	if movie.OriginalTitle != "" {
+		titles = append(titles, movie.OriginalTitle)
+	}
+	for _, title := range movie.Titles {
+		titles = append(titles, title)
+	}
+	return titles
+}
+
+// trigrams returns the trigrams of the words in s, padded as pg_trgm pads
+// them.
+func trigrams(s string) map[string]bool {
+	set := map[string]bool{}
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// wordSimilarity approximates pg_trgm's word_similarity: the share of the
+// search's trigrams found in the title.
+func wordSimilarity(search, title string) float64 {
+	want := trigrams(search)
+	if len(want) == 0 {
+		return 0
+	}
+	have := trigrams(title)
+	found := 0
+	for trigram := range want {
+		if have[trigram] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(want))
+}
+
+func (s memoryMovieStore) SearchTitles(ctx context.Context, search string, threshold float64, limit int) ([]*TitleMatch, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	type ranked struct {
+		*TitleMatch
+		exact bool
+	}
+	var found []ranked
+	for _, movie := range s.db.movies {
+		exact, score := false, 0.0
+		for _, title := range movieTitles(movie) {
+			exact = exact || (strings.TrimSpace(search) != "" && matchesTitle(title, search))
+			score = max(score, wordSimilarity(search, title))
+		}
+		if exact || score >= threshold {
+			found = append(found, ranked{&TitleMatch{Movie: copyMovie(movie), Score: score}, exact})
+		}
+	}
+	slices.SortFunc(found, func(a, b ranked) int {
+		if a.exact != b.exact {
+			if a.exact {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(cmp.Compare(b.Score, a.Score), strings.Compare(a.Title, b.Title), cmp.Compare(a.ID, b.ID))
+	})
+	matches := []*TitleMatch{}
+	for _, match := range found[:min(limit, len(found))] {
+		matches = append(matches, match.TitleMatch)
+	}
+	return matches, nil
+}
+
+func (s memoryMovieStore) GetAll(ctx context.Context, title string, genres []string, certificationSystem, certification string, releasedAfter, releasedBefore *Date, filters Filters) ([]*Movie, Metadata, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	movies := []*Movie{}
+	for _, movie := range s.db.movies {
+		if !slices.ContainsFunc(movieTitles(movie), func(t string) bool { return matchesTitle(t, title) }) {
+			continue
+		}
+		if !containsAll(movie.Genres, genres) {
+			continue
+		}
+		if certificationSystem != "" && movie.CertificationSystem != certificationSystem {
+			continue
+		}
+		if certification != "" && movie.Certification != certification {
+			continue
+		}
+		if releasedAfter != nil && (movie.ReleaseDate == nil || !movie.ReleaseDate.After(releasedAfter.Time)) {
+			continue
+		}
+		if releasedBefore != nil && (movie.ReleaseDate == nil || !movie.ReleaseDate.Before(releasedBefore.Time)) {
+			continue
+		}
+		movies = append(movies, copyMovie(movie))
+	}
+	columns := map[string]func(a, b *Movie) int{
+		"id":      func(a, b *Movie) int { return cmp.Compare(a.ID, b.ID) },
+		"title":   func(a, b *Movie) int { return strings.Compare(a.Title, b.Title) },
+		"year":    func(a, b *Movie) int { return cmp.Compare(a.Year, b.Year) },
+		"runtime": func(a, b *Movie) int { return cmp.Compare(a.Runtime, b.Runtime) },
+		"release_date": func(a, b *Movie) int {
+			return compareReleaseDates(a.ReleaseDate, b.ReleaseDate, filters.sortDirection() == "DESC")
+		},
+	}
+	movies, metadata := paginate(movies, filters, columns, func(m *Movie) int64 { return m.ID })
+	return movies, metadata, nil
+}
+
+func (s memoryMovieStore) GetAddedSince(ctx context.Context, since time.Time, genres []string, limit int) ([]*Movie, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	movies := []*Movie{}
+	for i := len(s.db.movies) - 1; i >= 0 && len(movies) < limit; i-- {
+		movie := s.db.movies[i]
+		if !movie.CreatedAt.After(since) {
+			continue
+		}
+		if len(genres) > 0 && !slices.ContainsFunc(movie.Genres, func(g string) bool { return slices.Contains(genres, g) }) {
+			continue
+		}
+		movies = append(movies, copyMovie(movie))
+	}
+	return movies, nil
+}
+
+func (s memoryMovieStore) SearchGenres(ctx context.Context, search string, limit int) ([]*GenreCount, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	counts := map[string]int{}
+	for _, movie := range s.db.movies {
+		for _, genre := range movie.Genres {
+			if strings.Contains(strings.ToLower(genre), strings.ToLower(search)) {
+				counts[genre]++
+			}
+		}
+	}
+	genres := []*GenreCount{}
+	for name, movies := range counts {
+		genres = append(genres, &GenreCount{Name: name, Movies: movies})
+	}
+	slices.SortFunc(genres, func(a, b *GenreCount) int {
+		return cmp.Or(cmp.Compare(b.Movies, a.Movies), strings.Compare(a.Name, b.Name))
+	})
+	return genres[:min(limit, len(genres))], nil
+}
+
+func (s memoryMovieStore) GetCatalogStats(ctx context.Context) (*CatalogStats, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	now := time.Now()
+	stats := CatalogStats{Movies: len(s.db.movies), ComputedAt: now}
+	genres := map[string]int{}
+	decades := map[int32]int{}
+	var runtimes, timed int
+	for _, movie := range s.db.movies {
+		for _, genre := range movie.Genres {
+			genres[genre]++
+		}
+		decades[movie.Year/10*10]++
+		if movie.Runtime > 0 {
+			runtimes += int(movie.Runtime)
+			timed++
+		}
+		if movie.CreatedAt.After(now.AddDate(0, 0, -7)) {
+			stats.AddedLastWeek++
+		}
+		if movie.CreatedAt.After(now.AddDate(0, 0, -30)) {
+			stats.AddedLastMonth++
+		}
+	}
+	if timed > 0 {
+		stats.AverageRuntime = float64(runtimes) / float64(timed)
+	}
+
+	stats.Genres = []*GenreCount{}
+	for name, movies := range genres {
+		stats.Genres = append(stats.Genres, &GenreCount{Name: name, Movies: movies})
+	}
+	slices.SortFunc(stats.Genres, func(a, b *GenreCount) int {
+		return cmp.Or(cmp.Compare(b.Movies, a.Movies), strings.Compare(a.Name, b.Name))
+	})
+	stats.Decades = []*DecadeCount{}
+	for decade, movies := range decades {
+		stats.Decades = append(stats.Decades, &DecadeCount{Decade: decade, Movies: movies})
+	}
+	slices.SortFunc(stats.Decades, func(a, b *DecadeCount) int {
+		return cmp.Compare(a.Decade, b.Decade)
+	})
+	return &stats, nil
+}
+
+// compareReleaseDates orders release dates, putting movies with no release
+// date last whichever the sort direction, like NULLS LAST in the SQL model.
+// paginate negates the result for descending sorts, so desc pre-empts that.
+func compareReleaseDates(a, b *Date, desc bool) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil || b == nil:
+		c := 1
+		if a != nil {
+			c = -1
+		}
+		if desc {
+			c = -c
+		}
+		return c
+	default:
+		return a.Compare(b.Time)
+	}
+}
+
+// recordRevision does the job of the SQL trigger which records every version
+// of a movie in movie_revisions.
+func (db *memoryDB) recordRevision(movie *Movie) {
+	revision := &MovieRevision{RevisedAt: time.Now(), Movie: *copyMovie(movie)}
+	revision.Poster = Poster{}
+	db.revisions = append(db.revisions, revision)
+}
+
+func copyRevision(revision *MovieRevision) *MovieRevision {
+	return &MovieRevision{RevisedAt: revision.RevisedAt, Movie: *copyMovie(&revision.Movie)}
+}
+
+func (s memoryMovieStore) SetPoster(ctx context.Context, movieID int64, poster *Poster) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, movie := range s.db.movies {
+		if movie.ID == movieID {
+			movie.Poster = *poster
+			movie.Poster.Sizes = maps.Clone(poster.Sizes)
+			return nil
+		}
+	}
+	return ErrRecordNotFound
+}
+
+func (s memoryMovieStore) UpdatePoster(ctx context.Context, movieID int64, poster *Poster) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, movie := range s.db.movies {
+		if movie.ID == movieID && movie.Poster.Status != "" && movie.Poster.Version == poster.Version {
+			movie.Poster = *poster
+			movie.Poster.Sizes = maps.Clone(poster.Sizes)
+			return nil
+		}
+	}
+	return ErrEditConflict
+}
+
+func (s memoryMovieStore) RevertPoster(ctx context.Context, movieID int64, version string, previous *Poster) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, movie := range s.db.movies {
+		if movie.ID == movieID && movie.Poster.Status != "" && movie.Poster.Version == version {
+			movie.Poster = *previous
+			movie.Poster.Sizes = maps.Clone(previous.Sizes)
+			return nil
+		}
+	}
+	return ErrEditConflict
+}
+
+type memoryEmbedding struct {
+	version   int32
+	model     string
+	embedding []float32
+}
+
+func (s memoryMovieStore) GetSimilarByGenre(ctx context.Context, id int64, limit int) ([]*SimilarMovie, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	var target *Movie
+	for _, movie := range s.db.movies {
+		if movie.ID == id {
+			target = movie
+		}
+	}
+	movies := []*SimilarMovie{}
+	if target == nil {
+		return movies, nil
+	}
+	for _, movie := range s.db.movies {
+		if movie.ID == id {
+			continue
+		}
+		shared := 0
+		for _, genre := range movie.Genres {
+			if slices.Contains(target.Genres, genre) {
+				shared++
+			}
+		}
+		if shared > 0 {
+			score := float64(shared) / float64(len(movie.Genres)+len(target.Genres)-shared)
+			movies = append(movies, &SimilarMovie{Movie: copyMovie(movie), Score: score})
+		}
+	}
+	return sortSimilar(movies, limit), nil
+}
+
+func (s memoryMovieStore) GetSimilarByEmbedding(ctx context.Context, id int64, model string, limit int) ([]*SimilarMovie, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	target, ok := s.db.embeddings[id]
+	if !ok || target.model != model {
+		return nil, ErrNoEmbedding
+	}
+	movies := []*SimilarMovie{}
+	for _, movie := range s.db.movies {
+		e, ok := s.db.embeddings[movie.ID]
+		if movie.ID == id || !ok || e.model != model {
+			continue
+		}
+		movies = append(movies, &SimilarMovie{Movie: copyMovie(movie), Score: cosineSimilarity(target.embedding, e.embedding)})
+	}
+	return sortSimilar(movies, limit), nil
+}
+
+// sortSimilar orders movies by descending score, then ID, and trims them to
+// limit, as the SQL model's ORDER BY/LIMIT do.
+func sortSimilar(movies []*SimilarMovie, limit int) []*SimilarMovie {
+	slices.SortFunc(movies, func(a, b *SimilarMovie) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return movies[:min(limit, len(movies))]
+}
+
+// cosineSimilarity mirrors 1 - (a <=> b) in pgvector.
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range min(len(a), len(b)) {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+func (s memoryMovieStore) GetUnembedded(ctx context.Context, model string, limit int) ([]*Movie, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	movies := []*Movie{}
+	for _, movie := range s.db.movies {
+		if len(movies) == limit {
+			break
+		}
+		e, ok := s.db.embeddings[movie.ID]
+		if !ok || e.model != model || e.version != movie.Version {
+			movies = append(movies, copyMovie(movie))
+		}
+	}
+	return movies, nil
+}
+
+func (s memoryMovieStore) SetEmbedding(ctx context.Context, movieID int64, version int32, model string, embedding []float32) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	if slices.ContainsFunc(s.db.movies, func(m *Movie) bool { return m.ID == movieID }) {
+		s.db.embeddings[movieID] = &memoryEmbedding{version: version, model: model, embedding: slices.Clone(embedding)}
+	}
+	return nil
+}
+
+func (s memoryMovieStore) GetRevisions(ctx context.Context, movieID int64) ([]*MovieRevision, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	revisions := []*MovieRevision{}
+	for i := len(s.db.revisions) - 1; i >= 0; i-- {
+		if s.db.revisions[i].ID == movieID {
+			revisions = append(revisions, copyRevision(s.db.revisions[i]))
+		}
+	}
+	return revisions, nil
+}
+
+func (s memoryMovieStore) GetRevision(ctx context.Context, movieID int64, version int32) (*MovieRevision, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, revision := range s.db.revisions {
+		if revision.ID == movieID && revision.Version == version {
+			return copyRevision(revision), nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+func containsAll(values, required []string) bool {
+	for _, r := range required {
+		if !slices.Contains(values, r) {
+			return false
+		}
+	}
+	return true
+}
+
+type memoryUserStore struct{ db *memoryDB }
+
+func copyUser(user *User) *User {
+	c := *user
+	return &c
+}
+
+func (s memoryUserStore) Insert(ctx context.Context, user *User) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, existing := range s.db.users {
+		if strings.EqualFold(existing.Email, user.Email) {
+			return ErrDuplicateEmail
+		}
+	}
+	user.ID = s.db.id()
+	user.CreatedAt = time.Now()
+	user.Version = 1
+	s.db.users = append(s.db.users, copyUser(user))
+	return nil
+}
+
+func (s memoryUserStore) Get(ctx context.Context, id int64) (*User, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, user := range s.db.users {
+		if user.ID == id {
+			return copyUser(user), nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+func (s memoryUserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, user := range s.db.users {
+		if strings.EqualFold(user.Email, email) {
+			return copyUser(user), nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+func (s memoryUserStore) Update(ctx context.Context, user *User) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, existing := range s.db.users {
+		if existing.ID != user.ID && strings.EqualFold(existing.Email, user.Email) {
+			return ErrDuplicateEmail
+		}
+	}
+	for i, existing := range s.db.users {
+		if existing.ID == user.ID && existing.Version == user.Version {
+			user.Version++
+			s.db.users[i] = copyUser(user)
+			return nil
+		}
+	}
+	return ErrEditConflict
+}
+
+func (s memoryUserStore) GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error) {
+	hashes := tokenHashes(tokenPlaintext)
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, token := range s.db.tokens {
+		matches := slices.ContainsFunc(hashes, func(hash []byte) bool { return bytes.Equal(token.Hash, hash) })
+		if !matches || token.Scope != tokenScope || !token.Expiry.After(time.Now()) {
+			continue
+		}
+		for _, user := range s.db.users {
+			if user.ID == token.UserID && !user.IsDeactivated() {
+				return copyUser(user), nil
+			}
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+func (s memoryUserStore) ActivateMany(ctx context.Context, ids []int64, emails []string) ([]*UserActivation, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	activations := []*UserActivation{}
+	for _, user := range s.db.users {
+		matched := slices.Contains(ids, user.ID) || slices.ContainsFunc(emails, func(email string) bool { return strings.EqualFold(email, user.Email) })
+		if !matched {
+			continue
+		}
+		activations = append(activations, &UserActivation{ID: user.ID, Email: user.Email, AlreadyActivated: user.Activated})
+		if !user.Activated {
+			user.Activated = true
+			user.Version++
+		}
+	}
+	return activations, nil
+}
+
+func (s memoryUserStore) GetCounts(ctx context.Context) (*UserCounts, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	counts := UserCounts{Total: len(s.db.users)}
+	for _, user := range s.db.users {
+		switch {
+		case user.DeactivatedAt != nil:
+			counts.Deactivated++
+		case user.Activated:
+			counts.Activated++
+		default:
+			counts.Pending++
+		}
+	}
+	return &counts, nil
+}
+
+func (s memoryUserStore) GetRegistrationsPerDay(ctx context.Context, since time.Time) ([]*DailyRegistrations, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	since = since.UTC().Truncate(24 * time.Hour)
+	counts := map[time.Time]int{}
+	for _, user := range s.db.users {
+		if !user.CreatedAt.Before(since) {
+			counts[user.CreatedAt.UTC().Truncate(24*time.Hour)]++
+		}
+	}
+	days := []*DailyRegistrations{}
+	for day, registrations := range counts {
+		days = append(days, &DailyRegistrations{Day: day, Date: day.Format(time.DateOnly), Registrations: registrations})
+	}
+	slices.SortFunc(days, func(a, b *DailyRegistrations) int { return a.Day.Compare(b.Day) })
+	return days, nil
+}
+
+type memoryTokenStore struct{ db *memoryDB }
+
+func (s memoryTokenStore) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error) {
+	token := generateToken(userID, ttl, scope)
+	err := s.Insert(ctx, token)
+	return token, err
+}
+
+func (s memoryTokenStore) Insert(ctx context.Context, token *Token) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	c := *token
+	c.Plaintext = ""
+	s.db.tokens = append(s.db.tokens, &c)
+	return nil
+}
+
+func (s memoryTokenStore) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	s.db.tokens = slices.DeleteFunc(s.db.tokens, func(t *Token) bool {
+		return t.Scope == scope && t.UserID == userID
+	})
+	return nil
+}
+
+func (s memoryTokenStore) CountActive(ctx context.Context) (map[string]int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	counts := map[string]int{}
+	now := time.Now()
+	for _, token := range s.db.tokens {
+		if token.Expiry.After(now) {
+			counts[token.Scope]++
+		}
+	}
+	return counts, nil
+}
+
+func (s memoryTokenStore) DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	var deleted int64
+	s.db.tokens = slices.DeleteFunc(s.db.tokens, func(token *Token) bool {
+		if deleted < int64(limit) && token.Expiry.Before(before) {
+			deleted++
+			return true
+		}
+		return false
+	})
+	return deleted, nil
+}
+
+// GetTableStats reports the number of tokens. The in-memory store has no
+// on-disk size.
+func (s memoryTokenStore) GetTableStats(ctx context.Context) (*TokenTableStats, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	return &TokenTableStats{Rows: int64(len(s.db.tokens))}, nil
+}
+
+type memoryPermissionStore struct{ db *memoryDB }
+
+func (s memoryPermissionStore) GetAllForUser(ctx context.Context, userID int64) (Permissions, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	return slices.Clone(s.db.permissions[userID]), nil
+}
+
+func (s memoryPermissionStore) AddForUser(ctx context.Context, userID int64, codes ...string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, code := range codes {
+		if !s.db.permissions[userID].Include(code) {
+			s.db.permissions[userID] = append(s.db.permissions[userID], code)
+		}
+	}
+	return nil
+}
+
+type memoryPreferenceStore struct{ db *memoryDB }
+
+func (s memoryPreferenceStore) Get(ctx context.Context, userID int64) (*Preferences, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	preferences, ok := s.db.preferences[userID]
+	if !ok {
+		return DefaultPreferences(userID), nil
+	}
+	c := *preferences
+	c.FavoriteGenres = slices.Clone(preferences.FavoriteGenres)
+	return &c, nil
+}
+
+func (s memoryPreferenceStore) Update(ctx context.Context, preferences *Preferences) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	existing, ok := s.db.preferences[preferences.UserID]
+	if ok && existing.Version != preferences.Version {
+		return ErrEditConflict
+	}
+	preferences.Version++
+	c := *preferences
+	c.FavoriteGenres = slices.Clone(preferences.FavoriteGenres)
+	s.db.preferences[preferences.UserID] = &c
+	return nil
+}
+
+func (s memoryPreferenceStore) GetDueForDigest(ctx context.Context, cutoff time.Time) ([]*DigestRecipient, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	recipients := []*DigestRecipient{}
+	for _, user := range s.db.users {
+		preferences, ok := s.db.preferences[user.ID]
+		if !ok || !user.Activated || user.IsDeactivated() || !preferences.WeeklyDigest {
+			continue
+		}
+		if last, ok := s.db.digests[user.ID]; ok && !last.Before(cutoff) {
+			continue
+		}
+		recipients = append(recipients, &DigestRecipient{
+			User:           copyUser(user),
+			FavoriteGenres: slices.Clone(preferences.FavoriteGenres),
+		})
+	}
+	return recipients, nil
+}
+
+func (s memoryPreferenceStore) MarkDigestSent(ctx context.Context, userID int64) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	s.db.digests[userID] = time.Now()
+	return nil
+}
+
+type memoryAuditStore struct{ db *memoryDB }
+
+func (s memoryAuditStore) Insert(ctx context.Context, event *AuditEvent) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	event.ID = s.db.id()
+	event.CreatedAt = time.Now()
+	c := *event
+	s.db.audit = append(s.db.audit, &c)
+	return nil
+}
+
+type memoryEmailStore struct{ db *memoryDB }
+
+func (s memoryEmailStore) Insert(ctx context.Context, email *Email) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	email.ID = s.db.id()
+	email.CreatedAt = time.Now()
+	email.UpdatedAt = email.CreatedAt
+	c := *email
+	s.db.emails = append(s.db.emails, &c)
+	return nil
+}
+
+func (s memoryEmailStore) UpdateStatus(ctx context.Context, email *Email) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, existing := range s.db.emails {
+		if existing.ID == email.ID {
+			email.UpdatedAt = time.Now()
+			existing.Status = email.Status
+			existing.MessageID = email.MessageID
+			existing.Error = email.Error
+			existing.UpdatedAt = email.UpdatedAt
+			return nil
+		}
+	}
+	return ErrRecordNotFound
+}
+
+func (s memoryEmailStore) GetAll(ctx context.Context, recipient string, status string, filters Filters) ([]*Email, Metadata, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	emails := []*Email{}
+	for _, email := range s.db.emails {
+		if recipient != "" && !strings.EqualFold(email.Recipient, recipient) {
+			continue
+		}
+		if status != "" && email.Status != status {
+			continue
+		}
+		c := *email
+		emails = append(emails, &c)
+	}
+	columns := map[string]func(a, b *Email) int{
+		"id":         func(a, b *Email) int { return cmp.Compare(a.ID, b.ID) },
+		"created_at": func(a, b *Email) int { return a.CreatedAt.Compare(b.CreatedAt) },
+		"status":     func(a, b *Email) int { return strings.Compare(a.Status, b.Status) },
+	}
+	emails, metadata := paginate(emails, filters, columns, func(e *Email) int64 { return e.ID })
+	return emails, metadata, nil
+}
+
+func (s memoryEmailStore) CountByStatus(ctx context.Context, since time.Time) (map[string]int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	counts := map[string]int{}
+	for _, email := range s.db.emails {
+		if !email.CreatedAt.Before(since) {
+			counts[email.Status]++
+		}
+	}
+	return counts, nil
+}
+
+type memoryInvitationStore struct{ db *memoryDB }
+
+// pending reports whether the invitation can still be accepted.
+func (s memoryInvitationStore) pending(invitation *Invitation) bool {
+	return invitation.AcceptedAt == nil && invitation.RevokedAt == nil && invitation.Expiry.After(time.Now())
+}
+
+func (s memoryInvitationStore) New(ctx context.Context, email, role string, invitedBy int64, ttl time.Duration) (*Invitation, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	plaintext := rand.Text()
+	invitation := &Invitation{
+		ID:        s.db.id(),
+		CreatedAt: time.Now(),
+		Email:     email,
+		Role:      role,
+		InvitedBy: invitedBy,
+		Expiry:    time.Now().Add(ttl),
+		Hash:      tokenHash(plaintext),
+	}
+	c := *invitation
+	s.db.invitations = append(s.db.invitations, &c)
+	invitation.Plaintext = plaintext
+	return invitation, nil
+}
+
+func (s memoryInvitationStore) GetForToken(ctx context.Context, tokenPlaintext string) (*Invitation, error) {
+	hashes := tokenHashes(tokenPlaintext)
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, invitation := range s.db.invitations {
+		matches := slices.ContainsFunc(hashes, func(hash []byte) bool { return bytes.Equal(invitation.Hash, hash) })
+		if matches && s.pending(invitation) {
+			c := *invitation
+			return &c, nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+func (s memoryInvitationStore) GetPending(ctx context.Context, id int64) (*Invitation, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, invitation := range s.db.invitations {
+		if invitation.ID == id && s.pending(invitation) {
+			c := *invitation
+			return &c, nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+func (s memoryInvitationStore) GetAllPending(ctx context.Context, filters Filters) ([]*Invitation, Metadata, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	invitations := []*Invitation{}
+	for _, invitation := range s.db.invitations {
+		if s.pending(invitation) {
+			c := *invitation
+			invitations = append(invitations, &c)
+		}
+	}
+	columns := map[string]func(a, b *Invitation) int{
+		"id":         func(a, b *Invitation) int { return cmp.Compare(a.ID, b.ID) },
+		"created_at": func(a, b *Invitation) int { return a.CreatedAt.Compare(b.CreatedAt) },
+		"email":      func(a, b *Invitation) int { return strings.Compare(strings.ToLower(a.Email), strings.ToLower(b.Email)) },
+		"expiry":     func(a, b *Invitation) int { return a.Expiry.Compare(b.Expiry) },
+	}
+	invitations, metadata := paginate(invitations, filters, columns, func(i *Invitation) int64 { return i.ID })
+	return invitations, metadata, nil
+}
+
+func (s memoryInvitationStore) Revoke(ctx context.Context, id int64) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, invitation := range s.db.invitations {
+		if invitation.ID == id && s.pending(invitation) {
+			now := time.Now()
+			invitation.RevokedAt = &now
+			return nil
+		}
+	}
+	return ErrRecordNotFound
+}
+
+func (s memoryInvitationStore) MarkAccepted(ctx context.Context, id, userID int64) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, invitation := range s.db.invitations {
+		if invitation.ID == id && invitation.AcceptedAt == nil && invitation.RevokedAt == nil {
+			now := time.Now()
+			invitation.AcceptedAt = &now
+			invitation.AcceptedBy = userID
+			return nil
+		}
+	}
+	return ErrEditConflict
+}
+
+// movieViewKey indexes raw views by movie, viewer and day, and rollups by
+// movie and day with an empty viewer.
+type movieViewKey struct {
+	movieID int64
+	viewer  string
+	day     time.Time
+}
+
+type memoryViewStore struct{ db *memoryDB }
+
+func (s memoryViewStore) RecordMany(ctx context.Context, views []ViewCount) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, view := range views {
+		s.db.views[movieViewKey{view.MovieID, view.Viewer, viewDay(view.At)}] += view.Hits
+	}
+	return nil
+}
+
+func (s memoryViewStore) Rollup(ctx context.Context, since time.Time) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	since = viewDay(since)
+	for key := range s.db.viewStats {
+		if !key.day.Before(since) {
+			delete(s.db.viewStats, key)
+		}
+	}
+	for key, hits := range s.db.views {
+		if key.day.Before(since) {
+			continue
+		}
+		statsKey := movieViewKey{movieID: key.movieID, day: key.day}
+		stats, ok := s.db.viewStats[statsKey]
+		if !ok {
+			stats = &DailyViews{Day: key.day, Date: key.day.Format(time.DateOnly)}
+			s.db.viewStats[statsKey] = stats
+		}
+		stats.Views += hits
+		stats.UniqueViewers++
+	}
+	return nil
+}
+
+func (s memoryViewStore) DeleteRawBefore(ctx context.Context, before time.Time) (int64, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	var deleted int64
+	for key := range s.db.views {
+		if key.day.Before(viewDay(before)) {
+			delete(s.db.views, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s memoryViewStore) GetDaily(ctx context.Context, movieID int64, since time.Time) ([]*DailyViews, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	days := []*DailyViews{}
+	for key, stats := range s.db.viewStats {
+		if key.movieID == movieID && !key.day.Before(viewDay(since)) {
+			c := *stats
+			days = append(days, &c)
+		}
+	}
+	slices.SortFunc(days, func(a, b *DailyViews) int { return a.Day.Compare(b.Day) })
+	return days, nil
+}
+
+type memoryUsageStore struct{ db *memoryDB }
+
+type userDayKey struct {
+	userID int64
+	day    time.Time
+}
+
+func (s memoryUsageStore) RecordMany(ctx context.Context, counts []UsageCount) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, count := range counts {
+		if !slices.ContainsFunc(s.db.users, func(u *User) bool { return u.ID == count.UserID }) {
+			continue
+		}
+		key := userDayKey{count.UserID, viewDay(count.At)}
+		usage, ok := s.db.usage[key]
+		if !ok {
+			usage = &DailyUsage{Day: key.day, Date: key.day.Format(time.DateOnly)}
+			s.db.usage[key] = usage
+		}
+		usage.Requests += count.Requests
+		usage.ClientErrors += count.ClientErrors
+		usage.ServerErrors += count.ServerErrors
+		usage.Bytes += count.Bytes
+	}
+	return nil
+}
+
+func (s memoryUsageStore) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	var deleted int64
+	for key := range s.db.usage {
+		if key.day.Before(viewDay(before)) {
+			delete(s.db.usage, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s memoryUsageStore) GetDaily(ctx context.Context, userID int64, since time.Time) ([]*DailyUsage, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	days := []*DailyUsage{}
+	for key, usage := range s.db.usage {
+		if key.userID == userID && !key.day.Before(viewDay(since)) {
+			c := *usage
+			days = append(days, &c)
+		}
+	}
+	slices.SortFunc(days, func(a, b *DailyUsage) int { return a.Day.Compare(b.Day) })
+	return days, nil
+}
+
+func (s memoryUsageStore) GetTop(ctx context.Context, since time.Time, sort string, limit int) ([]*UserUsage, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	totals := make(map[int64]*UserUsage)
+	for key, usage := range s.db.usage {
+		if key.day.Before(viewDay(since)) {
+			continue
+		}
+		total, ok := totals[key.userID]
+		if !ok {
+			total = &UserUsage{UserID: key.userID}
+			for _, user := range s.db.users {
+				if user.ID == key.userID {
+					total.Email = user.Email
+				}
+			}
+			totals[key.userID] = total
+		}
+		total.Requests += usage.Requests
+		total.ClientErrors += usage.ClientErrors
+		total.ServerErrors += usage.ServerErrors
+		total.Bytes += usage.Bytes
+	}
+
+	value := map[string]func(u *UserUsage) int64{
+		"requests": func(u *UserUsage) int64 { return u.Requests },
+		"errors":   func(u *UserUsage) int64 { return u.ClientErrors + u.ServerErrors },
+		"bytes":    func(u *UserUsage) int64 { return u.Bytes },
+	}[sort]
+	users := slices.Collect(maps.Values(totals))
+	slices.SortFunc(users, func(a, b *UserUsage) int {
+		if c := cmp.Compare(value(b), value(a)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.UserID, b.UserID)
+	})
+	return users[:min(limit, len(users))], nil
+}
+
+type memorySuggestionStore struct{ db *memoryDB }
+
+func copySuggestion(suggestion *Suggestion) *Suggestion {
+	c := *suggestion
+	c.Changes = slices.Clone(suggestion.Changes)
+	if suggestion.ReviewedAt != nil {
+		reviewedAt := *suggestion.ReviewedAt
+		c.ReviewedAt = &reviewedAt
+	}
+	return &c
+}
+
+func (s memorySuggestionStore) Insert(ctx context.Context, suggestion *Suggestion) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	if !slices.ContainsFunc(s.db.movies, func(m *Movie) bool { return m.ID == suggestion.MovieID }) {
+		return ErrRecordNotFound
+	}
+	suggestion.ID = s.db.id()
+	suggestion.CreatedAt = time.Now()
+	suggestion.Status = SuggestionPending
+	s.db.suggestions = append(s.db.suggestions, copySuggestion(suggestion))
+	return nil
+}
+
+func (s memorySuggestionStore) Get(ctx context.Context, id int64) (*Suggestion, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, suggestion := range s.db.suggestions {
+		if suggestion.ID == id {
+			return copySuggestion(suggestion), nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+func (s memorySuggestionStore) GetAll(ctx context.Context, movieID int64, status string, filters Filters) ([]*Suggestion, Metadata, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	suggestions := []*Suggestion{}
+	for _, suggestion := range s.db.suggestions {
+		if (movieID == 0 || suggestion.MovieID == movieID) && (status == "" || suggestion.Status == status) {
+			suggestions = append(suggestions, copySuggestion(suggestion))
+		}
+	}
+	columns := map[string]func(a, b *Suggestion) int{
+		"id":         func(a, b *Suggestion) int { return cmp.Compare(a.ID, b.ID) },
+		"created_at": func(a, b *Suggestion) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	}
+	suggestions, metadata := paginate(suggestions, filters, columns, func(s *Suggestion) int64 { return s.ID })
+	return suggestions, metadata, nil
+}
+
+func (s memorySuggestionStore) Review(ctx context.Context, suggestion *Suggestion) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for i, existing := range s.db.suggestions {
+		if existing.ID == suggestion.ID && existing.Status == SuggestionPending {
+			now := time.Now()
+			reviewed := copySuggestion(existing)
+			reviewed.Status = suggestion.Status
+			reviewed.ReviewedBy = suggestion.ReviewedBy
+			reviewed.ReviewedAt = &now
+			reviewed.Reason = suggestion.Reason
+			reviewed.AppliedVersion = suggestion.AppliedVersion
+			s.db.suggestions[i] = reviewed
+			suggestion.ReviewedAt = &now
+			return nil
+		}
+	}
+	return ErrEditConflict
+}
+
+type memoryMediaStore struct{ db *memoryDB }
+
+func copyMedia(media *Media) *Media {
+	c := *media
+	return &c
+}
+
+func (s memoryMediaStore) Insert(ctx context.Context, media *Media) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	if !slices.ContainsFunc(s.db.movies, func(m *Movie) bool { return m.ID == media.MovieID }) {
+		return ErrRecordNotFound
+	}
+	media.ID = s.db.id()
+	media.CreatedAt = time.Now()
+	media.Version = 1
+	s.db.media = append(s.db.media, copyMedia(media))
+	return nil
+}
+
+func (s memoryMediaStore) Get(ctx context.Context, movieID, id int64) (*Media, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, media := range s.db.media {
+		if media.ID == id && media.MovieID == movieID {
+			return copyMedia(media), nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+func (s memoryMediaStore) GetForMovies(ctx context.Context, movieIDs []int64) ([]*Media, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	links := []*Media{}
+	for _, media := range s.db.media {
+		if slices.Contains(movieIDs, media.MovieID) {
+			links = append(links, copyMedia(media))
+		}
+	}
+	slices.SortFunc(links, func(a, b *Media) int {
+		return cmp.Or(cmp.Compare(a.MovieID, b.MovieID), cmp.Compare(a.ID, b.ID))
+	})
+	return links, nil
+}
+
+func (s memoryMediaStore) Update(ctx context.Context, media *Media) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for i, existing := range s.db.media {
+		if existing.ID == media.ID && existing.MovieID == media.MovieID && existing.Version == media.Version {
+			media.Version++
+			s.db.media[i] = copyMedia(media)
+			return nil
+		}
+	}
+	return ErrEditConflict
+}
+
+func (s memoryMediaStore) Delete(ctx context.Context, movieID, id int64) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for i, media := range s.db.media {
+		if media.ID == id && media.MovieID == movieID {
+			s.db.media = slices.Delete(s.db.media, i, i+1)
+			return nil
+		}
+	}
+	return ErrRecordNotFound
+}
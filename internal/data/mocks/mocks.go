@@ -0,0 +1,30 @@
+// Package mocks provides moq-generated implementations of the data store
+// interfaces for use in handler tests. Each mock delegates to its
+// corresponding Func field and records its calls; unset funcs return zero
+// values, so tests should set the funcs the code under test relies on.
+package mocks
+
+import (
+	"github.com/ezechidc/greenlight/internal/data"
+)
+
+//go:generate moq -out stores.go -pkg mocks -stub .. AuditStore:AuditStore EmailStore:EmailStore InvitationStore:InvitationStore MediaStore:MediaStore MovieStore:MovieStore PermissionStore:PermissionStore PreferenceStore:PreferenceStore SuggestionStore:SuggestionStore TokenStore:TokenStore UsageStore:UsageStore UserStore:UserStore ViewStore:ViewStore
+
+// NewModels returns a data.Models backed entirely by empty mocks. Tests can
+// type-assert the fields back to their mock types to set behaviour.
+func NewModels() data.Models {
+	return data.Models{
+		Audit:       &AuditStore{},
+		Emails:      &EmailStore{},
+		Invitations: &InvitationStore{},
+		Media:       &MediaStore{},
+		Movies:      &MovieStore{},
+		Permissions: &PermissionStore{},
+		Preferences: &PreferenceStore{},
+		Suggestions: &SuggestionStore{},
+		Tokens:      &TokenStore{},
+		Usage:       &UsageStore{},
+		Users:       &UserStore{},
+		Views:       &ViewStore{},
+	}
+}
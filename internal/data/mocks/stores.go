@@ -0,0 +1,4163 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mocks
+
+import (
+	"context"
+	"github.com/ezechidc/greenlight/internal/data"
+	"sync"
+	"time"
+)
+
+// Ensure, that AuditStore does implement data.AuditStore.
+// If this is not the case, regenerate this file with moq.
+var _ data.AuditStore = &AuditStore{}
+
+// AuditStore is a mock implementation of data.AuditStore.
+//
+//	func TestSomethingThatUsesAuditStore(t *testing.T) {
+//
+//		// make and configure a mocked data.AuditStore
+//		mockedAuditStore := &AuditStore{
+//			InsertFunc: func(ctx context.Context, event *data.AuditEvent) error {
+//				panic("mock out the Insert method")
+//			},
+//		}
+//
+//		// use mockedAuditStore in code that requires data.AuditStore
+//		// and then make assertions.
+//
+//	}
+type AuditStore struct {
+	// InsertFunc mocks the Insert method.
+	InsertFunc func(ctx context.Context, event *data.AuditEvent) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Insert holds details about calls to the Insert method.
+		Insert []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Event is the event argument value.
+			Event *data.AuditEvent
+		}
+	}
+	lockInsert sync.RWMutex
+}
+
+// Insert calls InsertFunc.
+func (mock *AuditStore) Insert(ctx context.Context, event *data.AuditEvent) error {
+	callInfo := struct {
+		Ctx   context.Context
+		Event *data.AuditEvent
+	}{
+		Ctx:   ctx,
+		Event: event,
+	}
+	mock.lockInsert.Lock()
+	mock.calls.Insert = append(mock.calls.Insert, callInfo)
+	mock.lockInsert.Unlock()
+	if mock.InsertFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.InsertFunc(ctx, event)
+}
+
+// InsertCalls gets all the calls that were made to Insert.
+// Check the length with:
+//
+//	len(mockedAuditStore.InsertCalls())
+func (mock *AuditStore) InsertCalls() []struct {
+	Ctx   context.Context
+	Event *data.AuditEvent
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Event *data.AuditEvent
+	}
+	mock.lockInsert.RLock()
+	calls = mock.calls.Insert
+	mock.lockInsert.RUnlock()
+	return calls
+}
+
+// Ensure, that EmailStore does implement data.EmailStore.
+// If this is not the case, regenerate this file with moq.
+var _ data.EmailStore = &EmailStore{}
+
+// EmailStore is a mock implementation of data.EmailStore.
+//
+//	func TestSomethingThatUsesEmailStore(t *testing.T) {
+//
+//		// make and configure a mocked data.EmailStore
+//		mockedEmailStore := &EmailStore{
+//			CountByStatusFunc: func(ctx context.Context, since time.Time) (map[string]int, error) {
+//				panic("mock out the CountByStatus method")
+//			},
+//			GetAllFunc: func(ctx context.Context, recipient string, status string, filters data.Filters) ([]*data.Email, data.Metadata, error) {
+//				panic("mock out the GetAll method")
+//			},
+//			InsertFunc: func(ctx context.Context, email *data.Email) error {
+//				panic("mock out the Insert method")
+//			},
+//			UpdateStatusFunc: func(ctx context.Context, email *data.Email) error {
+//				panic("mock out the UpdateStatus method")
+//			},
+//		}
+//
+//		// use mockedEmailStore in code that requires data.EmailStore
+//		// and then make assertions.
+//
+//	}
+type EmailStore struct {
+	// CountByStatusFunc mocks the CountByStatus method.
+	CountByStatusFunc func(ctx context.Context, since time.Time) (map[string]int, error)
+
+	// GetAllFunc mocks the GetAll method.
+	GetAllFunc func(ctx context.Context, recipient string, status string, filters data.Filters) ([]*data.Email, data.Metadata, error)
+
+	// InsertFunc mocks the Insert method.
+	InsertFunc func(ctx context.Context, email *data.Email) error
+
+	// UpdateStatusFunc mocks the UpdateStatus method.
+	UpdateStatusFunc func(ctx context.Context, email *data.Email) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// CountByStatus holds details about calls to the CountByStatus method.
+		CountByStatus []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Since is the since argument value.
+			Since time.Time
+		}
+		// GetAll holds details about calls to the GetAll method.
+		GetAll []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Recipient is the recipient argument value.
+			Recipient string
+			// Status is the status argument value.
+			Status string
+			// Filters is the filters argument value.
+			Filters data.Filters
+		}
+		// Insert holds details about calls to the Insert method.
+		Insert []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Email is the email argument value.
+			Email *data.Email
+		}
+		// UpdateStatus holds details about calls to the UpdateStatus method.
+		UpdateStatus []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Email is the email argument value.
+			Email *data.Email
+		}
+	}
+	lockCountByStatus sync.RWMutex
+	lockGetAll        sync.RWMutex
+	lockInsert        sync.RWMutex
+	lockUpdateStatus  sync.RWMutex
+}
+
+// CountByStatus calls CountByStatusFunc.
+func (mock *EmailStore) CountByStatus(ctx context.Context, since time.Time) (map[string]int, error) {
+	callInfo := struct {
+		Ctx   context.Context
+		Since time.Time
+	}{
+		Ctx:   ctx,
+		Since: since,
+	}
+	mock.lockCountByStatus.Lock()
+	mock.calls.CountByStatus = append(mock.calls.CountByStatus, callInfo)
+	mock.lockCountByStatus.Unlock()
+	if mock.CountByStatusFunc == nil {
+		var (
+			stringToIntOut map[string]int
+			errOut         error
+		)
+		return stringToIntOut, errOut
+	}
+	return mock.CountByStatusFunc(ctx, since)
+}
+
+// CountByStatusCalls gets all the calls that were made to CountByStatus.
+// Check the length with:
+//
+//	len(mockedEmailStore.CountByStatusCalls())
+func (mock *EmailStore) CountByStatusCalls() []struct {
+	Ctx   context.Context
+	Since time.Time
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Since time.Time
+	}
+	mock.lockCountByStatus.RLock()
+	calls = mock.calls.CountByStatus
+	mock.lockCountByStatus.RUnlock()
+	return calls
+}
+
+// GetAll calls GetAllFunc.
+func (mock *EmailStore) GetAll(ctx context.Context, recipient string, status string, filters data.Filters) ([]*data.Email, data.Metadata, error) {
+	callInfo := struct {
+		Ctx       context.Context
+		Recipient string
+		Status    string
+		Filters   data.Filters
+	}{
+		Ctx:       ctx,
+		Recipient: recipient,
+		Status:    status,
+		Filters:   filters,
+	}
+	mock.lockGetAll.Lock()
+	mock.calls.GetAll = append(mock.calls.GetAll, callInfo)
+	mock.lockGetAll.Unlock()
+	if mock.GetAllFunc == nil {
+		var (
+			emailsOut   []*data.Email
+			metadataOut data.Metadata
+			errOut      error
+		)
+		return emailsOut, metadataOut, errOut
+	}
+	return mock.GetAllFunc(ctx, recipient, status, filters)
+}
+
+// GetAllCalls gets all the calls that were made to GetAll.
+// Check the length with:
+//
+//	len(mockedEmailStore.GetAllCalls())
+func (mock *EmailStore) GetAllCalls() []struct {
+	Ctx       context.Context
+	Recipient string
+	Status    string
+	Filters   data.Filters
+} {
+	var calls []struct {
+		Ctx       context.Context
+		Recipient string
+		Status    string
+		Filters   data.Filters
+	}
+	mock.lockGetAll.RLock()
+	calls = mock.calls.GetAll
+	mock.lockGetAll.RUnlock()
+	return calls
+}
+
+// Insert calls InsertFunc.
+func (mock *EmailStore) Insert(ctx context.Context, email *data.Email) error {
+	callInfo := struct {
+		Ctx   context.Context
+		Email *data.Email
+	}{
+		Ctx:   ctx,
+		Email: email,
+	}
+	mock.lockInsert.Lock()
+	mock.calls.Insert = append(mock.calls.Insert, callInfo)
+	mock.lockInsert.Unlock()
+	if mock.InsertFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.InsertFunc(ctx, email)
+}
+
+// InsertCalls gets all the calls that were made to Insert.
+// Check the length with:
+//
+//	len(mockedEmailStore.InsertCalls())
+func (mock *EmailStore) InsertCalls() []struct {
+	Ctx   context.Context
+	Email *data.Email
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Email *data.Email
+	}
+	mock.lockInsert.RLock()
+	calls = mock.calls.Insert
+	mock.lockInsert.RUnlock()
+	return calls
+}
+
+// UpdateStatus calls UpdateStatusFunc.
+func (mock *EmailStore) UpdateStatus(ctx context.Context, email *data.Email) error {
+	callInfo := struct {
+		Ctx   context.Context
+		Email *data.Email
+	}{
+		Ctx:   ctx,
+		Email: email,
+	}
+	mock.lockUpdateStatus.Lock()
+	mock.calls.UpdateStatus = append(mock.calls.UpdateStatus, callInfo)
+	mock.lockUpdateStatus.Unlock()
+	if mock.UpdateStatusFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.UpdateStatusFunc(ctx, email)
+}
+
+// UpdateStatusCalls gets all the calls that were made to UpdateStatus.
+// Check the length with:
+//
+//	len(mockedEmailStore.UpdateStatusCalls())
+func (mock *EmailStore) UpdateStatusCalls() []struct {
+	Ctx   context.Context
+	Email *data.Email
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Email *data.Email
+	}
+	mock.lockUpdateStatus.RLock()
+	calls = mock.calls.UpdateStatus
+	mock.lockUpdateStatus.RUnlock()
+	return calls
+}
+
+// Ensure, that InvitationStore does implement data.InvitationStore.
+// If this is not the case, regenerate this file with moq.
+var _ data.InvitationStore = &InvitationStore{}
+
+// InvitationStore is a mock implementation of data.InvitationStore.
+//
+//	func TestSomethingThatUsesInvitationStore(t *testing.T) {
+//
+//		// make and configure a mocked data.InvitationStore
+//		mockedInvitationStore := &InvitationStore{
+//			GetAllPendingFunc: func(ctx context.Context, filters data.Filters) ([]*data.Invitation, data.Metadata, error) {
+//				panic("mock out the GetAllPending method")
+//			},
+//			GetForTokenFunc: func(ctx context.Context, tokenPlaintext string) (*data.Invitation, error) {
+//				panic("mock out the GetForToken method")
+//			},
+//			GetPendingFunc: func(ctx context.Context, id int64) (*data.Invitation, error) {
+//				panic("mock out the GetPending method")
+//			},
+//			MarkAcceptedFunc: func(ctx context.Context, id int64, userID int64) error {
+//				panic("mock out the MarkAccepted method")
+//			},
+//			NewFunc: func(ctx context.Context, email string, role string, invitedBy int64, ttl time.Duration) (*data.Invitation, error) {
+//				panic("mock out the New method")
+//			},
+//			RevokeFunc: func(ctx context.Context, id int64) error {
+//				panic("mock out the Revoke method")
+//			},
+//		}
+//
+//		// use mockedInvitationStore in code that requires data.InvitationStore
+//		// and then make assertions.
+//
+//	}
+type InvitationStore struct {
+	// GetAllPendingFunc mocks the GetAllPending method.
+	GetAllPendingFunc func(ctx context.Context, filters data.Filters) ([]*data.Invitation, data.Metadata, error)
+
+	// GetForTokenFunc mocks the GetForToken method.
+	GetForTokenFunc func(ctx context.Context, tokenPlaintext string) (*data.Invitation, error)
+
+	// GetPendingFunc mocks the GetPending method.
+	GetPendingFunc func(ctx context.Context, id int64) (*data.Invitation, error)
+
+	// MarkAcceptedFunc mocks the MarkAccepted method.
+	MarkAcceptedFunc func(ctx context.Context, id int64, userID int64) error
+
+	// NewFunc mocks the New method.
+	NewFunc func(ctx context.Context, email string, role string, invitedBy int64, ttl time.Duration) (*data.Invitation, error)
+
+	// RevokeFunc mocks the Revoke method.
+	RevokeFunc func(ctx context.Context, id int64) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// GetAllPending holds details about calls to the GetAllPending method.
+		GetAllPending []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filters is the filters argument value.
+			Filters data.Filters
+		}
+		// GetForToken holds details about calls to the GetForToken method.
+		GetForToken []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// TokenPlaintext is the tokenPlaintext argument value.
+			TokenPlaintext string
+		}
+		// GetPending holds details about calls to the GetPending method.
+		GetPending []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int64
+		}
+		// MarkAccepted holds details about calls to the MarkAccepted method.
+		MarkAccepted []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int64
+			// UserID is the userID argument value.
+			UserID int64
+		}
+		// New holds details about calls to the New method.
+		New []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Email is the email argument value.
+			Email string
+			// Role is the role argument value.
+			Role string
+			// InvitedBy is the invitedBy argument value.
+			InvitedBy int64
+			// TTL is the ttl argument value.
+			TTL time.Duration
+		}
+		// Revoke holds details about calls to the Revoke method.
+		Revoke []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int64
+		}
+	}
+	lockGetAllPending sync.RWMutex
+	lockGetForToken   sync.RWMutex
+	lockGetPending    sync.RWMutex
+	lockMarkAccepted  sync.RWMutex
+	lockNew           sync.RWMutex
+	lockRevoke        sync.RWMutex
+}
+
+// GetAllPending calls GetAllPendingFunc.
+func (mock *InvitationStore) GetAllPending(ctx context.Context, filters data.Filters) ([]*data.Invitation, data.Metadata, error) {
+	callInfo := struct {
+		Ctx     context.Context
+		Filters data.Filters
+	}{
+		Ctx:     ctx,
+		Filters: filters,
+	}
+	mock.lockGetAllPending.Lock()
+	mock.calls.GetAllPending = append(mock.calls.GetAllPending, callInfo)
+	mock.lockGetAllPending.Unlock()
+	if mock.GetAllPendingFunc == nil {
+		var (
+			invitationsOut []*data.Invitation
+			metadataOut    data.Metadata
+			errOut         error
+		)
+		return invitationsOut, metadataOut, errOut
+	}
+	return mock.GetAllPendingFunc(ctx, filters)
+}
+
+// GetAllPendingCalls gets all the calls that were made to GetAllPending.
+// Check the length with:
+//
+//	len(mockedInvitationStore.GetAllPendingCalls())
+func (mock *InvitationStore) GetAllPendingCalls() []struct {
+	Ctx     context.Context
+	Filters data.Filters
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Filters data.Filters
+	}
+	mock.lockGetAllPending.RLock()
+	calls = mock.calls.GetAllPending
+	mock.lockGetAllPending.RUnlock()
+	return calls
+}
+
+// GetForToken calls GetForTokenFunc.
+func (mock *InvitationStore) GetForToken(ctx context.Context, tokenPlaintext string) (*data.Invitation, error) {
+	callInfo := struct {
+		Ctx            context.Context
+		TokenPlaintext string
+	}{
+		Ctx:            ctx,
+		TokenPlaintext: tokenPlaintext,
+	}
+	mock.lockGetForToken.Lock()
+	mock.calls.GetForToken = append(mock.calls.GetForToken, callInfo)
+	mock.lockGetForToken.Unlock()
+	if mock.GetForTokenFunc == nil {
+		var (
+			invitationOut *data.Invitation
+			errOut        error
+		)
+		return invitationOut, errOut
+	}
+	return mock.GetForTokenFunc(ctx, tokenPlaintext)
+}
+
+// GetForTokenCalls gets all the calls that were made to GetForToken.
+// Check the length with:
+//
+//	len(mockedInvitationStore.GetForTokenCalls())
+func (mock *InvitationStore) GetForTokenCalls() []struct {
+	Ctx            context.Context
+	TokenPlaintext string
+} {
+	var calls []struct {
+		Ctx            context.Context
+		TokenPlaintext string
+	}
+	mock.lockGetForToken.RLock()
+	calls = mock.calls.GetForToken
+	mock.lockGetForToken.RUnlock()
+	return calls
+}
+
+// GetPending calls GetPendingFunc.
+func (mock *InvitationStore) GetPending(ctx context.Context, id int64) (*data.Invitation, error) {
+	callInfo := struct {
+		Ctx context.Context
+		ID  int64
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetPending.Lock()
+	mock.calls.GetPending = append(mock.calls.GetPending, callInfo)
+	mock.lockGetPending.Unlock()
+	if mock.GetPendingFunc == nil {
+		var (
+			invitationOut *data.Invitation
+			errOut        error
+		)
+		return invitationOut, errOut
+	}
+	return mock.GetPendingFunc(ctx, id)
+}
+
+// GetPendingCalls gets all the calls that were made to GetPending.
+// Check the length with:
+//
+//	len(mockedInvitationStore.GetPendingCalls())
+func (mock *InvitationStore) GetPendingCalls() []struct {
+	Ctx context.Context
+	ID  int64
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int64
+	}
+	mock.lockGetPending.RLock()
+	calls = mock.calls.GetPending
+	mock.lockGetPending.RUnlock()
+	return calls
+}
+
+// MarkAccepted calls MarkAcceptedFunc.
+func (mock *InvitationStore) MarkAccepted(ctx context.Context, id int64, userID int64) error {
+	callInfo := struct {
+		Ctx    context.Context
+		ID     int64
+		UserID int64
+	}{
+		Ctx:    ctx,
+		ID:     id,
+		UserID: userID,
+	}
+	mock.lockMarkAccepted.Lock()
+	mock.calls.MarkAccepted = append(mock.calls.MarkAccepted, callInfo)
+	mock.lockMarkAccepted.Unlock()
+	if mock.MarkAcceptedFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.MarkAcceptedFunc(ctx, id, userID)
+}
+
+// MarkAcceptedCalls gets all the calls that were made to MarkAccepted.
+// Check the length with:
+//
+//	len(mockedInvitationStore.MarkAcceptedCalls())
+func (mock *InvitationStore) MarkAcceptedCalls() []struct {
+	Ctx    context.Context
+	ID     int64
+	UserID int64
+} {
+	var calls []struct {
+		Ctx    context.Context
+		ID     int64
+		UserID int64
+	}
+	mock.lockMarkAccepted.RLock()
+	calls = mock.calls.MarkAccepted
+	mock.lockMarkAccepted.RUnlock()
+	return calls
+}
+
+// New calls NewFunc.
+func (mock *InvitationStore) New(ctx context.Context, email string, role string, invitedBy int64, ttl time.Duration) (*data.Invitation, error) {
+	callInfo := struct {
+		Ctx       context.Context
+		Email     string
+		Role      string
+		InvitedBy int64
+		TTL       time.Duration
+	}{
+		Ctx:       ctx,
+		Email:     email,
+		Role:      role,
+		InvitedBy: invitedBy,
+		TTL:       ttl,
+	}
+	mock.lockNew.Lock()
+	mock.calls.New = append(mock.calls.New, callInfo)
+	mock.lockNew.Unlock()
+	if mock.NewFunc == nil {
+		var (
+			invitationOut *data.Invitation
+			errOut        error
+		)
+		return invitationOut, errOut
+	}
+	return mock.NewFunc(ctx, email, role, invitedBy, ttl)
+}
+
+// NewCalls gets all the calls that were made to New.
+// Check the length with:
+//
+//	len(mockedInvitationStore.NewCalls())
+func (mock *InvitationStore) NewCalls() []struct {
+	Ctx       context.Context
+	Email     string
+	Role      string
+	InvitedBy int64
+	TTL       time.Duration
+} {
+	var calls []struct {
+		Ctx       context.Context
+		Email     string
+		Role      string
+		InvitedBy int64
+		TTL       time.Duration
+	}
+	mock.lockNew.RLock()
+	calls = mock.calls.New
+	mock.lockNew.RUnlock()
+	return calls
+}
+
+// Revoke calls RevokeFunc.
+func (mock *InvitationStore) Revoke(ctx context.Context, id int64) error {
+	callInfo := struct {
+		Ctx context.Context
+		ID  int64
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockRevoke.Lock()
+	mock.calls.Revoke = append(mock.calls.Revoke, callInfo)
+	mock.lockRevoke.Unlock()
+	if mock.RevokeFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.RevokeFunc(ctx, id)
+}
+
+// RevokeCalls gets all the calls that were made to Revoke.
+// Check the length with:
+//
+//	len(mockedInvitationStore.RevokeCalls())
+func (mock *InvitationStore) RevokeCalls() []struct {
+	Ctx context.Context
+	ID  int64
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int64
+	}
+	mock.lockRevoke.RLock()
+	calls = mock.calls.Revoke
+	mock.lockRevoke.RUnlock()
+	return calls
+}
+
+// Ensure, that MediaStore does implement data.MediaStore.
+// If this is not the case, regenerate this file with moq.
+var _ data.MediaStore = &MediaStore{}
+
+// MediaStore is a mock implementation of data.MediaStore.
+//
+//	func TestSomethingThatUsesMediaStore(t *testing.T) {
+//
+//		// make and configure a mocked data.MediaStore
+//		mockedMediaStore := &MediaStore{
+//			DeleteFunc: func(ctx context.Context, movieID int64, id int64) error {
+//				panic("mock out the Delete method")
+//			},
+//			GetFunc: func(ctx context.Context, movieID int64, id int64) (*data.Media, error) {
+//				panic("mock out the Get method")
+//			},
+//			GetForMoviesFunc: func(ctx context.Context, movieIDs []int64) ([]*data.Media, error) {
+//				panic("mock out the GetForMovies method")
+//			},
+//			InsertFunc: func(ctx context.Context, media *data.Media) error {
+//				panic("mock out the Insert method")
+//			},
+//			UpdateFunc: func(ctx context.Context, media *data.Media) error {
+//				panic("mock out the Update method")
+//			},
+//		}
+//
+//		// use mockedMediaStore in code that requires data.MediaStore
+//		// and then make assertions.
+//
+//	}
+type MediaStore struct {
+	// DeleteFunc mocks the Delete method.
+	DeleteFunc func(ctx context.Context, movieID int64, id int64) error
+
+	// GetFunc mocks the Get method.
+	GetFunc func(ctx context.Context, movieID int64, id int64) (*data.Media, error)
+
+	// GetForMoviesFunc mocks the GetForMovies method.
+	GetForMoviesFunc func(ctx context.Context, movieIDs []int64) ([]*data.Media, error)
+
+	// InsertFunc mocks the Insert method.
+	InsertFunc func(ctx context.Context, media *data.Media) error
+
+	// UpdateFunc mocks the Update method.
+	UpdateFunc func(ctx context.Context, media *data.Media) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Delete holds details about calls to the Delete method.
+		Delete []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// MovieID is the movieID argument value.
+			MovieID int64
+			// ID is the id argument value.
+			ID int64
+		}
+		// Get holds details about calls to the Get method.
+		Get []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// MovieID is the movieID argument value.
+			MovieID int64
+			// ID is the id argument value.
+			ID int64
+		}
+		// GetForMovies holds details about calls to the GetForMovies method.
+		GetForMovies []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// MovieIDs is the movieIDs argument value.
+			MovieIDs []int64
+		}
+		// Insert holds details about calls to the Insert method.
+		Insert []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Media is the media argument value.
+			Media *data.Media
+		}
+		// Update holds details about calls to the Update method.
+		Update []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Media is the media argument value.
+			Media *data.Media
+		}
+	}
+	lockDelete       sync.RWMutex
+	lockGet          sync.RWMutex
+	lockGetForMovies sync.RWMutex
+	lockInsert       sync.RWMutex
+	lockUpdate       sync.RWMutex
+}
+
+// Delete calls DeleteFunc.
+func (mock *MediaStore) Delete(ctx context.Context, movieID int64, id int64) error {
+	callInfo := struct {
+		Ctx     context.Context
+		MovieID int64
+		ID      int64
+	}{
+		Ctx:     ctx,
+		MovieID: movieID,
+		ID:      id,
+	}
+	mock.lockDelete.Lock()
+	mock.calls.Delete = append(mock.calls.Delete, callInfo)
+	mock.lockDelete.Unlock()
+	if mock.DeleteFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.DeleteFunc(ctx, movieID, id)
+}
+
+// DeleteCalls gets all the calls that were made to Delete.
+// Check the length with:
+//
+//	len(mockedMediaStore.DeleteCalls())
+func (mock *MediaStore) DeleteCalls() []struct {
+	Ctx     context.Context
+	MovieID int64
+	ID      int64
+} {
+	var calls []struct {
+		Ctx     context.Context
+		MovieID int64
+		ID      int64
+	}
+	mock.lockDelete.RLock()
+	calls = mock.calls.Delete
+	mock.lockDelete.RUnlock()
+	return calls
+}
+
+// Get calls GetFunc.
+func (mock *MediaStore) Get(ctx context.Context, movieID int64, id int64) (*data.Media, error) {
+	callInfo := struct {
+		Ctx     context.Context
+		MovieID int64
+		ID      int64
+	}{
+		Ctx:     ctx,
+		MovieID: movieID,
+		ID:      id,
+	}
+	mock.lockGet.Lock()
+	mock.calls.Get = append(mock.calls.Get, callInfo)
+	mock.lockGet.Unlock()
+	if mock.GetFunc == nil {
+		var (
+			mediaOut *data.Media
+			errOut   error
+		)
+		return mediaOut, errOut
+	}
+	return mock.GetFunc(ctx, movieID, id)
+}
+
+// GetCalls gets all the calls that were made to Get.
+// Check the length with:
+//
+//	len(mockedMediaStore.GetCalls())
+func (mock *MediaStore) GetCalls() []struct {
+	Ctx     context.Context
+	MovieID int64
+	ID      int64
+} {
+	var calls []struct {
+		Ctx     context.Context
+		MovieID int64
+		ID      int64
+	}
+	mock.lockGet.RLock()
+	calls = mock.calls.Get
+	mock.lockGet.RUnlock()
+	return calls
+}
+
+// GetForMovies calls GetForMoviesFunc.
+func (mock *MediaStore) GetForMovies(ctx context.Context, movieIDs []int64) ([]*data.Media, error) {
+	callInfo := struct {
+		Ctx      context.Context
+		MovieIDs []int64
+	}{
+		Ctx:      ctx,
+		MovieIDs: movieIDs,
+	}
+	mock.lockGetForMovies.Lock()
+	mock.calls.GetForMovies = append(mock.calls.GetForMovies, callInfo)
+	mock.lockGetForMovies.Unlock()
+	if mock.GetForMoviesFunc == nil {
+		var (
+			mediasOut []*data.Media
+			errOut    error
+		)
+		return mediasOut, errOut
+	}
+	return mock.GetForMoviesFunc(ctx, movieIDs)
+}
+
+// GetForMoviesCalls gets all the calls that were made to GetForMovies.
+// Check the length with:
+//
+//	len(mockedMediaStore.GetForMoviesCalls())
+func (mock *MediaStore) GetForMoviesCalls() []struct {
+	Ctx      context.Context
+	MovieIDs []int64
+} {
+	var calls []struct {
+		Ctx      context.Context
+		MovieIDs []int64
+	}
+	mock.lockGetForMovies.RLock()
+	calls = mock.calls.GetForMovies
+	mock.lockGetForMovies.RUnlock()
+	return calls
+}
+
+// Insert calls InsertFunc.
+func (mock *MediaStore) Insert(ctx context.Context, media *data.Media) error {
+	callInfo := struct {
+		Ctx   context.Context
+		Media *data.Media
+	}{
+		Ctx:   ctx,
+		Media: media,
+	}
+	mock.lockInsert.Lock()
+	mock.calls.Insert = append(mock.calls.Insert, callInfo)
+	mock.lockInsert.Unlock()
+	if mock.InsertFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.InsertFunc(ctx, media)
+}
+
+// InsertCalls gets all the calls that were made to Insert.
+// Check the length with:
+//
+//	len(mockedMediaStore.InsertCalls())
+func (mock *MediaStore) InsertCalls() []struct {
+	Ctx   context.Context
+	Media *data.Media
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Media *data.Media
+	}
+	mock.lockInsert.RLock()
+	calls = mock.calls.Insert
+	mock.lockInsert.RUnlock()
+	return calls
+}
+
+// Update calls UpdateFunc.
+func (mock *MediaStore) Update(ctx context.Context, media *data.Media) error {
+	callInfo := struct {
+		Ctx   context.Context
+		Media *data.Media
+	}{
+		Ctx:   ctx,
+		Media: media,
+	}
+	mock.lockUpdate.Lock()
+	mock.calls.Update = append(mock.calls.Update, callInfo)
+	mock.lockUpdate.Unlock()
+	if mock.UpdateFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.UpdateFunc(ctx, media)
+}
+
+// UpdateCalls gets all the calls that were made to Update.
+// Check the length with:
+//
+//	len(mockedMediaStore.UpdateCalls())
+func (mock *MediaStore) UpdateCalls() []struct {
+	Ctx   context.Context
+	Media *data.Media
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Media *data.Media
+	}
+	mock.lockUpdate.RLock()
+	calls = mock.calls.Update
+	mock.lockUpdate.RUnlock()
+	return calls
+}
+
+// Ensure, that MovieStore does implement data.MovieStore.
+// If this is not the case, regenerate this file with moq.
+var _ data.MovieStore = &MovieStore{}
+
+// MovieStore is a mock implementation of data.MovieStore.
+//
+//	func TestSomethingThatUsesMovieStore(t *testing.T) {
+//
+//		// make and configure a mocked data.MovieStore
+//		mockedMovieStore := &MovieStore{
+//			DeleteFunc: func(ctx context.Context, id int64) error {
+//				panic("mock out the Delete method")
+//			},
+//			GetFunc: func(ctx context.Context, id int64) (*data.Movie, error) {
+//				panic("mock out the Get method")
+//			},
+//			GetAddedSinceFunc: func(ctx context.Context, since time.Time, genres []string, limit int) ([]*data.Movie, error) {
+//				panic("mock out the GetAddedSince method")
+//			},
+//			GetAllFunc: func(ctx context.Context, title string, genres []string, certificationSystem string, certification string, releasedAfter *data.Date, releasedBefore *data.Date, filters data.Filters) ([]*data.Movie, data.Metadata, error) {
+//				panic("mock out the GetAll method")
+//			},
+//			GetCatalogStatsFunc: func(ctx context.Context) (*data.CatalogStats, error) {
+//				panic("mock out the GetCatalogStats method")
+//			},
+//			GetManyFunc: func(ctx context.Context, ids []int64) ([]*data.Movie, error) {
+//				panic("mock out the GetMany method")
+//			},
+//			GetRevisionFunc: func(ctx context.Context, movieID int64, version int32) (*data.MovieRevision, error) {
+//				panic("mock out the GetRevision method")
+//			},
+//			GetRevisionsFunc: func(ctx context.Context, movieID int64) ([]*data.MovieRevision, error) {
+//				panic("mock out the GetRevisions method")
+//			},
+//			GetSimilarByEmbeddingFunc: func(ctx context.Context, id int64, model string, limit int) ([]*data.SimilarMovie, error) {
+//				panic("mock out the GetSimilarByEmbedding method")
+//			},
+//			GetSimilarByGenreFunc: func(ctx context.Context, id int64, limit int) ([]*data.SimilarMovie, error) {
+//				panic("mock out the GetSimilarByGenre method")
+//			},
+//			GetUnembeddedFunc: func(ctx context.Context, model string, limit int) ([]*data.Movie, error) {
+//				panic("mock out the GetUnembedded method")
+//			},
+//			InsertFunc: func(ctx context.Context, movie *data.Movie) error {
+//				panic("mock out the Insert method")
+//			},
+//			InsertManyFunc: func(ctx context.Context, movies []*data.Movie) error {
+//				panic("mock out the InsertMany method")
+//			},
+//			RevertPosterFunc: func(ctx context.Context, movieID int64, version string, previous *data.Poster) error {
+//				panic("mock out the RevertPoster method")
+//			},
+//			SearchGenresFunc: func(ctx context.Context, search string, limit int) ([]*data.GenreCount, error) {
+//				panic("mock out the SearchGenres method")
+//			},
+//			SearchTitlesFunc: func(ctx context.Context, search string, threshold float64, limit int) ([]*data.TitleMatch, error) {
+//				panic("mock out the SearchTitles method")
+//			},
+//			SetEmbeddingFunc: func(ctx context.Context, movieID int64, version int32, model string, embedding []float32) error {
+//				panic("mock out the SetEmbedding method")
+//			},
+//			SetPosterFunc: func(ctx context.Context, movieID int64, poster *data.Poster) error {
+//				panic("mock out the SetPoster method")
+//			},
+//			UpdateFunc: func(ctx context.Context, movie *data.Movie) error {
+//				panic("mock out the Update method")
+//			},
+//			UpdatePosterFunc: func(ctx context.Context, movieID int64, poster *data.Poster) error {
+//				panic("mock out the UpdatePoster method")
+//			},
+//		}
+//
+//		// use mockedMovieStore in code that requires data.MovieStore
+//		// and then make assertions.
+//
+//	}
+type MovieStore struct {
+	// DeleteFunc mocks the Delete method.
+	DeleteFunc func(ctx context.Context, id int64) error
+
+	// GetFunc mocks the Get method.
+	GetFunc func(ctx context.Context, id int64) (*data.Movie, error)
+
+	// GetAddedSinceFunc mocks the GetAddedSince method.
+	GetAddedSinceFunc func(ctx context.Context, since time.Time, genres []string, limit int) ([]*data.Movie, error)
+
+	// GetAllFunc mocks the GetAll method.
+	GetAllFunc func(ctx context.Context, title string, genres []string, certificationSystem string, certification string, releasedAfter *data.Date, releasedBefore *data.Date, filters data.Filters) ([]*data.Movie, data.Metadata, error)
+
+	// GetCatalogStatsFunc mocks the GetCatalogStats method.
+	GetCatalogStatsFunc func(ctx context.Context) (*data.CatalogStats, error)
+
+	// GetManyFunc mocks the GetMany method.
+	GetManyFunc func(ctx context.Context, ids []int64) ([]*data.Movie, error)
+
+	// GetRevisionFunc mocks the GetRevision method.
+	GetRevisionFunc func(ctx context.Context, movieID int64, version int32) (*data.MovieRevision, error)
+
+	// GetRevisionsFunc mocks the GetRevisions method.
+	GetRevisionsFunc func(ctx context.Context, movieID int64) ([]*data.MovieRevision, error)
+
+	// GetSimilarByEmbeddingFunc mocks the GetSimilarByEmbedding method.
+	GetSimilarByEmbeddingFunc func(ctx context.Context, id int64, model string, limit int) ([]*data.SimilarMovie, error)
+
+	// GetSimilarByGenreFunc mocks the GetSimilarByGenre method.
+	GetSimilarByGenreFunc func(ctx context.Context, id int64, limit int) ([]*data.SimilarMovie, error)
+
+	// GetUnembeddedFunc mocks the GetUnembedded method.
+	GetUnembeddedFunc func(ctx context.Context, model string, limit int) ([]*data.Movie, error)
+
+	// InsertFunc mocks the Insert method.
+	InsertFunc func(ctx context.Context, movie *data.Movie) error
+
+	// InsertManyFunc mocks the InsertMany method.
+	InsertManyFunc func(ctx context.Context, movies []*data.Movie) error
+
+	// RevertPosterFunc mocks the RevertPoster method.
+	RevertPosterFunc func(ctx context.Context, movieID int64, version string, previous *data.Poster) error
+
+	// SearchGenresFunc mocks the SearchGenres method.
+	SearchGenresFunc func(ctx context.Context, search string, limit int) ([]*data.GenreCount, error)
+
+	// SearchTitlesFunc mocks the SearchTitles method.
+	SearchTitlesFunc func(ctx context.Context, search string, threshold float64, limit int) ([]*data.TitleMatch, error)
+
+	// SetEmbeddingFunc mocks the SetEmbedding method.
+	SetEmbeddingFunc func(ctx context.Context, movieID int64, version int32, model string, embedding []float32) error
+
+	// SetPosterFunc mocks the SetPoster method.
+	SetPosterFunc func(ctx context.Context, movieID int64, poster *data.Poster) error
+
+	// UpdateFunc mocks the Update method.
+	UpdateFunc func(ctx context.Context, movie *data.Movie) error
+
+	// UpdatePosterFunc mocks the UpdatePoster method.
+	UpdatePosterFunc func(ctx context.Context, movieID int64, poster *data.Poster) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Delete holds details about calls to the Delete method.
+		Delete []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int64
+		}
+		// Get holds details about calls to the Get method.
+		Get []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int64
+		}
+		// GetAddedSince holds details about calls to the GetAddedSince method.
+		GetAddedSince []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Since is the since argument value.
+			Since time.Time
+			// Genres is the genres argument value.
+			Genres []string
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// GetAll holds details about calls to the GetAll method.
+		GetAll []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Title is the title argument value.
+			Title string
+			// Genres is the genres argument value.
+			Genres []string
+			// CertificationSystem is the certificationSystem argument value.
+			CertificationSystem string
+			// Certification is the certification argument value.
+			Certification string
+			// ReleasedAfter is the releasedAfter argument value.
+			ReleasedAfter *data.Date
+			// ReleasedBefore is the releasedBefore argument value.
+			ReleasedBefore *data.Date
+			// Filters is the filters argument value.
+			Filters data.Filters
+		}
+		// GetCatalogStats holds details about calls to the GetCatalogStats method.
+		GetCatalogStats []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// GetMany holds details about calls to the GetMany method.
+		GetMany []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ids is the ids argument value.
+			Ids []int64
+		}
+		// GetRevision holds details about calls to the GetRevision method.
+		GetRevision []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// MovieID is the movieID argument value.
+			MovieID int64
+			// Version is the version argument value.
+			Version int32
+		}
+		// GetRevisions holds details about calls to the GetRevisions method.
+		GetRevisions []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// MovieID is the movieID argument value.
+			MovieID int64
+		}
+		// GetSimilarByEmbedding holds details about calls to the GetSimilarByEmbedding method.
+		GetSimilarByEmbedding []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int64
+			// Model is the model argument value.
+			Model string
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// GetSimilarByGenre holds details about calls to the GetSimilarByGenre method.
+		GetSimilarByGenre []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int64
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// GetUnembedded holds details about calls to the GetUnembedded method.
+		GetUnembedded []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Model is the model argument value.
+			Model string
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// Insert holds details about calls to the Insert method.
+		Insert []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Movie is the movie argument value.
+			Movie *data.Movie
+		}
+		// InsertMany holds details about calls to the InsertMany method.
+		InsertMany []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Movies is the movies argument value.
+			Movies []*data.Movie
+		}
+		// RevertPoster holds details about calls to the RevertPoster method.
+		RevertPoster []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// MovieID is the movieID argument value.
+			MovieID int64
+			// Version is the version argument value.
+			Version string
+			// Previous is the previous argument value.
+			Previous *data.Poster
+		}
+		// SearchGenres holds details about calls to the SearchGenres method.
+		SearchGenres []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Search is the search argument value.
+			Search string
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// SearchTitles holds details about calls to the SearchTitles method.
+		SearchTitles []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Search is the search argument value.
+			Search string
+			// Threshold is the threshold argument value.
+			Threshold float64
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// SetEmbedding holds details about calls to the SetEmbedding method.
+		SetEmbedding []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// MovieID is the movieID argument value.
+			MovieID int64
+			// Version is the version argument value.
+			Version int32
+			// Model is the model argument value.
+			Model string
+			// Embedding is the embedding argument value.
+			Embedding []float32
+		}
+		// SetPoster holds details about calls to the SetPoster method.
+		SetPoster []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// MovieID is the movieID argument value.
+			MovieID int64
+			// Poster is the poster argument value.
+			Poster *data.Poster
+		}
+		// Update holds details about calls to the Update method.
+		Update []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Movie is the movie argument value.
+			Movie *data.Movie
+		}
+		// UpdatePoster holds details about calls to the UpdatePoster method.
+		UpdatePoster []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// MovieID is the movieID argument value.
+			MovieID int64
+			// Poster is the poster argument value.
+			Poster *data.Poster
+		}
+	}
+	lockDelete                sync.RWMutex
+	lockGet                   sync.RWMutex
+	lockGetAddedSince         sync.RWMutex
+	lockGetAll                sync.RWMutex
+	lockGetCatalogStats       sync.RWMutex
+	lockGetMany               sync.RWMutex
+	lockGetRevision           sync.RWMutex
+	lockGetRevisions          sync.RWMutex
+	lockGetSimilarByEmbedding sync.RWMutex
+	lockGetSimilarByGenre     sync.RWMutex
+	lockGetUnembedded         sync.RWMutex
+	lockInsert                sync.RWMutex
+	lockInsertMany            sync.RWMutex
+	lockRevertPoster          sync.RWMutex
+	lockSearchGenres          sync.RWMutex
+	lockSearchTitles          sync.RWMutex
+	lockSetEmbedding          sync.RWMutex
+	lockSetPoster             sync.RWMutex
+	lockUpdate                sync.RWMutex
+	lockUpdatePoster          sync.RWMutex
+}
+
+// Delete calls DeleteFunc.
+func (mock *MovieStore) Delete(ctx context.Context, id int64) error {
+	callInfo := struct {
+		Ctx context.Context
+		ID  int64
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockDelete.Lock()
+	mock.calls.Delete = append(mock.calls.Delete, callInfo)
+	mock.lockDelete.Unlock()
+	if mock.DeleteFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.DeleteFunc(ctx, id)
+}
+
+// DeleteCalls gets all the calls that were made to Delete.
+// Check the length with:
+//
+//	len(mockedMovieStore.DeleteCalls())
+func (mock *MovieStore) DeleteCalls() []struct {
+	Ctx context.Context
+	ID  int64
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int64
+	}
+	mock.lockDelete.RLock()
+	calls = mock.calls.Delete
+	mock.lockDelete.RUnlock()
+	return calls
+}
+
+// Get calls GetFunc.
+func (mock *MovieStore) Get(ctx context.Context, id int64) (*data.Movie, error) {
+	callInfo := struct {
+		Ctx context.Context
+		ID  int64
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGet.Lock()
+	mock.calls.Get = append(mock.calls.Get, callInfo)
+	mock.lockGet.Unlock()
+	if mock.GetFunc == nil {
+		var (
+			movieOut *data.Movie
+			errOut   error
+		)
+		return movieOut, errOut
+	}
+	return mock.GetFunc(ctx, id)
+}
+
+// GetCalls gets all the calls that were made to Get.
+// Check the length with:
+//
+//	len(mockedMovieStore.GetCalls())
+func (mock *MovieStore) GetCalls() []struct {
+	Ctx context.Context
+	ID  int64
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int64
+	}
+	mock.lockGet.RLock()
+	calls = mock.calls.Get
+	mock.lockGet.RUnlock()
+	return calls
+}
+
+// GetAddedSince calls GetAddedSinceFunc.
+func (mock *MovieStore) GetAddedSince(ctx context.Context, since time.Time, genres []string, limit int) ([]*data.Movie, error) {
+	callInfo := struct {
+		Ctx    context.Context
+		Since  time.Time
+		Genres []string
+		Limit  int
+	}{
+		Ctx:    ctx,
+		Since:  since,
+		Genres: genres,
+		Limit:  limit,
+	}
+	mock.lockGetAddedSince.Lock()
+	mock.calls.GetAddedSince = append(mock.calls.GetAddedSince, callInfo)
+	mock.lockGetAddedSince.Unlock()
+	if mock.GetAddedSinceFunc == nil {
+		var (
+			moviesOut []*data.Movie
+			errOut    error
+		)
+		return moviesOut, errOut
+	}
+	return mock.GetAddedSinceFunc(ctx, since, genres, limit)
+}
+
+// GetAddedSinceCalls gets all the calls that were made to GetAddedSince.
+// Check the length with:
+//
+//	len(mockedMovieStore.GetAddedSinceCalls())
+func (mock *MovieStore) GetAddedSinceCalls() []struct {
+	Ctx    context.Context
+	Since  time.Time
+	Genres []string
+	Limit  int
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Since  time.Time
+		Genres []string
+		Limit  int
+	}
+	mock.lockGetAddedSince.RLock()
+	calls = mock.calls.GetAddedSince
+	mock.lockGetAddedSince.RUnlock()
+	return calls
+}
+
+// GetAll calls GetAllFunc.
+func (mock *MovieStore) GetAll(ctx context.Context, title string, genres []string, certificationSystem string, certification string, releasedAfter *data.Date, releasedBefore *data.Date, filters data.Filters) ([]*data.Movie, data.Metadata, error) {
+	callInfo := struct {
+		Ctx                 context.Context
+		Title               string
+		Genres              []string
+		CertificationSystem string
+		Certification       string
+		ReleasedAfter       *data.Date
+		ReleasedBefore      *data.Date
+		Filters             data.Filters
+	}{
+		Ctx:                 ctx,
+		Title:               title,
+		Genres:              genres,
+		CertificationSystem: certificationSystem,
+		Certification:       certification,
+		ReleasedAfter:       releasedAfter,
+		ReleasedBefore:      releasedBefore,
+		Filters:             filters,
+	}
+	mock.lockGetAll.Lock()
+	mock.calls.GetAll = append(mock.calls.GetAll, callInfo)
+	mock.lockGetAll.Unlock()
+	if mock.GetAllFunc == nil {
+		var (
+			moviesOut   []*data.Movie
+			metadataOut data.Metadata
+			errOut      error
+		)
+		return moviesOut, metadataOut, errOut
+	}
+	return mock.GetAllFunc(ctx, title, genres, certificationSystem, certification, releasedAfter, releasedBefore, filters)
+}
+
+// GetAllCalls gets all the calls that were made to GetAll.
+// Check the length with:
+//
+//	len(mockedMovieStore.GetAllCalls())
+func (mock *MovieStore) GetAllCalls() []struct {
+	Ctx                 context.Context
+	Title               string
+	Genres              []string
+	CertificationSystem string
+	Certification       string
+	ReleasedAfter       *data.Date
+	ReleasedBefore      *data.Date
+	Filters             data.Filters
+} {
+	var calls []struct {
+		Ctx                 context.Context
+		Title               string
+		Genres              []string
+		CertificationSystem string
+		Certification       string
+		ReleasedAfter       *data.Date
+		ReleasedBefore      *data.Date
+		Filters             data.Filters
+	}
+	mock.lockGetAll.RLock()
+	calls = mock.calls.GetAll
+	mock.lockGetAll.RUnlock()
+	return calls
+}
+
+// GetCatalogStats calls GetCatalogStatsFunc.
+func (mock *MovieStore) GetCatalogStats(ctx context.Context) (*data.CatalogStats, error) {
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetCatalogStats.Lock()
+	mock.calls.GetCatalogStats = append(mock.calls.GetCatalogStats, callInfo)
+	mock.lockGetCatalogStats.Unlock()
+	if mock.GetCatalogStatsFunc == nil {
+		var (
+			catalogStatsOut *data.CatalogStats
+			errOut          error
+		)
+		return catalogStatsOut, errOut
+	}
+	return mock.GetCatalogStatsFunc(ctx)
+}
+
+// GetCatalogStatsCalls gets all the calls that were made to GetCatalogStats.
+// Check the length with:
+//
+//	len(mockedMovieStore.GetCatalogStatsCalls())
+func (mock *MovieStore) GetCatalogStatsCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetCatalogStats.RLock()
+	calls = mock.calls.GetCatalogStats
+	mock.lockGetCatalogStats.RUnlock()
+	return calls
+}
+
+// GetMany calls GetManyFunc.
+func (mock *MovieStore) GetMany(ctx context.Context, ids []int64) ([]*data.Movie, error) {
+	callInfo := struct {
+		Ctx context.Context
+		Ids []int64
+	}{
+		Ctx: ctx,
+		Ids: ids,
+	}
+	mock.lockGetMany.Lock()
+	mock.calls.GetMany = append(mock.calls.GetMany, callInfo)
+	mock.lockGetMany.Unlock()
+	if mock.GetManyFunc == nil {
+		var (
+			moviesOut []*data.Movie
+			errOut    error
+		)
+		return moviesOut, errOut
+	}
+	return mock.GetManyFunc(ctx, ids)
+}
+
+// GetManyCalls gets all the calls that were made to GetMany.
+// Check the length with:
+//
+//	len(mockedMovieStore.GetManyCalls())
+func (mock *MovieStore) GetManyCalls() []struct {
+	Ctx context.Context
+	Ids []int64
+} {
+	var calls []struct {
+		Ctx context.Context
+		Ids []int64
+	}
+	mock.lockGetMany.RLock()
+	calls = mock.calls.GetMany
+	mock.lockGetMany.RUnlock()
+	return calls
+}
+
+// GetRevision calls GetRevisionFunc.
+func (mock *MovieStore) GetRevision(ctx context.Context, movieID int64, version int32) (*data.MovieRevision, error) {
+	callInfo := struct {
+		Ctx     context.Context
+		MovieID int64
+		Version int32
+	}{
+		Ctx:     ctx,
+		MovieID: movieID,
+		Version: version,
+	}
+	mock.lockGetRevision.Lock()
+	mock.calls.GetRevision = append(mock.calls.GetRevision, callInfo)
+	mock.lockGetRevision.Unlock()
+	if mock.GetRevisionFunc == nil {
+		var (
+			movieRevisionOut *data.MovieRevision
+			errOut           error
+		)
+		return movieRevisionOut, errOut
+	}
+	return mock.GetRevisionFunc(ctx, movieID, version)
+}
+
+// GetRevisionCalls gets all the calls that were made to GetRevision.
+// Check the length with:
+//
+//	len(mockedMovieStore.GetRevisionCalls())
+func (mock *MovieStore) GetRevisionCalls() []struct {
+	Ctx     context.Context
+	MovieID int64
+	Version int32
+} {
+	var calls []struct {
+		Ctx     context.Context
+		MovieID int64
+		Version int32
+	}
+	mock.lockGetRevision.RLock()
+	calls = mock.calls.GetRevision
+	mock.lockGetRevision.RUnlock()
+	return calls
+}
+
+// GetRevisions calls GetRevisionsFunc.
+func (mock *MovieStore) GetRevisions(ctx context.Context, movieID int64) ([]*data.MovieRevision, error) {
+	callInfo := struct {
+		Ctx     context.Context
+		MovieID int64
+	}{
+		Ctx:     ctx,
+		MovieID: movieID,
+	}
+	mock.lockGetRevisions.Lock()
+	mock.calls.GetRevisions = append(mock.calls.GetRevisions, callInfo)
+	mock.lockGetRevisions.Unlock()
+	if mock.GetRevisionsFunc == nil {
+		var (
+			movieRevisionsOut []*data.MovieRevision
+			errOut            error
+		)
+		return movieRevisionsOut, errOut
+	}
+	return mock.GetRevisionsFunc(ctx, movieID)
+}
+
+// GetRevisionsCalls gets all the calls that were made to GetRevisions.
+// Check the length with:
+//
+//	len(mockedMovieStore.GetRevisionsCalls())
+func (mock *MovieStore) GetRevisionsCalls() []struct {
+	Ctx     context.Context
+	MovieID int64
+} {
+	var calls []struct {
+		Ctx     context.Context
+		MovieID int64
+	}
+	mock.lockGetRevisions.RLock()
+	calls = mock.calls.GetRevisions
+	mock.lockGetRevisions.RUnlock()
+	return calls
+}
+
+// GetSimilarByEmbedding calls GetSimilarByEmbeddingFunc.
+func (mock *MovieStore) GetSimilarByEmbedding(ctx context.Context, id int64, model string, limit int) ([]*data.SimilarMovie, error) {
+	callInfo := struct {
+		Ctx   context.Context
+		ID    int64
+		Model string
+		Limit int
+	}{
+		Ctx:   ctx,
+		ID:    id,
+		Model: model,
+		Limit: limit,
+	}
+	mock.lockGetSimilarByEmbedding.Lock()
+	mock.calls.GetSimilarByEmbedding = append(mock.calls.GetSimilarByEmbedding, callInfo)
+	mock.lockGetSimilarByEmbedding.Unlock()
+	if mock.GetSimilarByEmbeddingFunc == nil {
+		var (
+			similarMoviesOut []*data.SimilarMovie
+			errOut           error
+		)
+		return similarMoviesOut, errOut
+	}
+	return mock.GetSimilarByEmbeddingFunc(ctx, id, model, limit)
+}
+
+// GetSimilarByEmbeddingCalls gets all the calls that were made to GetSimilarByEmbedding.
+// Check the length with:
+//
+//	len(mockedMovieStore.GetSimilarByEmbeddingCalls())
+func (mock *MovieStore) GetSimilarByEmbeddingCalls() []struct {
+	Ctx   context.Context
+	ID    int64
+	Model string
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		ID    int64
+		Model string
+		Limit int
+	}
+	mock.lockGetSimilarByEmbedding.RLock()
+	calls = mock.calls.GetSimilarByEmbedding
+	mock.lockGetSimilarByEmbedding.RUnlock()
+	return calls
+}
+
+// GetSimilarByGenre calls GetSimilarByGenreFunc.
+func (mock *MovieStore) GetSimilarByGenre(ctx context.Context, id int64, limit int) ([]*data.SimilarMovie, error) {
+	callInfo := struct {
+		Ctx   context.Context
+		ID    int64
+		Limit int
+	}{
+		Ctx:   ctx,
+		ID:    id,
+		Limit: limit,
+	}
+	mock.lockGetSimilarByGenre.Lock()
+	mock.calls.GetSimilarByGenre = append(mock.calls.GetSimilarByGenre, callInfo)
+	mock.lockGetSimilarByGenre.Unlock()
+	if mock.GetSimilarByGenreFunc == nil {
+		var (
+			similarMoviesOut []*data.SimilarMovie
+			errOut           error
+		)
+		return similarMoviesOut, errOut
+	}
+	return mock.GetSimilarByGenreFunc(ctx, id, limit)
+}
+
+// GetSimilarByGenreCalls gets all the calls that were made to GetSimilarByGenre.
+// Check the length with:
+//
+//	len(mockedMovieStore.GetSimilarByGenreCalls())
+func (mock *MovieStore) GetSimilarByGenreCalls() []struct {
+	Ctx   context.Context
+	ID    int64
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		ID    int64
+		Limit int
+	}
+	mock.lockGetSimilarByGenre.RLock()
+	calls = mock.calls.GetSimilarByGenre
+	mock.lockGetSimilarByGenre.RUnlock()
+	return calls
+}
+
+// GetUnembedded calls GetUnembeddedFunc.
+func (mock *MovieStore) GetUnembedded(ctx context.Context, model string, limit int) ([]*data.Movie, error) {
+	callInfo := struct {
+		Ctx   context.Context
+		Model string
+		Limit int
+	}{
+		Ctx:   ctx,
+		Model: model,
+		Limit: limit,
+	}
+	mock.lockGetUnembedded.Lock()
+	mock.calls.GetUnembedded = append(mock.calls.GetUnembedded, callInfo)
+	mock.lockGetUnembedded.Unlock()
+	if mock.GetUnembeddedFunc == nil {
+		var (
+			moviesOut []*data.Movie
+			errOut    error
+		)
+		return moviesOut, errOut
+	}
+	return mock.GetUnembeddedFunc(ctx, model, limit)
+}
+
+// GetUnembeddedCalls gets all the calls that were made to GetUnembedded.
+// Check the length with:
+//
+//	len(mockedMovieStore.GetUnembeddedCalls())
+func (mock *MovieStore) GetUnembeddedCalls() []struct {
+	Ctx   context.Context
+	Model string
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Model string
+		Limit int
+	}
+	mock.lockGetUnembedded.RLock()
+	calls = mock.calls.GetUnembedded
+	mock.lockGetUnembedded.RUnlock()
+	return calls
+}
+
+// Insert calls InsertFunc.
+func (mock *MovieStore) Insert(ctx context.Context, movie *data.Movie) error {
+	callInfo := struct {
+		Ctx   context.Context
+		Movie *data.Movie
+	}{
+		Ctx:   ctx,
+		Movie: movie,
+	}
+	mock.lockInsert.Lock()
+	mock.calls.Insert = append(mock.calls.Insert, callInfo)
+	mock.lockInsert.Unlock()
+	if mock.InsertFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.InsertFunc(ctx, movie)
+}
+
+// InsertCalls gets all the calls that were made to Insert.
+// Check the length with:
+//
+//	len(mockedMovieStore.InsertCalls())
+func (mock *MovieStore) InsertCalls() []struct {
+	Ctx   context.Context
+	Movie *data.Movie
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Movie *data.Movie
+	}
+	mock.lockInsert.RLock()
+	calls = mock.calls.Insert
+	mock.lockInsert.RUnlock()
+	return calls
+}
+
+// InsertMany calls InsertManyFunc.
+func (mock *MovieStore) InsertMany(ctx context.Context, movies []*data.Movie) error {
+	callInfo := struct {
+		Ctx    context.Context
+		Movies []*data.Movie
+	}{
+		Ctx:    ctx,
+		Movies: movies,
+	}
+	mock.lockInsertMany.Lock()
+	mock.calls.InsertMany = append(mock.calls.InsertMany, callInfo)
+	mock.lockInsertMany.Unlock()
+	if mock.InsertManyFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.InsertManyFunc(ctx, movies)
+}
+
+// InsertManyCalls gets all the calls that were made to InsertMany.
+// Check the length with:
+//
+//	len(mockedMovieStore.InsertManyCalls())
+func (mock *MovieStore) InsertManyCalls() []struct {
+	Ctx    context.Context
+	Movies []*data.Movie
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Movies []*data.Movie
+	}
+	mock.lockInsertMany.RLock()
+	calls = mock.calls.InsertMany
+	mock.lockInsertMany.RUnlock()
+	return calls
+}
+
+// RevertPoster calls RevertPosterFunc.
+func (mock *MovieStore) RevertPoster(ctx context.Context, movieID int64, version string, previous *data.Poster) error {
+	callInfo := struct {
+		Ctx      context.Context
+		MovieID  int64
+		Version  string
+		Previous *data.Poster
+	}{
+		Ctx:      ctx,
+		MovieID:  movieID,
+		Version:  version,
+		Previous: previous,
+	}
+	mock.lockRevertPoster.Lock()
+	mock.calls.RevertPoster = append(mock.calls.RevertPoster, callInfo)
+	mock.lockRevertPoster.Unlock()
+	if mock.RevertPosterFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.RevertPosterFunc(ctx, movieID, version, previous)
+}
+
+// RevertPosterCalls gets all the calls that were made to RevertPoster.
+// Check the length with:
+//
+//	len(mockedMovieStore.RevertPosterCalls())
+func (mock *MovieStore) RevertPosterCalls() []struct {
+	Ctx      context.Context
+	MovieID  int64
+	Version  string
+	Previous *data.Poster
+} {
+	var calls []struct {
+		Ctx      context.Context
+		MovieID  int64
+		Version  string
+		Previous *data.Poster
+	}
+	mock.lockRevertPoster.RLock()
+	calls = mock.calls.RevertPoster
+	mock.lockRevertPoster.RUnlock()
+	return calls
+}
+
+// SearchGenres calls SearchGenresFunc.
+func (mock *MovieStore) SearchGenres(ctx context.Context, search string, limit int) ([]*data.GenreCount, error) {
+	callInfo := struct {
+		Ctx    context.Context
+		Search string
+		Limit  int
+	}{
+		Ctx:    ctx,
+		Search: search,
+		Limit:  limit,
+	}
+	mock.lockSearchGenres.Lock()
+	mock.calls.SearchGenres = append(mock.calls.SearchGenres, callInfo)
+	mock.lockSearchGenres.Unlock()
+	if mock.SearchGenresFunc == nil {
+		var (
+			genreCountsOut []*data.GenreCount
+			errOut         error
+		)
+		return genreCountsOut, errOut
+	}
+	return mock.SearchGenresFunc(ctx, search, limit)
+}
+
+// SearchGenresCalls gets all the calls that were made to SearchGenres.
+// Check the length with:
+//
+//	len(mockedMovieStore.SearchGenresCalls())
+func (mock *MovieStore) SearchGenresCalls() []struct {
+	Ctx    context.Context
+	Search string
+	Limit  int
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Search string
+		Limit  int
+	}
+	mock.lockSearchGenres.RLock()
+	calls = mock.calls.SearchGenres
+	mock.lockSearchGenres.RUnlock()
+	return calls
+}
+
+// SearchTitles calls SearchTitlesFunc.
+func (mock *MovieStore) SearchTitles(ctx context.Context, search string, threshold float64, limit int) ([]*data.TitleMatch, error) {
+	callInfo := struct {
+		Ctx       context.Context
+		Search    string
+		Threshold float64
+		Limit     int
+	}{
+		Ctx:       ctx,
+		Search:    search,
+		Threshold: threshold,
+		Limit:     limit,
+	}
+	mock.lockSearchTitles.Lock()
+	mock.calls.SearchTitles = append(mock.calls.SearchTitles, callInfo)
+	mock.lockSearchTitles.Unlock()
+	if mock.SearchTitlesFunc == nil {
+		var (
+			titleMatchsOut []*data.TitleMatch
+			errOut         error
+		)
+		return titleMatchsOut, errOut
+	}
+	return mock.SearchTitlesFunc(ctx, search, threshold, limit)
+}
+
+// SearchTitlesCalls gets all the calls that were made to SearchTitles.
+// Check the length with:
+//
+//	len(mockedMovieStore.SearchTitlesCalls())
+func (mock *MovieStore) SearchTitlesCalls() []struct {
+	Ctx       context.Context
+	Search    string
+	Threshold float64
+	Limit     int
+} {
+	var calls []struct {
+		Ctx       context.Context
+		Search    string
+		Threshold float64
+		Limit     int
+	}
+	mock.lockSearchTitles.RLock()
+	calls = mock.calls.SearchTitles
+	mock.lockSearchTitles.RUnlock()
+	return calls
+}
+
+// SetEmbedding calls SetEmbeddingFunc.
+func (mock *MovieStore) SetEmbedding(ctx context.Context, movieID int64, version int32, model string, embedding []float32) error {
+	callInfo := struct {
+		Ctx       context.Context
+		MovieID   int64
+		Version   int32
+		Model     string
+		Embedding []float32
+	}{
+		Ctx:       ctx,
+		MovieID:   movieID,
+		Version:   version,
+		Model:     model,
+		Embedding: embedding,
+	}
+	mock.lockSetEmbedding.Lock()
+	mock.calls.SetEmbedding = append(mock.calls.SetEmbedding, callInfo)
+	mock.lockSetEmbedding.Unlock()
+	if mock.SetEmbeddingFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.SetEmbeddingFunc(ctx, movieID, version, model, embedding)
+}
+
+// SetEmbeddingCalls gets all the calls that were made to SetEmbedding.
+// Check the length with:
+//
+//	len(mockedMovieStore.SetEmbeddingCalls())
+func (mock *MovieStore) SetEmbeddingCalls() []struct {
+	Ctx       context.Context
+	MovieID   int64
+	Version   int32
+	Model     string
+	Embedding []float32
+} {
+	var calls []struct {
+		Ctx       context.Context
+		MovieID   int64
+		Version   int32
+		Model     string
+		Embedding []float32
+	}
+	mock.lockSetEmbedding.RLock()
+	calls = mock.calls.SetEmbedding
+	mock.lockSetEmbedding.RUnlock()
+	return calls
+}
+
+// SetPoster calls SetPosterFunc.
+func (mock *MovieStore) SetPoster(ctx context.Context, movieID int64, poster *data.Poster) error {
+	callInfo := struct {
+		Ctx     context.Context
+		MovieID int64
+		Poster  *data.Poster
+	}{
+		Ctx:     ctx,
+		MovieID: movieID,
+		Poster:  poster,
+	}
+	mock.lockSetPoster.Lock()
+	mock.calls.SetPoster = append(mock.calls.SetPoster, callInfo)
+	mock.lockSetPoster.Unlock()
+	if mock.SetPosterFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.SetPosterFunc(ctx, movieID, poster)
+}
+
+// SetPosterCalls gets all the calls that were made to SetPoster.
+// Check the length with:
+//
+//	len(mockedMovieStore.SetPosterCalls())
+func (mock *MovieStore) SetPosterCalls() []struct {
+	Ctx     context.Context
+	MovieID int64
+	Poster  *data.Poster
+} {
+	var calls []struct {
+		Ctx     context.Context
+		MovieID int64
+		Poster  *data.Poster
+	}
+	mock.lockSetPoster.RLock()
+	calls = mock.calls.SetPoster
+	mock.lockSetPoster.RUnlock()
+	return calls
+}
+
+// Update calls UpdateFunc.
+func (mock *MovieStore) Update(ctx context.Context, movie *data.Movie) error {
+	callInfo := struct {
+		Ctx   context.Context
+		Movie *data.Movie
+	}{
+		Ctx:   ctx,
+		Movie: movie,
+	}
+	mock.lockUpdate.Lock()
+	mock.calls.Update = append(mock.calls.Update, callInfo)
+	mock.lockUpdate.Unlock()
+	if mock.UpdateFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.UpdateFunc(ctx, movie)
+}
+
+// UpdateCalls gets all the calls that were made to Update.
+// Check the length with:
+//
+//	len(mockedMovieStore.UpdateCalls())
+func (mock *MovieStore) UpdateCalls() []struct {
+	Ctx   context.Context
+	Movie *data.Movie
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Movie *data.Movie
+	}
+	mock.lockUpdate.RLock()
+	calls = mock.calls.Update
+	mock.lockUpdate.RUnlock()
+	return calls
+}
+
+// UpdatePoster calls UpdatePosterFunc.
+func (mock *MovieStore) UpdatePoster(ctx context.Context, movieID int64, poster *data.Poster) error {
+	callInfo := struct {
+		Ctx     context.Context
+		MovieID int64
+		Poster  *data.Poster
+	}{
+		Ctx:     ctx,
+		MovieID: movieID,
+		Poster:  poster,
+	}
+	mock.lockUpdatePoster.Lock()
+	mock.calls.UpdatePoster = append(mock.calls.UpdatePoster, callInfo)
+	mock.lockUpdatePoster.Unlock()
+	if mock.UpdatePosterFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.UpdatePosterFunc(ctx, movieID, poster)
+}
+
+// UpdatePosterCalls gets all the calls that were made to UpdatePoster.
+// Check the length with:
+//
+//	len(mockedMovieStore.UpdatePosterCalls())
+func (mock *MovieStore) UpdatePosterCalls() []struct {
+	Ctx     context.Context
+	MovieID int64
+	Poster  *data.Poster
+} {
+	var calls []struct {
+		Ctx     context.Context
+		MovieID int64
+		Poster  *data.Poster
+	}
+	mock.lockUpdatePoster.RLock()
+	calls = mock.calls.UpdatePoster
+	mock.lockUpdatePoster.RUnlock()
+	return calls
+}
+
+// Ensure, that PermissionStore does implement data.PermissionStore.
+// If this is not the case, regenerate this file with moq.
+var _ data.PermissionStore = &PermissionStore{}
+
+// PermissionStore is a mock implementation of data.PermissionStore.
+//
+//	func TestSomethingThatUsesPermissionStore(t *testing.T) {
+//
+//		// make and configure a mocked data.PermissionStore
+//		mockedPermissionStore := &PermissionStore{
+//			AddForUserFunc: func(ctx context.Context, userID int64, codes ...string) error {
+//				panic("mock out the AddForUser method")
+//			},
+//			GetAllForUserFunc: func(ctx context.Context, userID int64) (data.Permissions, error) {
+//				panic("mock out the GetAllForUser method")
+//			},
+//		}
+//
+//		// use mockedPermissionStore in code that requires data.PermissionStore
+//		// and then make assertions.
+//
+//	}
+type PermissionStore struct {
+	// AddForUserFunc mocks the AddForUser method.
+	AddForUserFunc func(ctx context.Context, userID int64, codes ...string) error
+
+	// GetAllForUserFunc mocks the GetAllForUser method.
+	GetAllForUserFunc func(ctx context.Context, userID int64) (data.Permissions, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// AddForUser holds details about calls to the AddForUser method.
+		AddForUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID int64
+			// Codes is the codes argument value.
+			Codes []string
+		}
+		// GetAllForUser holds details about calls to the GetAllForUser method.
+		GetAllForUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID int64
+		}
+	}
+	lockAddForUser    sync.RWMutex
+	lockGetAllForUser sync.RWMutex
+}
+
+// AddForUser calls AddForUserFunc.
+func (mock *PermissionStore) AddForUser(ctx context.Context, userID int64, codes ...string) error {
+	callInfo := struct {
+		Ctx    context.Context
+		UserID int64
+		Codes  []string
+	}{
+		Ctx:    ctx,
+		UserID: userID,
+		Codes:  codes,
+	}
+	mock.lockAddForUser.Lock()
+	mock.calls.AddForUser = append(mock.calls.AddForUser, callInfo)
+	mock.lockAddForUser.Unlock()
+	if mock.AddForUserFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.AddForUserFunc(ctx, userID, codes...)
+}
+
+// AddForUserCalls gets all the calls that were made to AddForUser.
+// Check the length with:
+//
+//	len(mockedPermissionStore.AddForUserCalls())
+func (mock *PermissionStore) AddForUserCalls() []struct {
+	Ctx    context.Context
+	UserID int64
+	Codes  []string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserID int64
+		Codes  []string
+	}
+	mock.lockAddForUser.RLock()
+	calls = mock.calls.AddForUser
+	mock.lockAddForUser.RUnlock()
+	return calls
+}
+
+// GetAllForUser calls GetAllForUserFunc.
+func (mock *PermissionStore) GetAllForUser(ctx context.Context, userID int64) (data.Permissions, error) {
+	callInfo := struct {
+		Ctx    context.Context
+		UserID int64
+	}{
+		Ctx:    ctx,
+		UserID: userID,
+	}
+	mock.lockGetAllForUser.Lock()
+	mock.calls.GetAllForUser = append(mock.calls.GetAllForUser, callInfo)
+	mock.lockGetAllForUser.Unlock()
+	if mock.GetAllForUserFunc == nil {
+		var (
+			permissionsOut data.Permissions
+			errOut         error
+		)
+		return permissionsOut, errOut
+	}
+	return mock.GetAllForUserFunc(ctx, userID)
+}
+
+// GetAllForUserCalls gets all the calls that were made to GetAllForUser.
+// Check the length with:
+//
+//	len(mockedPermissionStore.GetAllForUserCalls())
+func (mock *PermissionStore) GetAllForUserCalls() []struct {
+	Ctx    context.Context
+	UserID int64
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserID int64
+	}
+	mock.lockGetAllForUser.RLock()
+	calls = mock.calls.GetAllForUser
+	mock.lockGetAllForUser.RUnlock()
+	return calls
+}
+
+// Ensure, that PreferenceStore does implement data.PreferenceStore.
+// If this is not the case, regenerate this file with moq.
+var _ data.PreferenceStore = &PreferenceStore{}
+
+// PreferenceStore is a mock implementation of data.PreferenceStore.
+//
+//	func TestSomethingThatUsesPreferenceStore(t *testing.T) {
+//
+//		// make and configure a mocked data.PreferenceStore
+//		mockedPreferenceStore := &PreferenceStore{
+//			GetFunc: func(ctx context.Context, userID int64) (*data.Preferences, error) {
+//				panic("mock out the Get method")
+//			},
+//			GetDueForDigestFunc: func(ctx context.Context, cutoff time.Time) ([]*data.DigestRecipient, error) {
+//				panic("mock out the GetDueForDigest method")
+//			},
+//			MarkDigestSentFunc: func(ctx context.Context, userID int64) error {
+//				panic("mock out the MarkDigestSent method")
+//			},
+//			UpdateFunc: func(ctx context.Context, preferences *data.Preferences) error {
+//				panic("mock out the Update method")
+//			},
+//		}
+//
+//		// use mockedPreferenceStore in code that requires data.PreferenceStore
+//		// and then make assertions.
+//
+//	}
+type PreferenceStore struct {
+	// GetFunc mocks the Get method.
+	GetFunc func(ctx context.Context, userID int64) (*data.Preferences, error)
+
+	// GetDueForDigestFunc mocks the GetDueForDigest method.
+	GetDueForDigestFunc func(ctx context.Context, cutoff time.Time) ([]*data.DigestRecipient, error)
+
+	// MarkDigestSentFunc mocks the MarkDigestSent method.
+	MarkDigestSentFunc func(ctx context.Context, userID int64) error
+
+	// UpdateFunc mocks the Update method.
+	UpdateFunc func(ctx context.Context, preferences *data.Preferences) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Get holds details about calls to the Get method.
+		Get []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID int64
+		}
+		// GetDueForDigest holds details about calls to the GetDueForDigest method.
+		GetDueForDigest []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Cutoff is the cutoff argument value.
+			Cutoff time.Time
+		}
+		// MarkDigestSent holds details about calls to the MarkDigestSent method.
+		MarkDigestSent []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID int64
+		}
+		// Update holds details about calls to the Update method.
+		Update []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Preferences is the preferences argument value.
+			Preferences *data.Preferences
+		}
+	}
+	lockGet             sync.RWMutex
+	lockGetDueForDigest sync.RWMutex
+	lockMarkDigestSent  sync.RWMutex
+	lockUpdate          sync.RWMutex
+}
+
+// Get calls GetFunc.
+func (mock *PreferenceStore) Get(ctx context.Context, userID int64) (*data.Preferences, error) {
+	callInfo := struct {
+		Ctx    context.Context
+		UserID int64
+	}{
+		Ctx:    ctx,
+		UserID: userID,
+	}
+	mock.lockGet.Lock()
+	mock.calls.Get = append(mock.calls.Get, callInfo)
+	mock.lockGet.Unlock()
+	if mock.GetFunc == nil {
+		var (
+			preferencesOut *data.Preferences
+			errOut         error
+		)
+		return preferencesOut, errOut
+	}
+	return mock.GetFunc(ctx, userID)
+}
+
+// GetCalls gets all the calls that were made to Get.
+// Check the length with:
+//
+//	len(mockedPreferenceStore.GetCalls())
+func (mock *PreferenceStore) GetCalls() []struct {
+	Ctx    context.Context
+	UserID int64
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserID int64
+	}
+	mock.lockGet.RLock()
+	calls = mock.calls.Get
+	mock.lockGet.RUnlock()
+	return calls
+}
+
+// GetDueForDigest calls GetDueForDigestFunc.
+func (mock *PreferenceStore) GetDueForDigest(ctx context.Context, cutoff time.Time) ([]*data.DigestRecipient, error) {
+	callInfo := struct {
+		Ctx    context.Context
+		Cutoff time.Time
+	}{
+		Ctx:    ctx,
+		Cutoff: cutoff,
+	}
+	mock.lockGetDueForDigest.Lock()
+	mock.calls.GetDueForDigest = append(mock.calls.GetDueForDigest, callInfo)
+	mock.lockGetDueForDigest.Unlock()
+	if mock.GetDueForDigestFunc == nil {
+		var (
+			digestRecipientsOut []*data.DigestRecipient
+			errOut              error
+		)
+		return digestRecipientsOut, errOut
+	}
+	return mock.GetDueForDigestFunc(ctx, cutoff)
+}
+
+// GetDueForDigestCalls gets all the calls that were made to GetDueForDigest.
+// Check the length with:
+//
+//	len(mockedPreferenceStore.GetDueForDigestCalls())
+func (mock *PreferenceStore) GetDueForDigestCalls() []struct {
+	Ctx    context.Context
+	Cutoff time.Time
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Cutoff time.Time
+	}
+	mock.lockGetDueForDigest.RLock()
+	calls = mock.calls.GetDueForDigest
+	mock.lockGetDueForDigest.RUnlock()
+	return calls
+}
+
+// MarkDigestSent calls MarkDigestSentFunc.
+func (mock *PreferenceStore) MarkDigestSent(ctx context.Context, userID int64) error {
+	callInfo := struct {
+		Ctx    context.Context
+		UserID int64
+	}{
+		Ctx:    ctx,
+		UserID: userID,
+	}
+	mock.lockMarkDigestSent.Lock()
+	mock.calls.MarkDigestSent = append(mock.calls.MarkDigestSent, callInfo)
+	mock.lockMarkDigestSent.Unlock()
+	if mock.MarkDigestSentFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.MarkDigestSentFunc(ctx, userID)
+}
+
+// MarkDigestSentCalls gets all the calls that were made to MarkDigestSent.
+// Check the length with:
+//
+//	len(mockedPreferenceStore.MarkDigestSentCalls())
+func (mock *PreferenceStore) MarkDigestSentCalls() []struct {
+	Ctx    context.Context
+	UserID int64
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserID int64
+	}
+	mock.lockMarkDigestSent.RLock()
+	calls = mock.calls.MarkDigestSent
+	mock.lockMarkDigestSent.RUnlock()
+	return calls
+}
+
+// Update calls UpdateFunc.
+func (mock *PreferenceStore) Update(ctx context.Context, preferences *data.Preferences) error {
+	callInfo := struct {
+		Ctx         context.Context
+		Preferences *data.Preferences
+	}{
+		Ctx:         ctx,
+		Preferences: preferences,
+	}
+	mock.lockUpdate.Lock()
+	mock.calls.Update = append(mock.calls.Update, callInfo)
+	mock.lockUpdate.Unlock()
+	if mock.UpdateFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.UpdateFunc(ctx, preferences)
+}
+
+// UpdateCalls gets all the calls that were made to Update.
+// Check the length with:
+//
+//	len(mockedPreferenceStore.UpdateCalls())
+func (mock *PreferenceStore) UpdateCalls() []struct {
+	Ctx         context.Context
+	Preferences *data.Preferences
+} {
+	var calls []struct {
+		Ctx         context.Context
+		Preferences *data.Preferences
+	}
+	mock.lockUpdate.RLock()
+	calls = mock.calls.Update
+	mock.lockUpdate.RUnlock()
+	return calls
+}
+
+// Ensure, that SuggestionStore does implement data.SuggestionStore.
+// If this is not the case, regenerate this file with moq.
+var _ data.SuggestionStore = &SuggestionStore{}
+
+// SuggestionStore is a mock implementation of data.SuggestionStore.
+//
+//	func TestSomethingThatUsesSuggestionStore(t *testing.T) {
+//
+//		// make and configure a mocked data.SuggestionStore
+//		mockedSuggestionStore := &SuggestionStore{
+//			GetFunc: func(ctx context.Context, id int64) (*data.Suggestion, error) {
+//				panic("mock out the Get method")
+//			},
+//			GetAllFunc: func(ctx context.Context, movieID int64, status string, filters data.Filters) ([]*data.Suggestion, data.Metadata, error) {
+//				panic("mock out the GetAll method")
+//			},
+//			InsertFunc: func(ctx context.Context, suggestion *data.Suggestion) error {
+//				panic("mock out the Insert method")
+//			},
+//			ReviewFunc: func(ctx context.Context, suggestion *data.Suggestion) error {
+//				panic("mock out the Review method")
+//			},
+//		}
+//
+//		// use mockedSuggestionStore in code that requires data.SuggestionStore
+//		// and then make assertions.
+//
+//	}
+type SuggestionStore struct {
+	// GetFunc mocks the Get method.
+	GetFunc func(ctx context.Context, id int64) (*data.Suggestion, error)
+
+	// GetAllFunc mocks the GetAll method.
+	GetAllFunc func(ctx context.Context, movieID int64, status string, filters data.Filters) ([]*data.Suggestion, data.Metadata, error)
+
+	// InsertFunc mocks the Insert method.
+	InsertFunc func(ctx context.Context, suggestion *data.Suggestion) error
+
+	// ReviewFunc mocks the Review method.
+	ReviewFunc func(ctx context.Context, suggestion *data.Suggestion) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Get holds details about calls to the Get method.
+		Get []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int64
+		}
+		// GetAll holds details about calls to the GetAll method.
+		GetAll []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// MovieID is the movieID argument value.
+			MovieID int64
+			// Status is the status argument value.
+			Status string
+			// Filters is the filters argument value.
+			Filters data.Filters
+		}
+		// Insert holds details about calls to the Insert method.
+		Insert []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Suggestion is the suggestion argument value.
+			Suggestion *data.Suggestion
+		}
+		// Review holds details about calls to the Review method.
+		Review []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Suggestion is the suggestion argument value.
+			Suggestion *data.Suggestion
+		}
+	}
+	lockGet    sync.RWMutex
+	lockGetAll sync.RWMutex
+	lockInsert sync.RWMutex
+	lockReview sync.RWMutex
+}
+
+// Get calls GetFunc.
+func (mock *SuggestionStore) Get(ctx context.Context, id int64) (*data.Suggestion, error) {
+	callInfo := struct {
+		Ctx context.Context
+		ID  int64
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGet.Lock()
+	mock.calls.Get = append(mock.calls.Get, callInfo)
+	mock.lockGet.Unlock()
+	if mock.GetFunc == nil {
+		var (
+			suggestionOut *data.Suggestion
+			errOut        error
+		)
+		return suggestionOut, errOut
+	}
+	return mock.GetFunc(ctx, id)
+}
+
+// GetCalls gets all the calls that were made to Get.
+// Check the length with:
+//
+//	len(mockedSuggestionStore.GetCalls())
+func (mock *SuggestionStore) GetCalls() []struct {
+	Ctx context.Context
+	ID  int64
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int64
+	}
+	mock.lockGet.RLock()
+	calls = mock.calls.Get
+	mock.lockGet.RUnlock()
+	return calls
+}
+
+// GetAll calls GetAllFunc.
+func (mock *SuggestionStore) GetAll(ctx context.Context, movieID int64, status string, filters data.Filters) ([]*data.Suggestion, data.Metadata, error) {
+	callInfo := struct {
+		Ctx     context.Context
+		MovieID int64
+		Status  string
+		Filters data.Filters
+	}{
+		Ctx:     ctx,
+		MovieID: movieID,
+		Status:  status,
+		Filters: filters,
+	}
+	mock.lockGetAll.Lock()
+	mock.calls.GetAll = append(mock.calls.GetAll, callInfo)
+	mock.lockGetAll.Unlock()
+	if mock.GetAllFunc == nil {
+		var (
+			suggestionsOut []*data.Suggestion
+			metadataOut    data.Metadata
+			errOut         error
+		)
+		return suggestionsOut, metadataOut, errOut
+	}
+	return mock.GetAllFunc(ctx, movieID, status, filters)
+}
+
+// GetAllCalls gets all the calls that were made to GetAll.
+// Check the length with:
+//
+//	len(mockedSuggestionStore.GetAllCalls())
+func (mock *SuggestionStore) GetAllCalls() []struct {
+	Ctx     context.Context
+	MovieID int64
+	Status  string
+	Filters data.Filters
+} {
+	var calls []struct {
+		Ctx     context.Context
+		MovieID int64
+		Status  string
+		Filters data.Filters
+	}
+	mock.lockGetAll.RLock()
+	calls = mock.calls.GetAll
+	mock.lockGetAll.RUnlock()
+	return calls
+}
+
+// Insert calls InsertFunc.
+func (mock *SuggestionStore) Insert(ctx context.Context, suggestion *data.Suggestion) error {
+	callInfo := struct {
+		Ctx        context.Context
+		Suggestion *data.Suggestion
+	}{
+		Ctx:        ctx,
+		Suggestion: suggestion,
+	}
+	mock.lockInsert.Lock()
+	mock.calls.Insert = append(mock.calls.Insert, callInfo)
+	mock.lockInsert.Unlock()
+	if mock.InsertFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.InsertFunc(ctx, suggestion)
+}
+
+// InsertCalls gets all the calls that were made to Insert.
+// Check the length with:
+//
+//	len(mockedSuggestionStore.InsertCalls())
+func (mock *SuggestionStore) InsertCalls() []struct {
+	Ctx        context.Context
+	Suggestion *data.Suggestion
+} {
+	var calls []struct {
+		Ctx        context.Context
+		Suggestion *data.Suggestion
+	}
+	mock.lockInsert.RLock()
+	calls = mock.calls.Insert
+	mock.lockInsert.RUnlock()
+	return calls
+}
+
+// Review calls ReviewFunc.
+func (mock *SuggestionStore) Review(ctx context.Context, suggestion *data.Suggestion) error {
+	callInfo := struct {
+		Ctx        context.Context
+		Suggestion *data.Suggestion
+	}{
+		Ctx:        ctx,
+		Suggestion: suggestion,
+	}
+	mock.lockReview.Lock()
+	mock.calls.Review = append(mock.calls.Review, callInfo)
+	mock.lockReview.Unlock()
+	if mock.ReviewFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.ReviewFunc(ctx, suggestion)
+}
+
+// ReviewCalls gets all the calls that were made to Review.
+// Check the length with:
+//
+//	len(mockedSuggestionStore.ReviewCalls())
+func (mock *SuggestionStore) ReviewCalls() []struct {
+	Ctx        context.Context
+	Suggestion *data.Suggestion
+} {
+	var calls []struct {
+		Ctx        context.Context
+		Suggestion *data.Suggestion
+	}
+	mock.lockReview.RLock()
+	calls = mock.calls.Review
+	mock.lockReview.RUnlock()
+	return calls
+}
+
+// Ensure, that TokenStore does implement data.TokenStore.
+// If this is not the case, regenerate this file with moq.
+var _ data.TokenStore = &TokenStore{}
+
+// TokenStore is a mock implementation of data.TokenStore.
+//
+//	func TestSomethingThatUsesTokenStore(t *testing.T) {
+//
+//		// make and configure a mocked data.TokenStore
+//		mockedTokenStore := &TokenStore{
+//			CountActiveFunc: func(ctx context.Context) (map[string]int, error) {
+//				panic("mock out the CountActive method")
+//			},
+//			DeleteAllForUserFunc: func(ctx context.Context, scope string, userID int64) error {
+//				panic("mock out the DeleteAllForUser method")
+//			},
+//			DeleteExpiredFunc: func(ctx context.Context, before time.Time, limit int) (int64, error) {
+//				panic("mock out the DeleteExpired method")
+//			},
+//			GetTableStatsFunc: func(ctx context.Context) (*data.TokenTableStats, error) {
+//				panic("mock out the GetTableStats method")
+//			},
+//			InsertFunc: func(ctx context.Context, token *data.Token) error {
+//				panic("mock out the Insert method")
+//			},
+//			NewFunc: func(ctx context.Context, userID int64, ttl time.Duration, scope string) (*data.Token, error) {
+//				panic("mock out the New method")
+//			},
+//		}
+//
+//		// use mockedTokenStore in code that requires data.TokenStore
+//		// and then make assertions.
+//
+//	}
+type TokenStore struct {
+	// CountActiveFunc mocks the CountActive method.
+	CountActiveFunc func(ctx context.Context) (map[string]int, error)
+
+	// DeleteAllForUserFunc mocks the DeleteAllForUser method.
+	DeleteAllForUserFunc func(ctx context.Context, scope string, userID int64) error
+
+	// DeleteExpiredFunc mocks the DeleteExpired method.
+	DeleteExpiredFunc func(ctx context.Context, before time.Time, limit int) (int64, error)
+
+	// GetTableStatsFunc mocks the GetTableStats method.
+	GetTableStatsFunc func(ctx context.Context) (*data.TokenTableStats, error)
+
+	// InsertFunc mocks the Insert method.
+	InsertFunc func(ctx context.Context, token *data.Token) error
+
+	// NewFunc mocks the New method.
+	NewFunc func(ctx context.Context, userID int64, ttl time.Duration, scope string) (*data.Token, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// CountActive holds details about calls to the CountActive method.
+		CountActive []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// DeleteAllForUser holds details about calls to the DeleteAllForUser method.
+		DeleteAllForUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Scope is the scope argument value.
+			Scope string
+			// UserID is the userID argument value.
+			UserID int64
+		}
+		// DeleteExpired holds details about calls to the DeleteExpired method.
+		DeleteExpired []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Before is the before argument value.
+			Before time.Time
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// GetTableStats holds details about calls to the GetTableStats method.
+		GetTableStats []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// Insert holds details about calls to the Insert method.
+		Insert []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Token is the token argument value.
+			Token *data.Token
+		}
+		// New holds details about calls to the New method.
+		New []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID int64
+			// TTL is the ttl argument value.
+			TTL time.Duration
+			// Scope is the scope argument value.
+			Scope string
+		}
+	}
+	lockCountActive      sync.RWMutex
+	lockDeleteAllForUser sync.RWMutex
+	lockDeleteExpired    sync.RWMutex
+	lockGetTableStats    sync.RWMutex
+	lockInsert           sync.RWMutex
+	lockNew              sync.RWMutex
+}
+
+// CountActive calls CountActiveFunc.
+func (mock *TokenStore) CountActive(ctx context.Context) (map[string]int, error) {
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockCountActive.Lock()
+	mock.calls.CountActive = append(mock.calls.CountActive, callInfo)
+	mock.lockCountActive.Unlock()
+	if mock.CountActiveFunc == nil {
+		var (
+			stringToIntOut map[string]int
+			errOut         error
+		)
+		return stringToIntOut, errOut
+	}
+	return mock.CountActiveFunc(ctx)
+}
+
+// CountActiveCalls gets all the calls that were made to CountActive.
+// Check the length with:
+//
+//	len(mockedTokenStore.CountActiveCalls())
+func (mock *TokenStore) CountActiveCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockCountActive.RLock()
+	calls = mock.calls.CountActive
+	mock.lockCountActive.RUnlock()
+	return calls
+}
+
+// DeleteAllForUser calls DeleteAllForUserFunc.
+func (mock *TokenStore) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	callInfo := struct {
+		Ctx    context.Context
+		Scope  string
+		UserID int64
+	}{
+		Ctx:    ctx,
+		Scope:  scope,
+		UserID: userID,
+	}
+	mock.lockDeleteAllForUser.Lock()
+	mock.calls.DeleteAllForUser = append(mock.calls.DeleteAllForUser, callInfo)
+	mock.lockDeleteAllForUser.Unlock()
+	if mock.DeleteAllForUserFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.DeleteAllForUserFunc(ctx, scope, userID)
+}
+
+// DeleteAllForUserCalls gets all the calls that were made to DeleteAllForUser.
+// Check the length with:
+//
+//	len(mockedTokenStore.DeleteAllForUserCalls())
+func (mock *TokenStore) DeleteAllForUserCalls() []struct {
+	Ctx    context.Context
+	Scope  string
+	UserID int64
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Scope  string
+		UserID int64
+	}
+	mock.lockDeleteAllForUser.RLock()
+	calls = mock.calls.DeleteAllForUser
+	mock.lockDeleteAllForUser.RUnlock()
+	return calls
+}
+
+// DeleteExpired calls DeleteExpiredFunc.
+func (mock *TokenStore) DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error) {
+	callInfo := struct {
+		Ctx    context.Context
+		Before time.Time
+		Limit  int
+	}{
+		Ctx:    ctx,
+		Before: before,
+		Limit:  limit,
+	}
+	mock.lockDeleteExpired.Lock()
+	mock.calls.DeleteExpired = append(mock.calls.DeleteExpired, callInfo)
+	mock.lockDeleteExpired.Unlock()
+	if mock.DeleteExpiredFunc == nil {
+		var (
+			nOut   int64
+			errOut error
+		)
+		return nOut, errOut
+	}
+	return mock.DeleteExpiredFunc(ctx, before, limit)
+}
+
+// DeleteExpiredCalls gets all the calls that were made to DeleteExpired.
+// Check the length with:
+//
+//	len(mockedTokenStore.DeleteExpiredCalls())
+func (mock *TokenStore) DeleteExpiredCalls() []struct {
+	Ctx    context.Context
+	Before time.Time
+	Limit  int
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Before time.Time
+		Limit  int
+	}
+	mock.lockDeleteExpired.RLock()
+	calls = mock.calls.DeleteExpired
+	mock.lockDeleteExpired.RUnlock()
+	return calls
+}
+
+// GetTableStats calls GetTableStatsFunc.
+func (mock *TokenStore) GetTableStats(ctx context.Context) (*data.TokenTableStats, error) {
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetTableStats.Lock()
+	mock.calls.GetTableStats = append(mock.calls.GetTableStats, callInfo)
+	mock.lockGetTableStats.Unlock()
+	if mock.GetTableStatsFunc == nil {
+		var (
+			tokenTableStatsOut *data.TokenTableStats
+			errOut             error
+		)
+		return tokenTableStatsOut, errOut
+	}
+	return mock.GetTableStatsFunc(ctx)
+}
+
+// GetTableStatsCalls gets all the calls that were made to GetTableStats.
+// Check the length with:
+//
+//	len(mockedTokenStore.GetTableStatsCalls())
+func (mock *TokenStore) GetTableStatsCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetTableStats.RLock()
+	calls = mock.calls.GetTableStats
+	mock.lockGetTableStats.RUnlock()
+	return calls
+}
+
+// Insert calls InsertFunc.
+func (mock *TokenStore) Insert(ctx context.Context, token *data.Token) error {
+	callInfo := struct {
+		Ctx   context.Context
+		Token *data.Token
+	}{
+		Ctx:   ctx,
+		Token: token,
+	}
+	mock.lockInsert.Lock()
+	mock.calls.Insert = append(mock.calls.Insert, callInfo)
+	mock.lockInsert.Unlock()
+	if mock.InsertFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.InsertFunc(ctx, token)
+}
+
+// InsertCalls gets all the calls that were made to Insert.
+// Check the length with:
+//
+//	len(mockedTokenStore.InsertCalls())
+func (mock *TokenStore) InsertCalls() []struct {
+	Ctx   context.Context
+	Token *data.Token
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Token *data.Token
+	}
+	mock.lockInsert.RLock()
+	calls = mock.calls.Insert
+	mock.lockInsert.RUnlock()
+	return calls
+}
+
+// New calls NewFunc.
+func (mock *TokenStore) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*data.Token, error) {
+	callInfo := struct {
+		Ctx    context.Context
+		UserID int64
+		TTL    time.Duration
+		Scope  string
+	}{
+		Ctx:    ctx,
+		UserID: userID,
+		TTL:    ttl,
+		Scope:  scope,
+	}
+	mock.lockNew.Lock()
+	mock.calls.New = append(mock.calls.New, callInfo)
+	mock.lockNew.Unlock()
+	if mock.NewFunc == nil {
+		var (
+			tokenOut *data.Token
+			errOut   error
+		)
+		return tokenOut, errOut
+	}
+	return mock.NewFunc(ctx, userID, ttl, scope)
+}
+
+// NewCalls gets all the calls that were made to New.
+// Check the length with:
+//
+//	len(mockedTokenStore.NewCalls())
+func (mock *TokenStore) NewCalls() []struct {
+	Ctx    context.Context
+	UserID int64
+	TTL    time.Duration
+	Scope  string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserID int64
+		TTL    time.Duration
+		Scope  string
+	}
+	mock.lockNew.RLock()
+	calls = mock.calls.New
+	mock.lockNew.RUnlock()
+	return calls
+}
+
+// Ensure, that UsageStore does implement data.UsageStore.
+// If this is not the case, regenerate this file with moq.
+var _ data.UsageStore = &UsageStore{}
+
+// UsageStore is a mock implementation of data.UsageStore.
+//
+//	func TestSomethingThatUsesUsageStore(t *testing.T) {
+//
+//		// make and configure a mocked data.UsageStore
+//		mockedUsageStore := &UsageStore{
+//			DeleteBeforeFunc: func(ctx context.Context, before time.Time) (int64, error) {
+//				panic("mock out the DeleteBefore method")
+//			},
+//			GetDailyFunc: func(ctx context.Context, userID int64, since time.Time) ([]*data.DailyUsage, error) {
+//				panic("mock out the GetDaily method")
+//			},
+//			GetTopFunc: func(ctx context.Context, since time.Time, sort string, limit int) ([]*data.UserUsage, error) {
+//				panic("mock out the GetTop method")
+//			},
+//			RecordManyFunc: func(ctx context.Context, counts []data.UsageCount) error {
+//				panic("mock out the RecordMany method")
+//			},
+//		}
+//
+//		// use mockedUsageStore in code that requires data.UsageStore
+//		// and then make assertions.
+//
+//	}
+type UsageStore struct {
+	// DeleteBeforeFunc mocks the DeleteBefore method.
+	DeleteBeforeFunc func(ctx context.Context, before time.Time) (int64, error)
+
+	// GetDailyFunc mocks the GetDaily method.
+	GetDailyFunc func(ctx context.Context, userID int64, since time.Time) ([]*data.DailyUsage, error)
+
+	// GetTopFunc mocks the GetTop method.
+	GetTopFunc func(ctx context.Context, since time.Time, sort string, limit int) ([]*data.UserUsage, error)
+
+	// RecordManyFunc mocks the RecordMany method.
+	RecordManyFunc func(ctx context.Context, counts []data.UsageCount) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// DeleteBefore holds details about calls to the DeleteBefore method.
+		DeleteBefore []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Before is the before argument value.
+			Before time.Time
+		}
+		// GetDaily holds details about calls to the GetDaily method.
+		GetDaily []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID int64
+			// Since is the since argument value.
+			Since time.Time
+		}
+		// GetTop holds details about calls to the GetTop method.
+		GetTop []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Since is the since argument value.
+			Since time.Time
+			// Sort is the sort argument value.
+			Sort string
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// RecordMany holds details about calls to the RecordMany method.
+		RecordMany []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Counts is the counts argument value.
+			Counts []data.UsageCount
+		}
+	}
+	lockDeleteBefore sync.RWMutex
+	lockGetDaily     sync.RWMutex
+	lockGetTop       sync.RWMutex
+	lockRecordMany   sync.RWMutex
+}
+
+// DeleteBefore calls DeleteBeforeFunc.
+func (mock *UsageStore) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	callInfo := struct {
+		Ctx    context.Context
+		Before time.Time
+	}{
+		Ctx:    ctx,
+		Before: before,
+	}
+	mock.lockDeleteBefore.Lock()
+	mock.calls.DeleteBefore = append(mock.calls.DeleteBefore, callInfo)
+	mock.lockDeleteBefore.Unlock()
+	if mock.DeleteBeforeFunc == nil {
+		var (
+			nOut   int64
+			errOut error
+		)
+		return nOut, errOut
+	}
+	return mock.DeleteBeforeFunc(ctx, before)
+}
+
+// DeleteBeforeCalls gets all the calls that were made to DeleteBefore.
+// Check the length with:
+//
+//	len(mockedUsageStore.DeleteBeforeCalls())
+func (mock *UsageStore) DeleteBeforeCalls() []struct {
+	Ctx    context.Context
+	Before time.Time
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Before time.Time
+	}
+	mock.lockDeleteBefore.RLock()
+	calls = mock.calls.DeleteBefore
+	mock.lockDeleteBefore.RUnlock()
+	return calls
+}
+
+// GetDaily calls GetDailyFunc.
+func (mock *UsageStore) GetDaily(ctx context.Context, userID int64, since time.Time) ([]*data.DailyUsage, error) {
+	callInfo := struct {
+		Ctx    context.Context
+		UserID int64
+		Since  time.Time
+	}{
+		Ctx:    ctx,
+		UserID: userID,
+		Since:  since,
+	}
+	mock.lockGetDaily.Lock()
+	mock.calls.GetDaily = append(mock.calls.GetDaily, callInfo)
+	mock.lockGetDaily.Unlock()
+	if mock.GetDailyFunc == nil {
+		var (
+			dailyUsagesOut []*data.DailyUsage
+			errOut         error
+		)
+		return dailyUsagesOut, errOut
+	}
+	return mock.GetDailyFunc(ctx, userID, since)
+}
+
+// GetDailyCalls gets all the calls that were made to GetDaily.
+// Check the length with:
+//
+//	len(mockedUsageStore.GetDailyCalls())
+func (mock *UsageStore) GetDailyCalls() []struct {
+	Ctx    context.Context
+	UserID int64
+	Since  time.Time
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserID int64
+		Since  time.Time
+	}
+	mock.lockGetDaily.RLock()
+	calls = mock.calls.GetDaily
+	mock.lockGetDaily.RUnlock()
+	return calls
+}
+
+// GetTop calls GetTopFunc.
+func (mock *UsageStore) GetTop(ctx context.Context, since time.Time, sort string, limit int) ([]*data.UserUsage, error) {
+	callInfo := struct {
+		Ctx   context.Context
+		Since time.Time
+		Sort  string
+		Limit int
+	}{
+		Ctx:   ctx,
+		Since: since,
+		Sort:  sort,
+		Limit: limit,
+	}
+	mock.lockGetTop.Lock()
+	mock.calls.GetTop = append(mock.calls.GetTop, callInfo)
+	mock.lockGetTop.Unlock()
+	if mock.GetTopFunc == nil {
+		var (
+			userUsagesOut []*data.UserUsage
+			errOut        error
+		)
+		return userUsagesOut, errOut
+	}
+	return mock.GetTopFunc(ctx, since, sort, limit)
+}
+
+// GetTopCalls gets all the calls that were made to GetTop.
+// Check the length with:
+//
+//	len(mockedUsageStore.GetTopCalls())
+func (mock *UsageStore) GetTopCalls() []struct {
+	Ctx   context.Context
+	Since time.Time
+	Sort  string
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Since time.Time
+		Sort  string
+		Limit int
+	}
+	mock.lockGetTop.RLock()
+	calls = mock.calls.GetTop
+	mock.lockGetTop.RUnlock()
+	return calls
+}
+
+// RecordMany calls RecordManyFunc.
+func (mock *UsageStore) RecordMany(ctx context.Context, counts []data.UsageCount) error {
+	callInfo := struct {
+		Ctx    context.Context
+		Counts []data.UsageCount
+	}{
+		Ctx:    ctx,
+		Counts: counts,
+	}
+	mock.lockRecordMany.Lock()
+	mock.calls.RecordMany = append(mock.calls.RecordMany, callInfo)
+	mock.lockRecordMany.Unlock()
+	if mock.RecordManyFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.RecordManyFunc(ctx, counts)
+}
+
+// RecordManyCalls gets all the calls that were made to RecordMany.
+// Check the length with:
+//
+//	len(mockedUsageStore.RecordManyCalls())
+func (mock *UsageStore) RecordManyCalls() []struct {
+	Ctx    context.Context
+	Counts []data.UsageCount
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Counts []data.UsageCount
+	}
+	mock.lockRecordMany.RLock()
+	calls = mock.calls.RecordMany
+	mock.lockRecordMany.RUnlock()
+	return calls
+}
+
+// Ensure, that UserStore does implement data.UserStore.
+// If this is not the case, regenerate this file with moq.
+var _ data.UserStore = &UserStore{}
+
+// UserStore is a mock implementation of data.UserStore.
+//
+//	func TestSomethingThatUsesUserStore(t *testing.T) {
+//
+//		// make and configure a mocked data.UserStore
+//		mockedUserStore := &UserStore{
+//			ActivateManyFunc: func(ctx context.Context, ids []int64, emails []string) ([]*data.UserActivation, error) {
+//				panic("mock out the ActivateMany method")
+//			},
+//			GetFunc: func(ctx context.Context, id int64) (*data.User, error) {
+//				panic("mock out the Get method")
+//			},
+//			GetByEmailFunc: func(ctx context.Context, email string) (*data.User, error) {
+//				panic("mock out the GetByEmail method")
+//			},
+//			GetCountsFunc: func(ctx context.Context) (*data.UserCounts, error) {
+//				panic("mock out the GetCounts method")
+//			},
+//			GetForTokenFunc: func(ctx context.Context, tokenScope string, tokenPlaintext string) (*data.User, error) {
+//				panic("mock out the GetForToken method")
+//			},
+//			GetRegistrationsPerDayFunc: func(ctx context.Context, since time.Time) ([]*data.DailyRegistrations, error) {
+//				panic("mock out the GetRegistrationsPerDay method")
+//			},
+//			InsertFunc: func(ctx context.Context, user *data.User) error {
+//				panic("mock out the Insert method")
+//			},
+//			UpdateFunc: func(ctx context.Context, user *data.User) error {
+//				panic("mock out the Update method")
+//			},
+//		}
+//
+//		// use mockedUserStore in code that requires data.UserStore
+//		// and then make assertions.
+//
+//	}
+type UserStore struct {
+	// ActivateManyFunc mocks the ActivateMany method.
+	ActivateManyFunc func(ctx context.Context, ids []int64, emails []string) ([]*data.UserActivation, error)
+
+	// GetFunc mocks the Get method.
+	GetFunc func(ctx context.Context, id int64) (*data.User, error)
+
+	// GetByEmailFunc mocks the GetByEmail method.
+	GetByEmailFunc func(ctx context.Context, email string) (*data.User, error)
+
+	// GetCountsFunc mocks the GetCounts method.
+	GetCountsFunc func(ctx context.Context) (*data.UserCounts, error)
+
+	// GetForTokenFunc mocks the GetForToken method.
+	GetForTokenFunc func(ctx context.Context, tokenScope string, tokenPlaintext string) (*data.User, error)
+
+	// GetRegistrationsPerDayFunc mocks the GetRegistrationsPerDay method.
+	GetRegistrationsPerDayFunc func(ctx context.Context, since time.Time) ([]*data.DailyRegistrations, error)
+
+	// InsertFunc mocks the Insert method.
+	InsertFunc func(ctx context.Context, user *data.User) error
+
+	// UpdateFunc mocks the Update method.
+	UpdateFunc func(ctx context.Context, user *data.User) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// ActivateMany holds details about calls to the ActivateMany method.
+		ActivateMany []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ids is the ids argument value.
+			Ids []int64
+			// Emails is the emails argument value.
+			Emails []string
+		}
+		// Get holds details about calls to the Get method.
+		Get []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int64
+		}
+		// GetByEmail holds details about calls to the GetByEmail method.
+		GetByEmail []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Email is the email argument value.
+			Email string
+		}
+		// GetCounts holds details about calls to the GetCounts method.
+		GetCounts []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// GetForToken holds details about calls to the GetForToken method.
+		GetForToken []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// TokenScope is the tokenScope argument value.
+			TokenScope string
+			// TokenPlaintext is the tokenPlaintext argument value.
+			TokenPlaintext string
+		}
+		// GetRegistrationsPerDay holds details about calls to the GetRegistrationsPerDay method.
+		GetRegistrationsPerDay []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Since is the since argument value.
+			Since time.Time
+		}
+		// Insert holds details about calls to the Insert method.
+		Insert []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// User is the user argument value.
+			User *data.User
+		}
+		// Update holds details about calls to the Update method.
+		Update []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// User is the user argument value.
+			User *data.User
+		}
+	}
+	lockActivateMany           sync.RWMutex
+	lockGet                    sync.RWMutex
+	lockGetByEmail             sync.RWMutex
+	lockGetCounts              sync.RWMutex
+	lockGetForToken            sync.RWMutex
+	lockGetRegistrationsPerDay sync.RWMutex
+	lockInsert                 sync.RWMutex
+	lockUpdate                 sync.RWMutex
+}
+
+// ActivateMany calls ActivateManyFunc.
+func (mock *UserStore) ActivateMany(ctx context.Context, ids []int64, emails []string) ([]*data.UserActivation, error) {
+	callInfo := struct {
+		Ctx    context.Context
+		Ids    []int64
+		Emails []string
+	}{
+		Ctx:    ctx,
+		Ids:    ids,
+		Emails: emails,
+	}
+	mock.lockActivateMany.Lock()
+	mock.calls.ActivateMany = append(mock.calls.ActivateMany, callInfo)
+	mock.lockActivateMany.Unlock()
+	if mock.ActivateManyFunc == nil {
+		var (
+			userActivationsOut []*data.UserActivation
+			errOut             error
+		)
+		return userActivationsOut, errOut
+	}
+	return mock.ActivateManyFunc(ctx, ids, emails)
+}
+
+// ActivateManyCalls gets all the calls that were made to ActivateMany.
+// Check the length with:
+//
+//	len(mockedUserStore.ActivateManyCalls())
+func (mock *UserStore) ActivateManyCalls() []struct {
+	Ctx    context.Context
+	Ids    []int64
+	Emails []string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Ids    []int64
+		Emails []string
+	}
+	mock.lockActivateMany.RLock()
+	calls = mock.calls.ActivateMany
+	mock.lockActivateMany.RUnlock()
+	return calls
+}
+
+// Get calls GetFunc.
+func (mock *UserStore) Get(ctx context.Context, id int64) (*data.User, error) {
+	callInfo := struct {
+		Ctx context.Context
+		ID  int64
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGet.Lock()
+	mock.calls.Get = append(mock.calls.Get, callInfo)
+	mock.lockGet.Unlock()
+	if mock.GetFunc == nil {
+		var (
+			userOut *data.User
+			errOut  error
+		)
+		return userOut, errOut
+	}
+	return mock.GetFunc(ctx, id)
+}
+
+// GetCalls gets all the calls that were made to Get.
+// Check the length with:
+//
+//	len(mockedUserStore.GetCalls())
+func (mock *UserStore) GetCalls() []struct {
+	Ctx context.Context
+	ID  int64
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int64
+	}
+	mock.lockGet.RLock()
+	calls = mock.calls.Get
+	mock.lockGet.RUnlock()
+	return calls
+}
+
+// GetByEmail calls GetByEmailFunc.
+func (mock *UserStore) GetByEmail(ctx context.Context, email string) (*data.User, error) {
+	callInfo := struct {
+		Ctx   context.Context
+		Email string
+	}{
+		Ctx:   ctx,
+		Email: email,
+	}
+	mock.lockGetByEmail.Lock()
+	mock.calls.GetByEmail = append(mock.calls.GetByEmail, callInfo)
+	mock.lockGetByEmail.Unlock()
+	if mock.GetByEmailFunc == nil {
+		var (
+			userOut *data.User
+			errOut  error
+		)
+		return userOut, errOut
+	}
+	return mock.GetByEmailFunc(ctx, email)
+}
+
+// GetByEmailCalls gets all the calls that were made to GetByEmail.
+// Check the length with:
+//
+//	len(mockedUserStore.GetByEmailCalls())
+func (mock *UserStore) GetByEmailCalls() []struct {
+	Ctx   context.Context
+	Email string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Email string
+	}
+	mock.lockGetByEmail.RLock()
+	calls = mock.calls.GetByEmail
+	mock.lockGetByEmail.RUnlock()
+	return calls
+}
+
+// GetCounts calls GetCountsFunc.
+func (mock *UserStore) GetCounts(ctx context.Context) (*data.UserCounts, error) {
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetCounts.Lock()
+	mock.calls.GetCounts = append(mock.calls.GetCounts, callInfo)
+	mock.lockGetCounts.Unlock()
+	if mock.GetCountsFunc == nil {
+		var (
+			userCountsOut *data.UserCounts
+			errOut        error
+		)
+		return userCountsOut, errOut
+	}
+	return mock.GetCountsFunc(ctx)
+}
+
+// GetCountsCalls gets all the calls that were made to GetCounts.
+// Check the length with:
+//
+//	len(mockedUserStore.GetCountsCalls())
+func (mock *UserStore) GetCountsCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetCounts.RLock()
+	calls = mock.calls.GetCounts
+	mock.lockGetCounts.RUnlock()
+	return calls
+}
+
+// GetForToken calls GetForTokenFunc.
+func (mock *UserStore) GetForToken(ctx context.Context, tokenScope string, tokenPlaintext string) (*data.User, error) {
+	callInfo := struct {
+		Ctx            context.Context
+		TokenScope     string
+		TokenPlaintext string
+	}{
+		Ctx:            ctx,
+		TokenScope:     tokenScope,
+		TokenPlaintext: tokenPlaintext,
+	}
+	mock.lockGetForToken.Lock()
+	mock.calls.GetForToken = append(mock.calls.GetForToken, callInfo)
+	mock.lockGetForToken.Unlock()
+	if mock.GetForTokenFunc == nil {
+		var (
+			userOut *data.User
+			errOut  error
+		)
+		return userOut, errOut
+	}
+	return mock.GetForTokenFunc(ctx, tokenScope, tokenPlaintext)
+}
+
+// GetForTokenCalls gets all the calls that were made to GetForToken.
+// Check the length with:
+//
+//	len(mockedUserStore.GetForTokenCalls())
+func (mock *UserStore) GetForTokenCalls() []struct {
+	Ctx            context.Context
+	TokenScope     string
+	TokenPlaintext string
+} {
+	var calls []struct {
+		Ctx            context.Context
+		TokenScope     string
+		TokenPlaintext string
+	}
+	mock.lockGetForToken.RLock()
+	calls = mock.calls.GetForToken
+	mock.lockGetForToken.RUnlock()
+	return calls
+}
+
+// GetRegistrationsPerDay calls GetRegistrationsPerDayFunc.
+func (mock *UserStore) GetRegistrationsPerDay(ctx context.Context, since time.Time) ([]*data.DailyRegistrations, error) {
+	callInfo := struct {
+		Ctx   context.Context
+		Since time.Time
+	}{
+		Ctx:   ctx,
+		Since: since,
+	}
+	mock.lockGetRegistrationsPerDay.Lock()
+	mock.calls.GetRegistrationsPerDay = append(mock.calls.GetRegistrationsPerDay, callInfo)
+	mock.lockGetRegistrationsPerDay.Unlock()
+	if mock.GetRegistrationsPerDayFunc == nil {
+		var (
+			dailyRegistrationssOut []*data.DailyRegistrations
+			errOut                 error
+		)
+		return dailyRegistrationssOut, errOut
+	}
+	return mock.GetRegistrationsPerDayFunc(ctx, since)
+}
+
+// GetRegistrationsPerDayCalls gets all the calls that were made to GetRegistrationsPerDay.
+// Check the length with:
+//
+//	len(mockedUserStore.GetRegistrationsPerDayCalls())
+func (mock *UserStore) GetRegistrationsPerDayCalls() []struct {
+	Ctx   context.Context
+	Since time.Time
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Since time.Time
+	}
+	mock.lockGetRegistrationsPerDay.RLock()
+	calls = mock.calls.GetRegistrationsPerDay
+	mock.lockGetRegistrationsPerDay.RUnlock()
+	return calls
+}
+
+// Insert calls InsertFunc.
+func (mock *UserStore) Insert(ctx context.Context, user *data.User) error {
+	callInfo := struct {
+		Ctx  context.Context
+		User *data.User
+	}{
+		Ctx:  ctx,
+		User: user,
+	}
+	mock.lockInsert.Lock()
+	mock.calls.Insert = append(mock.calls.Insert, callInfo)
+	mock.lockInsert.Unlock()
+	if mock.InsertFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.InsertFunc(ctx, user)
+}
+
+// InsertCalls gets all the calls that were made to Insert.
+// Check the length with:
+//
+//	len(mockedUserStore.InsertCalls())
+func (mock *UserStore) InsertCalls() []struct {
+	Ctx  context.Context
+	User *data.User
+} {
+	var calls []struct {
+		Ctx  context.Context
+		User *data.User
+	}
+	mock.lockInsert.RLock()
+	calls = mock.calls.Insert
+	mock.lockInsert.RUnlock()
+	return calls
+}
+
+// Update calls UpdateFunc.
+func (mock *UserStore) Update(ctx context.Context, user *data.User) error {
+	callInfo := struct {
+		Ctx  context.Context
+		User *data.User
+	}{
+		Ctx:  ctx,
+		User: user,
+	}
+	mock.lockUpdate.Lock()
+	mock.calls.Update = append(mock.calls.Update, callInfo)
+	mock.lockUpdate.Unlock()
+	if mock.UpdateFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.UpdateFunc(ctx, user)
+}
+
+// UpdateCalls gets all the calls that were made to Update.
+// Check the length with:
+//
+//	len(mockedUserStore.UpdateCalls())
+func (mock *UserStore) UpdateCalls() []struct {
+	Ctx  context.Context
+	User *data.User
+} {
+	var calls []struct {
+		Ctx  context.Context
+		User *data.User
+	}
+	mock.lockUpdate.RLock()
+	calls = mock.calls.Update
+	mock.lockUpdate.RUnlock()
+	return calls
+}
+
+// Ensure, that ViewStore does implement data.ViewStore.
+// If this is not the case, regenerate this file with moq.
+var _ data.ViewStore = &ViewStore{}
+
+// ViewStore is a mock implementation of data.ViewStore.
+//
+//	func TestSomethingThatUsesViewStore(t *testing.T) {
+//
+//		// make and configure a mocked data.ViewStore
+//		mockedViewStore := &ViewStore{
+//			DeleteRawBeforeFunc: func(ctx context.Context, before time.Time) (int64, error) {
+//				panic("mock out the DeleteRawBefore method")
+//			},
+//			GetDailyFunc: func(ctx context.Context, movieID int64, since time.Time) ([]*data.DailyViews, error) {
+//				panic("mock out the GetDaily method")
+//			},
+//			RecordManyFunc: func(ctx context.Context, views []data.ViewCount) error {
+//				panic("mock out the RecordMany method")
+//			},
+//			RollupFunc: func(ctx context.Context, since time.Time) error {
+//				panic("mock out the Rollup method")
+//			},
+//		}
+//
+//		// use mockedViewStore in code that requires data.ViewStore
+//		// and then make assertions.
+//
+//	}
+type ViewStore struct {
+	// DeleteRawBeforeFunc mocks the DeleteRawBefore method.
+	DeleteRawBeforeFunc func(ctx context.Context, before time.Time) (int64, error)
+
+	// GetDailyFunc mocks the GetDaily method.
+	GetDailyFunc func(ctx context.Context, movieID int64, since time.Time) ([]*data.DailyViews, error)
+
+	// RecordManyFunc mocks the RecordMany method.
+	RecordManyFunc func(ctx context.Context, views []data.ViewCount) error
+
+	// RollupFunc mocks the Rollup method.
+	RollupFunc func(ctx context.Context, since time.Time) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// DeleteRawBefore holds details about calls to the DeleteRawBefore method.
+		DeleteRawBefore []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Before is the before argument value.
+			Before time.Time
+		}
+		// GetDaily holds details about calls to the GetDaily method.
+		GetDaily []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// MovieID is the movieID argument value.
+			MovieID int64
+			// Since is the since argument value.
+			Since time.Time
+		}
+		// RecordMany holds details about calls to the RecordMany method.
+		RecordMany []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Views is the views argument value.
+			Views []data.ViewCount
+		}
+		// Rollup holds details about calls to the Rollup method.
+		Rollup []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Since is the since argument value.
+			Since time.Time
+		}
+	}
+	lockDeleteRawBefore sync.RWMutex
+	lockGetDaily        sync.RWMutex
+	lockRecordMany      sync.RWMutex
+	lockRollup          sync.RWMutex
+}
+
+// DeleteRawBefore calls DeleteRawBeforeFunc.
+func (mock *ViewStore) DeleteRawBefore(ctx context.Context, before time.Time) (int64, error) {
+	callInfo := struct {
+		Ctx    context.Context
+		Before time.Time
+	}{
+		Ctx:    ctx,
+		Before: before,
+	}
+	mock.lockDeleteRawBefore.Lock()
+	mock.calls.DeleteRawBefore = append(mock.calls.DeleteRawBefore, callInfo)
+	mock.lockDeleteRawBefore.Unlock()
+	if mock.DeleteRawBeforeFunc == nil {
+		var (
+			nOut   int64
+			errOut error
+		)
+		return nOut, errOut
+	}
+	return mock.DeleteRawBeforeFunc(ctx, before)
+}
+
+// DeleteRawBeforeCalls gets all the calls that were made to DeleteRawBefore.
+// Check the length with:
+//
+//	len(mockedViewStore.DeleteRawBeforeCalls())
+func (mock *ViewStore) DeleteRawBeforeCalls() []struct {
+	Ctx    context.Context
+	Before time.Time
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Before time.Time
+	}
+	mock.lockDeleteRawBefore.RLock()
+	calls = mock.calls.DeleteRawBefore
+	mock.lockDeleteRawBefore.RUnlock()
+	return calls
+}
+
+// GetDaily calls GetDailyFunc.
+func (mock *ViewStore) GetDaily(ctx context.Context, movieID int64, since time.Time) ([]*data.DailyViews, error) {
+	callInfo := struct {
+		Ctx     context.Context
+		MovieID int64
+		Since   time.Time
+	}{
+		Ctx:     ctx,
+		MovieID: movieID,
+		Since:   since,
+	}
+	mock.lockGetDaily.Lock()
+	mock.calls.GetDaily = append(mock.calls.GetDaily, callInfo)
+	mock.lockGetDaily.Unlock()
+	if mock.GetDailyFunc == nil {
+		var (
+			dailyViewssOut []*data.DailyViews
+			errOut         error
+		)
+		return dailyViewssOut, errOut
+	}
+	return mock.GetDailyFunc(ctx, movieID, since)
+}
+
+// GetDailyCalls gets all the calls that were made to GetDaily.
+// Check the length with:
+//
+//	len(mockedViewStore.GetDailyCalls())
+func (mock *ViewStore) GetDailyCalls() []struct {
+	Ctx     context.Context
+	MovieID int64
+	Since   time.Time
+} {
+	var calls []struct {
+		Ctx     context.Context
+		MovieID int64
+		Since   time.Time
+	}
+	mock.lockGetDaily.RLock()
+	calls = mock.calls.GetDaily
+	mock.lockGetDaily.RUnlock()
+	return calls
+}
+
+// RecordMany calls RecordManyFunc.
+func (mock *ViewStore) RecordMany(ctx context.Context, views []data.ViewCount) error {
+	callInfo := struct {
+		Ctx   context.Context
+		Views []data.ViewCount
+	}{
+		Ctx:   ctx,
+		Views: views,
+	}
+	mock.lockRecordMany.Lock()
+	mock.calls.RecordMany = append(mock.calls.RecordMany, callInfo)
+	mock.lockRecordMany.Unlock()
+	if mock.RecordManyFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.RecordManyFunc(ctx, views)
+}
+
+// RecordManyCalls gets all the calls that were made to RecordMany.
+// Check the length with:
+//
+//	len(mockedViewStore.RecordManyCalls())
+func (mock *ViewStore) RecordManyCalls() []struct {
+	Ctx   context.Context
+	Views []data.ViewCount
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Views []data.ViewCount
+	}
+	mock.lockRecordMany.RLock()
+	calls = mock.calls.RecordMany
+	mock.lockRecordMany.RUnlock()
+	return calls
+}
+
+// Rollup calls RollupFunc.
+func (mock *ViewStore) Rollup(ctx context.Context, since time.Time) error {
+	callInfo := struct {
+		Ctx   context.Context
+		Since time.Time
+	}{
+		Ctx:   ctx,
+		Since: since,
+	}
+	mock.lockRollup.Lock()
+	mock.calls.Rollup = append(mock.calls.Rollup, callInfo)
+	mock.lockRollup.Unlock()
+	if mock.RollupFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.RollupFunc(ctx, since)
+}
+
+// RollupCalls gets all the calls that were made to Rollup.
+// Check the length with:
+//
+//	len(mockedViewStore.RollupCalls())
+func (mock *ViewStore) RollupCalls() []struct {
+	Ctx   context.Context
+	Since time.Time
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Since time.Time
+	}
+	mock.lockRollup.RLock()
+	calls = mock.calls.Rollup
+	mock.lockRollup.RUnlock()
+	return calls
+}
@@ -1,8 +1,10 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"time"
 )
 
 var (
@@ -10,16 +12,199 @@ var (
 	ErrEditConflict   = errors.New("edit conflict")
 )
 
+// DBTX is the subset of *sql.DB used by the models. It is also satisfied by
+// *sql.Tx, so the same models can run inside a transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// The store interfaces describe the operations the handlers rely on. The SQL
+// models below implement them against PostgreSQL, and the mocks package
+// provides stubs so handlers can be exercised without a database.
+type (
+	AuditStore interface {
+		Insert(ctx context.Context, event *AuditEvent) error
+	}
+
+	EmailStore interface {
+		Insert(ctx context.Context, email *Email) error
+		UpdateStatus(ctx context.Context, email *Email) error
+		GetAll(ctx context.Context, recipient string, status string, filters Filters) ([]*Email, Metadata, error)
+		CountByStatus(ctx context.Context, since time.Time) (map[string]int, error)
+	}
+
+	InvitationStore interface {
+		New(ctx context.Context, email, role string, invitedBy int64, ttl time.Duration) (*Invitation, error)
+		GetForToken(ctx context.Context, tokenPlaintext string) (*Invitation, error)
+		GetPending(ctx context.Context, id int64) (*Invitation, error)
+		GetAllPending(ctx context.Context, filters Filters) ([]*Invitation, Metadata, error)
+		Revoke(ctx context.Context, id int64) error
+		MarkAccepted(ctx context.Context, id, userID int64) error
+	}
+
+	MediaStore interface {
+		Insert(ctx context.Context, media *Media) error
+		Get(ctx context.Context, movieID, id int64) (*Media, error)
+		GetForMovies(ctx context.Context, movieIDs []int64) ([]*Media, error)
+		Update(ctx context.Context, media *Media) error
+		Delete(ctx context.Context, movieID, id int64) error
+	}
+
+	MovieStore interface {
+		Insert(ctx context.Context, movie *Movie) error
+		InsertMany(ctx context.Context, movies []*Movie) error
+		Get(ctx context.Context, id int64) (*Movie, error)
+		GetMany(ctx context.Context, ids []int64) ([]*Movie, error)
+		Update(ctx context.Context, movie *Movie) error
+		Delete(ctx context.Context, id int64) error
+		GetAll(ctx context.Context, title string, genres []string, certificationSystem, certification string, releasedAfter, releasedBefore *Date, filters Filters) ([]*Movie, Metadata, error)
+		GetAddedSince(ctx context.Context, since time.Time, genres []string, limit int) ([]*Movie, error)
+		SearchTitles(ctx context.Context, search string, threshold float64, limit int) ([]*TitleMatch, error)
+		SearchGenres(ctx context.Context, search string, limit int) ([]*GenreCount, error)
+		GetCatalogStats(ctx context.Context) (*CatalogStats, error)
+		GetSimilarByGenre(ctx context.Context, id int64, limit int) ([]*SimilarMovie, error)
+		GetSimilarByEmbedding(ctx context.Context, id int64, model string, limit int) ([]*SimilarMovie, error)
+		GetUnembedded(ctx context.Context, model string, limit int) ([]*Movie, error)
+		SetEmbedding(ctx context.Context, movieID int64, version int32, model string, embedding []float32) error
+		SetPoster(ctx context.Context, movieID int64, poster *Poster) error
+		UpdatePoster(ctx context.Context, movieID int64, poster *Poster) error
+		RevertPoster(ctx context.Context, movieID int64, version string, previous *Poster) error
+		GetRevisions(ctx context.Context, movieID int64) ([]*MovieRevision, error)
+		GetRevision(ctx context.Context, movieID int64, version int32) (*MovieRevision, error)
+	}
+
+	PermissionStore interface {
+		GetAllForUser(ctx context.Context, userID int64) (Permissions, error)
+		AddForUser(ctx context.Context, userID int64, codes ...string) error
+	}
+
+	PreferenceStore interface {
+		Get(ctx context.Context, userID int64) (*Preferences, error)
+		Update(ctx context.Context, preferences *Preferences) error
+		GetDueForDigest(ctx context.Context, cutoff time.Time) ([]*DigestRecipient, error)
+		MarkDigestSent(ctx context.Context, userID int64) error
+	}
+
+	SuggestionStore interface {
+		Insert(ctx context.Context, suggestion *Suggestion) error
+		Get(ctx context.Context, id int64) (*Suggestion, error)
+		GetAll(ctx context.Context, movieID int64, status string, filters Filters) ([]*Suggestion, Metadata, error)
+		Review(ctx context.Context, suggestion *Suggestion) error
+	}
+
+	TokenStore interface {
+		New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error)
+		Insert(ctx context.Context, token *Token) error
+		DeleteAllForUser(ctx context.Context, scope string, userID int64) error
+		CountActive(ctx context.Context) (map[string]int, error)
+		DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error)
+		GetTableStats(ctx context.Context) (*TokenTableStats, error)
+	}
+
+	UsageStore interface {
+		RecordMany(ctx context.Context, counts []UsageCount) error
+		DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+		GetDaily(ctx context.Context, userID int64, since time.Time) ([]*DailyUsage, error)
+		GetTop(ctx context.Context, since time.Time, sort string, limit int) ([]*UserUsage, error)
+	}
+
+	ViewStore interface {
+		RecordMany(ctx context.Context, views []ViewCount) error
+		Rollup(ctx context.Context, since time.Time) error
+		DeleteRawBefore(ctx context.Context, before time.Time) (int64, error)
+		GetDaily(ctx context.Context, movieID int64, since time.Time) ([]*DailyViews, error)
+	}
+
+	UserStore interface {
+		Insert(ctx context.Context, user *User) error
+		Get(ctx context.Context, id int64) (*User, error)
+		GetByEmail(ctx context.Context, email string) (*User, error)
+		Update(ctx context.Context, user *User) error
+		GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error)
+		ActivateMany(ctx context.Context, ids []int64, emails []string) ([]*UserActivation, error)
+		GetCounts(ctx context.Context) (*UserCounts, error)
+		GetRegistrationsPerDay(ctx context.Context, since time.Time) ([]*DailyRegistrations, error)
+	}
+)
+
+var (
+	_ AuditStore      = AuditModel{}
+	_ EmailStore      = EmailModel{}
+	_ InvitationStore = InvitationModel{}
+	_ MediaStore      = MediaModel{}
+	_ MovieStore      = MovieModel{}
+	_ PermissionStore = PermissionModel{}
+	_ PreferenceStore = PreferenceModel{}
+	_ SuggestionStore = SuggestionModel{}
+	_ TokenStore      = TokenModel{}
+	_ UsageStore      = UsageModel{}
+	_ UserStore       = UserModel{}
+	_ ViewStore       = ViewModel{}
+)
+
+// txBeginner is implemented by *sql.DB.
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
 type Models struct {
-	Movies MovieModel
-	Tokens TokenModel
-	Users  UserModel
+	Audit       AuditStore
+	Emails      EmailStore
+	Invitations InvitationStore
+	Media       MediaStore
+	Movies      MovieStore
+	Permissions PermissionStore
+	Preferences PreferenceStore
+	Suggestions SuggestionStore
+	Tokens      TokenStore
+	Usage       UsageStore
+	Users       UserStore
+	Views       ViewStore
+
+	// db is set when the models can start transactions, i.e. when they were
+	// built from a *sql.DB rather than a *sql.Tx.
+	db txBeginner
 }
 
-func NewModels(db *sql.DB) Models {
+func NewModels(db DBTX) Models {
+	beginner, _ := db.(txBeginner)
+	db = tracedDB{db}
 	return Models{
-		Movies: MovieModel{DB: db},
-		Tokens: TokenModel{DB: db},
-		Users:  UserModel{DB: db},
+		db:          beginner,
+		Audit:       AuditModel{DB: db},
+		Emails:      EmailModel{DB: db},
+		Invitations: InvitationModel{DB: db},
+		Media:       MediaModel{DB: db},
+		Movies:      MovieModel{DB: db},
+		Permissions: PermissionModel{DB: db},
+		Preferences: PreferenceModel{DB: db},
+		Suggestions: SuggestionModel{DB: db},
+		Tokens:      TokenModel{DB: db},
+		Usage:       UsageModel{DB: db},
+		Users:       UserModel{DB: db},
+		Views:       ViewModel{DB: db},
+	}
+}
+
+// WithTx runs fn with Models bound to a single transaction, which is committed
+// if fn returns nil and rolled back otherwise. Models which are already bound
+// to a transaction, or aren't backed by PostgreSQL, call fn with themselves,
+// so WithTx calls can nest.
+func (m Models) WithTx(ctx context.Context, fn func(m Models) error) error {
+	if m.db == nil {
+		return fn(m)
+	}
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(NewModels(tx))
+	if err != nil {
+		return err
 	}
+	return tx.Commit()
 }
@@ -7,69 +7,174 @@ import (
 	"fmt"
 	"github.com/ezechidc/greenlight/internal/validator"
 	"github.com/lib/pq"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type MovieModel struct {
-	DB *sql.DB
+	DB DBTX
 }
 
 type Movie struct {
 	ID        int64     `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	Title     string    `json:"title"`
-	Year      int32     `json:"year,omitzero"`
-	Runtime   Runtime   `json:"runtime,omitzero"`
-	Genres    []string  `json:"genres,omitzero"`
-	Version   int32     `json:"version"`
+	// OriginalTitle is the title in the movie's original language, if that
+	// differs from Title.
+	OriginalTitle string   `json:"original_title,omitzero"`
+	Year          int32    `json:"year,omitzero"`
+	Runtime       Runtime  `json:"runtime,omitzero"`
+	Genres        []string `json:"genres,omitzero"`
+	// ReleaseDate is nil when the release date isn't known.
+	ReleaseDate *Date `json:"release_date,omitzero"`
+	// CertificationSystem is the rating system Certification belongs to, a
+	// key of CertificationSystems. Both are empty if the movie hasn't been
+	// rated.
+	CertificationSystem string `json:"certification_system,omitzero"`
+	Certification       string `json:"certification,omitzero"`
+	Poster              Poster `json:"poster,omitzero"`
+	Titles              Titles `json:"titles,omitzero"`
+	Version             int32  `json:"version"`
+	// Media is only set when a response asks for it, with ?include=media.
+	Media []*Media `json:"media,omitzero"`
+}
+
+// CertificationSystems maps the supported rating systems to the ratings each
+// can give, from the least to the most restrictive: the US MPA ratings, the
+// UK BBFC ratings and the German FSK age ratings.
+var CertificationSystems = map[string][]string{
+	"BBFC": {"U", "PG", "12A", "12", "15", "18", "R18"},
+	"FSK":  {"0", "6", "12", "16", "18"},
+	"MPA":  {"G", "PG", "PG-13", "R", "NC-17"},
+}
+
+// ValidateCertification checks a rating system and certification, such as MPA
+// and PG-13, either of which may be empty. A certification without a system
+// must be a rating of one of them.
+func ValidateCertification(v *validator.Validator, system, certification string) {
+	ratings, ok := CertificationSystems[system]
+	v.CheckCode(system == "" || ok, "certification_system", "invalid_value", "must be one of BBFC, FSK or MPA")
+	switch {
+	case certification == "":
+	case ok:
+		v.CheckCode(validator.PermittedValue(certification, ratings...), "certification", "invalid_value", fmt.Sprintf("must be one of the %s ratings: %s", system, strings.Join(ratings, ", ")))
+	case system == "":
+		known := false
+		for _, ratings := range CertificationSystems {
+			known = known || slices.Contains(ratings, certification)
+		}
+		v.CheckCode(known, "certification", "invalid_value", "must be a BBFC, FSK or MPA rating")
+	}
+}
+
+// GenreCount is a genre and the number of movies in it.
+type GenreCount struct {
+	Name   string `json:"name"`
+	Movies int    `json:"movies"`
 }
 
 func ValidateMovie(v *validator.Validator, movie *Movie) {
-	v.Check(movie.Title != "", "title", "must be provided")
-	v.Check(len(movie.Title) <= 500, "title", "must not be more than 500 bytes long")
-	v.Check(movie.Year != 0, "year", "must be provided")
-	v.Check(movie.Year >= 1888, "year", "must be greater than 1888")
-	v.Check(movie.Year <= int32(time.Now().Year()), "year", "must not be in the future")
-	v.Check(movie.Runtime != 0, "runtime", "must be provided")
-	v.Check(movie.Runtime > 0, "runtime", "must be a positive integer")
-	v.Check(movie.Genres != nil, "genres", "must be provided")
-	v.Check(len(movie.Genres) >= 1, "genres", "must contain at least 1 genre")
-	v.Check(len(movie.Genres) <= 5, "genres", "must not contain more than 5 genres")
-	v.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
-}
-
-func (m MovieModel) Insert(movie *Movie) error {
+	v.CheckCode(movie.Title != "", "title", "required", "must be provided")
+	v.CheckCode(len(movie.Title) <= 500, "title", "too_long", "must not be more than 500 bytes long")
+	v.CheckCode(len(movie.OriginalTitle) <= 500, "original_title", "too_long", "must not be more than 500 bytes long")
+	v.CheckCode(len(movie.Titles) <= 50, "titles", "too_many", "must not contain more than 50 titles")
+	for locale, title := range movie.Titles {
+		v.CheckCode(LocaleRX.MatchString(locale), "titles", "invalid_locale", "must be keyed by language tags such as fr or pt-BR")
+		v.CheckCode(title != "", "titles", "required", "must not contain empty titles")
+		v.CheckCode(len(title) <= 500, "titles", "too_long", "must not contain titles more than 500 bytes long")
+	}
+	v.CheckCode(movie.Year != 0, "year", "required", "must be provided")
+	v.CheckCode(movie.Year >= 1888, "year", "too_small", "must be greater than 1888")
+	v.CheckCode(movie.Year <= int32(time.Now().Year()), "year", "in_future", "must not be in the future")
+	v.CheckCode(movie.Runtime != 0, "runtime", "required", "must be provided")
+	v.CheckCode(movie.Runtime > 0, "runtime", "must_be_positive", "must be a positive integer")
+	// An empty list is allowed, so updates can clear a movie's genres.
+	v.CheckCode(movie.Genres != nil, "genres", "required", "must be provided")
+	v.CheckCode(len(movie.Genres) <= 5, "genres", "too_many", "must not contain more than 5 genres")
+	v.CheckCode(validator.Unique(movie.Genres), "genres", "duplicate_values", "must not contain duplicate values")
+	if movie.ReleaseDate != nil {
+		v.CheckCode(movie.ReleaseDate.Year() >= 1888, "release_date", "too_small", "must be greater than 1888")
+	}
+	// A rating means nothing without its system, so they're set together.
+	if movie.CertificationSystem != "" || movie.Certification != "" {
+		v.CheckCode(movie.CertificationSystem != "", "certification_system", "required", "must be provided with a certification")
+		v.CheckCode(movie.Certification != "", "certification", "required", "must be provided with a certification system")
+	}
+	ValidateCertification(v, movie.CertificationSystem, movie.Certification)
+}
+
+func (m MovieModel) Insert(ctx context.Context, movie *Movie) error {
 	query := `
-		INSERT INTO movies (title, year, runtime, genres)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at, version`
-	args := []any{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres)}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		WITH movie AS (
+			INSERT INTO movies (title, original_title, year, runtime, genres, release_date, certification_system, certification)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id, created_at, version
+		), titles AS (
+			INSERT INTO movie_titles (movie_id, locale, title)
+			SELECT movie.id, t.locale, t.title
+			FROM movie, unnest($9::text[], $10::text[]) AS t(locale, title)
+		)
+		SELECT id, created_at, version FROM movie`
+	locales, titles := movie.Titles.arrays()
+	args := []any{movie.Title, movie.OriginalTitle, movie.Year, movie.Runtime, pq.Array(movie.Genres), movie.ReleaseDate, movie.CertificationSystem, movie.Certification, pq.Array(locales), pq.Array(titles)}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	return m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
 }
 
-func (m MovieModel) Get(id int64) (*Movie, error) {
+// InsertMany adds movies with a single multi-row INSERT, for bulk loading.
+// Unlike Insert it doesn't read back the generated IDs or store original or
+// localized titles. PostgreSQL allows at most 65535 parameters per statement,
+// so callers should keep batches below 9000 movies.
+func (m MovieModel) InsertMany(ctx context.Context, movies []*Movie) error {
+	if len(movies) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(movies))
+	args := make([]any, 0, 7*len(movies))
+	for i, movie := range movies {
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)", 7*i+1, 7*i+2, 7*i+3, 7*i+4, 7*i+5, 7*i+6, 7*i+7))
+		args = append(args, movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres), movie.ReleaseDate, movie.CertificationSystem, movie.Certification)
+	}
+	query := "INSERT INTO movies (title, year, runtime, genres, release_date, certification_system, certification) VALUES " + strings.Join(values, ", ")
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (m MovieModel) Get(ctx context.Context, id int64) (*Movie, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 	query := `
-		SELECT id, created_at, title, year, runtime, genres, version
+		SELECT id, created_at, title, original_title, year, runtime, genres, release_date, certification_system, certification, version,
+			(SELECT json_object_agg(mt.locale, mt.title) FROM movie_titles mt WHERE mt.movie_id = movies.id),
+			(SELECT mp.poster FROM movie_posters mp WHERE mp.movie_id = movies.id)
 		FROM movies
 		WHERE id = $1`
 	var movie Movie
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, id).Scan(
 		&movie.ID,
 		&movie.CreatedAt,
 		&movie.Title,
+		&movie.OriginalTitle,
 		&movie.Year,
 		&movie.Runtime,
 		pq.Array(&movie.Genres),
+		&movie.ReleaseDate,
+		&movie.CertificationSystem,
+		&movie.Certification,
 		&movie.Version,
+		&movie.Titles,
+		&movie.Poster,
 	)
 	if err != nil {
 		switch {
@@ -82,21 +187,89 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 	return &movie, nil
 }
 
-func (m MovieModel) Update(movie *Movie) error {
+// GetMany returns the movies with the given IDs in no particular order. IDs
+// with no matching movie are skipped rather than reported as an error.
+func (m MovieModel) GetMany(ctx context.Context, ids []int64) ([]*Movie, error) {
 	query := `
-		UPDATE movies
-		SET title = $1, year = $2, runtime = $3, genres = $4, version = version + 1
-		WHERE id = $5 AND version = $6
-		RETURNING version`
+		SELECT id, created_at, title, original_title, year, runtime, genres, release_date, certification_system, certification, version,
+			(SELECT json_object_agg(mt.locale, mt.title) FROM movie_titles mt WHERE mt.movie_id = movies.id),
+			(SELECT mp.poster FROM movie_posters mp WHERE mp.movie_id = movies.id)
+		FROM movies
+		WHERE id = ANY($1)`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movies := []*Movie{}
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.OriginalTitle,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.ReleaseDate,
+			&movie.CertificationSystem,
+			&movie.Certification,
+			&movie.Version,
+			&movie.Titles,
+			&movie.Poster,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movies = append(movies, &movie)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// Update saves the movie, replacing its localized titles with Titles.
+func (m MovieModel) Update(ctx context.Context, movie *Movie) error {
+	query := `
+		WITH movie AS (
+			UPDATE movies
+			SET title = $1, original_title = $2, year = $3, runtime = $4, genres = $5, release_date = $6,
+				certification_system = $7, certification = $8, version = version + 1
+			WHERE id = $9 AND version = $10
+			RETURNING id, version
+		), deleted AS (
+			DELETE FROM movie_titles
+			USING movie
+			WHERE movie_titles.movie_id = movie.id AND NOT movie_titles.locale = ANY($11)
+		), upserted AS (
+			INSERT INTO movie_titles (movie_id, locale, title)
+			SELECT movie.id, t.locale, t.title
+			FROM movie, unnest($11::text[], $12::text[]) AS t(locale, title)
+			ON CONFLICT (movie_id, locale) DO UPDATE SET title = EXCLUDED.title
+		)
+		SELECT version FROM movie`
+	locales, titles := movie.Titles.arrays()
 	args := []any{
 		movie.Title,
+		movie.OriginalTitle,
 		movie.Year,
 		movie.Runtime,
 		pq.Array(movie.Genres),
+		movie.ReleaseDate,
+		movie.CertificationSystem,
+		movie.Certification,
 		movie.ID,
 		movie.Version,
+		pq.Array(locales),
+		pq.Array(titles),
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
 	if err != nil {
@@ -110,12 +283,12 @@ func (m MovieModel) Update(movie *Movie) error {
 	return nil
 }
 
-func (m MovieModel) Delete(id int64) error {
+func (m MovieModel) Delete(ctx context.Context, id int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 	query := `DELETE FROM movies WHERE id = $1`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	result, err := m.DB.ExecContext(ctx, query, id)
 	if err != nil {
@@ -131,17 +304,35 @@ func (m MovieModel) Delete(id int64) error {
 	return nil
 }
 
-func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*Movie, Metadata, error) {
+// GetAll returns the movies matching title, genres, certification system and
+// certification, released strictly between releasedAfter and releasedBefore
+// when they're set. title matches the movie's title, original title or any of
+// its localized titles. Movies with no release date never match a release
+// date filter, and sort last.
+func (m MovieModel) GetAll(ctx context.Context, title string, genres []string, certificationSystem, certification string, releasedAfter, releasedBefore *Date, filters Filters) ([]*Movie, Metadata, error) {
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, created_at, title, year, runtime, genres, version
+		SELECT count(*) OVER(), id, created_at, title, original_title, year, runtime, genres, release_date, certification_system, certification, version,
+			(SELECT json_object_agg(mt.locale, mt.title) FROM movie_titles mt WHERE mt.movie_id = movies.id),
+			(SELECT mp.poster FROM movie_posters mp WHERE mp.movie_id = movies.id)
 		FROM movies
-		WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
+		WHERE ($1 = ''
+			OR to_tsvector('simple', title) @@ plainto_tsquery('simple', $1)
+			OR to_tsvector('simple', original_title) @@ plainto_tsquery('simple', $1)
+			OR EXISTS (
+				SELECT 1 FROM movie_titles mt
+				WHERE mt.movie_id = movies.id
+				AND to_tsvector('simple', mt.title) @@ plainto_tsquery('simple', $1)
+			))
 		AND (genres @> $2 OR $2 = '{}')
-		ORDER BY %s %s, id ASC
-		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		AND (certification = $3 OR $3 = '')
+		AND (certification_system = $8 OR $8 = '')
+		AND (release_date > $4 OR $4 IS NULL)
+		AND (release_date < $5 OR $5 IS NULL)
+		ORDER BY %s %s NULLS LAST, id ASC
+		LIMIT $6 OFFSET $7`, filters.sortColumn(), filters.sortDirection())
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	args := []any{title, pq.Array(genres), filters.limit(), filters.offset()}
+	args := []any{title, pq.Array(genres), certification, releasedAfter, releasedBefore, filters.limit(), filters.offset(), certificationSystem}
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -158,10 +349,16 @@ func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*M
 			&movie.ID,
 			&movie.CreatedAt,
 			&movie.Title,
+			&movie.OriginalTitle,
 			&movie.Year,
 			&movie.Runtime,
 			pq.Array(&movie.Genres),
+			&movie.ReleaseDate,
+			&movie.CertificationSystem,
+			&movie.Certification,
 			&movie.Version,
+			&movie.Titles,
+			&movie.Poster,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
@@ -174,3 +371,177 @@ func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*M
 	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
 	return movies, metadata, nil
 }
+
+// GetAddedSince returns up to limit movies created after since, optionally
+// restricted to those sharing at least one of the given genres.
+func (m MovieModel) GetAddedSince(ctx context.Context, since time.Time, genres []string, limit int) ([]*Movie, error) {
+	query := `
+		SELECT id, created_at, title, original_title, year, runtime, genres, release_date, certification_system, certification, version,
+			(SELECT json_object_agg(mt.locale, mt.title) FROM movie_titles mt WHERE mt.movie_id = movies.id),
+			(SELECT mp.poster FROM movie_posters mp WHERE mp.movie_id = movies.id)
+		FROM movies
+		WHERE created_at > $1
+		AND (genres && $2 OR $2 = '{}')
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, since, pq.Array(genres), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movies := []*Movie{}
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.OriginalTitle,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.ReleaseDate,
+			&movie.CertificationSystem,
+			&movie.Certification,
+			&movie.Version,
+			&movie.Titles,
+			&movie.Poster,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movies = append(movies, &movie)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// TitleMatch is a movie found by SearchTitles. Score is the trigram word
+// similarity of the search to the closest of the movie's titles, from 0 to 1.
+type TitleMatch struct {
+	*Movie
+	Score float64 `json:"score"`
+}
+
+// SearchTitles returns up to limit movies whose titles match search, either
+// through full-text search or, to tolerate typos, with a trigram word
+// similarity of at least threshold. The title, original title and localized
+// titles are all searched, and each movie is ranked by its best match.
+// Full-text matches come first, then the closest fuzzy matches.
+//
+// The trigram index is only used with the threshold in the
+// pg_trgm.word_similarity_threshold setting, which is set for the enclosing
+// transaction, so call it through Models.WithTx. Outside a transaction,
+// thresholds below PostgreSQL's default of 0.6 act as 0.6.
+func (m MovieModel) SearchTitles(ctx context.Context, search string, threshold float64, limit int) ([]*TitleMatch, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	query := `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`
+	_, err := m.DB.ExecContext(ctx, query, strconv.FormatFloat(threshold, 'f', -1, 64))
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		WITH titles AS (
+			SELECT id AS movie_id, title FROM movies
+			UNION ALL
+			SELECT id, original_title FROM movies WHERE original_title <> ''
+			UNION ALL
+			SELECT movie_id, title FROM movie_titles
+		), matches AS (
+			SELECT movie_id,
+				bool_or(to_tsvector('simple', title) @@ plainto_tsquery('simple', $1)) AS exact,
+				max(ts_rank(to_tsvector('simple', title), plainto_tsquery('simple', $1))) AS rank,
+				max(word_similarity($1, title)) AS score
+			FROM titles
+			WHERE to_tsvector('simple', title) @@ plainto_tsquery('simple', $1)
+			OR ($1 <% title AND word_similarity($1, title) >= $2)
+			GROUP BY movie_id
+		)
+		SELECT movies.id, movies.created_at, movies.title, movies.original_title, movies.year, movies.runtime,
+			movies.genres, movies.release_date, movies.certification_system, movies.certification, movies.version,
+			(SELECT json_object_agg(mt.locale, mt.title) FROM movie_titles mt WHERE mt.movie_id = movies.id),
+			(SELECT mp.poster FROM movie_posters mp WHERE mp.movie_id = movies.id),
+			matches.score
+		FROM matches
+		JOIN movies ON movies.id = matches.movie_id
+		ORDER BY matches.exact DESC, matches.rank DESC, matches.score DESC, movies.title ASC, movies.id ASC
+		LIMIT $3`
+	rows, err := m.DB.QueryContext(ctx, query, search, threshold, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []*TitleMatch{}
+	for rows.Next() {
+		var match TitleMatch
+		match.Movie = &Movie{}
+		err := rows.Scan(
+			&match.ID,
+			&match.CreatedAt,
+			&match.Title,
+			&match.OriginalTitle,
+			&match.Year,
+			&match.Runtime,
+			pq.Array(&match.Genres),
+			&match.ReleaseDate,
+			&match.CertificationSystem,
+			&match.Certification,
+			&match.Version,
+			&match.Titles,
+			&match.Poster,
+			&match.Score,
+		)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, &match)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// SearchGenres returns up to limit genres containing search, ignoring case,
+// with the most used first.
+func (m MovieModel) SearchGenres(ctx context.Context, search string, limit int) ([]*GenreCount, error) {
+	query := `
+		SELECT genre, count(*)
+		FROM movies, unnest(genres) AS genre
+		WHERE strpos(lower(genre), lower($1)) > 0
+		GROUP BY genre
+		ORDER BY count(*) DESC, genre ASC
+		LIMIT $2`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, search, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := []*GenreCount{}
+	for rows.Next() {
+		var genre GenreCount
+		err := rows.Scan(&genre.Name, &genre.Movies)
+		if err != nil {
+			return nil, err
+		}
+		genres = append(genres, &genre)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return genres, nil
+}
@@ -0,0 +1,445 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/datatest"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestMovieModelCRUD(t *testing.T) {
+	db := datatest.OpenDB(t)
+	ctx := context.Background()
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		movie := datatest.InsertMovie(t, m, datatest.NewMovie())
+		if movie.ID == 0 || movie.Version != 1 {
+			t.Fatalf("got id %d, version %d; want an id and version 1", movie.ID, movie.Version)
+		}
+
+		got, err := m.Movies.Get(ctx, movie.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Title != movie.Title || !slices.Equal(got.Genres, movie.Genres) {
+			t.Errorf("got %+v; want %+v", got, movie)
+		}
+
+		stale := *got
+		got.Title = "Updated"
+		err = m.Movies.Update(ctx, got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Version != 2 {
+			t.Errorf("got version %d after update; want 2", got.Version)
+		}
+
+		stale.Title = "Stale"
+		err = m.Movies.Update(ctx, &stale)
+		if !errors.Is(err, data.ErrEditConflict) {
+			t.Errorf("got %v updating a stale movie; want ErrEditConflict", err)
+		}
+
+		err = m.Movies.Delete(ctx, movie.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = m.Movies.Get(ctx, movie.ID)
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("got %v after delete; want ErrRecordNotFound", err)
+		}
+		err = m.Movies.Delete(ctx, movie.ID)
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("got %v deleting twice; want ErrRecordNotFound", err)
+		}
+	})
+}
+
+func TestMovieModelEmptyGenres(t *testing.T) {
+	db := datatest.OpenDB(t)
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		movie := datatest.InsertMovie(t, m, datatest.NewMovie(func(movie *data.Movie) {
+			movie.Genres = []string{}
+		}))
+		got, err := m.Movies.Get(context.Background(), movie.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Genres) != 0 {
+			t.Errorf("got genres %v; want none", got.Genres)
+		}
+	})
+}
+
+func TestMovieModelGetAll(t *testing.T) {
+	db := datatest.OpenDB(t)
+	datatest.Truncate(t, db, "movies")
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		err := m.Movies.InsertMany(context.Background(), []*data.Movie{
+			datatest.NewMovie(func(movie *data.Movie) { movie.Title = "The Black Cat" }),
+			datatest.NewMovie(func(movie *data.Movie) {
+				movie.Title = "Black Narcissus"
+				movie.Genres = []string{"drama", "romance"}
+				movie.ReleaseDate = date(t, "1947-04-24")
+				movie.CertificationSystem = "MPA"
+				movie.Certification = "PG"
+			}),
+			datatest.NewMovie(func(movie *data.Movie) {
+				movie.Title = "Moonlight"
+				movie.Genres = []string{"romance"}
+				movie.ReleaseDate = date(t, "2016-10-21")
+				movie.CertificationSystem = "BBFC"
+				movie.Certification = "15"
+			}),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name           string
+			title          string
+			genres         []string
+			system         string
+			certification  string
+			releasedAfter  *data.Date
+			releasedBefore *data.Date
+			sort           string
+			want           []string
+		}{
+			{name: "all", sort: "title", want: []string{"Black Narcissus", "Moonlight", "The Black Cat"}},
+			{name: "title search", title: "black", sort: "title", want: []string{"Black Narcissus", "The Black Cat"}},
+			{name: "genre", genres: []string{"romance"}, sort: "-title", want: []string{"Moonlight", "Black Narcissus"}},
+			{name: "title and genre", title: "black", genres: []string{"romance"}, sort: "id", want: []string{"Black Narcissus"}},
+			{name: "certification", certification: "PG", sort: "title", want: []string{"Black Narcissus"}},
+			{name: "certification system", system: "BBFC", sort: "title", want: []string{"Moonlight"}},
+			{name: "certification in a system", system: "MPA", certification: "15", sort: "title"},
+			{name: "released after", releasedAfter: date(t, "1947-04-24"), sort: "title", want: []string{"Moonlight"}},
+			{name: "released before", releasedBefore: date(t, "2016-10-21"), sort: "title", want: []string{"Black Narcissus"}},
+			{name: "release date, unknown last", sort: "-release_date", want: []string{"Moonlight", "Black Narcissus", "The Black Cat"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				filters := data.Filters{Page: 1, PageSize: 20, Sort: tt.sort, SortSafelist: []string{tt.sort}}
+				movies, metadata, err := m.Movies.GetAll(context.Background(), tt.title, tt.genres, tt.system, tt.certification, tt.releasedAfter, tt.releasedBefore, filters)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var titles []string
+				for _, movie := range movies {
+					titles = append(titles, movie.Title)
+				}
+				if !slices.Equal(titles, tt.want) {
+					t.Errorf("got %v; want %v", titles, tt.want)
+				}
+				if metadata.TotalRecords != len(tt.want) {
+					t.Errorf("got %d total records; want %d", metadata.TotalRecords, len(tt.want))
+				}
+			})
+		}
+	})
+}
+
+func TestMovieModelGetMany(t *testing.T) {
+	db := datatest.OpenDB(t)
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		first := datatest.InsertMovie(t, m, datatest.NewMovie())
+		second := datatest.InsertMovie(t, m, datatest.NewMovie())
+
+		movies, err := m.Movies.GetMany(context.Background(), []int64{second.ID, first.ID, -1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int64
+		for _, movie := range movies {
+			ids = append(ids, movie.ID)
+		}
+		slices.Sort(ids)
+		if want := []int64{first.ID, second.ID}; !slices.Equal(ids, want) {
+			t.Errorf("got ids %v; want %v", ids, want)
+		}
+	})
+}
+
+func TestMovieModelGetSimilar(t *testing.T) {
+	db := datatest.OpenDB(t)
+	ctx := context.Background()
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		target := datatest.InsertMovie(t, m, datatest.NewMovie(func(movie *data.Movie) { movie.Genres = []string{"zz-drama", "zz-war"} }))
+		near := datatest.InsertMovie(t, m, datatest.NewMovie(func(movie *data.Movie) { movie.Genres = []string{"zz-drama", "zz-war"} }))
+		far := datatest.InsertMovie(t, m, datatest.NewMovie(func(movie *data.Movie) { movie.Genres = []string{"zz-drama", "zz-comedy"} }))
+
+		byGenre, err := m.Movies.GetSimilarByGenre(ctx, target.ID, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(byGenre) != 2 || byGenre[0].ID != near.ID || byGenre[0].Score != 1 || byGenre[1].ID != far.ID {
+			t.Errorf("got %+v; want movie %d with score 1, then %d", byGenre, near.ID, far.ID)
+		}
+
+		_, err = m.Movies.GetSimilarByEmbedding(ctx, target.ID, "test", 2)
+		if !errors.Is(err, data.ErrNoEmbedding) {
+			t.Errorf("got %v before embedding; want ErrNoEmbedding", err)
+		}
+		unembedded, err := m.Movies.GetUnembedded(ctx, "test", 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.ContainsFunc(unembedded, func(movie *data.Movie) bool { return movie.ID == target.ID }) {
+			t.Errorf("movie %d isn't due to be embedded", target.ID)
+		}
+		for movie, embedding := range map[*data.Movie][]float32{target: {1, 0}, near: {1, 0.1}, far: {0, 1}} {
+			err = m.Movies.SetEmbedding(ctx, movie.ID, movie.Version, "test", embedding)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		byEmbedding, err := m.Movies.GetSimilarByEmbedding(ctx, target.ID, "test", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(byEmbedding) != 2 || byEmbedding[0].ID != near.ID || byEmbedding[1].ID != far.ID {
+			t.Errorf("got %+v; want movie %d, then %d", byEmbedding, near.ID, far.ID)
+		}
+	})
+}
+
+func TestMovieModelSearchGenres(t *testing.T) {
+	db := datatest.OpenDB(t)
+	datatest.Truncate(t, db, "movies")
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		err := m.Movies.InsertMany(context.Background(), []*data.Movie{
+			datatest.NewMovie(func(movie *data.Movie) { movie.Genres = []string{"drama", "romance"} }),
+			datatest.NewMovie(func(movie *data.Movie) { movie.Genres = []string{"romance", "comedy"} }),
+			datatest.NewMovie(func(movie *data.Movie) { movie.Genres = []string{"romantic comedy"} }),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		genres, err := m.Movies.SearchGenres(context.Background(), "ROMAN", 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []data.GenreCount{{Name: "romance", Movies: 2}, {Name: "romantic comedy", Movies: 1}}
+		if len(genres) != len(want) {
+			t.Fatalf("got %d genres; want %d", len(genres), len(want))
+		}
+		for i := range want {
+			if *genres[i] != want[i] {
+				t.Errorf("got genre %+v; want %+v", *genres[i], want[i])
+			}
+		}
+	})
+}
+
+func TestMovieModelSearchTitles(t *testing.T) {
+	db := datatest.OpenDB(t)
+	datatest.Truncate(t, db, "movies")
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		err := m.Movies.InsertMany(context.Background(), []*data.Movie{
+			datatest.NewMovie(func(movie *data.Movie) { movie.Title = "Gladiator" }),
+			datatest.NewMovie(func(movie *data.Movie) { movie.Title = "The Gladiators of Rome" }),
+			datatest.NewMovie(func(movie *data.Movie) { movie.Title = "Casablanca" }),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			search string
+			want   []string
+		}{
+			{search: "gladiator", want: []string{"Gladiator", "The Gladiators of Rome"}},
+			{search: "glladiator", want: []string{"Gladiator", "The Gladiators of Rome"}},
+			{search: "casablanka", want: []string{"Casablanca"}},
+			{search: "zzzz", want: []string{}},
+		}
+		for _, tt := range tests {
+			matches, err := m.Movies.SearchTitles(context.Background(), tt.search, 0.3, 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, match := range matches {
+				got = append(got, match.Title)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q for %q; want %q", got, tt.search, tt.want)
+			}
+		}
+	})
+}
+
+func TestMovieModelGetCatalogStats(t *testing.T) {
+	db := datatest.OpenDB(t)
+	datatest.Truncate(t, db, "movies")
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		err := m.Movies.InsertMany(context.Background(), []*data.Movie{
+			datatest.NewMovie(func(movie *data.Movie) { movie.Year, movie.Runtime, movie.Genres = 1994, 100, []string{"drama"} }),
+			datatest.NewMovie(func(movie *data.Movie) {
+				movie.Year, movie.Runtime, movie.Genres = 1999, 120, []string{"drama", "comedy"}
+			}),
+			datatest.NewMovie(func(movie *data.Movie) { movie.Year, movie.Runtime, movie.Genres = 2016, 0, []string{} }),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stats, err := m.Movies.GetCatalogStats(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Movies != 3 || stats.AddedLastWeek != 3 || stats.AverageRuntime != 110 {
+			t.Errorf("got stats %+v; want 3 movies, all added in the last week, averaging 110 minutes", stats)
+		}
+		wantDecades := []data.DecadeCount{{Decade: 1990, Movies: 2}, {Decade: 2010, Movies: 1}}
+		if len(stats.Decades) != len(wantDecades) {
+			t.Fatalf("got %d decades; want %d", len(stats.Decades), len(wantDecades))
+		}
+		for i := range wantDecades {
+			if *stats.Decades[i] != wantDecades[i] {
+				t.Errorf("got decade %+v; want %+v", *stats.Decades[i], wantDecades[i])
+			}
+		}
+		if len(stats.Genres) != 2 || *stats.Genres[0] != (data.GenreCount{Name: "drama", Movies: 2}) {
+			t.Errorf("got genres %+v; want drama first with 2 movies", stats.Genres)
+		}
+	})
+}
+
+func date(t *testing.T, s string) *data.Date {
+	t.Helper()
+	d, err := data.ParseDate(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &d
+}
+
+func TestMovieModelRevisions(t *testing.T) {
+	db := datatest.OpenDB(t)
+	ctx := context.Background()
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		movie := datatest.InsertMovie(t, m, datatest.NewMovie())
+		original := movie.Title
+		movie.Title = "Updated"
+		err := m.Movies.Update(ctx, movie)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		revisions, err := m.Movies.GetRevisions(ctx, movie.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revisions) != 2 || revisions[0].Version != 2 || revisions[1].Version != 1 {
+			t.Fatalf("got revisions %+v; want versions 2 and 1", revisions)
+		}
+
+		revision, err := m.Movies.GetRevision(ctx, movie.ID, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revision.Title != original {
+			t.Errorf("got revision 1 title %q; want %q", revision.Title, original)
+		}
+		_, err = m.Movies.GetRevision(ctx, movie.ID, 3)
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("got %v for a missing revision; want ErrRecordNotFound", err)
+		}
+	})
+}
+
+func TestMovieModelTitles(t *testing.T) {
+	db := datatest.OpenDB(t)
+	ctx := context.Background()
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		movie := datatest.InsertMovie(t, m, datatest.NewMovie(func(movie *data.Movie) {
+			movie.Title = "Spirited Away"
+			movie.OriginalTitle = "Sen to Chihiro no kamikakushi"
+			movie.Titles = data.Titles{"fr": "Le Voyage de Chihiro", "de": "Chihiros Reise ins Zauberland"}
+		}))
+
+		got, err := m.Movies.Get(ctx, movie.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.OriginalTitle != movie.OriginalTitle || !maps.Equal(got.Titles, movie.Titles) {
+			t.Errorf("got %q, %v; want %q, %v", got.OriginalTitle, got.Titles, movie.OriginalTitle, movie.Titles)
+		}
+
+		for _, search := range []string{"chihiro", "kamikakushi"} {
+			movies, _, err := m.Movies.GetAll(ctx, search, []string{}, "", "", nil, nil, data.Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: []string{"id"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.ContainsFunc(movies, func(found *data.Movie) bool { return found.ID == movie.ID }) {
+				t.Errorf("GetAll(%q) didn't find the movie by a localized title", search)
+			}
+		}
+		matches, err := m.Movies.SearchTitles(ctx, "voyage chihiro", 0.3, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) == 0 || matches[0].ID != movie.ID {
+			t.Errorf("got %v searching a localized title; want the movie first", matches)
+		}
+
+		got.Titles = data.Titles{"fr": "Le Voyage de Chihiro", "es": "El viaje de Chihiro"}
+		err = m.Movies.Update(ctx, got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		updated, err := m.Movies.Get(ctx, movie.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !maps.Equal(updated.Titles, got.Titles) {
+			t.Errorf("got titles %v after update; want %v", updated.Titles, got.Titles)
+		}
+
+		updated.Titles = nil
+		err = m.Movies.Update(ctx, updated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cleared, err := m.Movies.Get(ctx, movie.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cleared.Titles != nil {
+			t.Errorf("got titles %v after clearing; want none", cleared.Titles)
+		}
+
+		// Revisions record the titles as they were at each version.
+		revision, err := m.Movies.GetRevision(ctx, movie.ID, got.Version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revision.OriginalTitle != movie.OriginalTitle || !maps.Equal(revision.Titles, got.Titles) {
+			t.Errorf("got %q, %v in revision %d; want %q, %v", revision.OriginalTitle, revision.Titles, got.Version, movie.OriginalTitle, got.Titles)
+		}
+		revision, err = m.Movies.GetRevision(ctx, movie.ID, cleared.Version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revision.Titles) != 0 {
+			t.Errorf("got titles %v in revision %d; want none", revision.Titles, cleared.Version)
+		}
+	})
+}
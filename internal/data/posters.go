@@ -0,0 +1,143 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	PosterProcessing = "processing"
+	PosterReady      = "ready"
+	PosterFailed     = "failed"
+)
+
+// Poster is a movie's poster image. Uploads are resized in the background, so
+// a poster starts out processing and gains its Sizes, keyed by size name such
+// as "thumb", and Color once they're ready. Version identifies the upload and
+// changes with every new one.
+type Poster struct {
+	Status  string                 `json:"status"`
+	Version string                 `json:"version"`
+	Color   string                 `json:"color,omitzero"`
+	Sizes   map[string]PosterImage `json:"sizes,omitzero"`
+}
+
+// PosterImage is one size of a poster.
+type PosterImage struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// Scan reads the poster's jsonb column. NULL, for a movie without a poster,
+// leaves it zero.
+func (p *Poster) Scan(src any) error {
+	*p = Poster{}
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(src, p)
+	case string:
+		return json.Unmarshal([]byte(src), p)
+	default:
+		return fmt.Errorf("cannot scan %T into Poster", src)
+	}
+}
+
+// SetPoster replaces the movie's poster. It returns ErrRecordNotFound if the
+// movie doesn't exist.
+func (m MovieModel) SetPoster(ctx context.Context, movieID int64, poster *Poster) error {
+	js, err := json.Marshal(poster)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO movie_posters (movie_id, poster)
+		SELECT id, $2 FROM movies WHERE id = $1
+		ON CONFLICT (movie_id) DO UPDATE SET poster = EXCLUDED.poster, updated_at = NOW()
+		RETURNING movie_id`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err = m.DB.QueryRowContext(ctx, query, movieID, string(js)).Scan(&movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdatePoster replaces the movie's poster if it's still the same upload,
+// returning ErrEditConflict if a newer upload, with a different Version, has
+// replaced it since.
+func (m MovieModel) UpdatePoster(ctx context.Context, movieID int64, poster *Poster) error {
+	js, err := json.Marshal(poster)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE movie_posters
+		SET poster = $2, updated_at = NOW()
+		WHERE movie_id = $1 AND poster->>'version' = $3`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, movieID, string(js), poster.Version)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+	return nil
+}
+
+// RevertPoster puts back the poster an upload replaced, removing the poster
+// altogether if previous is zero. Like UpdatePoster, it returns
+// ErrEditConflict if the upload, identified by version, has itself been
+// replaced since.
+func (m MovieModel) RevertPoster(ctx context.Context, movieID int64, version string, previous *Poster) error {
+	query := `
+		DELETE FROM movie_posters
+		WHERE movie_id = $1 AND poster->>'version' = $2`
+	args := []any{movieID, version}
+	if previous.Status != "" {
+		js, err := json.Marshal(previous)
+		if err != nil {
+			return err
+		}
+		query = `
+			UPDATE movie_posters
+			SET poster = $3, updated_at = NOW()
+			WHERE movie_id = $1 AND poster->>'version' = $2`
+		args = append(args, string(js))
+	}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+	return nil
+}
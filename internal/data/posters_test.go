@@ -0,0 +1,107 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/datatest"
+	"testing"
+)
+
+func TestMovieModelPoster(t *testing.T) {
+	db := datatest.OpenDB(t)
+	ctx := context.Background()
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		movie := datatest.InsertMovie(t, m, datatest.NewMovie())
+
+		err := m.Movies.SetPoster(ctx, -1, &data.Poster{Status: data.PosterProcessing, Version: "a"})
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("got error %v for a missing movie; want ErrRecordNotFound", err)
+		}
+		err = m.Movies.SetPoster(ctx, movie.ID, &data.Poster{Status: data.PosterProcessing, Version: "a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = m.Movies.SetPoster(ctx, movie.ID, &data.Poster{Status: data.PosterProcessing, Version: "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The first upload has been replaced, so it can't be marked ready.
+		err = m.Movies.UpdatePoster(ctx, movie.ID, &data.Poster{Status: data.PosterReady, Version: "a"})
+		if !errors.Is(err, data.ErrEditConflict) {
+			t.Errorf("got %v updating a replaced poster; want ErrEditConflict", err)
+		}
+		ready := &data.Poster{
+			Status:  data.PosterReady,
+			Version: "b",
+			Color:   "#102030",
+			Sizes:   map[string]data.PosterImage{"thumb": {URL: "http://localhost/thumb.jpg", Width: 185, Height: 278}},
+		}
+		err = m.Movies.UpdatePoster(ctx, movie.ID, ready)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := m.Movies.Get(ctx, movie.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Poster.Status != data.PosterReady || got.Poster.Color != ready.Color || got.Poster.Sizes["thumb"] != ready.Sizes["thumb"] {
+			t.Errorf("got poster %+v; want %+v", got.Poster, ready)
+		}
+		// Setting the poster leaves the movie itself, and its history, alone.
+		if got.Version != movie.Version {
+			t.Errorf("got version %d; want %d", got.Version, movie.Version)
+		}
+	})
+}
+
+func TestMovieModelRevertPoster(t *testing.T) {
+	db := datatest.OpenDB(t)
+	ctx := context.Background()
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		movie := datatest.InsertMovie(t, m, datatest.NewMovie())
+		previous := &data.Poster{Status: data.PosterReady, Version: "a", Color: "#102030"}
+		err := m.Movies.SetPoster(ctx, movie.ID, previous)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = m.Movies.SetPoster(ctx, movie.ID, &data.Poster{Status: data.PosterProcessing, Version: "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Only the upload being undone can be reverted.
+		err = m.Movies.RevertPoster(ctx, movie.ID, "c", &data.Poster{})
+		if !errors.Is(err, data.ErrEditConflict) {
+			t.Errorf("got %v reverting a replaced upload; want ErrEditConflict", err)
+		}
+		err = m.Movies.RevertPoster(ctx, movie.ID, "b", previous)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := m.Movies.Get(ctx, movie.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Poster.Status != previous.Status || got.Poster.Version != previous.Version || got.Poster.Color != previous.Color {
+			t.Errorf("got poster %+v; want %+v", got.Poster, previous)
+		}
+
+		// Reverting a movie's first upload leaves it without a poster.
+		err = m.Movies.RevertPoster(ctx, movie.ID, "a", &data.Poster{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err = m.Movies.Get(ctx, movie.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Poster.Status != "" {
+			t.Errorf("got poster %+v; want none", got.Poster)
+		}
+	})
+}
@@ -0,0 +1,192 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"github.com/lib/pq"
+	"time"
+)
+
+const (
+	NotificationWeeklyDigest      = "weekly_digest"
+	NotificationReviewReplies     = "review_replies"
+	NotificationModerationUpdates = "moderation_updates"
+)
+
+var Notifications = []string{NotificationWeeklyDigest, NotificationReviewReplies, NotificationModerationUpdates}
+
+type Preferences struct {
+	UserID            int64    `json:"-"`
+	WeeklyDigest      bool     `json:"weekly_digest"`
+	FavoriteGenres    []string `json:"favorite_genres"`
+	ReviewReplies     bool     `json:"review_replies"`
+	ModerationUpdates bool     `json:"moderation_updates"`
+	Version           int      `json:"version"`
+}
+
+// DefaultPreferences returns the preferences used for users who have never
+// changed them. They must match the column defaults in user_preferences.
+func DefaultPreferences(userID int64) *Preferences {
+	return &Preferences{
+		UserID:            userID,
+		WeeklyDigest:      false,
+		FavoriteGenres:    []string{},
+		ReviewReplies:     true,
+		ModerationUpdates: true,
+	}
+}
+
+func ValidatePreferences(v *validator.Validator, preferences *Preferences) {
+	v.CheckCode(preferences.FavoriteGenres != nil, "favorite_genres", "required", "must be provided")
+	v.CheckCode(len(preferences.FavoriteGenres) <= 5, "favorite_genres", "too_many", "must not contain more than 5 genres")
+	v.CheckCode(validator.Unique(preferences.FavoriteGenres), "favorite_genres", "duplicate_values", "must not contain duplicate values")
+}
+
+// Allows reports whether the user wants to receive the given notification.
+func (p *Preferences) Allows(notification string) bool {
+	switch notification {
+	case NotificationWeeklyDigest:
+		return p.WeeklyDigest
+	case NotificationReviewReplies:
+		return p.ReviewReplies
+	case NotificationModerationUpdates:
+		return p.ModerationUpdates
+	}
+	return true
+}
+
+// Disable turns off the given notification.
+func (p *Preferences) Disable(notification string) {
+	switch notification {
+	case NotificationWeeklyDigest:
+		p.WeeklyDigest = false
+	case NotificationReviewReplies:
+		p.ReviewReplies = false
+	case NotificationModerationUpdates:
+		p.ModerationUpdates = false
+	}
+}
+
+type PreferenceModel struct {
+	DB DBTX
+}
+
+func (m PreferenceModel) Get(ctx context.Context, userID int64) (*Preferences, error) {
+	query := `
+		SELECT user_id, weekly_digest, favorite_genres, review_replies, moderation_updates, version
+		FROM user_preferences
+		WHERE user_id = $1`
+	var preferences Preferences
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(
+		&preferences.UserID,
+		&preferences.WeeklyDigest,
+		pq.Array(&preferences.FavoriteGenres),
+		&preferences.ReviewReplies,
+		&preferences.ModerationUpdates,
+		&preferences.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return DefaultPreferences(userID), nil
+		default:
+			return nil, err
+		}
+	}
+	return &preferences, nil
+}
+
+// Update stores the preferences, creating the row on first use. A version of
+// zero means the user still has the defaults and no row exists yet.
+func (m PreferenceModel) Update(ctx context.Context, preferences *Preferences) error {
+	query := `
+		INSERT INTO user_preferences (user_id, weekly_digest, favorite_genres, review_replies, moderation_updates)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET weekly_digest = $2, favorite_genres = $3, review_replies = $4, moderation_updates = $5,
+			version = user_preferences.version + 1
+		WHERE user_preferences.version = $6
+		RETURNING version`
+	args := []any{
+		preferences.UserID,
+		preferences.WeeklyDigest,
+		pq.Array(preferences.FavoriteGenres),
+		preferences.ReviewReplies,
+		preferences.ModerationUpdates,
+		preferences.Version,
+	}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&preferences.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// DigestRecipient is an activated user who has opted in to the weekly digest
+// and hasn't received one since the cutoff passed to GetDueForDigest.
+type DigestRecipient struct {
+	User           *User
+	FavoriteGenres []string
+}
+
+func (m PreferenceModel) GetDueForDigest(ctx context.Context, cutoff time.Time) ([]*DigestRecipient, error) {
+	query := `
+		SELECT users.id, users.name, users.email, users.language, user_preferences.favorite_genres
+		FROM users
+		INNER JOIN user_preferences ON user_preferences.user_id = users.id
+		WHERE users.activated = true
+		AND users.deactivated_at IS NULL
+		AND user_preferences.weekly_digest = true
+		AND (user_preferences.last_digest_at IS NULL OR user_preferences.last_digest_at < $1)`
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipients := []*DigestRecipient{}
+	for rows.Next() {
+		recipient := DigestRecipient{User: &User{}}
+		err := rows.Scan(
+			&recipient.User.ID,
+			&recipient.User.Name,
+			&recipient.User.Email,
+			&recipient.User.Language,
+			pq.Array(&recipient.FavoriteGenres),
+		)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, &recipient)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return recipients, nil
+}
+
+func (m PreferenceModel) MarkDigestSent(ctx context.Context, userID int64) error {
+	query := `
+		UPDATE user_preferences
+		SET last_digest_at = NOW()
+		WHERE user_id = $1`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	_, err := m.DB.ExecContext(ctx, query, userID)
+	return err
+}
@@ -0,0 +1,112 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"time"
+)
+
+// MovieRevision is a movie as it was at one version. Revisions are recorded
+// by a trigger on the movies table whenever a movie is inserted or updated, so
+// the latest revision is always the movie's current state.
+// Revisions recorded before original and localized titles were tracked have
+// the movie's current ones.
+type MovieRevision struct {
+	RevisedAt time.Time `json:"revised_at"`
+	Movie
+}
+
+// GetRevisions returns the movie's revisions, newest first.
+func (m MovieModel) GetRevisions(ctx context.Context, movieID int64) ([]*MovieRevision, error) {
+	query := `
+		SELECT movie_revisions.revised_at, movies.id, movies.created_at, movie_revisions.title,
+			COALESCE(movie_revisions.original_title, movies.original_title),
+			COALESCE(movie_revisions.titles, (SELECT jsonb_object_agg(mt.locale, mt.title) FROM movie_titles mt WHERE mt.movie_id = movies.id)),
+			movie_revisions.year, movie_revisions.runtime, movie_revisions.genres, movie_revisions.release_date,
+			movie_revisions.certification_system, movie_revisions.certification,
+			movie_revisions.version
+		FROM movie_revisions
+		INNER JOIN movies ON movies.id = movie_revisions.movie_id
+		WHERE movie_revisions.movie_id = $1
+		ORDER BY movie_revisions.version DESC`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*MovieRevision{}
+	for rows.Next() {
+		var revision MovieRevision
+		err := rows.Scan(
+			&revision.RevisedAt,
+			&revision.ID,
+			&revision.CreatedAt,
+			&revision.Title,
+			&revision.OriginalTitle,
+			&revision.Titles,
+			&revision.Year,
+			&revision.Runtime,
+			pq.Array(&revision.Genres),
+			&revision.ReleaseDate,
+			&revision.CertificationSystem,
+			&revision.Certification,
+			&revision.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, &revision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// GetRevision returns the movie as it was at version.
+func (m MovieModel) GetRevision(ctx context.Context, movieID int64, version int32) (*MovieRevision, error) {
+	query := `
+		SELECT movie_revisions.revised_at, movies.id, movies.created_at, movie_revisions.title,
+			COALESCE(movie_revisions.original_title, movies.original_title),
+			COALESCE(movie_revisions.titles, (SELECT jsonb_object_agg(mt.locale, mt.title) FROM movie_titles mt WHERE mt.movie_id = movies.id)),
+			movie_revisions.year, movie_revisions.runtime, movie_revisions.genres, movie_revisions.release_date,
+			movie_revisions.certification_system, movie_revisions.certification,
+			movie_revisions.version
+		FROM movie_revisions
+		INNER JOIN movies ON movies.id = movie_revisions.movie_id
+		WHERE movie_revisions.movie_id = $1 AND movie_revisions.version = $2`
+	var revision MovieRevision
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, movieID, version).Scan(
+		&revision.RevisedAt,
+		&revision.ID,
+		&revision.CreatedAt,
+		&revision.Title,
+		&revision.OriginalTitle,
+		&revision.Titles,
+		&revision.Year,
+		&revision.Runtime,
+		pq.Array(&revision.Genres),
+		&revision.ReleaseDate,
+		&revision.CertificationSystem,
+		&revision.Certification,
+		&revision.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &revision, nil
+}
@@ -1,6 +1,8 @@
 package data
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -17,17 +19,53 @@ func (r Runtime) MarshalJSON() ([]byte, error) {
 	return []byte(quotedJSONValue), nil
 }
 
+// UnmarshalJSON accepts a runtime as a "107 mins" string, as a plain number of
+// minutes, or as an object of hours and minutes such as {"hours": 1,
+// "minutes": 47}. It's always written back as a string.
 func (r *Runtime) UnmarshalJSON(jsonValue []byte) error {
-	unquotedJSONValue, err := strconv.Unquote(string(jsonValue))
-	if err != nil {
-		return ErrInvalidRuntimeFormat
-	}
-	parts := strings.Split(unquotedJSONValue, " ")
-	if len(parts) != 2 || parts[1] != "mins" {
-		return ErrInvalidRuntimeFormat
+	switch {
+	case bytes.HasPrefix(jsonValue, []byte(`"`)):
+		unquotedJSONValue, err := strconv.Unquote(string(jsonValue))
+		if err != nil {
+			return ErrInvalidRuntimeFormat
+		}
+		parts := strings.Split(unquotedJSONValue, " ")
+		if len(parts) != 2 || parts[1] != "mins" {
+			return ErrInvalidRuntimeFormat
+		}
+		return r.setMinutes(parts[0])
+
+	case bytes.HasPrefix(jsonValue, []byte(`{`)):
+		var parts struct {
+			Hours   *int32 `json:"hours"`
+			Minutes *int32 `json:"minutes"`
+		}
+		dec := json.NewDecoder(bytes.NewReader(jsonValue))
+		dec.DisallowUnknownFields()
+		if dec.Decode(&parts) != nil || (parts.Hours == nil && parts.Minutes == nil) {
+			return ErrInvalidRuntimeFormat
+		}
+		var total int64
+		if parts.Hours != nil {
+			total += int64(*parts.Hours) * 60
+		}
+		if parts.Minutes != nil {
+			total += int64(*parts.Minutes)
+		}
+		if total > 1<<31-1 {
+			return ErrInvalidRuntimeFormat
+		}
+		*r = Runtime(total)
+		return nil
+
+	default:
+		return r.setMinutes(string(jsonValue))
 	}
+}
 
-	i, err := strconv.ParseInt(parts[0], 10, 32)
+// setMinutes parses s as a whole number of minutes.
+func (r *Runtime) setMinutes(s string) error {
+	i, err := strconv.ParseInt(s, 10, 32)
 	if err != nil {
 		return ErrInvalidRuntimeFormat
 	}
@@ -0,0 +1,48 @@
+package data_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ezechidc/greenlight/internal/data"
+	"testing"
+)
+
+func TestRuntimeUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    data.Runtime
+		wantErr bool
+	}{
+		{input: `"107 mins"`, want: 107},
+		{input: `107`, want: 107},
+		{input: `{"hours": 1, "minutes": 47}`, want: 107},
+		{input: `{"minutes": 107}`, want: 107},
+		{input: `{"hours": 2}`, want: 120},
+		{input: `"107"`, wantErr: true},
+		{input: `"107 minutes"`, wantErr: true},
+		{input: `107.5`, wantErr: true},
+		{input: `{}`, wantErr: true},
+		{input: `{"seconds": 30}`, wantErr: true},
+		{input: `{"hours": "1"}`, wantErr: true},
+		{input: `null`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var got data.Runtime
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if tt.wantErr {
+				if !errors.Is(err, data.ErrInvalidRuntimeFormat) {
+					t.Errorf("got error %v; want ErrInvalidRuntimeFormat", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d; want %d", got, tt.want)
+			}
+		})
+	}
+}
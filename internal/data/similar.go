@@ -0,0 +1,183 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"github.com/lib/pq"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoEmbedding is returned by GetSimilarByEmbedding for a movie that hasn't
+// been embedded with the requested model yet.
+var ErrNoEmbedding = errors.New("no embedding")
+
+// SimilarMovie is a movie found by GetSimilarByGenre or GetSimilarByEmbedding.
+// Score is from 0 to 1, higher being more alike: the Jaccard index of the two
+// movies' genres, or the cosine similarity of their embeddings.
+type SimilarMovie struct {
+	*Movie
+	Score float64 `json:"score"`
+}
+
+// GetSimilarByGenre returns up to limit other movies sharing at least one
+// genre with the movie, those with the most genres in common first.
+func (m MovieModel) GetSimilarByGenre(ctx context.Context, id int64, limit int) ([]*SimilarMovie, error) {
+	query := `
+		SELECT m.id, m.created_at, m.title, m.original_title, m.year, m.runtime, m.genres, m.release_date, m.certification_system, m.certification, m.version,
+			(SELECT json_object_agg(mt.locale, mt.title) FROM movie_titles mt WHERE mt.movie_id = m.id),
+			(SELECT mp.poster FROM movie_posters mp WHERE mp.movie_id = m.id),
+			cardinality(ARRAY(SELECT unnest(m.genres) INTERSECT SELECT unnest(t.genres)))::float8 /
+			cardinality(ARRAY(SELECT unnest(m.genres) UNION SELECT unnest(t.genres))) AS score
+		FROM movies t
+		JOIN movies m ON m.id <> t.id AND m.genres && t.genres
+		WHERE t.id = $1
+		ORDER BY score DESC, m.id
+		LIMIT $2`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	return m.querySimilar(ctx, query, id, limit)
+}
+
+// GetSimilarByEmbedding returns up to limit other movies whose embeddings
+// from model are closest to the movie's, nearest first. It returns
+// ErrNoEmbedding if the movie has no embedding from model.
+func (m MovieModel) GetSimilarByEmbedding(ctx context.Context, id int64, model string, limit int) ([]*SimilarMovie, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM movie_embeddings WHERE movie_id = $1 AND model = $2)`
+	err := m.DB.QueryRowContext(ctx, query, id, model).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNoEmbedding
+	}
+
+	query = `
+		SELECT m.id, m.created_at, m.title, m.original_title, m.year, m.runtime, m.genres, m.release_date, m.certification_system, m.certification, m.version,
+			(SELECT json_object_agg(mt.locale, mt.title) FROM movie_titles mt WHERE mt.movie_id = m.id),
+			(SELECT mp.poster FROM movie_posters mp WHERE mp.movie_id = m.id),
+			1 - (e.embedding <=> t.embedding) AS score
+		FROM movie_embeddings t
+		JOIN movie_embeddings e ON e.movie_id <> t.movie_id AND e.model = t.model
+		JOIN movies m ON m.id = e.movie_id
+		WHERE t.movie_id = $1 AND t.model = $2
+		ORDER BY e.embedding <=> t.embedding, m.id
+		LIMIT $3`
+	return m.querySimilar(ctx, query, id, model, limit)
+}
+
+func (m MovieModel) querySimilar(ctx context.Context, query string, args ...any) ([]*SimilarMovie, error) {
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movies := []*SimilarMovie{}
+	for rows.Next() {
+		similar := SimilarMovie{Movie: &Movie{}}
+		err := rows.Scan(
+			&similar.ID,
+			&similar.CreatedAt,
+			&similar.Title,
+			&similar.OriginalTitle,
+			&similar.Year,
+			&similar.Runtime,
+			pq.Array(&similar.Genres),
+			&similar.ReleaseDate,
+			&similar.CertificationSystem,
+			&similar.Certification,
+			&similar.Version,
+			&similar.Titles,
+			&similar.Poster,
+			&similar.Score,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movies = append(movies, &similar)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// GetUnembedded returns up to limit movies with no embedding from model for
+// their current version, oldest first.
+func (m MovieModel) GetUnembedded(ctx context.Context, model string, limit int) ([]*Movie, error) {
+	query := `
+		SELECT m.id, m.created_at, m.title, m.year, m.runtime, m.genres, m.release_date, m.version
+		FROM movies m
+		LEFT JOIN movie_embeddings e ON e.movie_id = m.id AND e.model = $1 AND e.movie_version = m.version
+		WHERE e.movie_id IS NULL
+		ORDER BY m.id
+		LIMIT $2`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, model, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movies := []*Movie{}
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.ReleaseDate,
+			&movie.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movies = append(movies, &movie)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// SetEmbedding stores the embedding of a version of the movie, replacing any
+// earlier one. It does nothing if the movie has since been deleted.
+func (m MovieModel) SetEmbedding(ctx context.Context, movieID int64, version int32, model string, embedding []float32) error {
+	query := `
+		INSERT INTO movie_embeddings (movie_id, movie_version, model, embedding)
+		SELECT id, $2, $3, $4::vector FROM movies WHERE id = $1
+		ON CONFLICT (movie_id) DO UPDATE
+		SET movie_version = EXCLUDED.movie_version, model = EXCLUDED.model, embedding = EXCLUDED.embedding, updated_at = NOW()`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, movieID, version, model, formatVector(embedding))
+	return err
+}
+
+// formatVector writes v in pgvector's text format, "[1,2,3]", so embeddings
+// can be passed as ordinary parameters and cast to vector.
+func formatVector(v []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, f := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(f), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
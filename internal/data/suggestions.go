@@ -0,0 +1,194 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ezechidc/greenlight/internal/validator"
+	"time"
+)
+
+const (
+	SuggestionPending  = "pending"
+	SuggestionApproved = "approved"
+	SuggestionRejected = "rejected"
+)
+
+// Suggestion is an edit to a movie proposed by a user, for someone with the
+// movies:review permission to approve or reject. Changes is a JSON Merge
+// Patch (RFC 7386) of the movie's editable fields, and MovieVersion the
+// version it was suggested against. Once approved, AppliedVersion is the
+// version of the movie the changes produced, attributing it to the suggester
+// and the reviewer.
+type Suggestion struct {
+	ID             int64           `json:"id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	MovieID        int64           `json:"movie_id"`
+	MovieVersion   int32           `json:"movie_version"`
+	UserID         int64           `json:"user_id,omitzero"`
+	Changes        json.RawMessage `json:"changes"`
+	Comment        string          `json:"comment,omitzero"`
+	Status         string          `json:"status"`
+	ReviewedBy     int64           `json:"reviewed_by,omitzero"`
+	ReviewedAt     *time.Time      `json:"reviewed_at,omitempty"`
+	Reason         string          `json:"reason,omitzero"`
+	AppliedVersion int32           `json:"applied_version,omitzero"`
+}
+
+func ValidateSuggestion(v *validator.Validator, suggestion *Suggestion) {
+	v.CheckCode(len(suggestion.Comment) <= 1000, "comment", "too_long", "must not be more than 1000 bytes long")
+	if suggestion.Status == SuggestionRejected {
+		v.CheckCode(suggestion.Reason != "", "reason", "required", "must be provided")
+	}
+	v.CheckCode(len(suggestion.Reason) <= 1000, "reason", "too_long", "must not be more than 1000 bytes long")
+}
+
+func ValidateSuggestionStatus(v *validator.Validator, status string) {
+	if status != "" {
+		v.CheckCode(validator.PermittedValue(status, SuggestionPending, SuggestionApproved, SuggestionRejected), "status", "invalid_value", "invalid status value")
+	}
+}
+
+type SuggestionModel struct {
+	DB DBTX
+}
+
+// Insert adds a pending suggestion. It returns ErrRecordNotFound if the movie
+// has been deleted.
+func (m SuggestionModel) Insert(ctx context.Context, suggestion *Suggestion) error {
+	query := `
+		INSERT INTO movie_suggestions (movie_id, movie_version, user_id, changes, comment)
+		SELECT id, $2, $3, $4, $5 FROM movies WHERE id = $1
+		RETURNING id, created_at, status`
+	args := []any{suggestion.MovieID, suggestion.MovieVersion, suggestion.UserID, string(suggestion.Changes), suggestion.Comment}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&suggestion.ID, &suggestion.CreatedAt, &suggestion.Status)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m SuggestionModel) Get(ctx context.Context, id int64) (*Suggestion, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `
+		SELECT id, created_at, movie_id, movie_version, user_id, changes, comment, status,
+			COALESCE(reviewed_by, 0), reviewed_at, reason, COALESCE(applied_version, 0)
+		FROM movie_suggestions
+		WHERE id = $1`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var suggestion Suggestion
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&suggestion.ID,
+		&suggestion.CreatedAt,
+		&suggestion.MovieID,
+		&suggestion.MovieVersion,
+		&suggestion.UserID,
+		(*[]byte)(&suggestion.Changes),
+		&suggestion.Comment,
+		&suggestion.Status,
+		&suggestion.ReviewedBy,
+		&suggestion.ReviewedAt,
+		&suggestion.Reason,
+		&suggestion.AppliedVersion,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &suggestion, nil
+}
+
+// GetAll returns the suggestions for a movie, or for every movie if movieID
+// is zero, optionally restricted to those with the given status.
+func (m SuggestionModel) GetAll(ctx context.Context, movieID int64, status string, filters Filters) ([]*Suggestion, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, created_at, movie_id, movie_version, user_id, changes, comment, status,
+			COALESCE(reviewed_by, 0), reviewed_at, reason, COALESCE(applied_version, 0)
+		FROM movie_suggestions
+		WHERE (movie_id = $1 OR $1 = 0)
+		AND (status = $2 OR $2 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID, status, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	suggestions := []*Suggestion{}
+	for rows.Next() {
+		var suggestion Suggestion
+		err := rows.Scan(
+			&totalRecords,
+			&suggestion.ID,
+			&suggestion.CreatedAt,
+			&suggestion.MovieID,
+			&suggestion.MovieVersion,
+			&suggestion.UserID,
+			(*[]byte)(&suggestion.Changes),
+			&suggestion.Comment,
+			&suggestion.Status,
+			&suggestion.ReviewedBy,
+			&suggestion.ReviewedAt,
+			&suggestion.Reason,
+			&suggestion.AppliedVersion,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		suggestions = append(suggestions, &suggestion)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return suggestions, metadata, nil
+}
+
+// Review records the outcome of a pending suggestion: its Status, ReviewedBy,
+// Reason and, if approved, AppliedVersion. It returns ErrEditConflict if the
+// suggestion has already been reviewed.
+func (m SuggestionModel) Review(ctx context.Context, suggestion *Suggestion) error {
+	query := `
+		UPDATE movie_suggestions
+		SET status = $2, reviewed_by = $3, reviewed_at = NOW(), reason = $4, applied_version = $5
+		WHERE id = $1 AND status = 'pending'
+		RETURNING reviewed_at`
+	appliedVersion := sql.NullInt32{Int32: suggestion.AppliedVersion, Valid: suggestion.AppliedVersion != 0}
+	args := []any{suggestion.ID, suggestion.Status, suggestion.ReviewedBy, suggestion.Reason, appliedVersion}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&suggestion.ReviewedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/datatest"
+	"testing"
+)
+
+func TestSuggestionModel(t *testing.T) {
+	db := datatest.OpenDB(t)
+	ctx := context.Background()
+	filters := data.Filters{Page: 1, PageSize: 20, Sort: "created_at", SortSafelist: []string{"created_at"}}
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		movie := datatest.InsertMovie(t, m, datatest.NewMovie())
+		suggester := datatest.InsertUser(t, m, datatest.NewActivatedUser())
+		reviewer := datatest.InsertUser(t, m, datatest.NewActivatedUser())
+
+		suggestion := &data.Suggestion{MovieID: movie.ID, MovieVersion: movie.Version, UserID: suggester.ID, Changes: []byte(`{"year": 1943}`), Comment: "Released in 1943"}
+		err := m.Suggestions.Insert(ctx, suggestion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if suggestion.Status != data.SuggestionPending {
+			t.Errorf("got status %q; want pending", suggestion.Status)
+		}
+
+		err = m.Suggestions.Insert(ctx, &data.Suggestion{MovieID: -1, UserID: suggester.ID, Changes: []byte(`{}`)})
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("got error %v for a missing movie; want ErrRecordNotFound", err)
+		}
+
+		pending, metadata, err := m.Suggestions.GetAll(ctx, 0, data.SuggestionPending, filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) != 1 || metadata.TotalRecords != 1 || string(pending[0].Changes) != `{"year": 1943}` {
+			t.Errorf("got %+v; want the one pending suggestion", pending)
+		}
+
+		suggestion.Status = data.SuggestionApproved
+		suggestion.ReviewedBy = reviewer.ID
+		suggestion.AppliedVersion = movie.Version + 1
+		err = m.Suggestions.Review(ctx, suggestion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = m.Suggestions.Review(ctx, suggestion)
+		if !errors.Is(err, data.ErrEditConflict) {
+			t.Errorf("got error %v reviewing twice; want ErrEditConflict", err)
+		}
+
+		got, err := m.Suggestions.Get(ctx, suggestion.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != data.SuggestionApproved || got.ReviewedBy != reviewer.ID || got.ReviewedAt == nil || got.AppliedVersion != movie.Version+1 {
+			t.Errorf("got %+v; want approved by user %d", got, reviewer.ID)
+		}
+
+		pending, _, err = m.Suggestions.GetAll(ctx, movie.ID, data.SuggestionPending, filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) != 0 {
+			t.Errorf("got %d pending suggestions after review; want 0", len(pending))
+		}
+	})
+}
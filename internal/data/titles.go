@@ -0,0 +1,70 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// LocaleRX matches the language tags localized titles are keyed by: a
+// lowercase language, optionally followed by an uppercase region, such as fr
+// or pt-BR.
+var LocaleRX = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+
+// Titles maps language tags to a movie's title in that language. A movie's
+// Title is its title in the catalog's default language; Titles holds the
+// translations.
+type Titles map[string]string
+
+// Scan reads the JSON object built with json_object_agg. NULL, for a movie
+// without translations, leaves the map nil.
+func (t *Titles) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(src, t)
+	case string:
+		return json.Unmarshal([]byte(src), t)
+	default:
+		return fmt.Errorf("cannot scan %T into Titles", src)
+	}
+}
+
+// arrays returns the locales and titles as parallel slices, sorted by locale,
+// for passing to unnest. They're never nil, even for no titles, as pq sends
+// nil slices as NULL rather than empty arrays.
+func (t Titles) arrays() ([]string, []string) {
+	locales := slices.AppendSeq(make([]string, 0, len(t)), maps.Keys(t))
+	slices.Sort(locales)
+	titles := make([]string, len(locales))
+	for i, locale := range locales {
+		titles[i] = t[locale]
+	}
+	return locales, titles
+}
+
+// Best returns the translation for the first of the preferred language tags
+// which has one, trying each tag's base language (fr for fr-CA) after the tag
+// itself. Preferences which name defaultLocale, the language of title, stop
+// the search, since title is then the best match. ok is false if no
+// translation matched.
+func (t Titles) Best(preferred []string, defaultLocale string) (title string, ok bool) {
+	for _, tag := range preferred {
+		base, _, _ := strings.Cut(tag, "-")
+		if title, ok := t[tag]; ok {
+			return title, true
+		}
+		if title, ok := t[base]; ok {
+			return title, true
+		}
+		if base == defaultLocale {
+			return "", false
+		}
+	}
+	return "", false
+}
@@ -2,9 +2,9 @@ package data
 
 import (
 	"context"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
-	"database/sql"
 	"github.com/ezechidc/greenlight/internal/validator"
 	"time"
 )
@@ -12,8 +12,16 @@ import (
 const (
 	ScopeActivation     = "activation"
 	ScopeAuthentication = "authentication"
+	ScopeUnsubscribe    = "unsubscribe"
 )
 
+// UnsubscribeScope returns the token scope for unsubscribing from the given
+// notification, so a link sent with one email can't be used to turn off
+// another kind of notification.
+func UnsubscribeScope(notification string) string {
+	return ScopeUnsubscribe + ":" + notification
+}
+
 type Token struct {
 	Plaintext string
 	Hash      []byte
@@ -22,14 +30,45 @@ type Token struct {
 	Scope     string
 }
 
+// TokenPeppers holds the secret peppers mixed into token hashes with
+// HMAC-SHA256, so a database dump alone can't be used to check or forge tokens.
+// New tokens are hashed with the first pepper and lookups accept any of them,
+// which allows peppers to be rotated. An empty pepper means plain SHA-256, the
+// scheme used before peppers were introduced.
+var TokenPeppers = [][]byte{nil}
+
+// tokenHash returns the hash stored for a new token.
+func tokenHash(tokenPlaintext string) []byte {
+	return tokenHashWithPepper(TokenPeppers[0], tokenPlaintext)
+}
+
+// tokenHashes returns the hashes tokenPlaintext may have been stored under.
+func tokenHashes(tokenPlaintext string) [][]byte {
+	hashes := make([][]byte, 0, len(TokenPeppers))
+	for _, pepper := range TokenPeppers {
+		hashes = append(hashes, tokenHashWithPepper(pepper, tokenPlaintext))
+	}
+	return hashes
+}
+
+func tokenHashWithPepper(pepper []byte, tokenPlaintext string) []byte {
+	if len(pepper) == 0 {
+		hash := sha256.Sum256([]byte(tokenPlaintext))
+		return hash[:]
+	}
+	mac := hmac.New(sha256.New, pepper)
+	mac.Write([]byte(tokenPlaintext))
+	return mac.Sum(nil)
+}
+
 // Define the TokenModel type.
 type TokenModel struct {
-	DB *sql.DB
+	DB DBTX
 }
 
 func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
-	v.Check(tokenPlaintext != "", "token", "must be provided")
-	v.Check(len(tokenPlaintext) == 26, "token", "must be 26 bytes long")
+	v.CheckCode(tokenPlaintext != "", "token", "required", "must be provided")
+	v.CheckCode(len(tokenPlaintext) == 26, "token", "invalid_length", "must be 26 bytes long")
 }
 
 func generateToken(userID int64, ttl time.Duration, scope string) *Token {
@@ -39,34 +78,113 @@ func generateToken(userID int64, ttl time.Duration, scope string) *Token {
 		Expiry:    time.Now().Add(ttl),
 		Scope:     scope,
 	}
-	hash := sha256.Sum256([]byte(token.Plaintext))
-	token.Hash = hash[:]
+	token.Hash = tokenHash(token.Plaintext)
 	return token
 }
 
-func (m TokenModel) New(userID int64, ttl time.Duration, scope string) (*Token, error) {
+func (m TokenModel) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error) {
 	token := generateToken(userID, ttl, scope)
-	err := m.Insert(token)
+	err := m.Insert(ctx, token)
 	return token, err
 }
 
-func (m TokenModel) Insert(token *Token) error {
+func (m TokenModel) Insert(ctx context.Context, token *Token) error {
 	query := `
 		INSERT INTO tokens (hash, user_id, expiry, scope)
 		VALUES ($1, $2, $3, $4)`
 	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	_, err := m.DB.ExecContext(ctx, query, args...)
 	return err
 }
 
-func (m TokenModel) DeleteAllForUser(scope string, userID int64) error {
+func (m TokenModel) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
 	query := `
 		DELETE FROM tokens
 		WHERE scope = $1 AND user_id = $2`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	_, err := m.DB.ExecContext(ctx, query, scope, userID)
 	return err
 }
+
+// CountActive returns the number of unexpired tokens in each scope.
+func (m TokenModel) CountActive(ctx context.Context) (map[string]int, error) {
+	query := `
+		SELECT scope, count(*)
+		FROM tokens
+		WHERE expiry > now()
+		GROUP BY scope`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var (
+			scope string
+			count int
+		)
+		err := rows.Scan(&scope, &count)
+		if err != nil {
+			return nil, err
+		}
+		counts[scope] = count
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// DeleteExpired deletes up to limit tokens which expired before before, and
+// returns how many it deleted. Deleting in small batches keeps each statement
+// short, so pruning doesn't hold locks or bloat the WAL, and rows locked by
+// another instance pruning at the same time are skipped.
+func (m TokenModel) DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM tokens
+		WHERE hash IN (
+			SELECT hash
+			FROM tokens
+			WHERE expiry < $1
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)`
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	result, err := m.DB.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// TokenTableStats is the size of the tokens table. Rows is PostgreSQL's
+// estimate, as counting millions of rows exactly is itself expensive.
+type TokenTableStats struct {
+	Rows  int64 `json:"rows"`
+	Bytes int64 `json:"bytes"`
+}
+
+func (m TokenModel) GetTableStats(ctx context.Context) (*TokenTableStats, error) {
+	query := `
+		SELECT greatest(reltuples, 0)::bigint, pg_total_relation_size(oid)
+		FROM pg_class
+		WHERE oid = 'tokens'::regclass`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var stats TokenTableStats
+	err := m.DB.QueryRowContext(ctx, query).Scan(&stats.Rows, &stats.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
@@ -0,0 +1,62 @@
+package data_test
+
+import (
+	"context"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/datatest"
+	"testing"
+	"time"
+)
+
+func TestTokenModelDeleteExpired(t *testing.T) {
+	db := datatest.OpenDB(t)
+	ctx := context.Background()
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		user := datatest.InsertUser(t, m, datatest.NewUser())
+		for range 3 {
+			_, err := m.Tokens.New(ctx, user.ID, -time.Hour, data.ScopeAuthentication)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		live, err := m.Tokens.New(ctx, user.ID, time.Hour, data.ScopeAuthentication)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Earlier tests may have left expired tokens behind, so delete in
+		// batches until none are left rather than expecting exactly three.
+		for {
+			deleted, err := m.Tokens.DeleteExpired(ctx, time.Now(), 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deleted > 2 {
+				t.Fatalf("deleted %d tokens; want at most the limit of 2", deleted)
+			}
+			if deleted < 2 {
+				break
+			}
+		}
+
+		_, err = m.Users.GetForToken(ctx, data.ScopeAuthentication, live.Plaintext)
+		if err != nil {
+			t.Errorf("unexpired token: %v", err)
+		}
+		active, err := m.Tokens.CountActive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active[data.ScopeAuthentication] < 1 {
+			t.Errorf("got active tokens %v after pruning", active)
+		}
+		stats, err := m.Tokens.GetTableStats(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Bytes <= 0 {
+			t.Errorf("got table size %d; want it positive", stats.Bytes)
+		}
+	})
+}
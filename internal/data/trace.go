@@ -0,0 +1,125 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+type traceContextKey struct{}
+
+// TracedQuery summarises one statement executed while a QueryTrace was active.
+type TracedQuery struct {
+	Query    string        `json:"query"`
+	Args     int           `json:"args"`
+	Duration time.Duration `json:"-"`
+	Error    string        `json:"error,omitzero"`
+}
+
+// QueryTrace collects the statements executed through the models for a single
+// request. It is used by the API's debug mode.
+type QueryTrace struct {
+	mu      sync.Mutex
+	queries []TracedQuery
+}
+
+// WithQueryTrace returns a context which records every statement the models
+// execute with it into the returned QueryTrace.
+func WithQueryTrace(ctx context.Context) (context.Context, *QueryTrace) {
+	trace := &QueryTrace{}
+	return context.WithValue(ctx, traceContextKey{}, trace), trace
+}
+
+func (t *QueryTrace) Queries() []TracedQuery {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]TracedQuery{}, t.queries...)
+}
+
+func (t *QueryTrace) record(query string, args int, duration time.Duration, err error) {
+	q := TracedQuery{
+		Query:    strings.Join(strings.Fields(query), " "),
+		Args:     args,
+		Duration: duration,
+	}
+	if err != nil && err != sql.ErrNoRows {
+		q.Error = err.Error()
+	}
+	t.mu.Lock()
+	t.queries = append(t.queries, q)
+	t.mu.Unlock()
+}
+
+// SlowQuery describes a statement which took longer than SlowQueryThreshold.
+type SlowQuery struct {
+	// Name is the model method which ran the statement, such as
+	// "MovieModel.GetAll".
+	Name string
+	// Args lists the types of the statement's arguments. Their values are
+	// left out, as they can be password hashes or tokens.
+	Args     string
+	Duration time.Duration
+}
+
+var (
+	// SlowQueryThreshold is how long a statement can run before it's passed
+	// to OnSlowQuery. Zero turns slow query reporting off.
+	SlowQueryThreshold time.Duration
+	// OnSlowQuery is called with every statement which runs for longer than
+	// SlowQueryThreshold, with the context it ran with.
+	OnSlowQuery func(ctx context.Context, q SlowQuery)
+)
+
+// tracedDB wraps a DBTX and records statements into the QueryTrace carried by
+// the context, if any, and reports slow statements to OnSlowQuery. Without a
+// trace it adds only a context lookup.
+type tracedDB struct {
+	DBTX
+}
+
+func (db tracedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	result, err := db.DBTX.ExecContext(ctx, query, args...)
+	observe(ctx, query, args, time.Since(start), err)
+	return result, err
+}
+
+func (db tracedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := db.DBTX.QueryContext(ctx, query, args...)
+	observe(ctx, query, args, time.Since(start), err)
+	return rows, err
+}
+
+func (db tracedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	start := time.Now()
+	row := db.DBTX.QueryRowContext(ctx, query, args...)
+	observe(ctx, query, args, time.Since(start), row.Err())
+	return row
+}
+
+// observe is called by each tracedDB method straight after its statement
+// runs, so the model method which called it is two frames up.
+func observe(ctx context.Context, query string, args []any, duration time.Duration, err error) {
+	if trace, ok := ctx.Value(traceContextKey{}).(*QueryTrace); ok {
+		trace.record(query, len(args), duration, err)
+	}
+	if SlowQueryThreshold <= 0 || duration <= SlowQueryThreshold || OnSlowQuery == nil {
+		return
+	}
+	name := "unknown"
+	if pc, _, _, ok := runtime.Caller(2); ok {
+		name = runtime.FuncForPC(pc).Name()
+		name = name[strings.LastIndexByte(name, '/')+1:]
+		name = strings.TrimPrefix(name, "data.")
+	}
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i] = fmt.Sprintf("%T", arg)
+	}
+	OnSlowQuery(ctx, SlowQuery{Name: name, Args: strings.Join(types, ", "), Duration: duration})
+}
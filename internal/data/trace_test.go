@@ -0,0 +1,66 @@
+package data_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/ezechidc/greenlight/internal/data"
+	"testing"
+	"time"
+)
+
+// slowDB is a DBTX whose statements all take delay and affect one row.
+type slowDB struct {
+	data.DBTX
+	delay time.Duration
+}
+
+func (db slowDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	time.Sleep(db.delay)
+	return driver.RowsAffected(1), nil
+}
+
+func TestSlowQueryReporting(t *testing.T) {
+	tests := []struct {
+		name      string
+		delay     time.Duration
+		threshold time.Duration
+		wantSlow  bool
+	}{
+		{name: "slow", delay: 20 * time.Millisecond, threshold: time.Millisecond, wantSlow: true},
+		{name: "fast", delay: 0, threshold: time.Second},
+		{name: "disabled", delay: 20 * time.Millisecond, threshold: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reported []data.SlowQuery
+			data.SlowQueryThreshold = tt.threshold
+			data.OnSlowQuery = func(ctx context.Context, q data.SlowQuery) {
+				reported = append(reported, q)
+			}
+			t.Cleanup(func() {
+				data.SlowQueryThreshold = 0
+				data.OnSlowQuery = nil
+			})
+
+			models := data.NewModels(slowDB{delay: tt.delay})
+			err := models.Movies.Delete(context.Background(), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.wantSlow {
+				if len(reported) != 0 {
+					t.Errorf("got slow queries %+v; want none", reported)
+				}
+				return
+			}
+			if len(reported) != 1 {
+				t.Fatalf("got slow queries %+v; want one", reported)
+			}
+			if q := reported[0]; q.Name != "MovieModel.Delete" || q.Args != "int64" || q.Duration < tt.delay {
+				t.Errorf("got %+v; want MovieModel.Delete with an int64 argument taking at least %s", q, tt.delay)
+			}
+		})
+	}
+}
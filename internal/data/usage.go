@@ -0,0 +1,181 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+// DailyUsage is one user's API usage on one (UTC) day. ClientErrors and
+// ServerErrors count 4xx and 5xx responses, and Bytes is the size of the
+// response bodies sent.
+type DailyUsage struct {
+	Day          time.Time `json:"-"`
+	Date         string    `json:"date"`
+	Requests     int64     `json:"requests"`
+	ClientErrors int64     `json:"client_errors"`
+	ServerErrors int64     `json:"server_errors"`
+	Bytes        int64     `json:"bytes"`
+}
+
+// UsageCount is usage by one user on one day, to be added to the totals.
+type UsageCount struct {
+	UserID       int64
+	At           time.Time
+	Requests     int64
+	ClientErrors int64
+	ServerErrors int64
+	Bytes        int64
+}
+
+// UserUsage is one user's total usage over a period.
+type UserUsage struct {
+	UserID       int64  `json:"user_id"`
+	Email        string `json:"email"`
+	Requests     int64  `json:"requests"`
+	ClientErrors int64  `json:"client_errors"`
+	ServerErrors int64  `json:"server_errors"`
+	Bytes        int64  `json:"bytes"`
+}
+
+// UsageSortColumns are the columns GetTop can rank users by. errors is the
+// sum of client and server errors.
+var UsageSortColumns = []string{"requests", "errors", "bytes"}
+
+var usageOrderBy = map[string]string{
+	"requests": "sum(u.requests)",
+	"errors":   "sum(u.client_errors + u.server_errors)",
+	"bytes":    "sum(u.bytes)",
+}
+
+// UsageModel keeps per-user daily totals in user_usage. Counts are added as
+// they're flushed from the API's in-memory buffer, so each row is already the
+// day's rollup and there are no raw rows to keep.
+type UsageModel struct {
+	DB DBTX
+}
+
+// RecordMany adds a batch of usage counts in one statement. Each user and day
+// must appear at most once in the batch, and usage by users who have since
+// been deleted is skipped.
+func (m UsageModel) RecordMany(ctx context.Context, counts []UsageCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	userIDs := make([]int64, len(counts))
+	days := make([]string, len(counts))
+	requests := make([]int64, len(counts))
+	clientErrors := make([]int64, len(counts))
+	serverErrors := make([]int64, len(counts))
+	bytes := make([]int64, len(counts))
+	for i, count := range counts {
+		userIDs[i] = count.UserID
+		days[i] = viewDay(count.At).Format(time.DateOnly)
+		requests[i] = count.Requests
+		clientErrors[i] = count.ClientErrors
+		serverErrors[i] = count.ServerErrors
+		bytes[i] = count.Bytes
+	}
+	query := `
+		INSERT INTO user_usage (user_id, day, requests, client_errors, server_errors, bytes)
+		SELECT u.user_id, u.day, u.requests, u.client_errors, u.server_errors, u.bytes
+		FROM unnest($1::bigint[], $2::date[], $3::bigint[], $4::bigint[], $5::bigint[], $6::bigint[])
+			AS u(user_id, day, requests, client_errors, server_errors, bytes)
+		JOIN users ON users.id = u.user_id
+		ON CONFLICT (user_id, day) DO UPDATE
+		SET requests = user_usage.requests + EXCLUDED.requests,
+			client_errors = user_usage.client_errors + EXCLUDED.client_errors,
+			server_errors = user_usage.server_errors + EXCLUDED.server_errors,
+			bytes = user_usage.bytes + EXCLUDED.bytes`
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	_, err := m.DB.ExecContext(ctx, query, pq.Array(userIDs), pq.Array(days), pq.Array(requests), pq.Array(clientErrors), pq.Array(serverErrors), pq.Array(bytes))
+	return err
+}
+
+// DeleteBefore removes usage for days before the given time.
+func (m UsageModel) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM user_usage
+		WHERE day < $1`
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	result, err := m.DB.ExecContext(ctx, query, viewDay(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetDaily returns a user's usage from since onwards, oldest first. Days
+// without requests are omitted.
+func (m UsageModel) GetDaily(ctx context.Context, userID int64, since time.Time) ([]*DailyUsage, error) {
+	query := `
+		SELECT day, requests, client_errors, server_errors, bytes
+		FROM user_usage
+		WHERE user_id = $1 AND day >= $2
+		ORDER BY day`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, viewDay(since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []*DailyUsage{}
+	for rows.Next() {
+		var day DailyUsage
+		err := rows.Scan(&day.Day, &day.Requests, &day.ClientErrors, &day.ServerErrors, &day.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		day.Date = day.Day.Format(time.DateOnly)
+		days = append(days, &day)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+// GetTop returns the limit users with the highest usage from since onwards,
+// ranked by sort, which must be one of UsageSortColumns.
+func (m UsageModel) GetTop(ctx context.Context, since time.Time, sort string, limit int) ([]*UserUsage, error) {
+	orderBy, ok := usageOrderBy[sort]
+	if !ok {
+		panic("unsafe usage sort column: " + sort)
+	}
+	query := fmt.Sprintf(`
+		SELECT u.user_id, users.email, sum(u.requests), sum(u.client_errors), sum(u.server_errors), sum(u.bytes)
+		FROM user_usage u
+		JOIN users ON users.id = u.user_id
+		WHERE u.day >= $1
+		GROUP BY u.user_id, users.email
+		ORDER BY %s DESC, u.user_id
+		LIMIT $2`, orderBy)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, viewDay(since), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*UserUsage{}
+	for rows.Next() {
+		var usage UserUsage
+		err := rows.Scan(&usage.UserID, &usage.Email, &usage.Requests, &usage.ClientErrors, &usage.ServerErrors, &usage.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, &usage)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
@@ -0,0 +1,60 @@
+package data_test
+
+import (
+	"context"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/datatest"
+	"testing"
+	"time"
+)
+
+func TestUsageModel(t *testing.T) {
+	db := datatest.OpenDB(t)
+	ctx := context.Background()
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		light := datatest.InsertUser(t, m, datatest.NewActivatedUser())
+		heavy := datatest.InsertUser(t, m, datatest.NewActivatedUser())
+		now := time.Now()
+		old := now.AddDate(0, 0, -100)
+
+		// Flushes add to the day's totals rather than replacing them.
+		for range 2 {
+			err := m.Usage.RecordMany(ctx, []data.UsageCount{
+				{UserID: light.ID, At: now, Requests: 1, Bytes: 100},
+				{UserID: heavy.ID, At: now, Requests: 5, ServerErrors: 2, Bytes: 50},
+				{UserID: heavy.ID, At: old, Requests: 1},
+				{UserID: -1, At: now, Requests: 1},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		daily, err := m.Usage.GetDaily(ctx, heavy.ID, now.AddDate(0, 0, -1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(daily) != 1 || daily[0].Requests != 10 || daily[0].ServerErrors != 4 || daily[0].Bytes != 100 {
+			t.Errorf("got %+v; want one day with 10 requests, 4 server errors and 100 bytes", daily)
+		}
+
+		for sort, want := range map[string]int64{"requests": heavy.ID, "errors": heavy.ID, "bytes": light.ID} {
+			top, err := m.Usage.GetTop(ctx, now.AddDate(0, 0, -1), sort, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(top) != 1 || top[0].UserID != want {
+				t.Errorf("got %+v sorting by %s; want user %d", top, sort, want)
+			}
+		}
+
+		deleted, err := m.Usage.DeleteBefore(ctx, now.AddDate(0, 0, -90))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deleted < 1 {
+			t.Errorf("got %d rows deleted; want the old usage deleted", deleted)
+		}
+	})
+}
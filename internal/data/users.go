@@ -1,12 +1,21 @@
 package data
 
 import (
+	"bytes"
 	"context"
-	"crypto/sha256"
+	"crypto/rand"
+	"crypto/subtle"
 	"database/sql"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"github.com/ezechidc/greenlight/internal/validator"
+	"github.com/lib/pq"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
+	"math"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -19,13 +28,34 @@ type User struct {
 	Email     string    `json:"email"`
 	Password  password  `json:"-"`
 	Activated bool      `json:"activated"`
-	Version   int       `json:"-"`
+	Language  string    `json:"language"`
+	// AvatarURL is where the user's avatar can be downloaded, or empty if
+	// they haven't uploaded one.
+	AvatarURL string `json:"avatar_url,omitzero"`
+	// DeactivatedAt is set when the account has been closed. Unlike an
+	// unactivated account it can't authenticate, but its row and everything
+	// linked to it are kept so it can be reactivated.
+	DeactivatedAt *time.Time `json:"deactivated_at,omitzero"`
+	// DeactivatedBy is the ID of the user who closed the account: the user
+	// themselves, or an administrator who suspended it. Only accounts users
+	// closed themselves can be reopened with their own credentials.
+	DeactivatedBy *int64 `json:"deactivated_by,omitzero"`
+	Version       int    `json:"-"`
 }
 
 func (u *User) IsAnonymous() bool {
 	return u == AnonymousUser
 }
 
+func (u *User) IsDeactivated() bool {
+	return u.DeactivatedAt != nil
+}
+
+// IsSelfDeactivated reports whether the user closed their own account.
+func (u *User) IsSelfDeactivated() bool {
+	return u.IsDeactivated() && u.DeactivatedBy != nil && *u.DeactivatedBy == u.ID
+}
+
 type password struct {
 	plaintext *string
 	hash      []byte
@@ -35,25 +65,36 @@ var (
 	ErrDuplicateEmail = errors.New("duplicate email")
 )
 
+// SupportedLanguages lists the locales which have translated email templates.
+// The first entry is the default used when no better match is available.
+var SupportedLanguages = []string{"en", "fr"}
+
 type UserModel struct {
-	DB *sql.DB
+	DB DBTX
 }
 
 func ValidateEmail(v *validator.Validator, email string) {
-	v.Check(email != "", "email", "must be provided")
-	v.Check(validator.Matches(email, validator.EmailRX), "email", "must be a valid email address")
+	v.CheckCode(email != "", "email", "required", "must be provided")
+	v.CheckCode(validator.Matches(email, validator.EmailRX), "email", "invalid_email", "must be a valid email address")
 }
 
 func ValidatePasswordPlaintext(v *validator.Validator, password string) {
-	v.Check(password != "", "password", "must be provided")
-	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
-	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+	v.CheckCode(password != "", "password", "required", "must be provided")
+	v.CheckCode(len(password) >= 8, "password", "too_short", "must be at least 8 bytes long")
+	v.CheckCode(len(password) <= 72, "password", "too_long", "must not be more than 72 bytes long")
 }
 
-func ValidateUser(v *validator.Validator, user *User) {
-	v.Check(user.Name != "", "name", "must be provided")
-	v.Check(len(user.Name) <= 500, "name", "must not be more than 500 bytes long")
+// ValidateUserDetails checks everything but the password, so requests can be
+// rejected before paying for a password hash.
+func ValidateUserDetails(v *validator.Validator, user *User) {
+	v.CheckCode(user.Name != "", "name", "required", "must be provided")
+	v.CheckCode(len(user.Name) <= 500, "name", "too_long", "must not be more than 500 bytes long")
 	ValidateEmail(v, user.Email)
+	v.CheckCode(validator.PermittedValue(user.Language, SupportedLanguages...), "language", "unsupported_value", "unsupported language")
+}
+
+func ValidateUser(v *validator.Validator, user *User) {
+	ValidateUserDetails(v, user)
 	if user.Password.plaintext != nil {
 		ValidatePasswordPlaintext(v, *user.Password.plaintext)
 	}
@@ -62,8 +103,72 @@ func ValidateUser(v *validator.Validator, user *User) {
 	}
 }
 
-func (p *password) Set(plaintextPassword string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
+// PasswordParams holds the scheme and cost parameters used for new password
+// hashes. Hashes made with other settings still verify, and NeedsRehash
+// reports them so they can be upgraded at the next successful login.
+type PasswordParams struct {
+	Scheme        string
+	BcryptCost    int
+	Argon2Time    uint32
+	Argon2Memory  uint32
+	Argon2Threads uint8
+}
+
+// PasswordSchemes lists the supported values of PasswordParams.Scheme.
+var PasswordSchemes = []string{"bcrypt", "argon2id"}
+
+// DefaultPasswordParams returns the parameters used unless configured
+// otherwise.
+func DefaultPasswordParams() PasswordParams {
+	return PasswordParams{
+		Scheme:        "bcrypt",
+		BcryptCost:    12,
+		Argon2Time:    1,
+		Argon2Memory:  64 * 1024,
+		Argon2Threads: 2,
+	}
+}
+
+// NewPasswordParams checks the hashing settings, which usually come from the
+// command line, and returns them as PasswordParams. Values which would make
+// the hash functions fail or panic are rejected.
+func NewPasswordParams(scheme string, bcryptCost int, argon2Time, argon2Memory, argon2Threads uint) (PasswordParams, error) {
+	switch {
+	case !slices.Contains(PasswordSchemes, scheme):
+		return PasswordParams{}, fmt.Errorf("invalid password hashing scheme %q", scheme)
+	case bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost:
+		return PasswordParams{}, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	case argon2Time < 1 || argon2Time > math.MaxUint32:
+		return PasswordParams{}, errors.New("argon2id time must be at least 1")
+	case argon2Threads < 1 || argon2Threads > math.MaxUint8:
+		return PasswordParams{}, fmt.Errorf("argon2id threads must be between 1 and %d", math.MaxUint8)
+	case argon2Memory < 8*argon2Threads || argon2Memory > math.MaxUint32:
+		return PasswordParams{}, errors.New("argon2id memory must be at least 8 KiB per thread")
+	}
+	params := PasswordParams{
+		Scheme:        scheme,
+		BcryptCost:    bcryptCost,
+		Argon2Time:    uint32(argon2Time),
+		Argon2Memory:  uint32(argon2Memory),
+		Argon2Threads: uint8(argon2Threads),
+	}
+	return params, nil
+}
+
+const argon2idPrefix = "$argon2id$"
+
+// Set hashes the password using params.
+func (p *password) Set(plaintextPassword string, params PasswordParams) error {
+	var (
+		hash []byte
+		err  error
+	)
+	switch params.Scheme {
+	case "argon2id":
+		hash, err = argon2idHash(plaintextPassword, params)
+	default:
+		hash, err = bcrypt.GenerateFromPassword([]byte(plaintextPassword), params.BcryptCost)
+	}
 	if err != nil {
 		return err
 	}
@@ -73,6 +178,9 @@ func (p *password) Set(plaintextPassword string) error {
 }
 
 func (p *password) Matches(plaintextPassword string) (bool, error) {
+	if bytes.HasPrefix(p.hash, []byte(argon2idPrefix)) {
+		return argon2idMatches(p.hash, plaintextPassword)
+	}
 	err := bcrypt.CompareHashAndPassword(p.hash, []byte(plaintextPassword))
 	if err != nil {
 		switch {
@@ -85,13 +193,90 @@ func (p *password) Matches(plaintextPassword string) (bool, error) {
 	return true, nil
 }
 
-func (m UserModel) Insert(user *User) error {
+// NeedsRehash reports whether the stored hash was made with a different
+// scheme or cost than params.
+func (p *password) NeedsRehash(params PasswordParams) bool {
+	if bytes.HasPrefix(p.hash, []byte(argon2idPrefix)) {
+		if params.Scheme != "argon2id" {
+			return true
+		}
+		stored, _, _, err := argon2idDecode(p.hash)
+		return err != nil ||
+			stored.Argon2Time != params.Argon2Time ||
+			stored.Argon2Memory != params.Argon2Memory ||
+			stored.Argon2Threads != params.Argon2Threads
+	}
+	if params.Scheme != "bcrypt" {
+		return true
+	}
+	cost, err := bcrypt.Cost(p.hash)
+	return err != nil || cost != params.BcryptCost
+}
+
+// argon2idHash encodes the hash in the PHC string format, so the parameters
+// it was made with travel with it.
+func argon2idHash(plaintextPassword string, params PasswordParams) ([]byte, error) {
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+	key := argon2.IDKey([]byte(plaintextPassword), salt, params.Argon2Time, params.Argon2Memory, params.Argon2Threads, 32)
+	encoded := fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		params.Argon2Memory, params.Argon2Time, params.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+	return []byte(encoded), nil
+}
+
+func argon2idMatches(hash []byte, plaintextPassword string) (bool, error) {
+	params, salt, key, err := argon2idDecode(hash)
+	if err != nil {
+		return false, err
+	}
+	other := argon2.IDKey([]byte(plaintextPassword), salt, params.Argon2Time, params.Argon2Memory, params.Argon2Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func argon2idDecode(hash []byte) (params PasswordParams, salt, key []byte, err error) {
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 {
+		return params, nil, nil, errors.New("invalid argon2id hash")
+	}
+	var version int
+	_, err = fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil {
+		return params, nil, nil, err
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Argon2Memory, &params.Argon2Time, &params.Argon2Threads)
+	if err != nil {
+		return params, nil, nil, err
+	}
+	// argon2.IDKey panics on these, so don't trust what's stored.
+	if params.Argon2Time < 1 || params.Argon2Threads < 1 {
+		return params, nil, nil, errors.New("invalid argon2id parameters")
+	}
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	params.Scheme = "argon2id"
+	return params, salt, key, nil
+}
+
+func (m UserModel) Insert(ctx context.Context, user *User) error {
 	query := `
-		INSERT INTO users (name, email, password_hash, activated)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO users (name, email, password_hash, activated, language)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at, version`
-	args := []any{user.Name, user.Email, user.Password.hash, user.Activated}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	args := []any{user.Name, user.Email, user.Password.hash, user.Activated, user.Language}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
 	if err != nil {
@@ -105,13 +290,13 @@ func (m UserModel) Insert(user *User) error {
 	return nil
 }
 
-func (m UserModel) GetByEmail(email string) (*User, error) {
+func (m UserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, created_at, name, email, password_hash, activated, version
+		SELECT id, created_at, name, email, password_hash, activated, language, avatar_url, deactivated_at, deactivated_by, version
 		FROM users
 		WHERE email = $1`
 	var user User
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	err := m.DB.QueryRowContext(ctx, query, email).Scan(
 		&user.ID,
@@ -120,6 +305,42 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.Language,
+		&user.AvatarURL,
+		&user.DeactivatedAt,
+		&user.DeactivatedBy,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &user, nil
+}
+
+func (m UserModel) Get(ctx context.Context, id int64) (*User, error) {
+	query := `
+		SELECT id, created_at, name, email, password_hash, activated, language, avatar_url, deactivated_at, deactivated_by, version
+		FROM users
+		WHERE id = $1`
+	var user User
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Language,
+		&user.AvatarURL,
+		&user.DeactivatedAt,
+		&user.DeactivatedBy,
 		&user.Version,
 	)
 	if err != nil {
@@ -133,14 +354,14 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
-func (m UserModel) Update(user *User) error {
+func (m UserModel) Update(ctx context.Context, user *User) error {
 	query := `
 		UPDATE users
-		SET name = $1, email = $2, password_hash = $3, activated = $4, version = version + 1
-		WHERE id = $5 AND version = $6
+		SET name = $1, email = $2, password_hash = $3, activated = $4, language = $5, avatar_url = $6, deactivated_at = $7, deactivated_by = $8, version = version + 1
+		WHERE id = $9 AND version = $10
 		RETURNING version`
-	args := []any{user.Name, user.Email, user.Password.hash, user.Activated, user.ID, user.Version}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	args := []any{user.Name, user.Email, user.Password.hash, user.Activated, user.Language, user.AvatarURL, user.DeactivatedAt, user.DeactivatedBy, user.ID, user.Version}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version)
 	if err != nil {
@@ -156,20 +377,20 @@ func (m UserModel) Update(user *User) error {
 	return nil
 }
 
-func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
-	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+func (m UserModel) GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error) {
 	query := `
-		SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version
+		SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.language, users.avatar_url, users.deactivated_at, users.deactivated_by, users.version
 		FROM users
 		INNER JOIN tokens
 		ON users.id = tokens.user_id
-		WHERE tokens.hash = $1
+		WHERE tokens.hash = ANY($1)
 		AND tokens.scope = $2
-		AND tokens.expiry > $3`
-	args := []any{tokenHash[:], tokenScope, time.Now()}
+		AND tokens.expiry > $3
+		AND users.deactivated_at IS NULL`
+	args := []any{pq.ByteaArray(tokenHashes(tokenPlaintext)), tokenScope, time.Now()}
 
 	var user User
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
 		&user.ID,
@@ -178,6 +399,10 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.Language,
+		&user.AvatarURL,
+		&user.DeactivatedAt,
+		&user.DeactivatedBy,
 		&user.Version,
 	)
 	if err != nil {
@@ -190,3 +415,122 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	}
 	return &user, nil
 }
+
+// UserActivation is the outcome of activating one user with ActivateMany.
+type UserActivation struct {
+	ID               int64  `json:"id"`
+	Email            string `json:"email"`
+	AlreadyActivated bool   `json:"-"`
+}
+
+// ActivateMany activates every user matching one of the IDs or email
+// addresses, without needing their activation tokens. It returns one entry per
+// matched user, recording whether they were already active.
+func (m UserModel) ActivateMany(ctx context.Context, ids []int64, emails []string) ([]*UserActivation, error) {
+	query := `
+		WITH matched AS (
+			SELECT id, email, activated
+			FROM users
+			WHERE id = ANY($1) OR email = ANY($2::citext[])
+			FOR UPDATE
+		), updated AS (
+			UPDATE users
+			SET activated = true, version = version + 1
+			FROM matched
+			WHERE users.id = matched.id AND NOT matched.activated
+		)
+		SELECT id, email, activated
+		FROM matched
+		ORDER BY id`
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids), pq.Array(emails))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activations := []*UserActivation{}
+	for rows.Next() {
+		var activation UserActivation
+		err := rows.Scan(&activation.ID, &activation.Email, &activation.AlreadyActivated)
+		if err != nil {
+			return nil, err
+		}
+		activations = append(activations, &activation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return activations, nil
+}
+
+// UserCounts is the number of user accounts in each state. Deactivated
+// accounts aren't counted as activated or pending.
+type UserCounts struct {
+	Total       int `json:"total"`
+	Activated   int `json:"activated"`
+	Pending     int `json:"pending_activation"`
+	Deactivated int `json:"deactivated"`
+}
+
+func (m UserModel) GetCounts(ctx context.Context) (*UserCounts, error) {
+	query := `
+		SELECT count(*),
+			count(*) FILTER (WHERE activated AND deactivated_at IS NULL),
+			count(*) FILTER (WHERE NOT activated AND deactivated_at IS NULL),
+			count(*) FILTER (WHERE deactivated_at IS NOT NULL)
+		FROM users`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var counts UserCounts
+	err := m.DB.QueryRowContext(ctx, query).Scan(&counts.Total, &counts.Activated, &counts.Pending, &counts.Deactivated)
+	if err != nil {
+		return nil, err
+	}
+	return &counts, nil
+}
+
+// DailyRegistrations is the number of users who registered on a day.
+type DailyRegistrations struct {
+	Day           time.Time `json:"-"`
+	Date          string    `json:"date"`
+	Registrations int       `json:"registrations"`
+}
+
+// GetRegistrationsPerDay returns the number of registrations on each UTC day
+// since the day of since. Days without registrations are omitted.
+func (m UserModel) GetRegistrationsPerDay(ctx context.Context, since time.Time) ([]*DailyRegistrations, error) {
+	query := `
+		SELECT date_trunc('day', created_at AT TIME ZONE 'UTC') AS day, count(*)
+		FROM users
+		WHERE created_at >= $1
+		GROUP BY day
+		ORDER BY day`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, since.UTC().Truncate(24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []*DailyRegistrations{}
+	for rows.Next() {
+		var day DailyRegistrations
+		err := rows.Scan(&day.Day, &day.Registrations)
+		if err != nil {
+			return nil, err
+		}
+		day.Day = day.Day.UTC()
+		day.Date = day.Day.Format(time.DateOnly)
+		days = append(days, &day)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return days, nil
+}
@@ -0,0 +1,175 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/datatest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserModelGetForToken(t *testing.T) {
+	db := datatest.OpenDB(t)
+	ctx := context.Background()
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		user := datatest.InsertUser(t, m, datatest.NewUser())
+		token, err := m.Tokens.New(ctx, user.ID, time.Hour, data.ScopeActivation)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := m.Users.GetForToken(ctx, data.ScopeActivation, token.Plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != user.ID {
+			t.Errorf("got user %d; want %d", got.ID, user.ID)
+		}
+		match, err := got.Password.Matches(datatest.Password)
+		if err != nil || !match {
+			t.Errorf("password doesn't match after a round trip (err %v)", err)
+		}
+
+		_, err = m.Users.GetForToken(ctx, data.ScopeAuthentication, token.Plaintext)
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("got %v for the wrong scope; want ErrRecordNotFound", err)
+		}
+
+		err = m.Tokens.DeleteAllForUser(ctx, data.ScopeActivation, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = m.Users.GetForToken(ctx, data.ScopeActivation, token.Plaintext)
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			t.Errorf("got %v for a deleted token; want ErrRecordNotFound", err)
+		}
+	})
+}
+
+func TestUserModelActivateMany(t *testing.T) {
+	db := datatest.OpenDB(t)
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		inactive := datatest.InsertUser(t, m, datatest.NewUser())
+		active := datatest.InsertUser(t, m, datatest.NewActivatedUser())
+		byEmail := datatest.InsertUser(t, m, datatest.NewUser())
+
+		activations, err := m.Users.ActivateMany(context.Background(), []int64{inactive.ID, active.ID, -1}, []string{strings.ToUpper(byEmail.Email)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[int64]bool{inactive.ID: false, active.ID: true, byEmail.ID: false}
+		if len(activations) != len(want) {
+			t.Fatalf("got %d activations; want %d", len(activations), len(want))
+		}
+		for _, activation := range activations {
+			already, ok := want[activation.ID]
+			if !ok || activation.AlreadyActivated != already {
+				t.Errorf("got %+v; want already activated %t", activation, already)
+			}
+		}
+
+		for _, id := range []int64{inactive.ID, byEmail.ID} {
+			user, err := m.Users.Get(context.Background(), id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !user.Activated {
+				t.Errorf("user %d wasn't activated", id)
+			}
+		}
+	})
+}
+
+func TestUserModelGetCounts(t *testing.T) {
+	db := datatest.OpenDB(t)
+	ctx := context.Background()
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		before, err := m.Users.GetCounts(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		datatest.InsertUser(t, m, datatest.NewUser())
+		datatest.InsertUser(t, m, datatest.NewActivatedUser())
+		deactivated := datatest.InsertUser(t, m, datatest.NewActivatedUser())
+		now := time.Now()
+		deactivated.DeactivatedAt = &now
+		err = m.Users.Update(ctx, deactivated)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := m.Users.GetCounts(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := data.UserCounts{
+			Total:       before.Total + 3,
+			Activated:   before.Activated + 1,
+			Pending:     before.Pending + 1,
+			Deactivated: before.Deactivated + 1,
+		}
+		if *got != want {
+			t.Errorf("got %+v; want %+v", *got, want)
+		}
+	})
+}
+
+func TestUserModelGetRegistrationsPerDay(t *testing.T) {
+	db := datatest.OpenDB(t)
+	ctx := context.Background()
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		since := time.Now()
+		before, err := m.Users.GetRegistrationsPerDay(ctx, since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user := datatest.InsertUser(t, m, datatest.NewUser())
+		_, err = m.Tokens.New(ctx, user.ID, time.Hour, data.ScopeActivation)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		days, err := m.Users.GetRegistrationsPerDay(ctx, since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		today := time.Now().UTC().Format(time.DateOnly)
+		if len(days) != 1 || days[0].Date != today {
+			t.Fatalf("got %d days (first %+v); want just %s", len(days), days, today)
+		}
+		want := 1
+		if len(before) == 1 {
+			want += before[0].Registrations
+		}
+		if days[0].Registrations != want {
+			t.Errorf("got %d registrations; want %d", days[0].Registrations, want)
+		}
+
+		tokens, err := m.Tokens.CountActive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tokens[data.ScopeActivation] < 1 {
+			t.Errorf("got active tokens %v; want at least one activation token", tokens)
+		}
+	})
+}
+
+func TestUserModelDuplicateEmail(t *testing.T) {
+	db := datatest.OpenDB(t)
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		user := datatest.InsertUser(t, m, datatest.NewUser())
+		duplicate := datatest.NewUser(func(u *data.User) { u.Email = strings.ToUpper(user.Email) })
+		err := m.Users.Insert(context.Background(), duplicate)
+		if !errors.Is(err, data.ErrDuplicateEmail) {
+			t.Errorf("got %v; want ErrDuplicateEmail", err)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package data
+
+import (
+	"context"
+	"github.com/lib/pq"
+	"time"
+)
+
+// DailyViews is the rollup of one movie's views on one (UTC) day. Views counts
+// every request, and UniqueViewers counts each user or IP address once.
+type DailyViews struct {
+	Day           time.Time `json:"-"`
+	Date          string    `json:"date"`
+	Views         int       `json:"views"`
+	UniqueViewers int       `json:"unique_viewers"`
+}
+
+// ViewCount is a number of views of one movie by one viewer on one day.
+type ViewCount struct {
+	MovieID int64
+	Viewer  string
+	At      time.Time
+	Hits    int
+}
+
+// ViewModel records movie views in movie_views, one row per movie, viewer and
+// day, and rolls them up into movie_view_stats. The raw rows only need to be
+// kept until the day has been rolled up for the last time.
+type ViewModel struct {
+	DB DBTX
+}
+
+func viewDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// RecordMany adds a batch of view counts in one statement. Each movie, viewer
+// and day must appear at most once in the batch, and views of movies which
+// have since been deleted are skipped.
+func (m ViewModel) RecordMany(ctx context.Context, views []ViewCount) error {
+	if len(views) == 0 {
+		return nil
+	}
+	movieIDs := make([]int64, len(views))
+	viewers := make([]string, len(views))
+	days := make([]string, len(views))
+	hits := make([]int64, len(views))
+	for i, view := range views {
+		movieIDs[i] = view.MovieID
+		viewers[i] = view.Viewer
+		days[i] = viewDay(view.At).Format(time.DateOnly)
+		hits[i] = int64(view.Hits)
+	}
+	query := `
+		INSERT INTO movie_views (movie_id, viewer, day, hits)
+		SELECT v.movie_id, v.viewer, v.day, v.hits
+		FROM unnest($1::bigint[], $2::text[], $3::date[], $4::integer[]) AS v(movie_id, viewer, day, hits)
+		JOIN movies ON movies.id = v.movie_id
+		ON CONFLICT (movie_id, viewer, day) DO UPDATE SET hits = movie_views.hits + EXCLUDED.hits`
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	_, err := m.DB.ExecContext(ctx, query, pq.Array(movieIDs), pq.Array(viewers), pq.Array(days), pq.Array(hits))
+	return err
+}
+
+// Rollup recomputes the daily stats for every day from since onwards.
+func (m ViewModel) Rollup(ctx context.Context, since time.Time) error {
+	query := `
+		INSERT INTO movie_view_stats (movie_id, day, views, unique_viewers)
+		SELECT movie_id, day, sum(hits), count(*)
+		FROM movie_views
+		WHERE day >= $1
+		GROUP BY movie_id, day
+		ON CONFLICT (movie_id, day) DO UPDATE
+		SET views = EXCLUDED.views, unique_viewers = EXCLUDED.unique_viewers`
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	_, err := m.DB.ExecContext(ctx, query, viewDay(since))
+	return err
+}
+
+// DeleteRawBefore removes raw view rows for days before the given time.
+func (m ViewModel) DeleteRawBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM movie_views
+		WHERE day < $1`
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	result, err := m.DB.ExecContext(ctx, query, viewDay(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetDaily returns the rolled-up stats for a movie from since onwards, oldest
+// first. Days without views are omitted.
+func (m ViewModel) GetDaily(ctx context.Context, movieID int64, since time.Time) ([]*DailyViews, error) {
+	query := `
+		SELECT day, views, unique_viewers
+		FROM movie_view_stats
+		WHERE movie_id = $1 AND day >= $2
+		ORDER BY day`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID, viewDay(since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []*DailyViews{}
+	for rows.Next() {
+		var day DailyViews
+		err := rows.Scan(&day.Day, &day.Views, &day.UniqueViewers)
+		if err != nil {
+			return nil, err
+		}
+		day.Date = day.Day.Format(time.DateOnly)
+		days = append(days, &day)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return days, nil
+}
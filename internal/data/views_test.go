@@ -0,0 +1,72 @@
+package data_test
+
+import (
+	"context"
+	"github.com/ezechidc/greenlight/internal/data"
+	"github.com/ezechidc/greenlight/internal/data/datatest"
+	"testing"
+	"time"
+)
+
+func TestViewModelRollup(t *testing.T) {
+	db := datatest.OpenDB(t)
+	ctx := context.Background()
+
+	datatest.WithTx(t, db, func(m data.Models) {
+		movie := datatest.InsertMovie(t, m, datatest.NewMovie())
+		deleted := datatest.InsertMovie(t, m, datatest.NewMovie())
+		err := m.Movies.Delete(ctx, deleted.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		yesterday := today.AddDate(0, 0, -1)
+		batches := [][]data.ViewCount{
+			{
+				{MovieID: movie.ID, Viewer: "user:1", At: yesterday, Hits: 2},
+				{MovieID: movie.ID, Viewer: "user:1", At: today, Hits: 1},
+				{MovieID: deleted.ID, Viewer: "user:1", At: today, Hits: 1},
+			},
+			{
+				{MovieID: movie.ID, Viewer: "user:1", At: today, Hits: 3},
+				{MovieID: movie.ID, Viewer: "ip:abc", At: today, Hits: 1},
+			},
+		}
+		for _, batch := range batches {
+			err := m.Views.RecordMany(ctx, batch)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err = m.Views.Rollup(ctx, yesterday)
+		if err != nil {
+			t.Fatal(err)
+		}
+		days, err := m.Views.GetDaily(ctx, movie.ID, yesterday)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []data.DailyViews{
+			{Date: yesterday.Format(time.DateOnly), Views: 2, UniqueViewers: 1},
+			{Date: today.Format(time.DateOnly), Views: 5, UniqueViewers: 2},
+		}
+		if len(days) != len(want) {
+			t.Fatalf("got %d days; want %d", len(days), len(want))
+		}
+		for i, day := range days {
+			if day.Date != want[i].Date || day.Views != want[i].Views || day.UniqueViewers != want[i].UniqueViewers {
+				t.Errorf("day %d: got %+v; want %+v", i, *day, want[i])
+			}
+		}
+
+		removed, err := m.Views.DeleteRawBefore(ctx, today)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if removed != 1 {
+			t.Errorf("got %d raw rows deleted; want 1", removed)
+		}
+	})
+}
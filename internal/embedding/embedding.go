@@ -0,0 +1,121 @@
+// Package embedding turns text into vectors using an external embedding
+// model. OpenAI's API and Ollama are supported; other services exposing the
+// OpenAI protocol can be used by pointing the openai provider at their URL.
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+)
+
+var defaultURLs = map[string]string{
+	ProviderOpenAI: "https://api.openai.com/v1/embeddings",
+	ProviderOllama: "http://localhost:11434/api/embed",
+}
+
+// Provider embeds a batch of texts, returning one vector per text in the same
+// order. Vectors from different models can't be compared, so Model names the
+// model that produced them.
+type Provider interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Model() string
+}
+
+type httpProvider struct {
+	provider string
+	url      string
+	model    string
+	key      string
+	client   *http.Client
+}
+
+// New returns a Provider for the named provider and model. An empty url uses
+// the provider's default endpoint.
+func New(provider, url, model, key string) (Provider, error) {
+	defaultURL, ok := defaultURLs[provider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported embedding provider %q", provider)
+	}
+	if model == "" {
+		return nil, fmt.Errorf("missing model for embedding provider %q", provider)
+	}
+	if provider == ProviderOpenAI && key == "" && url == "" {
+		return nil, fmt.Errorf("missing key for embedding provider %q", provider)
+	}
+	if url == "" {
+		url = defaultURL
+	}
+	return &httpProvider{
+		provider: provider,
+		url:      url,
+		model:    model,
+		key:      key,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (p *httpProvider) Model() string {
+	return p.model
+}
+
+func (p *httpProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]any{"model": p.model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.key != "" {
+		req.Header.Set("Authorization", "Bearer "+p.key)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding provider returned status %d", resp.StatusCode)
+	}
+
+	// OpenAI wraps each vector in an object with its input index, while
+	// Ollama returns the vectors directly in input order.
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("decode embedding response: %w", err)
+	}
+
+	vectors := result.Embeddings
+	if p.provider == ProviderOpenAI {
+		vectors = make([][]float32, len(result.Data))
+		for _, d := range result.Data {
+			if d.Index < 0 || d.Index >= len(vectors) {
+				return nil, fmt.Errorf("embedding response has out of range index %d", d.Index)
+			}
+			vectors[d.Index] = d.Embedding
+		}
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("embedding provider returned %d vectors for %d texts", len(vectors), len(texts))
+	}
+	return vectors, nil
+}
@@ -0,0 +1,70 @@
+package embedding_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ezechidc/greenlight/internal/embedding"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestEmbed(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		response string
+	}{
+		// OpenAI may return the vectors out of input order.
+		{name: "openai", provider: embedding.ProviderOpenAI, response: `{"data": [{"index": 1, "embedding": [0, 1]}, {"index": 0, "embedding": [1, 0]}]}`},
+		{name: "ollama", provider: embedding.ProviderOllama, response: `{"embeddings": [[1, 0], [0, 1]]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var input struct {
+					Model string   `json:"model"`
+					Input []string `json:"input"`
+				}
+				err := json.NewDecoder(r.Body).Decode(&input)
+				if err != nil || input.Model != "test-model" || len(input.Input) != 2 {
+					t.Errorf("got request %+v (err %v); want two texts for test-model", input, err)
+				}
+				if tt.provider == embedding.ProviderOpenAI && r.Header.Get("Authorization") != "Bearer key" {
+					t.Errorf("got Authorization %q; want the API key", r.Header.Get("Authorization"))
+				}
+				w.Write([]byte(tt.response))
+			}))
+			defer ts.Close()
+
+			p, err := embedding.New(tt.provider, ts.URL, "test-model", "key")
+			if err != nil {
+				t.Fatal(err)
+			}
+			vectors, err := p.Embed(context.Background(), []string{"first", "second"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(vectors) != 2 || !slices.Equal(vectors[0], []float32{1, 0}) || !slices.Equal(vectors[1], []float32{0, 1}) {
+				t.Errorf("got %v; want [[1 0] [0 1]]", vectors)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := embedding.New("word2vec", "", "model", "")
+	if err == nil {
+		t.Error("got no error for an unsupported provider")
+	}
+	_, err = embedding.New(embedding.ProviderOpenAI, "", "text-embedding-3-small", "")
+	if err == nil {
+		t.Error("got no error for OpenAI without a key")
+	}
+	_, err = embedding.New(embedding.ProviderOllama, "", "", "")
+	if err == nil {
+		t.Error("got no error without a model")
+	}
+}
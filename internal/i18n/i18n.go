@@ -0,0 +1,96 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// DefaultLocale is the language the source messages are written in. Messages
+// are returned unchanged for this locale, and for any locale or message which
+// has no translation.
+const DefaultLocale = "en"
+
+//go:embed "catalogs"
+var catalogFS embed.FS
+
+var verbRX = regexp.MustCompile(`%[sdqv]`)
+
+// pattern matches messages built with fmt.Sprintf, such as "body contains
+// unknown key %s", so that the formatted arguments can be carried over into the
+// translated message.
+type pattern struct {
+	re          *regexp.Regexp
+	translation string
+}
+
+type catalog struct {
+	messages map[string]string
+	patterns []pattern
+}
+
+var catalogs = mustLoadCatalogs()
+
+func mustLoadCatalogs() map[string]*catalog {
+	entries, err := catalogFS.ReadDir("catalogs")
+	if err != nil {
+		panic(err)
+	}
+	catalogs := make(map[string]*catalog)
+	for _, entry := range entries {
+		b, err := catalogFS.ReadFile(path.Join("catalogs", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		c := &catalog{messages: make(map[string]string)}
+		err = json.Unmarshal(b, &c.messages)
+		if err != nil {
+			panic(entry.Name() + ": " + err.Error())
+		}
+		for message, translation := range c.messages {
+			if !verbRX.MatchString(message) {
+				continue
+			}
+			parts := verbRX.Split(message, -1)
+			for i := range parts {
+				parts[i] = regexp.QuoteMeta(parts[i])
+			}
+			c.patterns = append(c.patterns, pattern{
+				re:          regexp.MustCompile("^" + strings.Join(parts, "(.+?)") + "$"),
+				translation: translation,
+			})
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = c
+	}
+	return catalogs
+}
+
+// Translate returns message translated into locale, falling back to the
+// original message if there is no catalog entry for it.
+func Translate(locale, message string) string {
+	c, ok := catalogs[locale]
+	if !ok {
+		return message
+	}
+	if translation, ok := c.messages[message]; ok {
+		return translation
+	}
+	for _, p := range c.patterns {
+		args := p.re.FindStringSubmatch(message)
+		if args == nil {
+			continue
+		}
+		args = args[1:]
+		return verbRX.ReplaceAllStringFunc(p.translation, func(string) string {
+			if len(args) == 0 {
+				return ""
+			}
+			arg := args[0]
+			args = args[1:]
+			return arg
+		})
+	}
+	return message
+}
@@ -0,0 +1,250 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// errAny marks test cases which should fail without a specific error.
+var errAny = errors.New("any error")
+
+func decode(t *testing.T, js string) any {
+	t.Helper()
+	var value any
+	err := json.Unmarshal([]byte(js), &value)
+	if err != nil {
+		t.Fatalf("decode %s: %v", js, err)
+	}
+	return value
+}
+
+// The cases are the examples from RFC 6902 appendix A, plus a few for null
+// values and array indices.
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "add an object member",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			want:  `{"baz": "qux", "foo": "bar"}`,
+		},
+		{
+			name:  "add an array element",
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			want:  `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		{
+			name:  "remove an object member",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "remove", "path": "/baz"}]`,
+			want:  `{"foo": "bar"}`,
+		},
+		{
+			name:  "remove an array element",
+			doc:   `{"foo": ["bar", "qux", "baz"]}`,
+			patch: `[{"op": "remove", "path": "/foo/1"}]`,
+			want:  `{"foo": ["bar", "baz"]}`,
+		},
+		{
+			name:  "replace a value",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			want:  `{"baz": "boo", "foo": "bar"}`,
+		},
+		{
+			name:  "move a value",
+			doc:   `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch: `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			want:  `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{
+			name:  "move an array element",
+			doc:   `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch: `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			want:  `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		{
+			name:    "move a value into itself",
+			doc:     `{"foo": {"bar": 1}}`,
+			patch:   `[{"op": "move", "from": "/foo", "path": "/foo/bar"}]`,
+			wantErr: errAny,
+		},
+		{
+			name:  "copy a value",
+			doc:   `{"foo": {"bar": 1}}`,
+			patch: `[{"op": "copy", "from": "/foo", "path": "/baz"}, {"op": "replace", "path": "/baz/bar", "value": 2}]`,
+			want:  `{"foo": {"bar": 1}, "baz": {"bar": 2}}`,
+		},
+		{
+			name: "test a value",
+			doc:  `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			patch: `[
+				{"op": "test", "path": "/baz", "value": "qux"},
+				{"op": "test", "path": "/foo/1", "value": 2}
+			]`,
+			want: `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		},
+		{
+			name:    "test a value which doesn't match",
+			doc:     `{"baz": "qux"}`,
+			patch:   `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			wantErr: ErrTestFailed,
+		},
+		{
+			name:  "add a nested member object",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			want:  `{"foo": "bar", "child": {"grandchild": {}}}`,
+		},
+		{
+			name:    "add to a nonexistent target",
+			doc:     `{"foo": "bar"}`,
+			patch:   `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+			wantErr: errAny,
+		},
+		{
+			name:    "unrecognized op",
+			doc:     `{"foo": "bar"}`,
+			patch:   `[{"op": "foo", "path": "/baz", "value": "qux"}]`,
+			wantErr: errAny,
+		},
+		{
+			name: "~0 and ~1 escaping",
+			doc:  `{"/": 9, "~1": 10}`,
+			patch: `[
+				{"op": "test", "path": "/~01", "value": 10},
+				{"op": "replace", "path": "/~1", "value": 8}
+			]`,
+			want: `{"/": 8, "~1": 10}`,
+		},
+		{
+			name:    "compare strings and numbers",
+			doc:     `{"/": 9, "~1": 10}`,
+			patch:   `[{"op": "test", "path": "/~01", "value": "10"}]`,
+			wantErr: ErrTestFailed,
+		},
+		{
+			name:  "add an array value",
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			want:  `{"foo": ["bar", ["abc", "def"]]}`,
+		},
+		{
+			name:  "replace the whole document",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "replace", "path": "", "value": ["baz"]}]`,
+			want:  `["baz"]`,
+		},
+		{
+			name:  "add a null value",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": null}]`,
+			want:  `{"foo": "bar", "baz": null}`,
+		},
+		{
+			name:  "test a null value",
+			doc:   `{"foo": null}`,
+			patch: `[{"op": "test", "path": "/foo", "value": null}]`,
+			want:  `{"foo": null}`,
+		},
+		{
+			name:    "add without a value",
+			doc:     `{"foo": "bar"}`,
+			patch:   `[{"op": "add", "path": "/baz"}]`,
+			wantErr: errAny,
+		},
+		{
+			name:    "array index past the end",
+			doc:     `{"foo": ["bar"]}`,
+			patch:   `[{"op": "add", "path": "/foo/2", "value": "baz"}]`,
+			wantErr: errAny,
+		},
+		{
+			name:    "array index with a leading zero",
+			doc:     `{"foo": ["bar", "baz"]}`,
+			patch:   `[{"op": "remove", "path": "/foo/01"}]`,
+			wantErr: errAny,
+		},
+		{
+			name:    "remove the end of an array",
+			doc:     `{"foo": ["bar"]}`,
+			patch:   `[{"op": "remove", "path": "/foo/-"}]`,
+			wantErr: errAny,
+		},
+		{
+			name:    "pointer without a leading slash",
+			doc:     `{"foo": "bar"}`,
+			patch:   `[{"op": "remove", "path": "foo"}]`,
+			wantErr: errAny,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ops []Operation
+			err := json.Unmarshal([]byte(tt.patch), &ops)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Apply(decode(t, tt.doc), ops)
+			switch {
+			case tt.wantErr == errAny:
+				if err == nil {
+					t.Fatalf("got %v; want an error", got)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v; want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case !reflect.DeepEqual(got, decode(t, tt.want)):
+				t.Errorf("got %v; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// The cases are the examples from RFC 7386 appendix A.
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		{`{"a": "b"}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": "b"}`, `{"b": "c"}`, `{"a": "b", "b": "c"}`},
+		{`{"a": "b"}`, `{"a": null}`, `{}`},
+		{`{"a": "b", "b": "c"}`, `{"a": null}`, `{"b": "c"}`},
+		{`{"a": ["b"]}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": "c"}`, `{"a": ["b"]}`, `{"a": ["b"]}`},
+		{`{"a": {"b": "c"}}`, `{"a": {"b": "d", "c": null}}`, `{"a": {"b": "d"}}`},
+		{`{"a": [{"b": "c"}]}`, `{"a": [1]}`, `{"a": [1]}`},
+		{`["a", "b"]`, `["c", "d"]`, `["c", "d"]`},
+		{`{"a": "b"}`, `["c"]`, `["c"]`},
+		{`{"a": "foo"}`, `null`, `null`},
+		{`{"a": "foo"}`, `"bar"`, `"bar"`},
+		{`{"e": null}`, `{"a": 1}`, `{"e": null, "a": 1}`},
+		{`[1, 2]`, `{"a": "b", "c": null}`, `{"a": "b"}`},
+		{`{}`, `{"a": {"bb": {"ccc": null}}}`, `{"a": {"bb": {}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.patch, func(t *testing.T) {
+			got := MergePatch(decode(t, tt.doc), decode(t, tt.patch))
+			if !reflect.DeepEqual(got, decode(t, tt.want)) {
+				t.Errorf("MergePatch(%s, %s) = %v; want %s", tt.doc, tt.patch, got, tt.want)
+			}
+		})
+	}
+}
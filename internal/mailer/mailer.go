@@ -2,8 +2,17 @@ package mailer
 
 import (
 	"bytes"
+	"context"
 	"embed"
+	"errors"
+	"fmt"
 	"github.com/wneessen/go-mail"
+	"github.com/wneessen/go-mail/smtp"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	ht "html/template"
@@ -13,80 +22,316 @@ import (
 //go:embed "templates"
 var templateFS embed.FS
 
+// Delivery modes. In ModeLog rendered emails are written to the logger (or to
+// a directory of .eml files) instead of being sent over SMTP, and in
+// ModeDiscard they are rendered and then dropped.
+const (
+	ModeLive    = "live"
+	ModeLog     = "log"
+	ModeDiscard = "discard"
+)
+
 type Mailer struct {
 	client *mail.Client
 	sender string
+	mode   string
+	logger *slog.Logger
+	dir    string
+
+	// idle holds open, authenticated SMTP connections for reuse. It's nil
+	// when connections aren't reused.
+	idle      chan *idleConn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type idleConn struct {
+	client *smtp.Client
+	since  time.Time
+}
+
+// Idle pooled connections are checked with a NOOP every keepaliveInterval,
+// which keeps them open on servers with an idle timeout, and closed once
+// they've been idle for maxIdle.
+const (
+	keepaliveInterval = 30 * time.Second
+	maxIdle           = 5 * time.Minute
+)
+
+// TLS policies for New. TLSMandatory refuses servers without STARTTLS,
+// TLSOpportunistic uses it when offered, and TLSNone never does, which is only
+// meant for local relays and test servers.
+const (
+	TLSMandatory     = "mandatory"
+	TLSOpportunistic = "opportunistic"
+	TLSNone          = "none"
+)
+
+var tlsPolicies = map[string]mail.TLSPolicy{
+	TLSMandatory:     mail.TLSMandatory,
+	TLSOpportunistic: mail.TLSOpportunistic,
+	TLSNone:          mail.NoTLS,
 }
 
-func New(host string, port int, username, password, sender string) (*Mailer, error) {
-	client, err := mail.NewClient(
-		host,
-		mail.WithSMTPAuth(mail.SMTPAuthLogin),
+// New returns a Mailer which sends over SMTP. It authenticates only when a
+// username is given. Up to poolSize connections are kept open between
+// messages, so bursts of email don't dial and authenticate for each one; with
+// a poolSize of 0 every message gets its own connection.
+func New(host string, port int, username, password, sender, tlsPolicy string, poolSize int) (*Mailer, error) {
+	policy, ok := tlsPolicies[tlsPolicy]
+	if !ok {
+		return nil, fmt.Errorf("invalid SMTP TLS policy %q", tlsPolicy)
+	}
+	options := []mail.Option{
 		mail.WithPort(port),
-		mail.WithUsername(username),
-		mail.WithPassword(password),
-		mail.WithTimeout(5*time.Second),
-	)
+		mail.WithTLSPortPolicy(policy),
+		mail.WithTimeout(5 * time.Second),
+	}
+	if username != "" {
+		options = append(options,
+			mail.WithSMTPAuth(mail.SMTPAuthLogin),
+			mail.WithUsername(username),
+			mail.WithPassword(password),
+		)
+	}
+	client, err := mail.NewClient(host, options...)
 	if err != nil {
 		return nil, err
 	}
 	mailer := &Mailer{
 		client: client,
 		sender: sender,
+		mode:   ModeLive,
+	}
+	if poolSize > 0 {
+		mailer.idle = make(chan *idleConn, poolSize)
+		mailer.done = make(chan struct{})
+		go mailer.keepalive()
 	}
 	return mailer, nil
 }
 
-func (m *Mailer) Send(recipient string, templateFile string, data any) error {
+// keepalive checks the idle connections every keepaliveInterval until Close
+// is called.
+func (m *Mailer) keepalive() {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-m.done:
+			return
+		}
+		// Check only the connections idle now, as others may be returned
+		// while this runs.
+		for range len(m.idle) {
+			var conn *idleConn
+			select {
+			case conn = <-m.idle:
+			default:
+			}
+			if conn == nil {
+				break
+			}
+			if time.Since(conn.since) > maxIdle || conn.client.Noop() != nil {
+				m.client.CloseWithSMTPClient(conn.client)
+				continue
+			}
+			m.release(conn.client, conn.since)
+		}
+	}
+}
+
+// conn returns an idle connection if there is one, or dials a new one.
+// pooled reports which.
+func (m *Mailer) conn() (client *smtp.Client, pooled bool, err error) {
+	if m.idle != nil {
+		select {
+		case conn := <-m.idle:
+			return conn.client, true, nil
+		default:
+		}
+	}
+	client, err = m.client.DialToSMTPClientWithContext(context.Background())
+	return client, false, err
+}
+
+// release returns a connection to the pool, or closes it if the pool is full
+// or the connection isn't reused.
+func (m *Mailer) release(client *smtp.Client, since time.Time) {
+	if m.idle != nil && !m.closed() {
+		select {
+		case m.idle <- &idleConn{client: client, since: since}:
+			return
+		default:
+		}
+	}
+	m.client.CloseWithSMTPClient(client)
+}
+
+func (m *Mailer) closed() bool {
+	select {
+	case <-m.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close closes the idle connections. The Mailer can still send afterwards,
+// but each message gets its own connection.
+func (m *Mailer) Close() {
+	if m.idle == nil {
+		return
+	}
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+	for {
+		select {
+		case conn := <-m.idle:
+			m.client.CloseWithSMTPClient(conn.client)
+		default:
+			return
+		}
+	}
+}
+
+// NewLogMailer returns a Mailer which never dials SMTP. If dir is non-empty
+// each email is written there as an .eml file, otherwise it is logged.
+func NewLogMailer(logger *slog.Logger, dir, sender string) *Mailer {
+	return &Mailer{
+		sender: sender,
+		mode:   ModeLog,
+		logger: logger,
+		dir:    dir,
+	}
+}
+
+// NewDiscardMailer returns a Mailer which renders emails and then drops them.
+func NewDiscardMailer(sender string) *Mailer {
+	return &Mailer{
+		sender: sender,
+		mode:   ModeDiscard,
+	}
+}
+
+// localizedTemplate returns the locale-specific variant of templateFile (for
+// example "user_welcome.fr.tmpl"), falling back to the default English
+// template when no such variant exists.
+func localizedTemplate(templateFile, locale string) string {
+	if locale == "" || locale == "en" {
+		return templateFile
+	}
+	name, ext, _ := strings.Cut(templateFile, ".")
+	localized := name + "." + locale + "." + ext
+	if _, err := fs.Stat(templateFS, "templates/"+localized); err != nil {
+		return templateFile
+	}
+	return localized
+}
+
+// Send renders and delivers the given template, returning the Message-ID
+// header of the sent email so it can be correlated with provider logs.
+func (m *Mailer) Send(recipient, locale, templateFile string, data any) (string, error) {
+	templateFile = localizedTemplate(templateFile, locale)
 	textTmpl, err := tt.New("").ParseFS(templateFS, "templates/"+templateFile)
 	if err != nil {
-		return err
+		return "", err
 	}
 	subject := new(bytes.Buffer)
 	err = textTmpl.ExecuteTemplate(subject, "subject", data)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	plainBody := new(bytes.Buffer)
 	err = textTmpl.ExecuteTemplate(plainBody, "plainBody", data)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	htmlTmpl, err := ht.New("").ParseFS(templateFS, "templates/"+templateFile)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	htmlBody := new(bytes.Buffer)
 	err = htmlTmpl.ExecuteTemplate(htmlBody, "htmlBody", data)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	msg := mail.NewMsg()
 	err = msg.To(recipient)
 	if err != nil {
-		return err
+		return "", err
 	}
 	err = msg.From(m.sender)
 	if err != nil {
-		return err
+		return "", err
 	}
 
+	msg.SetMessageID()
 	msg.Subject(subject.String())
+	// Advertise RFC 8058 one-click unsubscribe for emails which carry an
+	// unsubscribe link, so mail clients can offer it without opening the link.
+	if templateData, ok := data.(map[string]any); ok {
+		if unsubscribeURL, ok := templateData["unsubscribeURL"].(string); ok {
+			msg.SetGenHeader(mail.HeaderListUnsubscribe, "<"+unsubscribeURL+">")
+			msg.SetGenHeader(mail.HeaderListUnsubscribePost, "List-Unsubscribe=One-Click")
+		}
+	}
 	msg.SetBodyString(mail.TypeTextPlain, plainBody.String())
 	msg.AddAlternativeString(mail.TypeTextHTML, htmlBody.String())
+
+	switch m.mode {
+	case ModeDiscard:
+		return msg.GetMessageID(), nil
+	case ModeLog:
+		if m.dir != "" {
+			name := strings.Trim(msg.GetMessageID(), "<>") + ".eml"
+			err = msg.WriteToFile(filepath.Join(m.dir, name))
+			if err != nil {
+				return "", err
+			}
+		} else {
+			m.logger.Info("email not sent (log mode)",
+				"recipient", recipient,
+				"template", templateFile,
+				"subject", subject.String(),
+				"body", plainBody.String(),
+			)
+		}
+		return msg.GetMessageID(), nil
+	}
+
 	for i := 1; i <= 3; i++ {
-		err = m.client.DialAndSend(msg)
+		var (
+			client *smtp.Client
+			pooled bool
+		)
+		client, pooled, err = m.conn()
 		if err == nil {
-			return nil
+			// SendWithSMTPClient checks the connection with a NOOP first.
+			err = m.client.SendWithSMTPClient(client, msg)
+			if err == nil {
+				m.release(client, time.Now())
+				return msg.GetMessageID(), nil
+			}
+			m.client.CloseWithSMTPClient(client)
+			// A pooled connection the server has dropped doesn't count as an
+			// attempt.
+			var sendErr *mail.SendError
+			if pooled && errors.As(err, &sendErr) && sendErr.Reason == mail.ErrConnCheck {
+				i--
+				continue
+			}
 		}
 		// If it didn't work, sleep for a short time and retry.
 		if i != 3 {
 			time.Sleep(500 * time.Millisecond)
 		}
 	}
-	return err
+	return "", err
 }
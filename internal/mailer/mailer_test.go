@@ -0,0 +1,95 @@
+package mailer
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newSMTPServer starts an SMTP server which accepts every message, returning
+// its port and a count of the connections made to it.
+func newSMTPServer(t *testing.T) (int, *atomic.Int32) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var conns atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns.Add(1)
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				fmt.Fprint(conn, "220 localhost ESMTP\r\n")
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					command := strings.ToUpper(strings.TrimSpace(line))
+					switch {
+					case strings.HasPrefix(command, "EHLO"):
+						fmt.Fprint(conn, "250-localhost\r\n250 8BITMIME\r\n")
+					case command == "DATA":
+						fmt.Fprint(conn, "354 end data with <CR><LF>.<CR><LF>\r\n")
+						for line != ".\r\n" {
+							line, err = reader.ReadString('\n')
+							if err != nil {
+								return
+							}
+						}
+						fmt.Fprint(conn, "250 OK\r\n")
+					case command == "QUIT":
+						fmt.Fprint(conn, "221 bye\r\n")
+						return
+					default:
+						fmt.Fprint(conn, "250 OK\r\n")
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, &conns
+}
+
+func TestSendReusesConnections(t *testing.T) {
+	tests := []struct {
+		name      string
+		poolSize  int
+		wantConns int32
+	}{
+		{name: "pooled", poolSize: 2, wantConns: 1},
+		{name: "not pooled", poolSize: 0, wantConns: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, conns := newSMTPServer(t)
+			m, err := New("127.0.0.1", port, "", "", "Greenlight <no-reply@greenlight.example>", TLSNone, tt.poolSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer m.Close()
+
+			for range 3 {
+				_, err := m.Send("alice@example.com", "en", "user_welcome.tmpl", map[string]any{"userID": 1, "activationToken": "TOKEN"})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := conns.Load(); got != tt.wantConns {
+				t.Errorf("got %d connections; want %d", got, tt.wantConns)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// Package pwned checks passwords against the Have I Been Pwned "Pwned
+// Passwords" range API. Only the first five characters of the password's
+// SHA-1 hash are sent, so the password itself never leaves the server.
+package pwned
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const rangeURL = "https://api.pwnedpasswords.com/range/"
+
+type Checker struct {
+	client *http.Client
+}
+
+func New(timeout time.Duration) *Checker {
+	return &Checker{client: &http.Client{Timeout: timeout}}
+}
+
+// Compromised reports whether password appears in a known breach.
+func (c *Checker) Compromised(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rangeURL+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// Padding hides the true size of the response from anyone watching.
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("pwned passwords returned status %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		// Padding entries have a count of zero.
+		if ok && candidate == suffix && count != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,125 @@
+// Package storage keeps files uploaded through the API, such as avatars. Only
+// the local backend, which writes them to a directory, is built in; object
+// stores can be added as further implementations of Store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const BackendLocal = "local"
+
+// Store saves files under slash-separated keys, such as "avatars/1.jpg", and
+// knows the public URL each can be downloaded from.
+type Store interface {
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// New returns a Store for the named backend. Files are served from baseURL,
+// which for the local backend is wherever its Handler is mounted.
+func New(backend, dir, baseURL string) (Store, error) {
+	switch backend {
+	case BackendLocal:
+		if dir == "" {
+			return nil, fmt.Errorf("missing directory for storage backend %q", backend)
+		}
+		return &Local{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage backend %q", backend)
+	}
+}
+
+// Local stores files in a directory on disk.
+type Local struct {
+	dir     string
+	baseURL string
+}
+
+func (s *Local) path(key string) (string, error) {
+	if !fs.ValidPath(key) || key == "." {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes the file to a temporary name and renames it into place, so
+// readers never see it half written.
+func (s *Local) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(name), 0o755)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(f.Name(), 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// Delete removes the file. Deleting a file which doesn't exist isn't an
+// error.
+func (s *Local) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *Local) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// Handler serves the stored files, with the request path taken as the key.
+// Directories aren't listed.
+func (s *Local) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, err := s.path(strings.TrimPrefix(path.Clean(r.URL.Path), "/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	})
+}
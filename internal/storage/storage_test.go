@@ -0,0 +1,72 @@
+package storage_test
+
+import (
+	"context"
+	"github.com/ezechidc/greenlight/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	s, err := storage.New(storage.BackendLocal, t.TempDir(), "http://localhost:4000/v1/files/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.URL("avatars/1.jpg"); got != "http://localhost:4000/v1/files/avatars/1.jpg" {
+		t.Errorf("got URL %q", got)
+	}
+
+	err = s.Put(ctx, "avatars/1.jpg", []byte("first"), "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Put(ctx, "avatars/1.jpg", []byte("second"), "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Put(ctx, "../escape", []byte("no"), "text/plain")
+	if err == nil {
+		t.Error("got no error for a key outside the directory")
+	}
+
+	handler := s.(*storage.Local).Handler()
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{path: "/avatars/1.jpg", wantStatus: http.StatusOK, wantBody: "second"},
+		{path: "/avatars/2.jpg", wantStatus: http.StatusNotFound},
+		{path: "/avatars", wantStatus: http.StatusNotFound},
+		{path: "/", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rr.Code != tt.wantStatus || (tt.wantBody != "" && rr.Body.String() != tt.wantBody) {
+			t.Errorf("%s: got %d %q; want %d %q", tt.path, rr.Code, rr.Body.String(), tt.wantStatus, tt.wantBody)
+		}
+	}
+
+	err = s.Delete(ctx, "avatars/1.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Delete(ctx, "avatars/1.jpg")
+	if err != nil {
+		t.Errorf("got error %v deleting a missing file", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := storage.New("s3", "", "")
+	if err == nil {
+		t.Error("got no error for an unsupported backend")
+	}
+	_, err = storage.New(storage.BackendLocal, "", "")
+	if err == nil {
+		t.Error("got no error without a directory")
+	}
+}
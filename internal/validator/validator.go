@@ -1,6 +1,7 @@
 package validator
 
 import (
+	"net/url"
 	"regexp"
 	"slices"
 )
@@ -9,8 +10,20 @@ var (
 	EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 )
 
+// CodeInvalid is used for errors added without an explicit code.
+const CodeInvalid = "invalid"
+
+// FieldError is the machine-readable form of a validation failure, letting
+// clients map errors to UI fields without matching on the message text.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 type Validator struct {
-	Errors map[string]string `json:"errors"`
+	Errors      map[string]string `json:"errors"`
+	FieldErrors []FieldError      `json:"field_errors"`
 }
 
 func New() *Validator {
@@ -22,14 +35,23 @@ func (v *Validator) Valid() bool {
 }
 
 func (v *Validator) Check(ok bool, key, message string) {
+	v.CheckCode(ok, key, CodeInvalid, message)
+}
+
+func (v *Validator) CheckCode(ok bool, key, code, message string) {
 	if !ok {
-		v.AddError(key, message)
+		v.AddErrorCode(key, code, message)
 	}
 }
 
 func (v *Validator) AddError(key, message string) {
+	v.AddErrorCode(key, CodeInvalid, message)
+}
+
+func (v *Validator) AddErrorCode(key, code, message string) {
 	if _, exists := v.Errors[key]; !exists {
 		v.Errors[key] = message
+		v.FieldErrors = append(v.FieldErrors, FieldError{Field: key, Code: code, Message: message})
 	}
 }
 
@@ -48,3 +70,13 @@ func Unique[T comparable](values []T) bool {
 	}
 	return len(values) == len(uniqueValues)
 }
+
+// WebURL reports whether value is an absolute http or https URL with a host
+// and no credentials.
+func WebURL(value string) bool {
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.User == nil
+}
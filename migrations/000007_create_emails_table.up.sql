@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS emails (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    recipient citext NOT NULL,
+    template text NOT NULL,
+    status text NOT NULL,
+    message_id text NOT NULL DEFAULT '',
+    error text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS emails_recipient_idx ON emails (recipient);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id bigint PRIMARY KEY REFERENCES users ON DELETE CASCADE,
+    weekly_digest bool NOT NULL DEFAULT false,
+    review_replies bool NOT NULL DEFAULT true,
+    moderation_updates bool NOT NULL DEFAULT true,
+    version integer NOT NULL DEFAULT 1
+);
@@ -0,0 +1,2 @@
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS last_digest_at;
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS favorite_genres;
@@ -0,0 +1,2 @@
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS favorite_genres text[] NOT NULL DEFAULT '{}';
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS last_digest_at timestamp(0) with time zone;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS movie_views (
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    viewer text NOT NULL,
+    day date NOT NULL,
+    hits integer NOT NULL DEFAULT 1,
+    PRIMARY KEY (movie_id, viewer, day)
+);
+
+CREATE INDEX IF NOT EXISTS movie_views_day_idx ON movie_views (day);
+
+CREATE TABLE IF NOT EXISTS movie_view_stats (
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    day date NOT NULL,
+    views integer NOT NULL,
+    unique_viewers integer NOT NULL,
+    PRIMARY KEY (movie_id, day)
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS invitations (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    email citext NOT NULL,
+    role text NOT NULL,
+    hash bytea NOT NULL UNIQUE,
+    invited_by bigint REFERENCES users ON DELETE SET NULL,
+    expiry timestamp(0) with time zone NOT NULL,
+    accepted_at timestamp(0) with time zone,
+    accepted_by bigint REFERENCES users ON DELETE SET NULL,
+    revoked_at timestamp(0) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS invitations_email_idx ON invitations (email);
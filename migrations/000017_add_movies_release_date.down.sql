@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS movies_release_date_idx;
+ALTER TABLE movies DROP COLUMN IF EXISTS release_date;
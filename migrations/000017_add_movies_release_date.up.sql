@@ -0,0 +1,2 @@
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS release_date date;
+CREATE INDEX IF NOT EXISTS movies_release_date_idx ON movies (release_date);
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS movie_revisions (
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    version integer NOT NULL,
+    revised_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    title text NOT NULL,
+    year integer NOT NULL,
+    runtime integer NOT NULL,
+    genres text[] NOT NULL,
+    release_date date,
+    PRIMARY KEY (movie_id, version)
+);
+
+-- Every version of a movie is recorded as it's written, however it's written.
+CREATE OR REPLACE FUNCTION record_movie_revision() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO movie_revisions (movie_id, version, title, year, runtime, genres, release_date)
+    VALUES (NEW.id, NEW.version, NEW.title, NEW.year, NEW.runtime, NEW.genres, NEW.release_date);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS movies_record_revision ON movies;
+CREATE TRIGGER movies_record_revision
+    AFTER INSERT OR UPDATE ON movies
+    FOR EACH ROW EXECUTE FUNCTION record_movie_revision();
+
+-- Existing movies start their history at their current version.
+INSERT INTO movie_revisions (movie_id, version, title, year, runtime, genres, release_date)
+SELECT id, version, title, year, runtime, genres, release_date FROM movies
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS movies_imdb_id_idx;
+ALTER TABLE movies DROP COLUMN IF EXISTS imdb_id;
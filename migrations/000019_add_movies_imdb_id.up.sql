@@ -0,0 +1,2 @@
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS imdb_id integer;
+CREATE UNIQUE INDEX IF NOT EXISTS movies_imdb_id_idx ON movies (imdb_id);
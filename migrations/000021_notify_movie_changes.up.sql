@@ -0,0 +1,15 @@
+-- Every statement which changes movies notifies the API instances, so they
+-- can drop what they've cached about the catalog. The trigger fires once per
+-- statement rather than per row, so bulk loads send a single notification,
+-- and PostgreSQL folds identical notifications within a transaction.
+CREATE OR REPLACE FUNCTION notify_movie_changes() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('movie_changes', TG_OP);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS movies_notify_changes ON movies;
+CREATE TRIGGER movies_notify_changes
+    AFTER INSERT OR UPDATE OR DELETE ON movies
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_movie_changes();
@@ -0,0 +1,2 @@
+-- Needs the pg_trgm extension, created by init/init.sql as a superuser.
+CREATE INDEX IF NOT EXISTS movies_title_trgm_idx ON movies USING GIN (title gin_trgm_ops);
@@ -0,0 +1,11 @@
+-- Needs the pgvector extension, created by init/init.sql as a superuser.
+-- The vector column is left unsized because its dimensions depend on the
+-- configured embedding model, so similar movies are found by an exact scan
+-- rather than an approximate index.
+CREATE TABLE IF NOT EXISTS movie_embeddings (
+    movie_id bigint PRIMARY KEY REFERENCES movies ON DELETE CASCADE,
+    movie_version integer NOT NULL,
+    model text NOT NULL,
+    embedding vector NOT NULL,
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_usage (
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    day date NOT NULL,
+    requests bigint NOT NULL DEFAULT 0,
+    client_errors bigint NOT NULL DEFAULT 0,
+    server_errors bigint NOT NULL DEFAULT 0,
+    bytes bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS user_usage_day_idx ON user_usage (day);
@@ -0,0 +1,2 @@
+DELETE FROM permissions WHERE code = 'movies:review';
+DROP TABLE IF EXISTS movie_suggestions;
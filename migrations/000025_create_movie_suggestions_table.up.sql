@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS movie_suggestions (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    movie_version integer NOT NULL,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    changes jsonb NOT NULL,
+    comment text NOT NULL DEFAULT '',
+    status text NOT NULL DEFAULT 'pending',
+    reviewed_by bigint REFERENCES users ON DELETE SET NULL,
+    reviewed_at timestamp(0) with time zone,
+    reason text NOT NULL DEFAULT '',
+    applied_version integer
+);
+
+CREATE INDEX IF NOT EXISTS movie_suggestions_movie_id_idx ON movie_suggestions (movie_id);
+CREATE INDEX IF NOT EXISTS movie_suggestions_pending_idx ON movie_suggestions (created_at) WHERE status = 'pending';
+
+-- Moderators and admins, who hold admin:read, can review suggestions, matching
+-- the roles new invitations grant. Editors are given movies:review on its own.
+INSERT INTO permissions (code)
+SELECT 'movies:review'
+WHERE NOT EXISTS (SELECT 1 FROM permissions WHERE code = 'movies:review');
+
+INSERT INTO users_permissions (user_id, permission_id)
+SELECT up.user_id, (SELECT id FROM permissions WHERE code = 'movies:review')
+FROM users_permissions up
+JOIN permissions p ON p.id = up.permission_id
+WHERE p.code = 'admin:read'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS movie_titles;
+ALTER TABLE movies DROP COLUMN IF EXISTS original_title;
@@ -0,0 +1,15 @@
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS original_title text NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS movie_titles (
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    locale text NOT NULL,
+    title text NOT NULL,
+    PRIMARY KEY (movie_id, locale)
+);
+
+-- Title searches match the original and localized titles as well, so they
+-- get the same full-text and trigram indexes as movies.title.
+CREATE INDEX IF NOT EXISTS movies_original_title_idx ON movies USING GIN (to_tsvector('simple', original_title));
+CREATE INDEX IF NOT EXISTS movies_original_title_trgm_idx ON movies USING GIN (original_title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS movie_titles_title_idx ON movie_titles USING GIN (to_tsvector('simple', title));
+CREATE INDEX IF NOT EXISTS movie_titles_title_trgm_idx ON movie_titles USING GIN (title gin_trgm_ops);
@@ -0,0 +1,13 @@
+CREATE OR REPLACE FUNCTION record_movie_revision() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO movie_revisions (movie_id, version, title, year, runtime, genres, release_date)
+    VALUES (NEW.id, NEW.version, NEW.title, NEW.year, NEW.runtime, NEW.genres, NEW.release_date);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE movie_revisions DROP COLUMN IF EXISTS certification;
+
+DROP INDEX IF EXISTS movies_certification_idx;
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_certification_check;
+ALTER TABLE movies DROP COLUMN IF EXISTS certification;
@@ -0,0 +1,13 @@
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS certification text NOT NULL DEFAULT '';
+ALTER TABLE movies ADD CONSTRAINT movies_certification_check CHECK (certification IN ('', 'G', 'PG', 'PG-13', 'R', 'NC-17'));
+CREATE INDEX IF NOT EXISTS movies_certification_idx ON movies (certification);
+
+ALTER TABLE movie_revisions ADD COLUMN IF NOT EXISTS certification text NOT NULL DEFAULT '';
+
+CREATE OR REPLACE FUNCTION record_movie_revision() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO movie_revisions (movie_id, version, title, year, runtime, genres, release_date, certification)
+    VALUES (NEW.id, NEW.version, NEW.title, NEW.year, NEW.runtime, NEW.genres, NEW.release_date, NEW.certification);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS movie_media (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    type text NOT NULL,
+    provider text NOT NULL,
+    url text NOT NULL,
+    language text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS movie_media_movie_id_idx ON movie_media (movie_id);
@@ -0,0 +1,7 @@
+-- Posters are kept apart from movies so that background resizing doesn't
+-- create a movie revision or conflict with edits.
+CREATE TABLE IF NOT EXISTS movie_posters (
+    movie_id bigint PRIMARY KEY REFERENCES movies ON DELETE CASCADE,
+    poster jsonb NOT NULL,
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+-- deactivated_by records who closed an account, so that accounts suspended by
+-- an administrator can't be reopened by their owners. It's left NULL for
+-- accounts closed before it was added, as there's no telling who closed them;
+-- only an administrator can reactivate those.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_by bigint REFERENCES users ON DELETE SET NULL;
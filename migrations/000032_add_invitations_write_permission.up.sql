@@ -0,0 +1,23 @@
+-- invitations:write lets users send and revoke invitations, which used to
+-- need only admin:read. It's given to admins, who hold admin:write, and to
+-- editors, who hold movies:review without admin:read.
+INSERT INTO permissions (code)
+SELECT 'invitations:write'
+WHERE NOT EXISTS (SELECT 1 FROM permissions WHERE code = 'invitations:write');
+
+INSERT INTO users_permissions (user_id, permission_id)
+SELECT u.id, (SELECT id FROM permissions WHERE code = 'invitations:write')
+FROM users u
+WHERE EXISTS (
+    SELECT 1 FROM users_permissions up JOIN permissions p ON p.id = up.permission_id
+    WHERE up.user_id = u.id AND p.code = 'admin:write'
+) OR (
+    EXISTS (
+        SELECT 1 FROM users_permissions up JOIN permissions p ON p.id = up.permission_id
+        WHERE up.user_id = u.id AND p.code = 'movies:review'
+    ) AND NOT EXISTS (
+        SELECT 1 FROM users_permissions up JOIN permissions p ON p.id = up.permission_id
+        WHERE up.user_id = u.id AND p.code = 'admin:read'
+    )
+)
+ON CONFLICT DO NOTHING;
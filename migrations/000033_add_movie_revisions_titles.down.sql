@@ -0,0 +1,10 @@
+CREATE OR REPLACE FUNCTION record_movie_revision() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO movie_revisions (movie_id, version, title, year, runtime, genres, release_date, certification)
+    VALUES (NEW.id, NEW.version, NEW.title, NEW.year, NEW.runtime, NEW.genres, NEW.release_date, NEW.certification);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE movie_revisions DROP COLUMN IF EXISTS titles;
+ALTER TABLE movie_revisions DROP COLUMN IF EXISTS original_title;
@@ -0,0 +1,20 @@
+-- Revisions record the original and localized titles too. Revisions recorded
+-- before this have NULL for both, as what they were isn't known, and are read
+-- as the movie's current values so history and reverts leave them alone.
+ALTER TABLE movie_revisions ADD COLUMN IF NOT EXISTS original_title text;
+ALTER TABLE movie_revisions ADD COLUMN IF NOT EXISTS titles jsonb;
+
+-- The trigger fires after the whole statement which changed the movie, so it
+-- sees the movie_titles rows written alongside it by MovieModel.Insert and
+-- MovieModel.Update.
+CREATE OR REPLACE FUNCTION record_movie_revision() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO movie_revisions (movie_id, version, title, original_title, titles, year, runtime, genres, release_date, certification)
+    VALUES (
+        NEW.id, NEW.version, NEW.title, NEW.original_title,
+        COALESCE((SELECT jsonb_object_agg(mt.locale, mt.title) FROM movie_titles mt WHERE mt.movie_id = NEW.id), '{}'),
+        NEW.year, NEW.runtime, NEW.genres, NEW.release_date, NEW.certification
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
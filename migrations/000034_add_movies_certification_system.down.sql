@@ -0,0 +1,25 @@
+CREATE OR REPLACE FUNCTION record_movie_revision() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO movie_revisions (movie_id, version, title, original_title, titles, year, runtime, genres, release_date, certification)
+    VALUES (
+        NEW.id, NEW.version, NEW.title, NEW.original_title,
+        COALESCE((SELECT jsonb_object_agg(mt.locale, mt.title) FROM movie_titles mt WHERE mt.movie_id = NEW.id), '{}'),
+        NEW.year, NEW.runtime, NEW.genres, NEW.release_date, NEW.certification
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE movie_revisions DROP COLUMN IF EXISTS certification_system;
+
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_certification_check;
+
+-- Ratings from other systems can't be kept without their system.
+ALTER TABLE movies DISABLE TRIGGER movies_record_revision;
+UPDATE movies SET certification = '' WHERE certification_system NOT IN ('', 'MPA');
+ALTER TABLE movies ENABLE TRIGGER movies_record_revision;
+
+DROP INDEX IF EXISTS movies_certification_idx;
+CREATE INDEX IF NOT EXISTS movies_certification_idx ON movies (certification);
+ALTER TABLE movies ADD CONSTRAINT movies_certification_check CHECK (certification IN ('', 'G', 'PG', 'PG-13', 'R', 'NC-17'));
+ALTER TABLE movies DROP COLUMN IF EXISTS certification_system;
@@ -0,0 +1,32 @@
+-- Certifications belong to a rating system. Existing certifications were all
+-- MPA ratings. The backfill must not record revisions, as it doesn't bump the
+-- movies' versions.
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS certification_system text NOT NULL DEFAULT '';
+ALTER TABLE movies DISABLE TRIGGER movies_record_revision;
+UPDATE movies SET certification_system = 'MPA' WHERE certification <> '';
+ALTER TABLE movies ENABLE TRIGGER movies_record_revision;
+
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_certification_check;
+ALTER TABLE movies ADD CONSTRAINT movies_certification_check CHECK (
+    (certification_system = '' AND certification = '')
+    OR (certification_system = 'BBFC' AND certification IN ('U', 'PG', '12A', '12', '15', '18', 'R18'))
+    OR (certification_system = 'FSK' AND certification IN ('0', '6', '12', '16', '18'))
+    OR (certification_system = 'MPA' AND certification IN ('G', 'PG', 'PG-13', 'R', 'NC-17'))
+);
+DROP INDEX IF EXISTS movies_certification_idx;
+CREATE INDEX IF NOT EXISTS movies_certification_idx ON movies (certification, certification_system);
+
+ALTER TABLE movie_revisions ADD COLUMN IF NOT EXISTS certification_system text NOT NULL DEFAULT '';
+UPDATE movie_revisions SET certification_system = 'MPA' WHERE certification <> '';
+
+CREATE OR REPLACE FUNCTION record_movie_revision() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO movie_revisions (movie_id, version, title, original_title, titles, year, runtime, genres, release_date, certification_system, certification)
+    VALUES (
+        NEW.id, NEW.version, NEW.title, NEW.original_title,
+        COALESCE((SELECT jsonb_object_agg(mt.locale, mt.title) FROM movie_titles mt WHERE mt.movie_id = NEW.id), '{}'),
+        NEW.year, NEW.runtime, NEW.genres, NEW.release_date, NEW.certification_system, NEW.certification
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;